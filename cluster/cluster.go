@@ -150,10 +150,22 @@ type Cluster struct {
 	idSchedulerRollingRestart     cron.EntryID                `json:"-"`
 	idSchedulerDbsjobsSsh         cron.EntryID                `json:"-"`
 	idSchedulerRollingReprov      cron.EntryID                `json:"-"`
+	idSchedulerConsistencyCheck   cron.EntryID                `json:"-"`
+	ConsistencyCheckResults       []ConsistencyCheckResult    `json:"-"`
 	WaitingRejoin                 int                         `json:"waitingRejoin"`
 	WaitingSwitchover             int                         `json:"waitingSwitchover"`
 	WaitingFailover               int                         `json:"waitingFailover"`
 	DiffVariables                 []VariableDiff              `json:"diffVariables"`
+	MaintenanceWindows            []MaintenanceWindow         `json:"maintenanceWindows"`
+	MetricSink                    MetricSink                  `json:"-"`
+	WriteCircuitBreaker           WriteCircuitBreakerAction   `json:"-"`
+	IsWriteCircuitOpen            bool                        `json:"isWriteCircuitOpen"`
+	statusFileTime                time.Time                   `json:"-"`
+	replicationStream             replicationStream           `json:"-"`
+	KillPolicies                  []KillPolicy                `json:"killPolicies"`
+	userQuotas                    map[string]UserQuota        `json:"-"`
+	backupSources                 map[string]BackupSource     `json:"-"`
+	backupSourcesMutex            sync.Mutex                  `json:"-"`
 	sync.Mutex
 }
 
@@ -219,6 +231,135 @@ type VariableDiff struct {
 	DiffValues   []Diff `json:"diffValues"`
 }
 
+type MaintenanceWindow struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+type HealthScore struct {
+	Score      int                    `json:"score"`
+	Components []HealthScoreComponent `json:"components"`
+}
+
+// HealthScoreComponent is a signal of the health score, penalty goes from 0 healthy to 1 fully degraded
+type HealthScoreComponent struct {
+	Name    string  `json:"name"`
+	Weight  int     `json:"weight"`
+	Penalty float64 `json:"penalty"`
+	Detail  string  `json:"detail"`
+}
+
+// ReplicaReadWeight is the share of reads of a slave, weights of the eligible slaves sum to 100
+type ReplicaReadWeight struct {
+	URL      string `json:"url"`
+	Delay    int64  `json:"delay"`
+	Eligible bool   `json:"eligible"`
+	Weight   int    `json:"weight"`
+	Reason   string `json:"reason"`
+}
+
+// GroupReplicationSummary rolls up the clusters of a cluster-group, clusters of its sub groups included, a cluster
+// is healthy when its master is up, it is not in failover and it has no open error
+type GroupReplicationSummary struct {
+	Group           string   `json:"group"`
+	Clusters        []string `json:"clusters"`
+	Healthy         int      `json:"healthy"`
+	MasterFailed    int      `json:"masterFailed"`
+	InFailover      int      `json:"inFailover"`
+	MaxDelay        int64    `json:"maxDelay"`
+	MaxDelayCluster string   `json:"maxDelayCluster"`
+	DelayUnknown    []string `json:"delayUnknown"`
+}
+
+// BackupSource is the server a backup file was started from with its replication delay at start, Running until
+// the backup or the physical backup stream ends, Failed when it ended on error
+type BackupSource struct {
+	Server       string    `json:"server"`
+	Type         string    `json:"type"`
+	Start        time.Time `json:"start"`
+	Delay        int64     `json:"delay"`
+	DelayUnknown bool      `json:"delayUnknown"`
+	Running      bool      `json:"running"`
+	Failed       bool      `json:"failed"`
+}
+
+// BackupFreshness is the newest backup found in the backup directories of the cluster, SourceKnown is false
+// for a backup not started by this monitor, its source delay is then unknown. Candidate is the server the next
+// backup would be taken from
+type BackupFreshness struct {
+	Found          bool      `json:"found"`
+	Server         string    `json:"server"`
+	Type           string    `json:"type"`
+	File           string    `json:"file"`
+	End            time.Time `json:"end"`
+	Age            int64     `json:"age"`
+	MaxAge         int64     `json:"maxAge"`
+	Stale          bool      `json:"stale"`
+	SourceKnown    bool      `json:"sourceKnown"`
+	SourceDelay    int64     `json:"sourceDelay"`
+	SourceCaughtUp bool      `json:"sourceCaughtUp"`
+	Candidate      string    `json:"candidate"`
+}
+
+type PendingCookie struct {
+	URL     string    `json:"url"`
+	Created time.Time `json:"created"`
+}
+
+type ServerMaintenance struct {
+	URL     string   `json:"url"`
+	Reasons []string `json:"reasons"`
+}
+
+type GtidStrictnessIssue struct {
+	URL         string   `json:"url"`
+	Strict      bool     `json:"strict"`
+	Statements  []string `json:"statements"`
+	ConfigLines []string `json:"configLines"`
+}
+
+type ReplicationThroughput struct {
+	URL             string  `json:"url"`
+	Delay           int64   `json:"delay"`
+	ApplyRate       float64 `json:"applyRate"`
+	MasterWriteRate float64 `json:"masterWriteRate"`
+}
+
+type ConsistencyCheckResult struct {
+	Time            time.Time `json:"time"`
+	Schema          string    `json:"schema"`
+	Table           string    `json:"table"`
+	URL             string    `json:"url"`
+	Chunks          int       `json:"chunks"`
+	DivergentChunks int       `json:"divergentChunks"`
+}
+
+type ReadWriteSplitAdvice struct {
+	Reads            int64    `json:"reads"`
+	Writes           int64    `json:"writes"`
+	ReadRatio        float64  `json:"readRatio"`
+	OffloadableReads int64    `json:"offloadableReads"`
+	OffloadRatio     float64  `json:"offloadRatio"`
+	ReadPool         []string `json:"readPool"`
+}
+
+type ReplicationSourceAdvice struct {
+	URL          string `json:"url"`
+	FailedSource string `json:"failedSource"`
+	Source       string `json:"source"`
+	Reason       string `json:"reason"`
+	Mode         string `json:"mode"`
+	ChangeMaster string `json:"changeMaster"`
+}
+
+type VersionSkew struct {
+	URL          string `json:"url"`
+	Version      string `json:"version"`
+	Lagging      bool   `json:"lagging"`
+	Incompatible bool   `json:"incompatible"`
+	Reason       string `json:"reason"`
+}
+
 const (
 	stateClusterStart string = "Running starting"
 	stateClusterDown  string = "Running cluster down"
@@ -230,6 +371,10 @@ const (
 	ConstJobCreateFile string = "JOB_O_CREATE_FILE"
 	ConstJobAppendFile string = "JOB_O_APPEND_FILE"
 )
+const (
+	ReadWeightDecayLinear      string = "linear"
+	ReadWeightDecayExponential string = "exponential"
+)
 const (
 	ConstMonitorActif   string = "A"
 	ConstMonitorStandby string = "S"
@@ -321,6 +466,17 @@ func (cluster *Cluster) Init(conf config.Config, cfgGroup string, tlog *s18log.T
 		logsqlgen.WithError(err).Error("Can't init general sql log file")
 	}
 	logsqlgen.AddHook(hookgen)
+	if cluster.Conf.MonitorReplicationDelaySinkFile != "" {
+		sink, err := newFileMetricSink(cluster.Conf.MonitorReplicationDelaySinkFile)
+		if err != nil {
+			cluster.LogPrintf(LvlErr, "Can't init replication delay sink file %s: %s", cluster.Conf.MonitorReplicationDelaySinkFile, err)
+		} else {
+			cluster.MetricSink = sink
+		}
+	}
+	cluster.WriteCircuitBreaker = newWriteCircuitBreakerAction(cluster.Conf.WriteCircuitBreakerAction)
+	cluster.LoadKillPolicies()
+	cluster.LoadUserQuotas()
 	cluster.LoadAPIUsers()
 	// createKeys do nothing yet
 	cluster.createKeys()
@@ -379,6 +535,7 @@ func (cluster *Cluster) initScheduler() {
 		cluster.SetSchedulerSlaRotate()
 		cluster.SetSchedulerRollingRestart()
 		cluster.SetSchedulerDbJobsSsh()
+		cluster.SetSchedulerConsistencyCheck()
 		cluster.scheduler.Start()
 	}
 
@@ -444,12 +601,16 @@ func (cluster *Cluster) Run() {
 						cluster.MonitorQueryRules()
 						cluster.MonitorVariablesDiff()
 						cluster.ResticFetchRepo()
+						cluster.CheckMisdirectedReplicas()
+						cluster.CheckSchemaCharsets()
 
 					} else {
 						cluster.sme.PreserveState("WARN0093")
 						cluster.sme.PreserveState("WARN0084")
 						cluster.sme.PreserveState("WARN0095")
 						cluster.sme.PreserveState("ERR00082")
+						cluster.sme.PreserveState("WARN0102")
+						cluster.sme.PreserveState("WARN0103")
 					}
 					if cluster.sme.GetHeartbeats()%36000 == 0 {
 						cluster.ResticPurgeRepo()
@@ -459,6 +620,18 @@ func (cluster *Cluster) Run() {
 				}
 
 				wg.Wait()
+				// the checks below read the servers refreshed by TopologyDiscover
+				if cluster.Conf.MonitorProcessList {
+					cluster.ApplyKillPolicies()
+					cluster.CheckUserQuotas()
+				}
+				cluster.CheckWriteCircuitBreaker()
+				cluster.CheckVersionSkew()
+				cluster.CheckClusterStatusFile()
+				cluster.CheckMasterConsistency()
+				cluster.PublishReplicationStream()
+				cluster.ClearCompletedRestartCookies()
+				cluster.CheckBackupFreshness()
 
 				cluster.IsFailable = cluster.GetStatus()
 				// CheckFailed trigger failover code if passing all false positiv and constraints
@@ -479,7 +652,7 @@ func (cluster *Cluster) StateProcessing() {
 	if !cluster.sme.IsInFailover() {
 		// trigger action on resolving states
 		cstates := cluster.sme.GetResolvedStates()
-		mybcksrv := cluster.GetBackupServer()
+		mybcksrv, _ := cluster.GetBackupServer()
 		master := cluster.GetMaster()
 		for _, s := range cstates {
 			servertoreseed := cluster.GetServerFromURL(s.ServerUrl)
@@ -553,11 +726,13 @@ func (cluster *Cluster) Stop() {
 func (cluster *Cluster) Save() error {
 
 	type Save struct {
-		Servers    string      `json:"servers"`
-		Crashes    crashList   `json:"crashes"`
-		SLA        state.Sla   `json:"sla"`
-		SLAHistory []state.Sla `json:"slaHistory"`
-		IsAllDbUp  bool        `json:"provisioned"`
+		Servers            string              `json:"servers"`
+		Crashes            crashList           `json:"crashes"`
+		SLA                state.Sla           `json:"sla"`
+		SLAHistory         []state.Sla         `json:"slaHistory"`
+		IsAllDbUp          bool                `json:"provisioned"`
+		MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows"`
+		KillPolicies       []KillPolicy        `json:"killPolicies"`
 	}
 
 	var clsave Save
@@ -566,6 +741,8 @@ func (cluster *Cluster) Save() error {
 	clsave.SLA = cluster.sme.GetSla()
 	clsave.IsAllDbUp = cluster.IsAllDbUp
 	clsave.SLAHistory = cluster.SLAHistory
+	clsave.MaintenanceWindows = cluster.GetMaintenanceWindows()
+	clsave.KillPolicies = cluster.GetKillPolicies()
 
 	saveJson, _ := json.MarshalIndent(clsave, "", "\t")
 	err := ioutil.WriteFile(cluster.Conf.WorkingDir+"/"+cluster.Name+"/clusterstate.json", saveJson, 0644)
@@ -616,6 +793,7 @@ func (cluster *Cluster) InitAgent(conf config.Config) {
 
 func (cluster *Cluster) ReloadConfig(conf config.Config) {
 	cluster.Conf = conf
+	cluster.LoadUserQuotas()
 	cluster.sme.SetFailoverState()
 	cluster.newServerList()
 	cluster.newProxyList()
@@ -702,6 +880,9 @@ func (cluster *Cluster) Close() {
 	for _, server := range cluster.Servers {
 		defer server.Conn.Close()
 	}
+	if cluster.MetricSink != nil {
+		cluster.MetricSink.Close()
+	}
 }
 
 func (cluster *Cluster) ResetFailoverCtr() {
@@ -20,6 +20,12 @@ type APIUser struct {
 	Grants   map[string]bool `json:"grants"`
 }
 
+// IsProcessListRedacted returns true when monitoring-processlist-redact is set and the user does not have
+// the db-show-process-literals grant
+func (cluster *Cluster) IsProcessListRedacted(strUser string) bool {
+	return cluster.Conf.MonitorProcessListRedact && !cluster.APIUsers[strUser].Grants[config.GrantDBShowProcessLiterals]
+}
+
 func (cluster *Cluster) IsValidACL(strUser string, strPassword string, URL string) bool {
 	if user, ok := cluster.APIUsers[strUser]; ok {
 		if user.Password == strPassword {
@@ -180,12 +186,24 @@ func (cluster *Cluster) IsURLPassDatabasesACL(strUser string, URL string) bool {
 		if strings.Contains(URL, "actions/skip-replication-event") {
 			return true
 		}
+		if strings.Contains(URL, "actions/skip-replication-error") {
+			return true
+		}
 		if strings.Contains(URL, "actions/reset-master") {
 			return true
 		}
 		if strings.Contains(URL, "actions/reset-slave-all") {
 			return true
 		}
+		if strings.Contains(URL, "/replication-apply-bottleneck") {
+			return true
+		}
+		if strings.Contains(URL, "actions/wait-catch-up") {
+			return true
+		}
+		if strings.Contains(URL, "/replication-threads") {
+			return true
+		}
 	}
 	if cluster.APIUsers[strUser].Grants[config.GrantDBBackup] {
 		if strings.Contains(URL, "/actions/backup-logical") {
@@ -238,6 +256,18 @@ func (cluster *Cluster) IsURLPassDatabasesACL(strUser string, URL string) bool {
 		if strings.Contains(URL, "/meta-data-locks") {
 			return true
 		}
+		if strings.Contains(URL, "/temp-usage") {
+			return true
+		}
+		if strings.Contains(URL, "/mdl-waiters") {
+			return true
+		}
+		if strings.Contains(URL, "/lock-wait-chains") {
+			return true
+		}
+		if strings.Contains(URL, "/user-resource-usage") {
+			return true
+		}
 		if strings.Contains(URL, "/digest-statements-pfs") {
 			return true
 		}
@@ -341,6 +371,12 @@ func (cluster *Cluster) IsURLPassDatabasesACL(strUser string, URL string) bool {
 		if strings.Contains(URL, "/status-delta") {
 			return true
 		}
+		if strings.Contains(URL, "/purge-lag") {
+			return true
+		}
+		if strings.Contains(URL, "/thread-pool") {
+			return true
+		}
 	}
 	cluster.LogPrintf(LvlInfo, "ACL check failed for user %s : %s ", strUser, URL)
 	return false
@@ -452,6 +488,14 @@ func (cluster *Cluster) IsURLPassACL(strUser string, URL string) bool {
 			return true
 		}
 	}
+	if cluster.APIUsers[strUser].Grants[config.GrantDBKill] {
+		if strings.Contains(URL, "/api/clusters/"+cluster.Name+"/kill-policies") {
+			return true
+		}
+		if strings.Contains(URL, "/api/clusters/"+cluster.Name+"/actions/kill-policies") {
+			return true
+		}
+	}
 	if cluster.APIUsers[strUser].Grants[config.GrantClusterBench] {
 		if strings.Contains(URL, "/api/clusters/"+cluster.Name+"/actions/sysbench") {
 			return true
@@ -468,6 +512,9 @@ func (cluster *Cluster) IsURLPassACL(strUser string, URL string) bool {
 		}
 	}
 	if cluster.APIUsers[strUser].Grants[config.GrantClusterReplication] {
+		if strings.Contains(URL, "/api/clusters/"+cluster.Name+"/topology/replication-stream") {
+			return true
+		}
 		if strings.Contains(URL, "/api/clusters/"+cluster.Name+"/actions/replication/bootstrap") {
 			return true
 		}
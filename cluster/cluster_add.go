@@ -50,7 +50,7 @@ func (cluster *Cluster) AddDBTag(tag string) {
 				if !srv.IsMariaDB() {
 					cmd = "mysql_command"
 				}
-				srv.GetDatabaseConfig()
+				srv.GetDatabaseConfigManifest()
 				_, needrestart := srv.ExecScriptSQL(strings.Split(srv.GetDatabaseDynamicConfig(tag, cmd), ";"))
 				if needrestart {
 					srv.SetRestartCookie()
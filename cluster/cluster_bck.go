@@ -17,6 +17,7 @@ import (
 	"strconv"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/signal18/replication-manager/config"
 	"github.com/signal18/replication-manager/utils/state"
@@ -181,3 +182,96 @@ func (cluster *Cluster) ResticFetchRepo() error {
 
 	return nil
 }
+
+func (cluster *Cluster) setBackupSource(server *ServerMonitor, backupType string, file string, running bool) {
+	src := BackupSource{Server: server.URL, Type: backupType, Start: time.Now(), Running: running}
+	if server.IsSlave {
+		src.Delay = server.GetReplicationDelay()
+		src.DelayUnknown = !server.HasReplicationDelay()
+	}
+	cluster.backupSourcesMutex.Lock()
+	if cluster.backupSources == nil {
+		cluster.backupSources = make(map[string]BackupSource)
+	}
+	cluster.backupSources[file] = src
+	cluster.backupSourcesMutex.Unlock()
+}
+
+func (cluster *Cluster) setBackupSourceDone(file string, err error) {
+	cluster.backupSourcesMutex.Lock()
+	if src, ok := cluster.backupSources[file]; ok {
+		src.Running = false
+		src.Failed = err != nil
+		cluster.backupSources[file] = src
+	}
+	cluster.backupSourcesMutex.Unlock()
+}
+
+// getBackupFiles returns the file written last by each backup type in the backup directory of the server
+func (server *ServerMonitor) getBackupFiles() map[string]string {
+	dir := server.getBackupDirectory() + "/"
+	return map[string]string{
+		config.ConstBackupLogicalTypeMysqldump:    dir + "mysqldump.sql.gz",
+		config.ConstBackupLogicalTypeMydumper:     dir + "metadata",
+		config.ConstBackupPhysicalTypeXtrabackup:  dir + config.ConstBackupPhysicalTypeXtrabackup + ".xbtream",
+		config.ConstBackupPhysicalTypeMariaBackup: dir + config.ConstBackupPhysicalTypeMariaBackup + ".xbtream",
+	}
+}
+
+// GetBackupFreshness returns the age of the newest backup of the cluster and whether its source was within
+// failover-max-slave-delay when the backup started, running and failed backups are skipped
+func (cluster *Cluster) GetBackupFreshness() BackupFreshness {
+	return cluster.getBackupFreshness(time.Now())
+}
+
+func (cluster *Cluster) getBackupFreshness(now time.Time) BackupFreshness {
+	f := BackupFreshness{MaxAge: cluster.Conf.BackupFreshnessMaxAge}
+	if srv, err := cluster.GetBackupServer(); err == nil {
+		f.Candidate = srv.URL
+	}
+	cluster.backupSourcesMutex.Lock()
+	defer cluster.backupSourcesMutex.Unlock()
+	for _, server := range cluster.Servers {
+		if server == nil {
+			continue
+		}
+		for backupType, file := range server.getBackupFiles() {
+			fi, err := os.Stat(file)
+			if err != nil || fi.Size() == 0 || (f.Found && !fi.ModTime().After(f.End)) {
+				continue
+			}
+			// a file older than the last recorded start was left by a previous backup
+			src, known := cluster.backupSources[file]
+			known = known && !src.Start.After(fi.ModTime())
+			if known && (src.Running || src.Failed) {
+				continue
+			}
+			f = BackupFreshness{Found: true, Server: server.URL, Type: backupType, File: file, End: fi.ModTime(), MaxAge: f.MaxAge, Candidate: f.Candidate, SourceKnown: known}
+			if known {
+				f.SourceDelay = src.Delay
+				f.SourceCaughtUp = !src.DelayUnknown && (cluster.Conf.FailMaxDelay == -1 || src.Delay <= cluster.Conf.FailMaxDelay)
+			}
+		}
+	}
+	if f.Found {
+		f.Age = int64(now.Sub(f.End).Seconds())
+	}
+	f.Stale = f.MaxAge > 0 && (!f.Found || f.Age > f.MaxAge)
+	return f
+}
+
+// CheckBackupFreshness raises WARN0112 when the newest backup is older than backup-freshness-max-age
+func (cluster *Cluster) CheckBackupFreshness() {
+	if cluster.Conf.BackupFreshnessMaxAge <= 0 {
+		return
+	}
+	f := cluster.GetBackupFreshness()
+	if !f.Stale {
+		return
+	}
+	desc := "not found"
+	if f.Found {
+		desc = fmt.Sprintf("%s of %s is %s old", f.Type, f.Server, time.Duration(f.Age)*time.Second)
+	}
+	cluster.SetState("WARN0112", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0112"], desc, f.MaxAge), ErrFrom: "BACKUP"})
+}
@@ -0,0 +1,115 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 Cloud SAS
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+
+package cluster
+
+import (
+	"database/sql"
+	"errors"
+	"net"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+func TestBackupFreshness(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{Name: "c1", sme: sme, Conf: config.Config{WorkingDir: t.TempDir(), FailMaxDelay: 30, BackupFreshnessMaxAge: 3600}}
+	master := &ServerMonitor{URL: "db1:3306", Host: "db1", Port: "3306", State: stateMaster, ClusterGroup: cluster}
+	slave := &ServerMonitor{URL: "db2:3306", Host: "db2", Port: "3306", State: stateSlave, IsSlave: true, ClusterGroup: cluster, ReplicationStatus: replicationStatusFixture{{
+		SecondsBehindMaster: sql.NullInt64{Int64: 60, Valid: true},
+		SlaveIORunning:      sql.NullString{String: "Yes", Valid: true},
+		SlaveSQLRunning:     sql.NullString{String: "Yes", Valid: true},
+	}}}
+	cluster.Servers = serverList{master, slave}
+	write := func(file string, mtime time.Time) {
+		if err := os.WriteFile(file, []byte("backup"), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(file, mtime, mtime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	now := time.Now()
+	if f := cluster.getBackupFreshness(now); f.Found || !f.Stale {
+		t.Fatalf("Expected stale freshness without backup, got %+v", f)
+	}
+
+	physical := master.GetMyBackupDirectory() + config.ConstBackupPhysicalTypeMariaBackup + ".xbtream"
+	write(physical, now.Add(-2*time.Hour))
+	dump := slave.GetMyBackupDirectory() + "mysqldump.sql.gz"
+	cluster.setBackupSource(slave, config.ConstBackupLogicalTypeMysqldump, dump, true)
+	write(dump, time.Now().Add(time.Second))
+	if f := cluster.getBackupFreshness(now); f.File != physical || f.SourceKnown || f.Age != 7200 || !f.Stale {
+		t.Fatalf("Expected running dump skipped and unknown physical source, got %+v", f)
+	}
+	cluster.setBackupSourceDone(dump, nil)
+	f := cluster.getBackupFreshness(now)
+	if f.File != dump || f.Server != "db2:3306" || !f.SourceKnown || f.SourceDelay != 60 || f.SourceCaughtUp || f.Stale {
+		t.Fatalf("Expected fresh dump from a lagging slave, got %+v", f)
+	}
+	cluster.Conf.FailMaxDelay = 120
+	if f := cluster.getBackupFreshness(now); !f.SourceCaughtUp {
+		t.Fatalf("Expected caught up source within failover-max-slave-delay, got %+v", f)
+	}
+	cluster.setBackupSourceDone(dump, errors.New("mysqldump: Got error"))
+	if f := cluster.getBackupFreshness(now); f.File != physical {
+		t.Fatalf("Expected failed dump skipped, got %+v", f)
+	}
+
+	cluster.CheckBackupFreshness()
+	if !sme.CurState.Search("WARN0112") {
+		t.Fatalf("Expected WARN0112 for a backup over backup-freshness-max-age")
+	}
+}
+
+func TestPhysicalBackupStreamEnd(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{Name: "c1", sme: sme, Conf: config.Config{WorkingDir: t.TempDir(), BindAddr: "127.0.0.1"}}
+	master := &ServerMonitor{URL: "db1:3306", Host: "db1", Port: "3306", State: stateMaster, ClusterGroup: cluster}
+	cluster.Servers = serverList{master}
+	file := master.GetMyBackupDirectory() + config.ConstBackupPhysicalTypeMariaBackup + ".xbtream"
+	cluster.setBackupSource(master, config.ConstBackupPhysicalTypeMariaBackup, file, true)
+	ended := make(chan error, 1)
+	port, err := cluster.SSTRunReceiverToFile(file, ConstJobCreateFile, func(err error) {
+		cluster.setBackupSourceDone(file, err)
+		ended <- err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn, err := net.Dial("tcp", "127.0.0.1:"+port)
+	if err != nil {
+		t.Fatal(err)
+	}
+	conn.Write([]byte("backup"))
+	for i := 0; i < 100; i++ {
+		if fi, err := os.Stat(file); err == nil && fi.Size() > 0 {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if f := cluster.getBackupFreshness(time.Now()); f.Found {
+		t.Fatalf("Expected physical backup still streamed skipped, got %+v", f)
+	}
+	conn.Close()
+	select {
+	case err := <-ended:
+		if err != nil {
+			t.Fatalf("Expected physical backup stream completed, got %s", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Physical backup stream end not recorded")
+	}
+	if f := cluster.getBackupFreshness(time.Now()); f.File != file || !f.SourceKnown {
+		t.Fatalf("Expected completed physical backup found with its source, got %+v", f)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -45,8 +46,10 @@ func (cluster *Cluster) CheckFailed() {
 													if cluster.isExternalOk() == false {
 														if cluster.isOneSlaveHeartbeatIncreasing() == false {
 															if cluster.isMaxscaleSupectRunning() == false {
-																cluster.MasterFailover(true)
-																cluster.failoverCond.Send <- true
+																if cluster.isMasterNotFenced() == false {
+																	cluster.MasterFailover(true)
+																	cluster.failoverCond.Send <- true
+																}
 															}
 														}
 													}
@@ -120,6 +123,70 @@ func (cluster *Cluster) isSlaveElectableForSwitchover(sl *ServerMonitor, forcing
 	return true
 }
 
+// CanSwitchoverTo validates a planned switchover target, the replica must be caught up within
+// switchover-max-slave-delay, failover-max-slave-delay when not set, replicate without error or filter,
+// have no transaction unknown to the master and the same table definitions. The blocking reasons are returned
+func (cluster *Cluster) CanSwitchoverTo(target *ServerMonitor) (bool, []string) {
+	var reasons []string
+	if target == nil {
+		return false, []string{"unknown target"}
+	}
+	master := cluster.GetMaster()
+	if master == nil || master.IsFailed() {
+		return false, []string{"no running master"}
+	}
+	if target == master {
+		return false, []string{"target is the current master"}
+	}
+	if target.IsFailed() || target.IsIgnored() || target.IsMaintenance {
+		reasons = append(reasons, "target is failed, ignored or in maintenance")
+	}
+	if target.IsRelay || target.IsMaxscale || target.IsDelayed {
+		reasons = append(reasons, "target is a relay or delayed replica")
+	}
+	maxDelay := cluster.Conf.SwitchMaxDelay
+	if maxDelay == 0 {
+		maxDelay = cluster.Conf.FailMaxDelay
+	}
+	if !target.HasReplicationDelay() {
+		reasons = append(reasons, "replication delay unknown")
+	} else if delay := target.GetReplicationDelay(); maxDelay >= 0 && delay > maxDelay {
+		reasons = append(reasons, fmt.Sprintf("replication delay %d over %d", delay, maxDelay))
+	}
+	if target.IsReplicationBroken() || target.HasReplicationError() {
+		reasons = append(reasons, "replication stopped or in error")
+	}
+	if target.HasReplicationFilters() {
+		reasons = append(reasons, "replication filters")
+	}
+	if hasBinlogs, _ := cluster.IsEqualBinlogFilters(master, target); !hasBinlogs && cluster.Conf.CheckBinFilter {
+		reasons = append(reasons, "binlog filters differ from master")
+	}
+	if master.CurrentGtid != nil && target.CurrentGtid != nil && !master.CurrentGtid.Covers(target.CurrentGtid, target.IsMariaDB()) {
+		reasons = append(reasons, "errant GTID not applied on master")
+	}
+	if tables := cluster.getTableDefinitionDrift(master, target); len(tables) > 0 {
+		reasons = append(reasons, "table definitions differ: "+strings.Join(tables, ","))
+	}
+	return len(reasons) == 0, reasons
+}
+
+// getTableDefinitionDrift returns the master tables missing or with a different column definition checksum on a replica
+func (cluster *Cluster) getTableDefinitionDrift(m *ServerMonitor, s *ServerMonitor) []string {
+	var tables []string
+	if len(m.DictTables) == 0 || len(s.DictTables) == 0 {
+		return tables
+	}
+	for name, t := range m.DictTables {
+		st, ok := s.DictTables[name]
+		if !ok || st.Table_crc != t.Table_crc {
+			tables = append(tables, name)
+		}
+	}
+	sort.Strings(tables)
+	return tables
+}
+
 func (cluster *Cluster) isAutomaticFailover() bool {
 	if cluster.Conf.Interactive == false {
 		return true
@@ -464,12 +531,55 @@ func (cluster *Cluster) CheckCapture(state state.State) {
 }
 
 func (cluster *Cluster) CheckAllTableChecksum() {
+	pks := make(map[string]map[string][]string)
 	for _, t := range cluster.master.Tables {
-		cluster.CheckTableChecksum(t.Table_schema, t.Table_name)
+		if _, ok := pks[t.Table_schema]; !ok {
+			pks[t.Table_schema], _ = cluster.master.GetTablePKs(t.Table_schema)
+		}
+		cluster.checkTableChecksum(t.Table_schema, t.Table_name, strings.Join(pks[t.Table_schema][t.Table_name], ","))
 	}
 }
 
+// CheckSchemaChecksum checksum in chunks every table of a schema between the master and the slaves, the remaining
+// tables are skipped when a failover or switchover starts
+func (cluster *Cluster) CheckSchemaChecksum(schema string) {
+	if cluster.master == nil || cluster.IsInFailover() {
+		return
+	}
+	pks, err := cluster.master.GetTablePKs(schema)
+	if err != nil {
+		cluster.LogPrintf(LvlErr, "Checksum, could not get primary keys of schema %s: %s", schema, err)
+		return
+	}
+	for _, t := range cluster.master.Tables {
+		if t.Table_schema != schema {
+			continue
+		}
+		if cluster.IsInFailover() {
+			cluster.LogPrintf(LvlInfo, "Checksum of schema %s cancelled by failover", schema)
+			return
+		}
+		if len(pks[t.Table_name]) == 0 {
+			cluster.LogPrintf(LvlWarn, "Checksum, skipping table %s.%s without primary key", schema, t.Table_name)
+			cluster.setTableSync(schema, t.Table_name, "NA")
+			continue
+		}
+		cluster.checkTableChecksum(t.Table_schema, t.Table_name, strings.Join(pks[t.Table_name], ","))
+	}
+}
+
+func (cluster *Cluster) setTableSync(schema string, table string, sync string) {
+	t := cluster.master.DictTables[schema+"."+table]
+	t.Table_sync = sync
+	cluster.master.DictTables[schema+"."+table] = t
+}
+
 func (cluster *Cluster) CheckTableChecksum(schema string, table string) {
+	pk, _ := cluster.master.GetTablePK(schema, table)
+	cluster.checkTableChecksum(schema, table, pk)
+}
+
+func (cluster *Cluster) checkTableChecksum(schema string, table string, pk string) {
 
 	cluster.LogPrintf(LvlInfo, "Checksum master table %s.%s %s", schema, table, cluster.master.URL)
 
@@ -480,7 +590,6 @@ func (cluster *Cluster) CheckTableChecksum(schema string, table string) {
 	}
 	defer Conn.Close()
 	Conn.SetConnMaxLifetime(3595 * time.Second)
-	pk, _ := cluster.master.GetTablePK(schema, table)
 	if pk == "" {
 		cluster.master.ClusterGroup.LogPrintf(LvlErr, "Checksum, no primary key for table %s.%s", schema, table)
 		t := cluster.master.DictTables[schema+"."+table]
@@ -497,7 +606,7 @@ func (cluster *Cluster) CheckTableChecksum(schema string, table string) {
 	Conn.Exec("SET SESSION group_concat_max_len = 1000000")
 
 	Conn.Exec("CREATE OR REPLACE TABLE replication_manager_schema.table_checksum(chunkId BIGINT,chunkMinKey VARCHAR(254),chunkMaxKey VARCHAR(254),chunkCheckSum BIGINT UNSIGNED ) ENGINE=MYISAM")
-	query := "CREATE TEMPORARY TABLE replication_manager_schema.table_chunck ENGINE=MYISAM SELECT FLOOR((@rows:=@rows+1/2000)) as chunkId, MIN(CONCAT_WS('/*;*/'," + pk + ")) as chunkMinKey, MAX(CONCAT_WS('/*;*/'," + pk + ")) as chunkMaxKey from " + schema + "." + table + " , (SELECT @rows:=0 FROM DUAL) A group by chunkId"
+	query := "CREATE TEMPORARY TABLE replication_manager_schema.table_chunck ENGINE=MYISAM SELECT FLOOR((@rows:=@rows+1/" + strconv.Itoa(cluster.getChecksumChunkSize()) + ")) as chunkId, MIN(CONCAT_WS('/*;*/'," + pk + ")) as chunkMinKey, MAX(CONCAT_WS('/*;*/'," + pk + ")) as chunkMaxKey from " + schema + "." + table + " , (SELECT @rows:=0 FROM DUAL) A group by chunkId"
 	_, err = Conn.Exec(query)
 	Conn.Exec("SET SESSION binlog_format = 'STATEMENT'")
 	if err != nil {
@@ -551,12 +660,10 @@ func (cluster *Cluster) CheckTableChecksum(schema string, table string) {
 			cluster.LogPrintf(LvlInfo, "Finished checksum table %s.%s", schema, table)
 			break
 		}
-		/*	slave := cluster.GetFirstWorkingSlave()
-			if slave != nil {
-				if slave.GetReplicationDelay() > 5 {
-					time.Sleep(time.Duration(slave.GetReplicationDelay()) * time.Second)
-				}
-			}*/
+		if err := cluster.throttleChecksumChunk(); err != nil {
+			cluster.LogPrintf(LvlErr, "Checksum aborted on table %s.%s: %s", schema, table, err)
+			return
+		}
 	}
 	cluster.master.Refresh()
 	masterSeq := cluster.master.CurrentGtid.GetSeqServerIdNos(uint64(cluster.master.ServerID))
@@ -564,6 +671,7 @@ func (cluster *Cluster) CheckTableChecksum(schema string, table string) {
 
 	for _, s := range cluster.slaves {
 		if !s.IsFailed() && !s.IsReplicationBroken() {
+			start := time.Now()
 			for true {
 				slaveSeq := s.SlaveGtid.GetSeqServerIdNos(uint64(cluster.master.ServerID))
 				cluster.LogPrintf(LvlInfo, "Wait sync on slave %s sequence %d", s.URL, slaveSeq)
@@ -572,6 +680,10 @@ func (cluster *Cluster) CheckTableChecksum(schema string, table string) {
 				} else {
 					cluster.SetState("WARN0086", state.State{ErrType: "WARNING", ErrDesc: fmt.Sprintf(clusterError["WARN0086"], s.URL), ErrFrom: "MON", ServerUrl: s.URL})
 				}
+				if cluster.Conf.ChecksumMaxPause > 0 && time.Since(start) > time.Duration(cluster.Conf.ChecksumMaxPause)*time.Second {
+					cluster.LogPrintf(LvlErr, "Checksum aborted on table %s.%s: slave %s not in sync after checksum-max-pause %ds", schema, table, s.URL, cluster.Conf.ChecksumMaxPause)
+					return
+				}
 				time.Sleep(1 * time.Second)
 			}
 
@@ -584,9 +696,11 @@ func (cluster *Cluster) CheckTableChecksum(schema string, table string) {
 		slaveChecksums, logs, err := dbhelper.GetTableChecksumResult(s.Conn)
 		cluster.LogSQL(logs, err, s.URL, "CheckTableChecksum", LvlDbg, "GetTableChecksumResult")
 		checkok := true
+		result := ConsistencyCheckResult{Time: time.Now(), Schema: schema, Table: table, URL: s.URL, Chunks: len(masterChecksums)}
 		for _, chunk := range masterChecksums {
 			if chunk.ChunkCheckSum != slaveChecksums[chunk.ChunkId].ChunkCheckSum {
 				checkok = false
+				result.DivergentChunks++
 				cluster.LogPrintf(LvlInfo, "Checksum table failed chunk(%s,%s) %s.%s %s", chunk.ChunkMinKey, chunk.ChunkMaxKey, schema, table, s.URL)
 				t := cluster.master.DictTables[schema+"."+table]
 				t.Table_sync = "ER"
@@ -600,6 +714,185 @@ func (cluster *Cluster) CheckTableChecksum(schema string, table string) {
 			t.Table_sync = "OK"
 			cluster.master.DictTables[schema+"."+table] = t
 		}
+		cluster.addConsistencyCheckResult(result)
+	}
+}
+
+func (cluster *Cluster) getChecksumChunkSize() int {
+	if cluster.Conf.ChecksumChunkSize <= 0 {
+		return 2000
+	}
+	return cluster.Conf.ChecksumChunkSize
+}
+
+// throttleChecksumChunk sleep checksum-chunk-sleep between chunks and wait for slaves to come back
+// under checksum-max-slave-delay, up to checksum-max-pause
+func (cluster *Cluster) throttleChecksumChunk() error {
+	if cluster.Conf.ChecksumChunkSleep > 0 {
+		time.Sleep(time.Duration(cluster.Conf.ChecksumChunkSleep) * time.Millisecond)
+	}
+	if cluster.Conf.ChecksumMaxSlaveDelay <= 0 {
+		return nil
+	}
+	start := time.Now()
+	for _, s := range cluster.slaves {
+		for !s.IsFailed() && !s.IsReplicationBroken() && s.GetReplicationDelay() > cluster.Conf.ChecksumMaxSlaveDelay {
+			if cluster.Conf.ChecksumMaxPause > 0 && time.Since(start) > time.Duration(cluster.Conf.ChecksumMaxPause)*time.Second {
+				return fmt.Errorf("slave %s delay %d over checksum-max-slave-delay for more than checksum-max-pause %ds", s.URL, s.GetReplicationDelay(), cluster.Conf.ChecksumMaxPause)
+			}
+			cluster.LogPrintf(LvlInfo, "Checksum paused, slave %s delay %d over checksum-max-slave-delay", s.URL, s.GetReplicationDelay())
+			time.Sleep(time.Duration(cluster.Conf.MonitoringTicker) * time.Second)
+		}
+	}
+	return nil
+}
+
+const consistencyCheckResultsMax = 1000
+
+// addConsistencyCheckResult keep the last checksum results to follow divergence over time
+func (cluster *Cluster) addConsistencyCheckResult(result ConsistencyCheckResult) {
+	cluster.Lock()
+	defer cluster.Unlock()
+	cluster.ConsistencyCheckResults = append(cluster.ConsistencyCheckResults, result)
+	if len(cluster.ConsistencyCheckResults) > consistencyCheckResultsMax {
+		cluster.ConsistencyCheckResults = cluster.ConsistencyCheckResults[len(cluster.ConsistencyCheckResults)-consistencyCheckResultsMax:]
+	}
+}
+
+// CheckMisdirectedReplicas raise a warning for slaves not replicating from the elected master and redirect
+// GTID slaves when autorejoin-misdirected-slaves is enabled
+func (cluster *Cluster) CheckMisdirectedReplicas() {
+	for _, sl := range cluster.GetMisdirectedReplicas() {
+		cluster.sme.AddState("WARN0102", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0102"], sl.URL, sl.GetReplicationMasterHost()+":"+sl.GetReplicationMasterPort(), cluster.master.URL), ErrFrom: "TOPO", ServerUrl: sl.URL})
+		if !cluster.Conf.AutorejoinMisdirectedSlaves || !cluster.IsActive() || cluster.IsInFailover() {
+			continue
+		}
+		if !sl.HasGTIDReplication() {
+			cluster.LogPrintf(LvlWarn, "Can't redirect slave %s to master %s without GTID replication", sl.URL, cluster.master.URL)
+			continue
+		}
+		cluster.LogPrintf(LvlInfo, "Redirecting slave %s to master %s", sl.URL, cluster.master.URL)
+		logs, err := sl.SetReplicationGTIDSlavePosFromServer(cluster.master)
+		cluster.LogSQL(logs, err, sl.URL, "Rejoin", LvlErr, "Failed to change master on slave %s: %s", sl.URL, err)
+		if err == nil {
+			logs, err = sl.StartSlave()
+			cluster.LogSQL(logs, err, sl.URL, "Rejoin", LvlErr, "Failed to start slave on %s: %s", sl.URL, err)
+		}
+	}
+}
+
+// CheckSchemaCharsets raise a warning for each schema of a slave with a default charset or collation
+// different from the master one
+func (cluster *Cluster) CheckSchemaCharsets() {
+	if cluster.master == nil || cluster.master.IsFailed() || cluster.master.Conn == nil {
+		return
+	}
+	master, logs, err := cluster.master.GetSchemasDetailed()
+	cluster.LogSQL(logs, err, cluster.master.URL, "Monitor", LvlDbg, "Could not get schemas %s", err)
+	if err != nil {
+		return
+	}
+	for _, sl := range cluster.slaves {
+		if sl.IsFailed() || sl.Conn == nil {
+			continue
+		}
+		schemas, logs, err := sl.GetSchemasDetailed()
+		cluster.LogSQL(logs, err, sl.URL, "Monitor", LvlDbg, "Could not get schemas %s", err)
+		if err != nil {
+			continue
+		}
+		for _, d := range getSchemaCharsetDiffs(master, schemas) {
+			cluster.sme.AddState("WARN0103", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0103"], d[1].Name, sl.URL, d[1].Charset, d[1].Collation, d[0].Charset, d[0].Collation), ErrFrom: "MON", ServerUrl: sl.URL})
+		}
+	}
+}
+
+// getSchemaCharsetDiffs returns the master and slave definitions of schemas existing on both with a different charset or collation
+func getSchemaCharsetDiffs(master []dbhelper.SchemaDetail, slave []dbhelper.SchemaDetail) [][2]dbhelper.SchemaDetail {
+	var diffs [][2]dbhelper.SchemaDetail
+	schemas := make(map[string]dbhelper.SchemaDetail, len(master))
+	for _, m := range master {
+		schemas[m.Name] = m
+	}
+	for _, s := range slave {
+		m, ok := schemas[s.Name]
+		if ok && (m.Charset != s.Charset || m.Collation != s.Collation) {
+			diffs = append(diffs, [2]dbhelper.SchemaDetail{m, s})
+		}
+	}
+	return diffs
+}
+
+// CheckWriteCircuitBreaker open the write circuit when the replication safety margin exceeds
+// write-circuit-breaker-max-slave-delay or when the replication of every reachable slave is broken, slaves the
+// monitor can not reach do not trip it, and close it when a slave is back within the bound. The circuit state only
+// changes when the action succeeds so that it is retried at next monitoring loop
+func (cluster *Cluster) CheckWriteCircuitBreaker() {
+	open := false
+	var margin int64
+	if cluster.Conf.WriteCircuitBreakerMaxDelay > 0 && len(cluster.slaves) > 0 && !cluster.IsInFailover() {
+		var err error
+		margin, err = cluster.GetReplicationSafetyMargin()
+		if err == nil {
+			open = margin > cluster.Conf.WriteCircuitBreakerMaxDelay
+		} else {
+			open = cluster.hasConfirmedReplicationBreakage()
+		}
+	}
+	if open {
+		cluster.sme.AddState("WARN0104", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0104"], cluster.Conf.WriteCircuitBreakerMaxDelay), ErrFrom: "MON"})
+	}
+	if open == cluster.IsWriteCircuitOpen || cluster.WriteCircuitBreaker == nil {
+		return
+	}
+	var err error
+	if open {
+		err = cluster.WriteCircuitBreaker.Open(cluster, margin)
+	} else {
+		err = cluster.WriteCircuitBreaker.Close(cluster)
+	}
+	if err != nil {
+		cluster.LogPrintf(LvlErr, "Write circuit breaker action failed, retrying at next monitoring loop: %s", err)
+		return
+	}
+	cluster.IsWriteCircuitOpen = open
+}
+
+// hasConfirmedReplicationBreakage tells if a slave the monitor reaches reports stopped replication threads,
+// failed, suspect, ignored or maintenance slaves are left out
+func (cluster *Cluster) hasConfirmedReplicationBreakage() bool {
+	for _, sl := range cluster.slaves {
+		if sl.IsFailed() || sl.State == stateSuspect || sl.IsIgnored() || sl.IsMaintenance {
+			continue
+		}
+		if sl.IsReplicationBroken() {
+			return true
+		}
+	}
+	return false
+}
+
+// CheckVersionSkew raise a warning for servers left behind by a rolling upgrade and for slaves running a version
+// that can not replicate from the master, skipped before discovery and while the master is switched
+func (cluster *Cluster) CheckVersionSkew() {
+	master := cluster.master
+	if cluster.IsInFailover() || master == nil || master.DBVersion == nil {
+		return
+	}
+	for _, vs := range cluster.GetVersionSkew() {
+		if vs.Lagging {
+			cluster.sme.AddState("WARN0105", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0105"], vs.URL, vs.Version, cluster.getNewestVersion().ToString()), ErrFrom: "MON", ServerUrl: vs.URL})
+		}
+		if vs.Incompatible {
+			cluster.sme.AddState("WARN0106", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0106"], vs.URL, vs.Version, master.DBVersion.ToString(), vs.Reason), ErrFrom: "MON", ServerUrl: vs.URL})
+		}
+	}
+}
+
+// CheckMasterConsistency raises the GetMasterConsistencyWarnings states
+func (cluster *Cluster) CheckMasterConsistency() {
+	for _, st := range cluster.getMasterConsistencyStates() {
+		cluster.sme.AddState(st.ErrKey, st)
 	}
 }
 
@@ -0,0 +1,369 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/dbhelper"
+	"github.com/signal18/replication-manager/utils/gtid"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+func TestSchemaCharsetDiffs(t *testing.T) {
+	master := []dbhelper.SchemaDetail{
+		{Name: "app", Charset: "utf8mb4", Collation: "utf8mb4_general_ci"},
+		{Name: "log", Charset: "latin1", Collation: "latin1_swedish_ci"},
+		{Name: "crm", Charset: "utf8mb4", Collation: "utf8mb4_general_ci"},
+	}
+	slave := []dbhelper.SchemaDetail{
+		{Name: "app", Charset: "utf8", Collation: "utf8_general_ci"},
+		{Name: "log", Charset: "latin1", Collation: "latin1_swedish_ci"},
+		{Name: "crm", Charset: "utf8mb4", Collation: "utf8mb4_unicode_ci"},
+		{Name: "local", Charset: "utf8", Collation: "utf8_general_ci"},
+	}
+	diffs := getSchemaCharsetDiffs(master, slave)
+	if len(diffs) != 2 {
+		t.Fatalf("Got %d diffs, expected 2", len(diffs))
+	}
+	if diffs[0][0].Charset != "utf8mb4" || diffs[0][1].Charset != "utf8" || diffs[1][1].Collation != "utf8mb4_unicode_ci" {
+		t.Fatalf("Unexpected diffs %v", diffs)
+	}
+}
+
+func TestConsistencyCheckResults(t *testing.T) {
+	cluster := &Cluster{}
+	if err := cluster.ScheduleConsistencyCheck("test"); err == nil {
+		t.Fatal("Expected error without scheduler")
+	}
+	for i := 0; i < consistencyCheckResultsMax+10; i++ {
+		cluster.addConsistencyCheckResult(ConsistencyCheckResult{Schema: "test", Table: "t", Chunks: i})
+	}
+	res := cluster.GetConsistencyCheckResults()
+	if len(res) != consistencyCheckResultsMax || res[0].Chunks != 10 || res[len(res)-1].Chunks != consistencyCheckResultsMax+9 {
+		t.Fatalf("Got %d results from %d, expected %d from 10", len(res), res[0].Chunks, consistencyCheckResultsMax)
+	}
+	if size := cluster.getChecksumChunkSize(); size != 2000 {
+		t.Fatalf("Got chunk size %d, expected default 2000", size)
+	}
+}
+
+func TestCheckSchemaChecksumSkipsNoPK(t *testing.T) {
+	name := fmt.Sprintf("pkchecksum%d", time.Now().UnixNano())
+	sql.Register(name, pkDriver{tables: 1})
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{sme: sme}
+	cluster.master = &ServerMonitor{URL: "db1:3306", Conn: db, ClusterGroup: cluster, DictTables: map[string]dbhelper.Table{},
+		Tables: []dbhelper.Table{{Table_schema: "test", Table_name: "nopk"}, {Table_schema: "other", Table_name: "t0"}}}
+	sme.SetFailoverState()
+	cluster.CheckSchemaChecksum("test")
+	if _, ok := cluster.master.DictTables["test.nopk"]; ok {
+		t.Fatal("Expected no checksum during failover")
+	}
+	sme.RemoveFailoverState()
+	cluster.CheckSchemaChecksum("test")
+	if sync := cluster.master.DictTables["test.nopk"].Table_sync; sync != "NA" {
+		t.Fatalf("Expected table without primary key skipped as NA, got %q", sync)
+	}
+	if _, ok := cluster.master.DictTables["other.t0"]; ok {
+		t.Fatal("Expected table of another schema left out")
+	}
+}
+
+func TestThrottleChecksumChunkMaxPause(t *testing.T) {
+	cluster := &Cluster{Conf: config.Config{ChecksumMaxSlaveDelay: 10, ChecksumMaxPause: 1, MonitoringTicker: 1}}
+	lagging := &ServerMonitor{URL: "db2:3306", State: stateSlave, ClusterGroup: cluster, ReplicationStatus: replicationStatusFixture{{
+		SecondsBehindMaster: sql.NullInt64{Int64: 60, Valid: true},
+		SlaveIORunning:      sql.NullString{String: "Yes", Valid: true},
+		SlaveSQLRunning:     sql.NullString{String: "Yes", Valid: true},
+	}}}
+	cluster.slaves = serverList{lagging}
+	start := time.Now()
+	if err := cluster.throttleChecksumChunk(); err == nil {
+		t.Fatal("Expected the checksum aborted on a slave staying late")
+	}
+	if elapsed := time.Since(start); elapsed < time.Second || elapsed > 5*time.Second {
+		t.Fatalf("Expected the pause capped by checksum-max-pause, took %s", elapsed)
+	}
+	cluster.Conf.ChecksumMaxSlaveDelay = 120
+	if err := cluster.throttleChecksumChunk(); err != nil {
+		t.Fatalf("Expected no pause under checksum-max-slave-delay, got %s", err)
+	}
+}
+
+func TestCanSwitchoverTo(t *testing.T) {
+	cluster := &Cluster{Conf: config.Config{FailMaxDelay: 30}}
+	master := &ServerMonitor{URL: "db1:3306", State: stateMaster, ClusterGroup: cluster, DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", ""), CurrentGtid: gtid.NewList("0-1-200"),
+		DictTables: map[string]dbhelper.Table{"app.orders": {Table_crc: 11}, "app.stock": {Table_crc: 12}}}
+	target := &ServerMonitor{URL: "db2:3306", State: stateSlave, ClusterGroup: cluster, DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", ""), CurrentGtid: gtid.NewList("0-1-195"),
+		DictTables:        map[string]dbhelper.Table{"app.orders": {Table_crc: 11}, "app.stock": {Table_crc: 12}},
+		ReplicationStatus: replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: 2, Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}}}}
+	cluster.master = master
+	if ok, reasons := cluster.CanSwitchoverTo(target); !ok {
+		t.Fatalf("Expected switchover allowed, got %v", reasons)
+	}
+	target.ReplicationStatus = replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: 45, Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}}}
+	target.CurrentGtid = gtid.NewList("0-1-195,1-2-3")
+	target.DictTables["app.stock"] = dbhelper.Table{Table_crc: 99}
+	target.Variables = map[string]string{"REPLICATE_DO_DB": "app"}
+	ok, reasons := cluster.CanSwitchoverTo(target)
+	expected := []string{"replication delay 45 over 30", "replication filters", "errant GTID not applied on master", "table definitions differ: app.stock"}
+	if ok || !reflect.DeepEqual(reasons, expected) {
+		t.Fatalf("Got %t %v, expected blocked by %v", ok, reasons, expected)
+	}
+	if ok, _ := cluster.CanSwitchoverTo(master); ok {
+		t.Fatal("Expected switchover to the master refused")
+	}
+}
+
+func TestPingServersSkipsRefreshing(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{sme: sme, Conf: config.Config{MonitorRefreshWorkers: 1, MonitorRefreshTimeout: 1, MaxFail: 5}}
+	db1 := &ServerMonitor{URL: "db1:3306", ClusterGroup: cluster, refreshing: 1}
+	db2 := &ServerMonitor{URL: "db2:3306", ClusterGroup: cluster, refreshing: 1}
+	cluster.pingServers(serverList{db1, db2})
+	if db1.refreshing != 1 || db2.refreshing != 1 {
+		t.Fatal("Expected servers with a refresh still running not pinged again")
+	}
+	if db1.FailCount != 0 || db2.FailCount != 0 || db1.State != "" {
+		t.Fatalf("Expected no failed ping recorded for servers still refreshing, got %d %d %s", db1.FailCount, db2.FailCount, db1.State)
+	}
+}
+
+func TestPingServerSlowRefresh(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{sme: sme, Conf: config.Config{MonitorRefreshWorkers: 1, MonitorRefreshTimeout: 1, MaxFail: 2}}
+	db1 := &ServerMonitor{URL: "db1:3306", ClusterGroup: cluster, refreshing: 1}
+	release := make(chan bool)
+	processed := int32(0)
+	check := func() (*sqlx.DB, error) { return nil, nil }
+	process := func(conn *sqlx.DB, err error) {
+		<-release
+		atomic.AddInt32(&processed, 1)
+	}
+	cluster.pingServer(db1, 100*time.Millisecond, check, process)
+	if atomic.LoadInt32(&db1.refreshing) != 1 || db1.FailCount != 0 || db1.State != "" {
+		t.Fatalf("Expected a slow refresh released without failed ping, got refreshing %d fail count %d state %s", db1.refreshing, db1.FailCount, db1.State)
+	}
+	cluster.pingServers(serverList{db1})
+	if db1.FailCount != 0 {
+		t.Fatalf("Expected a server still refreshing skipped, got fail count %d", db1.FailCount)
+	}
+	close(release)
+	for i := 0; i < 100 && atomic.LoadInt32(&db1.refreshing) == 1; i++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if atomic.LoadInt32(&db1.refreshing) != 0 || atomic.LoadInt32(&processed) != 1 {
+		t.Fatal("Expected the refresh to clear the refreshing flag once done")
+	}
+
+	// a connection check exceeding the timeout is a failed ping and its late result is dropped
+	db1.refreshing = 1
+	hang := make(chan bool)
+	checked := make(chan bool)
+	check = func() (*sqlx.DB, error) {
+		<-hang
+		defer close(checked)
+		return nil, nil
+	}
+	cluster.pingServer(db1, 100*time.Millisecond, check, process)
+	if atomic.LoadInt32(&db1.refreshing) != 0 || db1.FailCount != 1 || db1.State != stateSuspect {
+		t.Fatalf("Expected a failed ping on connection check timeout, got refreshing %d fail count %d state %s", db1.refreshing, db1.FailCount, db1.State)
+	}
+	close(hang)
+	<-checked
+	time.Sleep(10 * time.Millisecond)
+	if atomic.LoadInt32(&processed) != 1 {
+		t.Fatal("Expected the late connection check not processed")
+	}
+}
+
+func TestPingServersTimeout(t *testing.T) {
+	// a server accepting connections without ever answering the handshake
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var conns []net.Conn
+	accepted := make(chan bool)
+	go func() {
+		for {
+			c, err := ln.Accept()
+			if err != nil {
+				close(accepted)
+				return
+			}
+			conns = append(conns, c)
+		}
+	}()
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{sme: sme, Conf: config.Config{CheckType: "tcp", Timeout: 1, ReadTimeout: 60, MonitorRefreshWorkers: 2, MonitorRefreshTimeout: 1, MaxFail: 2}}
+	host, port, _ := net.SplitHostPort(ln.Addr().String())
+	hung := &ServerMonitor{URL: ln.Addr().String(), Host: host, Port: port, User: "root", ClusterGroup: cluster, TLSConfigUsed: ConstTLSCurrentConfig}
+	hung.SetDSN()
+
+	start := time.Now()
+	cluster.pingServers(serverList{hung})
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Fatalf("Expected the loop not to wait for the hung server, took %s", elapsed)
+	}
+	if atomic.LoadInt32(&hung.refreshing) != 0 || hung.FailCount != 1 || hung.State != stateSuspect {
+		t.Fatalf("Expected a failed ping recorded on timeout, got refreshing %d fail count %d state %s", hung.refreshing, hung.FailCount, hung.State)
+	}
+	cluster.pingServers(serverList{hung})
+	if hung.FailCount != 2 || hung.State != stateFailed {
+		t.Fatalf("Expected the hung server failed after failcount loops, got fail count %d state %s", hung.FailCount, hung.State)
+	}
+
+	// unblock the hung connection checks
+	ln.Close()
+	<-accepted
+	for _, c := range conns {
+		c.Close()
+	}
+}
+
+// execDriver records the statements run with their arguments, queries return the rows of the rows function or
+// no rows
+type execDriver struct {
+	log  *execLog
+	rows func(query string, args []driver.Value) *pkRows
+}
+
+type execLog struct {
+	sync.Mutex
+	stmts []string
+}
+
+func (d execDriver) Open(name string) (driver.Conn, error) { return execConn(d), nil }
+
+type execConn execDriver
+
+func (c execConn) Prepare(query string) (driver.Stmt, error) {
+	return execStmt{execDriver(c), query}, nil
+}
+func (c execConn) Close() error              { return nil }
+func (c execConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("not supported") }
+
+type execStmt struct {
+	d     execDriver
+	query string
+}
+
+func (s execStmt) Close() error  { return nil }
+func (s execStmt) NumInput() int { return -1 }
+func (s execStmt) Exec(args []driver.Value) (driver.Result, error) {
+	s.d.log.Lock()
+	stmt := s.query
+	if len(args) > 0 {
+		stmt += fmt.Sprint(" ", args)
+	}
+	s.d.log.stmts = append(s.d.log.stmts, stmt)
+	s.d.log.Unlock()
+	return driver.RowsAffected(0), nil
+}
+func (s execStmt) Query(args []driver.Value) (driver.Rows, error) {
+	if s.d.rows != nil {
+		if r := s.d.rows(s.query, args); r != nil {
+			return r, nil
+		}
+	}
+	return &pkRows{}, nil
+}
+
+func TestMisdirectedReplicas(t *testing.T) {
+	slave := func(url string, host string, port string) *ServerMonitor {
+		return &ServerMonitor{URL: url, Id: url, IsSlave: true, State: stateSlave, DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", ""),
+			ReplicationStatus: replicationStatusFixture{{MasterHost: sql.NullString{String: host, Valid: true}, MasterPort: sql.NullString{String: port, Valid: true}}}}
+	}
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{sme: sme, Status: ConstMonitorActif}
+	cluster.master = &ServerMonitor{URL: "db1:3306", Id: "db1", Host: "db1", IP: "10.0.0.1", Port: "3306", State: stateMaster}
+	byHost := slave("db2:3306", "db1", "3306")
+	byIP := slave("db3:3306", "10.0.0.1", "3306")
+	otherHost := slave("db4:3306", "db3", "3306")
+	otherPort := slave("db5:3306", "db1", "3307")
+	failed := slave("db6:3306", "db3", "3306")
+	failed.State = stateFailed
+	cluster.slaves = serverList{byHost, byIP, otherHost, otherPort, failed}
+	cluster.master.ClusterGroup = cluster
+	for _, sl := range cluster.slaves {
+		sl.ClusterGroup = cluster
+	}
+	misdirected := cluster.GetMisdirectedReplicas()
+	if len(misdirected) != 2 || misdirected[0] != otherHost || misdirected[1] != otherPort {
+		t.Fatalf("Expected db4 and db5 misdirected, got %d replicas", len(misdirected))
+	}
+	cluster.Conf.MultiTierSlave = true
+	if len(cluster.GetMisdirectedReplicas()) != 0 {
+		t.Fatal("Expected no misdirected replica with multi tier slaves")
+	}
+	cluster.Conf.MultiTierSlave = false
+
+	cluster.master.ServerID = 1
+	alias := slave("db7:3306", "db1.example.com", "3306")
+	alias.ReplicationStatus.(replicationStatusFixture)[0].MasterServerID = 1
+	stale := slave("db8:3306", "db1", "3306")
+	stale.ReplicationStatus.(replicationStatusFixture)[0].MasterServerID = 2
+	alias.ClusterGroup, stale.ClusterGroup = cluster, cluster
+	cluster.slaves = serverList{byHost, alias, stale}
+	misdirected = cluster.GetMisdirectedReplicas()
+	if len(misdirected) != 1 || misdirected[0] != stale {
+		t.Fatalf("Expected only db8 misdirected by source server id, got %d replicas", len(misdirected))
+	}
+	cluster.slaves = serverList{byHost, byIP, otherHost, otherPort, failed}
+
+	log := &execLog{}
+	name := fmt.Sprintf("misdirected%d", time.Now().UnixNano())
+	sql.Register(name, execDriver{log: log})
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherHost.Conn = db
+	otherPort.Conn = db
+	otherPort.DBVersion = dbhelper.NewMySQLVersion("8.0.32", "")
+	cluster.CheckMisdirectedReplicas()
+	if !sme.CurState.Search("WARN0102") {
+		t.Fatal("Expected WARN0102 for misdirected replicas")
+	}
+	if len(log.stmts) != 0 {
+		t.Fatalf("Unexpected redirect with autorejoin-misdirected-slaves disabled: %v", log.stmts)
+	}
+	cluster.Conf.AutorejoinMisdirectedSlaves = true
+	cluster.CheckMisdirectedReplicas()
+	stmts := strings.Join(log.stmts, ";")
+	if !strings.Contains(stmts, "master_host='db1'") || !strings.Contains(stmts, "START SLAVE") {
+		t.Fatalf("Expected GTID slave redirected to db1, got %s", stmts)
+	}
+	if strings.Count(stmts, "CHANGE MASTER") != 1 {
+		t.Fatalf("Expected the slave without GTID replication left alone, got %s", stmts)
+	}
+}
@@ -6,7 +6,10 @@
 
 package cluster
 
-import "strings"
+import (
+	"strings"
+	"time"
+)
 
 func (cluster *Cluster) CancelRollingRestart() error {
 	cluster.LogPrintf(LvlInfo, "API receive cancel rolling restart")
@@ -19,6 +22,23 @@ func (cluster *Cluster) CancelRollingRestart() error {
 	return nil
 }
 
+// ClearCompletedRestartCookies removes the restart cookie of the servers restarted and caught up since it was
+// set, so a rolling restart does not stay pending once done
+func (cluster *Cluster) ClearCompletedRestartCookies() {
+	cleared := false
+	for _, s := range cluster.Servers {
+		if s.HasCompletedRestart(time.Now()) {
+			cluster.LogPrintf(LvlInfo, "Restart completed on server %s, removing restart cookie", s.URL)
+			s.DelRestartCookie()
+			cleared = true
+		}
+	}
+	if cleared {
+		cluster.IsNeedDatabasesRollingRestart = cluster.HasRequestDBRollingRestart()
+		cluster.IsNeedDatabasesRestart = cluster.HasRequestDBRestart()
+	}
+}
+
 func (cluster *Cluster) CancelRollingReprov() error {
 	cluster.LogPrintf(LvlInfo, "API receive cancel rolling re-provision")
 	for _, pr := range cluster.Proxies {
@@ -40,7 +60,7 @@ func (cluster *Cluster) DropDBTag(dtag string) {
 			if !srv.IsMariaDB() {
 				cmd = "mysql_default"
 			}
-			srv.GetDatabaseConfig()
+			srv.GetDatabaseConfigManifest()
 			_, needrestart := srv.ExecScriptSQL(strings.Split(srv.GetDatabaseDynamicConfig(dtag, cmd), ";"))
 			if needrestart {
 				srv.SetRestartCookie()
@@ -1009,6 +1009,13 @@ func (cluster *Cluster) isSlaveElectable(sl *ServerMonitor, forcingLog bool) boo
 		}
 		return false
 	}
+	if sl.HasReplicationFilters() && !cluster.Conf.FailAllowReplicationFilters {
+		cluster.sme.AddState("ERR00085", state.State{ErrType: "WARNING", ErrDesc: fmt.Sprintf(clusterError["ERR00085"], sl.URL, sl.GetReplicationFiltersString()), ErrFrom: "CHECK", ServerUrl: sl.URL})
+		if cluster.Conf.LogLevel > 1 || forcingLog {
+			cluster.LogPrintf(LvlWarn, "Unsafe failover condition. Slave %s has replication filters %s. Skipping", sl.URL, sl.GetReplicationFiltersString())
+		}
+		return false
+	}
 	if sl.IsIgnored() {
 		if cluster.Conf.LogLevel > 1 || forcingLog {
 			cluster.LogPrintf(LvlWarn, "Slave is in ignored list %s", sl.URL)
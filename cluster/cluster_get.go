@@ -9,7 +9,10 @@ package cluster
 import (
 	"encoding/json"
 	"errors"
+	"fmt"
 	"io/ioutil"
+	"math"
+	"os"
 	"sort"
 	"strconv"
 	"strings"
@@ -17,6 +20,7 @@ import (
 	"github.com/siddontang/go/log"
 	"github.com/signal18/replication-manager/config"
 	"github.com/signal18/replication-manager/utils/cron"
+	"github.com/signal18/replication-manager/utils/dbhelper"
 	"github.com/signal18/replication-manager/utils/misc"
 	"github.com/signal18/replication-manager/utils/state"
 )
@@ -70,13 +74,22 @@ func (cluster *Cluster) GetDomainHeadCluster() string {
 	return ""
 }
 
+// GetMaintenanceWindows returns a copy of the maintenance windows, expired ones included until the next window set
+func (cluster *Cluster) GetMaintenanceWindows() []MaintenanceWindow {
+	cluster.Lock()
+	defer cluster.Unlock()
+	return append([]MaintenanceWindow{}, cluster.MaintenanceWindows...)
+}
+
 func (cluster *Cluster) GetPersitentState() error {
 
 	type Save struct {
-		Servers    string      `json:"servers"`
-		Crashes    crashList   `json:"crashes"`
-		SLA        state.Sla   `json:"sla"`
-		SLAHistory []state.Sla `json:"slaHistory"`
+		Servers            string              `json:"servers"`
+		Crashes            crashList           `json:"crashes"`
+		SLA                state.Sla           `json:"sla"`
+		SLAHistory         []state.Sla         `json:"slaHistory"`
+		MaintenanceWindows []MaintenanceWindow `json:"maintenanceWindows"`
+		KillPolicies       []KillPolicy        `json:"killPolicies"`
 	}
 
 	var clsave Save
@@ -95,6 +108,15 @@ func (cluster *Cluster) GetPersitentState() error {
 	}
 	cluster.SLAHistory = clsave.SLAHistory
 	cluster.Crashes = clsave.Crashes
+	cluster.Lock()
+	cluster.MaintenanceWindows = append([]MaintenanceWindow{}, clsave.MaintenanceWindows...)
+	cluster.Unlock()
+	// policies added from the API override the monitoring-kill-policies of the same name
+	for _, p := range clsave.KillPolicies {
+		if err := cluster.AddKillPolicy(p); err != nil {
+			cluster.LogPrintf(LvlErr, "Skip saved kill policy: %s", err)
+		}
+	}
 	cluster.sme.SetSla(clsave.SLA)
 	cluster.sme.SetMasterUpAndSyncRestart()
 
@@ -383,6 +405,102 @@ func (cluster *Cluster) GetMasterFromReplication(slave *ServerMonitor) (*ServerM
 	return nil, nil
 }
 
+// getReplicationSource returns the monitored server a replica was last seen replicating from
+func (cluster *Cluster) getReplicationSource(server *ServerMonitor) *ServerMonitor {
+	ss, err := server.GetSlaveStatusLastSeen(server.ReplicationSourceName)
+	if err != nil || ss.MasterHost.String == "" {
+		return nil
+	}
+	return cluster.GetServerFromURL(ss.MasterHost.String + ":" + ss.MasterPort.String)
+}
+
+// GetOrphanedReplicas returns the replicas whose immediate source is a failed relay, the replicas of a failed
+// master are left to the failover
+func (cluster *Cluster) GetOrphanedReplicas() []*ServerMonitor {
+	var orphans []*ServerMonitor
+	for _, s := range cluster.Servers {
+		if s == nil || s.IsFailed() || s.IsIgnored() {
+			continue
+		}
+		source := cluster.getReplicationSource(s)
+		if source == nil || source == cluster.master || !source.IsFailed() {
+			continue
+		}
+		orphans = append(orphans, s)
+	}
+	return orphans
+}
+
+// GetBestNewSourceFor suggests a source for a replica of a failed relay, the relay own source or one of its
+// healthy siblings logging slave updates, that has applied at least the replica GTID position. The least
+// delayed candidate is preferred, the grandparent on equal delay
+func (cluster *Cluster) GetBestNewSourceFor(replica *ServerMonitor) (ReplicationSourceAdvice, error) {
+	advice := ReplicationSourceAdvice{URL: replica.URL}
+	relay := cluster.getReplicationSource(replica)
+	if relay == nil || !relay.IsFailed() {
+		return advice, errors.New("Replica source is not a failed server")
+	}
+	advice.FailedSource = relay.URL
+	var mode string
+	switch {
+	case replica.DBVersion.IsMariaDB() && replica.HasMariaDBGTID():
+		mode = "SLAVE_POS"
+	case replica.DBVersion.IsMySQLOrPercona() && replica.HaveMySQLGTID:
+		mode = "MASTER_AUTO_POSITION"
+	default:
+		return advice, errors.New("Replica source can not be changed without GTID")
+	}
+	var candidates []*ServerMonitor
+	grandparent := cluster.getReplicationSource(relay)
+	if grandparent == nil && cluster.master != nil && cluster.master != relay {
+		grandparent = cluster.master
+	}
+	if grandparent != nil {
+		candidates = append(candidates, grandparent)
+		for _, s := range cluster.Servers {
+			if s != relay && s != replica && s != grandparent && s.HaveBinlog && s.HaveBinlogSlaveUpdates && !s.IsReplicationBroken() && cluster.getReplicationSource(s) == grandparent {
+				candidates = append(candidates, s)
+			}
+		}
+	}
+	var best *ServerMonitor
+	for _, c := range candidates {
+		if c.IsFailed() || c.IsIgnored() || c.IsMaintenance || c.CurrentGtid == nil {
+			continue
+		}
+		if !c.CurrentGtid.Covers(replica.CurrentGtid, replica.DBVersion.IsMariaDB()) {
+			continue
+		}
+		if best == nil || c.GetReplicationDelay() < best.GetReplicationDelay() {
+			best = c
+		}
+	}
+	if best == nil {
+		return advice, errors.New("No healthy source has applied the replica GTID position")
+	}
+	advice.Source = best.URL
+	advice.Mode = mode
+	if best == grandparent {
+		advice.Reason = "source of the failed relay"
+	} else {
+		advice.Reason = "sibling of the failed relay"
+	}
+	advice.ChangeMaster = dbhelper.GetChangeMasterStatement(dbhelper.ChangeMasterOpt{
+		Host:      best.Host,
+		Port:      best.Port,
+		User:      cluster.rplUser,
+		Password:  "XXX",
+		Retry:     strconv.Itoa(cluster.Conf.ForceSlaveHeartbeatRetry),
+		Heartbeat: strconv.Itoa(cluster.Conf.ForceSlaveHeartbeatTime),
+		Mode:      mode,
+		SSL:       cluster.Conf.ReplicationSSL,
+		Channel:   replica.ReplicationSourceName,
+		IsDelayed: replica.IsDelayed,
+		Delay:     strconv.Itoa(cluster.Conf.HostsDelayedTime),
+	}, replica.DBVersion)
+	return advice, nil
+}
+
 func (cluster *Cluster) GetFailedServer() *ServerMonitor {
 	for _, server := range cluster.Servers {
 		if server.State == stateFailed {
@@ -392,12 +510,8 @@ func (cluster *Cluster) GetFailedServer() *ServerMonitor {
 	return nil
 }
 
-func (cluster *Cluster) GetBackupServer() *ServerMonitor {
-	if !cluster.IsDiscovered() || len(cluster.Servers) < 1 {
-		return nil
-	}
-	//1	cluster.LogPrintf(LvlInfo, "%d ", len(cluster.Servers))
-
+// getPreferedBackupServer returns the first non failed prefered backup host
+func (cluster *Cluster) getPreferedBackupServer() *ServerMonitor {
 	for _, server := range cluster.Servers {
 		if server == nil {
 			return nil
@@ -412,6 +526,36 @@ func (cluster *Cluster) GetBackupServer() *ServerMonitor {
 	return nil
 }
 
+// GetBackupServer returns the best node to take a backup from: a prefered backup host, else the least delayed
+// slave under failover-max-slave-delay, the master only when no slave can serve, nil with the reason otherwise
+func (cluster *Cluster) GetBackupServer() (*ServerMonitor, error) {
+	if !cluster.IsDiscovered() || len(cluster.Servers) < 1 {
+		return nil, errors.New("Cluster topology not discovered")
+	}
+	if srv := cluster.getPreferedBackupServer(); srv != nil {
+		return srv, nil
+	}
+	var candidate *ServerMonitor
+	for _, sl := range cluster.slaves {
+		if sl.IsFailed() || sl.IsMaintenance || sl.IsIgnored() || !sl.IsSQLThreadRunning() {
+			continue
+		}
+		if cluster.Conf.FailMaxDelay != -1 && sl.GetReplicationDelay() > cluster.Conf.FailMaxDelay {
+			continue
+		}
+		if candidate == nil || sl.GetReplicationDelay() < candidate.GetReplicationDelay() {
+			candidate = sl
+		}
+	}
+	if candidate != nil {
+		return candidate, nil
+	}
+	if cluster.master != nil && !cluster.master.IsFailed() {
+		return cluster.master, nil
+	}
+	return nil, errors.New("No prefered backup host, slave or master available for backup")
+}
+
 func (cluster *Cluster) GetFirstWorkingSlave() *ServerMonitor {
 	for _, server := range cluster.slaves {
 		if !server.IsDown() && !server.IsReplicationBroken() {
@@ -549,6 +693,647 @@ func (cluster *Cluster) GetClusterFromName(name string) (*Cluster, error) {
 	return nil, errors.New("No cluster found")
 }
 
+// ExportAllVariables write the variables of every monitored server into dir, one cnf file per server
+func (cluster *Cluster) ExportAllVariables(dir string) error {
+	err := os.MkdirAll(dir, 0755)
+	if err != nil {
+		return err
+	}
+	for _, server := range cluster.Servers {
+		if server.IsFailed() || len(server.Variables) == 0 {
+			continue
+		}
+		err = server.ExportVariables(dir + "/" + server.Id + "_variables.cnf")
+		if err != nil {
+			cluster.LogPrintf(LvlErr, "Could not export variables of server %s: %s", server.URL, err)
+			return err
+		}
+	}
+	return nil
+}
+
+// GetConsistencyCheckResults returns the chunked checksum results per table and slave, oldest first
+func (cluster *Cluster) GetConsistencyCheckResults() []ConsistencyCheckResult {
+	cluster.Lock()
+	defer cluster.Unlock()
+	res := make([]ConsistencyCheckResult, len(cluster.ConsistencyCheckResults))
+	copy(res, cluster.ConsistencyCheckResults)
+	return res
+}
+
+// GetMasterBinlogWriteRate returns the bytes per second written to the master binary log between the last two polls
+func (cluster *Cluster) GetMasterBinlogWriteRate() float64 {
+	if cluster.master == nil {
+		return 0
+	}
+	return cluster.master.BinlogWriteRate
+}
+
+// GetReplicationThroughput returns for each slave its delay and apply rate next to the master write rate,
+// a slave applying slower than the master writes lags because of its own capacity
+func (cluster *Cluster) GetReplicationThroughput() []ReplicationThroughput {
+	var res []ReplicationThroughput
+	for _, sl := range cluster.slaves {
+		res = append(res, ReplicationThroughput{URL: sl.URL, Delay: sl.GetReplicationDelay(), ApplyRate: sl.ReplicationApplyRate, MasterWriteRate: cluster.GetMasterBinlogWriteRate()})
+	}
+	return res
+}
+
+// GetVersionSkew returns the version of each monitored server, flagging servers older than the cluster newest
+// and slaves that can not safely replicate from the master version
+func (cluster *Cluster) GetVersionSkew() []VersionSkew {
+	newest := cluster.getNewestVersion()
+	var skew []VersionSkew
+	for _, server := range cluster.Servers {
+		if server.IsFailed() || server.DBVersion == nil {
+			continue
+		}
+		vs := VersionSkew{URL: server.URL, Version: server.DBVersion.ToString(), Lagging: server.DBVersion.Compare(newest) < 0}
+		if cluster.master != nil && cluster.master.DBVersion != nil && server != cluster.master {
+			vs.Reason = getReplicationIncompatibility(cluster.master.DBVersion, server.DBVersion)
+			vs.Incompatible = vs.Reason != ""
+		}
+		skew = append(skew, vs)
+	}
+	return skew
+}
+
+// GetServersNeedingUpgrade returns the servers running a version older than the cluster newest
+func (cluster *Cluster) GetServersNeedingUpgrade() serverList {
+	newest := cluster.getNewestVersion()
+	var servers serverList
+	for _, server := range cluster.Servers {
+		if !server.IsFailed() && server.DBVersion != nil && server.DBVersion.Compare(newest) < 0 {
+			servers = append(servers, server)
+		}
+	}
+	return servers
+}
+
+func (cluster *Cluster) getNewestVersion() *dbhelper.MySQLVersion {
+	var newest *dbhelper.MySQLVersion
+	for _, server := range cluster.Servers {
+		if server.IsFailed() || server.DBVersion == nil {
+			continue
+		}
+		if newest == nil || server.DBVersion.Compare(newest) > 0 {
+			newest = server.DBVersion
+		}
+	}
+	return newest
+}
+
+// getReplicationIncompatibility returns why a slave can not replicate from master, replication is only supported
+// from an older or same major version to a newer one and between flavors sharing the same replication protocol
+func getReplicationIncompatibility(master *dbhelper.MySQLVersion, slave *dbhelper.MySQLVersion) string {
+	if master.IsMariaDB() != slave.IsMariaDB() || master.IsPPostgreSQL() != slave.IsPPostgreSQL() {
+		return "flavor mismatch"
+	}
+	if master.Major > slave.Major || (master.Major == slave.Major && master.Minor > slave.Minor) {
+		return "replicating from newer to older major version"
+	}
+	return ""
+}
+
+// GetGtidStrictnessIssues returns the servers where GTID strictness differs from the cluster norm, the norm
+// is the master strictness or the strictness of most servers when there is no master
+func (cluster *Cluster) GetGtidStrictnessIssues() []GtidStrictnessIssue {
+	var issues []GtidStrictnessIssue
+	var servers []*ServerMonitor
+	strictCount := 0
+	for _, server := range cluster.Servers {
+		if server.IsFailed() || len(server.Variables) == 0 {
+			continue
+		}
+		servers = append(servers, server)
+		if server.HasGtidStrictness() {
+			strictCount++
+		}
+	}
+	norm := strictCount*2 >= len(servers)
+	if cluster.master != nil && len(cluster.master.Variables) > 0 {
+		norm = cluster.master.HasGtidStrictness()
+	}
+	for _, server := range servers {
+		if server.HasGtidStrictness() == norm {
+			continue
+		}
+		statements, configLines := server.GetGtidStrictnessRemediation(norm)
+		issues = append(issues, GtidStrictnessIssue{URL: server.URL, Strict: server.HasGtidStrictness(), Statements: statements, ConfigLines: configLines})
+	}
+	return issues
+}
+
+// GetRollingRestartOrder returns the servers in restart order, leaf slaves before relays, the most
+// lagging first so that the least lagging slave stays available the longest, and the master last
+func (cluster *Cluster) GetRollingRestartOrder() []*ServerMonitor {
+	var order []*ServerMonitor
+	for _, sl := range cluster.slaves {
+		if sl != cluster.master {
+			order = append(order, sl)
+		}
+	}
+	sort.SliceStable(order, func(i, j int) bool {
+		if order[i].IsRelay != order[j].IsRelay {
+			return !order[i].IsRelay
+		}
+		return order[i].GetReplicationDelay() > order[j].GetReplicationDelay()
+	})
+	if cluster.master != nil {
+		order = append(order, cluster.master)
+	}
+	return order
+}
+
+// GetPendingRestartServers returns the servers holding a restart cookie with the cookie creation time
+func (cluster *Cluster) GetPendingRestartServers() []PendingCookie {
+	return cluster.getPendingCookieServers("restart")
+}
+
+// GetPendingReprovServers returns the servers holding a reprov cookie with the cookie creation time
+func (cluster *Cluster) GetPendingReprovServers() []PendingCookie {
+	return cluster.getPendingCookieServers("reprov")
+}
+
+func (cluster *Cluster) getPendingCookieServers(cookie string) []PendingCookie {
+	var pending []PendingCookie
+	for _, s := range cluster.Servers {
+		if created, ok := s.GetCookieTime(cookie); ok {
+			pending = append(pending, PendingCookie{URL: s.URL, Created: created})
+		}
+	}
+	return pending
+}
+
+// GetServersInMaintenance returns the servers intentionally parked with the reasons they are excluded
+// from failover, reads or health, servers without reason are not returned
+func (cluster *Cluster) GetServersInMaintenance() []ServerMaintenance {
+	var res []ServerMaintenance
+	inWindow := cluster.IsInMaintenanceWindow()
+	for _, s := range cluster.Servers {
+		var reasons []string
+		if s.IsIgnored() || cluster.IsInIgnoredHosts(s) {
+			reasons = append(reasons, "ignored host")
+		}
+		if s.IsMaintenance {
+			reasons = append(reasons, "maintenance mode")
+		}
+		if inWindow {
+			reasons = append(reasons, "cluster maintenance window")
+		}
+		if s.HasRestartCookie() {
+			reasons = append(reasons, "pending restart cookie")
+		}
+		if s.HasReprovCookie() {
+			reasons = append(reasons, "pending reprov cookie")
+		}
+		if s.HasWaitStartCookie() {
+			reasons = append(reasons, "waiting start cookie")
+		}
+		if s.HasWaitStopCookie() {
+			reasons = append(reasons, "waiting stop cookie")
+		}
+		if s.HasReplicationFilters() && !cluster.Conf.FailAllowReplicationFilters {
+			reasons = append(reasons, "replication filters exclude from election")
+		}
+		if !s.IsReadEligible() {
+			reasons = append(reasons, "replication delay exclude from reads")
+		}
+		if len(reasons) > 0 {
+			res = append(res, ServerMaintenance{URL: s.URL, Reasons: reasons})
+		}
+	}
+	return res
+}
+
+// GetReadWriteSplitAdvice estimates from the status counters delta of the last monitoring loop how much of the
+// read load served by the master could move to the replicas, the read pool is the replicas eligible for reads
+// right now ordered by replication delay
+func (cluster *Cluster) GetReadWriteSplitAdvice() ReadWriteSplitAdvice {
+	var advice ReadWriteSplitAdvice
+	var pool []*ServerMonitor
+	for _, s := range cluster.Servers {
+		if s == nil || s.IsFailed() {
+			continue
+		}
+		advice.Reads += s.GetStatusDeltaValue("COM_SELECT")
+	}
+	for _, sl := range cluster.slaves {
+		if sl.IsFailed() || sl.IsIgnored() || sl.IsMaintenance || sl.IsReplicationBroken() || !sl.IsReadEligible() {
+			continue
+		}
+		pool = append(pool, sl)
+	}
+	sort.SliceStable(pool, func(i, j int) bool { return pool[i].GetReplicationDelay() < pool[j].GetReplicationDelay() })
+	advice.ReadPool = []string{}
+	for _, sl := range pool {
+		advice.ReadPool = append(advice.ReadPool, sl.URL)
+	}
+	if cluster.master != nil && !cluster.master.IsFailed() {
+		for _, key := range []string{"COM_INSERT", "COM_INSERT_SELECT", "COM_UPDATE", "COM_UPDATE_MULTI", "COM_DELETE", "COM_DELETE_MULTI", "COM_REPLACE", "COM_REPLACE_SELECT"} {
+			advice.Writes += cluster.master.GetStatusDeltaValue(key)
+		}
+		if len(pool) > 0 {
+			advice.OffloadableReads = cluster.master.GetStatusDeltaValue("COM_SELECT")
+		}
+	}
+	if total := advice.Reads + advice.Writes; total > 0 {
+		advice.ReadRatio = float64(advice.Reads) / float64(total)
+		advice.OffloadRatio = float64(advice.OffloadableReads) / float64(total)
+	}
+	return advice
+}
+
+// GetMasterConsistencyWarnings returns the signs of a wrong master designation, a master applying events from a
+// running source within failover-max-slave-delay is a replica, a writable server without replication is a second
+// master. Multi master topologies are not checked
+func (cluster *Cluster) GetMasterConsistencyWarnings() []string {
+	warnings := []string{}
+	for _, st := range cluster.getMasterConsistencyStates() {
+		warnings = append(warnings, st.ErrDesc)
+	}
+	return warnings
+}
+
+func (cluster *Cluster) getMasterConsistencyStates() []state.State {
+	var states []state.State
+	master := cluster.GetMaster()
+	if master == nil || master.IsFailed() || cluster.Conf.MultiMaster || cluster.Conf.MultiMasterRing || cluster.Conf.MultiMasterWsrep {
+		return states
+	}
+	if ss, err := master.GetSlaveStatus(master.ReplicationSourceName); err == nil && master.IsSQLThreadRunning() && ss.MasterHost.String != "" && ss.SecondsBehindMaster.Valid {
+		if cluster.Conf.FailMaxDelay <= 0 || ss.SecondsBehindMaster.Int64 <= cluster.Conf.FailMaxDelay {
+			states = append(states, state.State{ErrKey: "WARN0107", ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0107"], master.URL, ss.MasterHost.String+":"+ss.MasterPort.String, ss.SecondsBehindMaster.Int64), ErrFrom: "MON", ServerUrl: master.URL})
+		}
+	}
+	for _, s := range cluster.Servers {
+		if s == nil || s == master || s.IsFailed() || s.IsIgnored() || s.IsMaintenance || s.IsReadOnly() {
+			continue
+		}
+		if _, err := s.GetSlaveStatus(s.ReplicationSourceName); err != nil {
+			states = append(states, state.State{ErrKey: "WARN0108", ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0108"], s.URL, master.URL), ErrFrom: "MON", ServerUrl: s.URL})
+		}
+	}
+	return states
+}
+
+// GetReplicationDelayPercentile returns the replication delay percentile of the running slaves using nearest rank
+func (cluster *Cluster) GetReplicationDelayPercentile(percentile float64) int64 {
+	var delays []int64
+	for _, sl := range cluster.slaves {
+		if sl.IsFailed() {
+			continue
+		}
+		delays = append(delays, sl.GetReplicationDelay())
+	}
+	if len(delays) == 0 {
+		return 0
+	}
+	sort.Slice(delays, func(i, j int) bool { return delays[i] < delays[j] })
+	rank := int(math.Ceil(percentile/100*float64(len(delays)))) - 1
+	if rank < 0 {
+		rank = 0
+	}
+	if rank >= len(delays) {
+		rank = len(delays) - 1
+	}
+	return delays[rank]
+}
+
+// GetReplicationSafetyMargin returns the replication delay of the least delayed healthy slave, the data that
+// could be lost on failover, an error is returned when no slave is healthy
+func (cluster *Cluster) GetReplicationSafetyMargin() (int64, error) {
+	margin := int64(-1)
+	for _, sl := range cluster.slaves {
+		if sl.IsFailed() || sl.IsIgnored() || sl.IsMaintenance || sl.IsReplicationBroken() {
+			continue
+		}
+		if delay := sl.GetReplicationDelay(); margin == -1 || delay < margin {
+			margin = delay
+		}
+	}
+	if margin == -1 {
+		return 0, errors.New("No healthy slave")
+	}
+	return margin, nil
+}
+
+// GetMaxReplicationDelay returns the highest measured delay of the running slaves and true when a slave delay
+// can not be measured, IO thread stopped or Seconds_Behind_Master NULL, an unknown delay is worse than any finite one
+func (cluster *Cluster) GetMaxReplicationDelay() (int64, bool) {
+	var delay int64
+	unknown := false
+	for _, sl := range cluster.slaves {
+		if sl.IsFailed() {
+			continue
+		}
+		if !sl.HasReplicationDelay() {
+			unknown = true
+			continue
+		}
+		if d := sl.GetReplicationDelay(); d > delay {
+			delay = d
+		}
+	}
+	return delay, unknown
+}
+
+// getGroupPath returns the groups of the cluster from the top of the hierarchy down, emea/prod gives emea and
+// emea/prod
+func (cluster *Cluster) getGroupPath() []string {
+	var groups []string
+	var path string
+	for _, g := range strings.Split(cluster.Conf.ClusterGroup, "/") {
+		g = strings.TrimSpace(g)
+		if g == "" {
+			continue
+		}
+		if path != "" {
+			path = path + "/"
+		}
+		path = path + g
+		groups = append(groups, path)
+	}
+	return groups
+}
+
+// GetGroupReplicationSummary returns the roll up of the groups of the cluster from the top of the hierarchy
+// down, nothing when the cluster has no cluster-group
+func (cluster *Cluster) GetGroupReplicationSummary() []GroupReplicationSummary {
+	return cluster.GetGroupReplicationSummaryOf(cluster.clusterList)
+}
+
+// GetGroupReplicationSummaryOf returns the roll up of the groups of the cluster restricted to the given
+// clusters, used to leave out the clusters an API user can not access
+func (cluster *Cluster) GetGroupReplicationSummaryOf(clusters map[string]*Cluster) []GroupReplicationSummary {
+	groups := cluster.getGroupPath()
+	if len(groups) == 0 {
+		return nil
+	}
+	var summaries []GroupReplicationSummary
+	for _, s := range GetGroupReplicationSummaries(clusters) {
+		for _, g := range groups {
+			if s.Group == g {
+				summaries = append(summaries, s)
+			}
+		}
+	}
+	return summaries
+}
+
+// GetGroupReplicationSummaries returns the roll up of every group of the clusters ordered by group, clusters
+// without cluster-group are left out
+func GetGroupReplicationSummaries(clusters map[string]*Cluster) []GroupReplicationSummary {
+	var names []string
+	for name := range clusters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	groups := make(map[string]*GroupReplicationSummary)
+	var order []string
+	for _, name := range names {
+		c := clusters[name]
+		if c == nil {
+			continue
+		}
+		path := c.getGroupPath()
+		if len(path) == 0 {
+			continue
+		}
+		masterFailed := c.IsMasterFailed()
+		inFailover := c.IsInFailover()
+		healthy := !masterFailed && !inFailover && c.GetStatus()
+		delay, unknown := c.GetMaxReplicationDelay()
+		for _, g := range path {
+			s, ok := groups[g]
+			if !ok {
+				s = &GroupReplicationSummary{Group: g, Clusters: []string{}, DelayUnknown: []string{}}
+				groups[g] = s
+				order = append(order, g)
+			}
+			s.Clusters = append(s.Clusters, c.Name)
+			if healthy {
+				s.Healthy++
+			}
+			if masterFailed {
+				s.MasterFailed++
+			}
+			if inFailover {
+				s.InFailover++
+			}
+			if unknown {
+				s.DelayUnknown = append(s.DelayUnknown, c.Name)
+			}
+			if delay > s.MaxDelay {
+				s.MaxDelay = delay
+				s.MaxDelayCluster = c.Name
+			}
+		}
+	}
+	sort.Strings(order)
+	var summaries []GroupReplicationSummary
+	for _, g := range order {
+		summaries = append(summaries, *groups[g])
+	}
+	return summaries
+}
+
+// GetGroupPrometheusMetrics returns the roll up metrics of the groups labelled by group
+func GetGroupPrometheusMetrics(summaries []GroupReplicationSummary) string {
+	replacer := strings.NewReplacer("\\", "\\\\", "\"", "\\\"", "\n", "\\n")
+	var s string
+	for _, g := range summaries {
+		label := "{group=\"" + replacer.Replace(g.Group) + "\"} "
+		s = s + "group_clusters" + label + strconv.Itoa(len(g.Clusters)) + "\n"
+		s = s + "group_clusters_healthy" + label + strconv.Itoa(g.Healthy) + "\n"
+		s = s + "group_clusters_master_failed" + label + strconv.Itoa(g.MasterFailed) + "\n"
+		s = s + "group_clusters_in_failover" + label + strconv.Itoa(g.InFailover) + "\n"
+		s = s + "group_replication_delay_max_seconds" + label + strconv.FormatInt(g.MaxDelay, 10) + "\n"
+		s = s + "group_replication_delay_unknown_clusters" + label + strconv.Itoa(len(g.DelayUnknown)) + "\n"
+	}
+	return s
+}
+
+// GetReplicaReadWeights returns the read weights of the slaves for the proxies supporting weighted routing,
+// the weight decays with the replication delay according to read-weight-decay and drops to zero past
+// read-max-slave-delay, broken or failed slaves get no reads
+func (cluster *Cluster) GetReplicaReadWeights() []ReplicaReadWeight {
+	var weights []ReplicaReadWeight
+	var raw []float64
+	for _, sl := range cluster.slaves {
+		if sl == nil {
+			continue
+		}
+		rw := ReplicaReadWeight{URL: sl.URL, Delay: sl.GetReplicationDelay()}
+		w := 0.0
+		switch {
+		case sl.IsDown():
+			rw.Reason = "down"
+		case sl.IsIgnored() || sl.IsMaintenance:
+			rw.Reason = "ignored or in maintenance"
+		case !sl.IsIOThreadRunning() || !sl.IsSQLThreadRunning() || !sl.HasReplicationDelay():
+			rw.Reason = "replication not running"
+		case !sl.IsReadEligible() || (cluster.Conf.MaxReadLag > 0 && rw.Delay > cluster.Conf.MaxReadLag):
+			rw.Reason = "delay over read-max-slave-delay"
+		default:
+			w = cluster.getReadWeightDecay(rw.Delay)
+			rw.Eligible = w > 0
+			if !rw.Eligible {
+				rw.Reason = "delay over read-max-slave-delay"
+			}
+		}
+		weights = append(weights, rw)
+		raw = append(raw, w)
+	}
+	normalizeReadWeights(weights, raw)
+	return weights
+}
+
+func (cluster *Cluster) getReadWeightDecay(delay int64) float64 {
+	if cluster.Conf.ReadWeightDecay == ReadWeightDecayExponential {
+		halfLife := cluster.Conf.ReadWeightHalfLife
+		if halfLife <= 0 {
+			halfLife = 10
+		}
+		return math.Pow(0.5, float64(delay)/float64(halfLife))
+	}
+	maxDelay := cluster.Conf.MaxReadLag
+	if maxDelay <= 0 {
+		maxDelay = cluster.Conf.FailMaxDelay
+	}
+	if maxDelay <= 0 {
+		maxDelay = 30
+	}
+	return math.Max(0, 1-float64(delay)/float64(maxDelay))
+}
+
+// normalizeReadWeights scales the weights of the eligible slaves to sum to 100, rounding errors are given to the
+// slave with the highest weight
+func normalizeReadWeights(weights []ReplicaReadWeight, raw []float64) {
+	var sum float64
+	for _, w := range raw {
+		sum += w
+	}
+	if sum == 0 {
+		return
+	}
+	total, top := 0, 0
+	for i, w := range raw {
+		weights[i].Weight = int(math.Round(w * 100 / sum))
+		total += weights[i].Weight
+		if raw[i] > raw[top] {
+			top = i
+		}
+	}
+	weights[top].Weight += 100 - total
+}
+
+// GetHealthScore returns a 0 to 100 cluster health score from replication delay, failed servers, GTID errant
+// transactions and schema drift, each signal weighted by its health-score-weight
+func (cluster *Cluster) GetHealthScore() HealthScore {
+	var hs HealthScore
+	maxDelay := cluster.Conf.FailMaxDelay
+	if maxDelay <= 0 {
+		maxDelay = 30
+	}
+	delay := cluster.GetReplicationDelayPercentile(90)
+	hs.Components = append(hs.Components, HealthScoreComponent{Name: "delay", Weight: cluster.Conf.HealthScoreWeightDelay, Penalty: math.Min(1, float64(delay)/float64(maxDelay)), Detail: fmt.Sprintf("90th percentile replication delay %ds", delay)})
+	down := 0
+	for _, s := range cluster.Servers {
+		if s.State == stateFailed || s.State == stateSuspect {
+			down++
+		}
+	}
+	var penalty float64
+	if len(cluster.Servers) > 0 {
+		penalty = float64(down) / float64(len(cluster.Servers))
+	}
+	hs.Components = append(hs.Components, HealthScoreComponent{Name: "down", Weight: cluster.Conf.HealthScoreWeightDown, Penalty: penalty, Detail: fmt.Sprintf("%d of %d servers down", down, len(cluster.Servers))})
+	hs.Components = append(hs.Components, cluster.getHealthScoreStateComponent("gtid", cluster.Conf.HealthScoreWeightGtid, "WARN0091", "errant transactions"))
+	hs.Components = append(hs.Components, cluster.getHealthScoreStateComponent("drift", cluster.Conf.HealthScoreWeightDrift, "WARN0103", "schema charset drift"))
+	weights := 0
+	var lost float64
+	for _, c := range hs.Components {
+		weights += c.Weight
+		lost += float64(c.Weight) * c.Penalty
+	}
+	hs.Score = 100
+	if weights > 0 {
+		hs.Score = int(math.Round(100 - lost*100/float64(weights)))
+	}
+	return hs
+}
+
+func (cluster *Cluster) getHealthScoreStateComponent(name string, weight int, key string, detail string) HealthScoreComponent {
+	if cluster.sme.IsInState(key) {
+		return HealthScoreComponent{Name: name, Weight: weight, Penalty: 1, Detail: detail}
+	}
+	return HealthScoreComponent{Name: name, Weight: weight, Detail: "no " + detail}
+}
+
+// GetServerWithMostSlaves returns the server the most replicas are attached to according to their slave status, nil when no replication exists
+func (cluster *Cluster) GetServerWithMostSlaves() *ServerMonitor {
+	var top *ServerMonitor
+	topCount := 0
+	for _, server := range cluster.Servers {
+		if server.ServerID == 0 {
+			continue
+		}
+		count := 0
+		for _, sl := range cluster.Servers {
+			if sl != server && sl.GetReplicationServerID() == server.ServerID {
+				count++
+			}
+		}
+		if count > topCount {
+			top = server
+			topCount = count
+		}
+	}
+	return top
+}
+
+// GetMisdirectedReplicas returns slaves replicating from another server than the elected master, matched on the
+// source server id and on the master host and port when the id is not known yet
+func (cluster *Cluster) GetMisdirectedReplicas() []*ServerMonitor {
+	var replicas []*ServerMonitor
+	if cluster.master == nil || cluster.master.IsFailed() || cluster.Conf.MultiTierSlave || cluster.Conf.MultiMaster || cluster.Conf.MultiMasterRing || cluster.Conf.MultiMasterWsrep {
+		return replicas
+	}
+	for _, sl := range cluster.slaves {
+		if sl.IsFailed() || sl.IsIgnored() || !sl.IsSlave || sl.Id == cluster.master.Id {
+			continue
+		}
+		if sid := sl.GetReplicationServerID(); sid != 0 && cluster.master.ServerID != 0 {
+			if sid != cluster.master.ServerID {
+				replicas = append(replicas, sl)
+			}
+			continue
+		}
+		host := sl.GetReplicationMasterHost()
+		if (host != cluster.master.Host && host != cluster.master.IP) || sl.GetReplicationMasterPort() != cluster.master.Port {
+			replicas = append(replicas, sl)
+		}
+	}
+	return replicas
+}
+
+// GetNonTransactionalTables returns master tables not crash safe for replication, sorted by size
+func (cluster *Cluster) GetNonTransactionalTables() []dbhelper.Table {
+	var tables []dbhelper.Table
+	if cluster.master == nil {
+		return tables
+	}
+	for _, t := range cluster.master.GetDictTables() {
+		switch strings.ToLower(t.Engine) {
+		case "innodb", "xtradb", "tokudb", "rocksdb", "postgres", "":
+			continue
+		}
+		tables = append(tables, t)
+	}
+	return tables
+}
+
 func (cluster *Cluster) GetTableDLL(schema string, table string, srv *ServerMonitor) (string, error) {
 	query := "SHOW CREATE TABLE `" + schema + "`.`" + table + "`"
 	var tbl, ddl string
@@ -0,0 +1,566 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/dbhelper"
+	"github.com/signal18/replication-manager/utils/gtid"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+func TestServerWithMostSlaves(t *testing.T) {
+	slaveOf := func(id uint64, masterID uint64) *ServerMonitor {
+		server := &ServerMonitor{ServerID: id}
+		if masterID != 0 {
+			server.ReplicationStatus = replicationStatusFixture{{MasterServerID: masterID}}
+		}
+		return server
+	}
+	cluster := &Cluster{Servers: serverList{slaveOf(1, 0), slaveOf(2, 0), slaveOf(3, 2)}}
+	if m := cluster.GetServerWithMostSlaves(); m == nil || m.ServerID != 2 {
+		t.Fatalf("Expected server 2, got %v", m)
+	}
+	cluster.Servers = append(cluster.Servers, slaveOf(4, 1), slaveOf(5, 1))
+	if m := cluster.GetServerWithMostSlaves(); m == nil || m.ServerID != 1 {
+		t.Fatalf("Expected server 1, got %v", m)
+	}
+	cluster.Servers = serverList{slaveOf(1, 0), slaveOf(2, 0)}
+	if m := cluster.GetServerWithMostSlaves(); m != nil {
+		t.Fatalf("Expected no server, got %d", m.ServerID)
+	}
+}
+
+func TestGtidStrictnessIssues(t *testing.T) {
+	mariadb := func(url string, strict string) *ServerMonitor {
+		return &ServerMonitor{URL: url, DBVersion: &dbhelper.MySQLVersion{Flavor: "MariaDB"}, Variables: map[string]string{"GTID_STRICT_MODE": strict}}
+	}
+	cluster := &Cluster{Servers: serverList{mariadb("db1:3306", "ON"), mariadb("db2:3306", "OFF"), mariadb("db3:3306", "ON")}}
+	issues := cluster.GetGtidStrictnessIssues()
+	if len(issues) != 1 || issues[0].URL != "db2:3306" || issues[0].Statements[0] != "SET GLOBAL gtid_strict_mode=ON" {
+		t.Fatalf("Unexpected issues %v", issues)
+	}
+	cluster.master = cluster.Servers[1]
+	if issues = cluster.GetGtidStrictnessIssues(); len(issues) != 2 || issues[0].ConfigLines[0] != "gtid_strict_mode = 0" {
+		t.Fatalf("Unexpected issues with master norm %v", issues)
+	}
+
+	mysql := &ServerMonitor{DBVersion: &dbhelper.MySQLVersion{Flavor: "MySQL"}, Variables: map[string]string{"GTID_MODE": "OFF_PERMISSIVE", "ENFORCE_GTID_CONSISTENCY": "WARN"}}
+	statements, _ := mysql.GetGtidStrictnessRemediation(true)
+	expected := []string{"SET GLOBAL enforce_gtid_consistency=ON", "SET GLOBAL gtid_mode=ON_PERMISSIVE", "SET GLOBAL gtid_mode=ON"}
+	if !reflect.DeepEqual(statements, expected) {
+		t.Fatalf("Got %v, expected %v", statements, expected)
+	}
+}
+
+func TestRollingRestartOrder(t *testing.T) {
+	slave := func(url string, delay int64, relay bool) *ServerMonitor {
+		return &ServerMonitor{URL: url, IsRelay: relay, ReplicationStatus: replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}}}}
+	}
+	master := &ServerMonitor{URL: "db1:3306"}
+	cluster := &Cluster{master: master, slaves: serverList{slave("db2:3306", 0, false), slave("db3:3306", 5, true), slave("db4:3306", 10, false), slave("db5:3306", 2, false)}}
+	var urls []string
+	for _, s := range cluster.GetRollingRestartOrder() {
+		urls = append(urls, s.URL)
+	}
+	expected := []string{"db4:3306", "db5:3306", "db2:3306", "db3:3306", "db1:3306"}
+	if !reflect.DeepEqual(urls, expected) {
+		t.Fatalf("Got %v, expected %v", urls, expected)
+	}
+}
+
+func TestPendingCookieServers(t *testing.T) {
+	db1 := &ServerMonitor{URL: "db1:3306", Datadir: t.TempDir()}
+	db2 := &ServerMonitor{URL: "db2:3306", Datadir: t.TempDir()}
+	cluster := &Cluster{Servers: serverList{db1, db2}}
+	db2.SetRestartCookie()
+	pending := cluster.GetPendingRestartServers()
+	if len(pending) != 1 || pending[0].URL != "db2:3306" || pending[0].Created.IsZero() {
+		t.Fatalf("Unexpected pending restart %v", pending)
+	}
+	if pending = cluster.GetPendingReprovServers(); len(pending) != 0 {
+		t.Fatalf("Unexpected pending reprov %v", pending)
+	}
+}
+
+func TestHealthScore(t *testing.T) {
+	slave := func(delay int64) *ServerMonitor {
+		return &ServerMonitor{State: stateSlave, ReplicationStatus: replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}}}}
+	}
+	sme := new(state.StateMachine)
+	sme.Init()
+	master := &ServerMonitor{State: stateMaster}
+	cluster := &Cluster{sme: sme, master: master, Conf: config.Config{FailMaxDelay: 30, HealthScoreWeightDelay: 40, HealthScoreWeightDown: 30, HealthScoreWeightGtid: 15, HealthScoreWeightDrift: 15}}
+	cluster.slaves = serverList{slave(0), slave(0), slave(15)}
+	cluster.Servers = append(serverList{master}, cluster.slaves...)
+	if d, _ := cluster.GetMaxReplicationDelay(); d != 15 {
+		t.Fatalf("Got max delay %d, expected 15", d)
+	}
+	if hs := cluster.GetHealthScore(); hs.Score != 80 || len(hs.Components) != 4 {
+		t.Fatalf("Got score %d with %v, expected 80", hs.Score, hs.Components)
+	}
+	cluster.slaves[2].ReplicationStatus = replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: 0, Valid: true}}}
+	cluster.slaves[1].State = stateFailed
+	if hs := cluster.GetHealthScore(); hs.Score != 93 {
+		t.Fatalf("Got score %d with %v, expected 93", hs.Score, hs.Components)
+	}
+}
+
+type recordWriteCircuitBreaker struct {
+	opened, closed int
+	err            error
+}
+
+func (r *recordWriteCircuitBreaker) Open(cluster *Cluster, margin int64) error {
+	r.opened++
+	return r.err
+}
+
+func (r *recordWriteCircuitBreaker) Close(cluster *Cluster) error {
+	r.closed++
+	return r.err
+}
+
+func TestReplicationSafetyMargin(t *testing.T) {
+	slave := func(delay int64, running string) *ServerMonitor {
+		return &ServerMonitor{State: stateSlave, ReplicationStatus: replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}, SlaveSQLRunning: sql.NullString{String: running, Valid: true}, SlaveIORunning: sql.NullString{String: running, Valid: true}}}}
+	}
+	sme := new(state.StateMachine)
+	sme.Init()
+	action := &recordWriteCircuitBreaker{}
+	cluster := &Cluster{sme: sme, WriteCircuitBreaker: action, Conf: config.Config{WriteCircuitBreakerMaxDelay: 10}}
+	cluster.slaves = serverList{slave(20, "Yes"), slave(0, "No"), slave(15, "Yes")}
+	if m, err := cluster.GetReplicationSafetyMargin(); err != nil || m != 15 {
+		t.Fatalf("Got margin %d %v, expected 15", m, err)
+	}
+	cluster.CheckWriteCircuitBreaker()
+	cluster.CheckWriteCircuitBreaker()
+	if !cluster.IsWriteCircuitOpen || action.opened != 1 || !sme.CurState.Search("WARN0104") {
+		t.Fatalf("Expected write circuit open once, got open %t opened %d", cluster.IsWriteCircuitOpen, action.opened)
+	}
+	cluster.slaves[2] = slave(5, "Yes")
+	cluster.CheckWriteCircuitBreaker()
+	if cluster.IsWriteCircuitOpen || action.closed != 1 {
+		t.Fatalf("Expected write circuit closed, got open %t closed %d", cluster.IsWriteCircuitOpen, action.closed)
+	}
+	cluster.slaves = serverList{slave(0, "No")}
+	if _, err := cluster.GetReplicationSafetyMargin(); err == nil {
+		t.Fatal("Expected error without healthy slave")
+	}
+
+	// slaves the monitor can not reach do not trip the circuit
+	unreachable := slave(0, "Yes")
+	unreachable.State = stateFailed
+	suspect := slave(0, "No")
+	suspect.State = stateSuspect
+	cluster.slaves = serverList{unreachable, suspect}
+	cluster.CheckWriteCircuitBreaker()
+	if cluster.IsWriteCircuitOpen || action.opened != 1 {
+		t.Fatalf("Expected write circuit closed with unreachable slaves, got open %t opened %d", cluster.IsWriteCircuitOpen, action.opened)
+	}
+	// a failing action leaves the circuit as it is and is retried
+	cluster.slaves = serverList{slave(0, "No")}
+	action.err = errors.New("read only failed")
+	cluster.CheckWriteCircuitBreaker()
+	if cluster.IsWriteCircuitOpen || action.opened != 2 {
+		t.Fatalf("Expected write circuit left closed on action failure, got open %t opened %d", cluster.IsWriteCircuitOpen, action.opened)
+	}
+	action.err = nil
+	cluster.CheckWriteCircuitBreaker()
+	if !cluster.IsWriteCircuitOpen || action.opened != 3 {
+		t.Fatalf("Expected write circuit open on confirmed breakage, got open %t opened %d", cluster.IsWriteCircuitOpen, action.opened)
+	}
+}
+
+func TestVersionSkew(t *testing.T) {
+	server := func(url string, version string) *ServerMonitor {
+		return &ServerMonitor{URL: url, State: stateSlave, DBVersion: dbhelper.NewMySQLVersion(version, "")}
+	}
+	master := server("db1:3306", "10.5.12-MariaDB-log")
+	master.State = stateMaster
+	cluster := &Cluster{master: master}
+	cluster.Servers = serverList{master, server("db2:3306", "10.6.4-MariaDB"), server("db3:3306", "10.4.21-MariaDB"), server("db4:3306", "8.0.26")}
+	skew := cluster.GetVersionSkew()
+	if len(skew) != 4 {
+		t.Fatalf("Got %d servers, expected 4", len(skew))
+	}
+	expected := []VersionSkew{
+		{URL: "db1:3306", Version: "MariaDB 10.5.12", Lagging: true},
+		{URL: "db2:3306", Version: "MariaDB 10.6.4"},
+		{URL: "db3:3306", Version: "MariaDB 10.4.21", Lagging: true, Incompatible: true, Reason: "replicating from newer to older major version"},
+		{URL: "db4:3306", Version: "MySQL 8.0.26", Lagging: true, Incompatible: true, Reason: "flavor mismatch"},
+	}
+	if !reflect.DeepEqual(skew, expected) {
+		t.Fatalf("Got %v, expected %v", skew, expected)
+	}
+	if servers := cluster.GetServersNeedingUpgrade(); len(servers) != 3 || servers[0] != master {
+		t.Fatalf("Got %d servers needing upgrade, expected 3", len(servers))
+	}
+
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster.sme = sme
+	sme.SetFailoverState()
+	cluster.CheckVersionSkew()
+	if sme.CurState.Search("WARN0105") {
+		t.Fatal("Expected no version skew check during failover")
+	}
+	sme.RemoveFailoverState()
+	cluster.master = nil
+	cluster.CheckVersionSkew()
+	if sme.CurState.Search("WARN0105") {
+		t.Fatal("Expected no version skew check without a master")
+	}
+	cluster.master = master
+	cluster.CheckVersionSkew()
+	if !sme.CurState.Search("WARN0105") || !sme.CurState.Search("WARN0106") {
+		t.Fatal("Expected version skew warnings")
+	}
+}
+
+func TestMaxReplicationDelayUnknown(t *testing.T) {
+	slave := func(delay sql.NullInt64) *ServerMonitor {
+		return &ServerMonitor{State: stateSlave, ReplicationStatus: replicationStatusFixture{{SecondsBehindMaster: delay}}}
+	}
+	cluster := &Cluster{}
+	cluster.slaves = serverList{slave(sql.NullInt64{Int64: 5, Valid: true}), slave(sql.NullInt64{Int64: 120, Valid: true})}
+	if d, unknown := cluster.GetMaxReplicationDelay(); d != 120 || unknown {
+		t.Fatalf("Got %d unknown %t, expected 120 measured", d, unknown)
+	}
+	cluster.slaves = append(cluster.slaves, slave(sql.NullInt64{}))
+	if d, unknown := cluster.GetMaxReplicationDelay(); d != 120 || !unknown {
+		t.Fatalf("Got %d unknown %t, expected unknown delay to dominate", d, unknown)
+	}
+	cluster.slaves[2].State = stateFailed
+	if _, unknown := cluster.GetMaxReplicationDelay(); unknown {
+		t.Fatal("Expected failed slave to be ignored")
+	}
+	cluster.slaves = serverList{slave(sql.NullInt64{})}
+	if d, unknown := cluster.GetMaxReplicationDelay(); d != 0 || !unknown {
+		t.Fatalf("Got %d unknown %t, expected only unknown delay", d, unknown)
+	}
+}
+
+func TestGetBackupServer(t *testing.T) {
+	slave := func(url string, delay int64) *ServerMonitor {
+		return &ServerMonitor{URL: url, State: stateSlave, IsSlave: true, ReplicationStatus: replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}}}}
+	}
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{sme: sme, Conf: config.Config{FailMaxDelay: 30}}
+	if _, err := cluster.GetBackupServer(); err == nil {
+		t.Fatal("Expected no backup server before discovery")
+	}
+	sme.CanMonitor()
+	master := &ServerMonitor{URL: "db1:3306", State: stateMaster}
+	db2, db3, db4 := slave("db2:3306", 10), slave("db3:3306", 2), slave("db4:3306", 60)
+	cluster.master = master
+	cluster.slaves = serverList{db2, db3, db4}
+	cluster.Servers = serverList{master, db2, db3, db4}
+	if srv, err := cluster.GetBackupServer(); err != nil || srv != db3 {
+		t.Fatalf("Expected the least delayed slave db3, got %v %v", srv, err)
+	}
+	db4.PreferedBackup = true
+	if srv, _ := cluster.GetBackupServer(); srv != db4 {
+		t.Fatalf("Expected the prefered backup host db4, got %v", srv)
+	}
+	db4.PreferedBackup = false
+	db2.State, db3.State = stateFailed, stateFailed
+	if srv, _ := cluster.GetBackupServer(); srv != master {
+		t.Fatalf("Expected the master when no slave is under the max delay, got %v", srv)
+	}
+	master.State = stateFailed
+	if srv, err := cluster.GetBackupServer(); err == nil || srv != nil {
+		t.Fatalf("Expected no backup server, got %v", srv)
+	}
+}
+
+func TestServersInMaintenance(t *testing.T) {
+	db1 := &ServerMonitor{URL: "db1:3306", Name: "db1", Datadir: t.TempDir()}
+	db2 := &ServerMonitor{URL: "db2:3306", Name: "db2", Datadir: t.TempDir(), IsMaintenance: true}
+	db3 := &ServerMonitor{URL: "db3:3306", Name: "db3", Datadir: t.TempDir(), Variables: map[string]string{"REPLICATE_DO_DB": "app"}}
+	cluster := &Cluster{Servers: serverList{db1, db2, db3}, Conf: config.Config{IgnoreSrv: "db3:3306"}}
+	db2.SetReprovCookie()
+	res := cluster.GetServersInMaintenance()
+	expected := []ServerMaintenance{
+		{URL: "db2:3306", Reasons: []string{"maintenance mode", "pending reprov cookie"}},
+		{URL: "db3:3306", Reasons: []string{"ignored host", "replication filters exclude from election"}},
+	}
+	if !reflect.DeepEqual(res, expected) {
+		t.Fatalf("Got %v, expected %v", res, expected)
+	}
+}
+
+func TestDuplicateServers(t *testing.T) {
+	host := &ServerMonitor{URL: "db1:3306", Host: "db1", Port: "3306", IP: "10.0.0.1"}
+	ip := &ServerMonitor{URL: "10.0.0.1:3306", Host: "10.0.0.1", Port: "3306", IP: "10.0.0.1"}
+	fqdn := &ServerMonitor{URL: "db1.example.com", Host: "db1.example.com", IP: "10.0.0.1"}
+	otherPort := &ServerMonitor{URL: "db1:3307", Host: "db1", Port: "3307", IP: "10.0.0.1"}
+	unresolved := &ServerMonitor{URL: "db2:3306", Host: "db2", Port: "3306"}
+	unresolvedCase := &ServerMonitor{URL: "DB2:3306", Host: "DB2", Port: "3306"}
+	other := &ServerMonitor{URL: "db3:3306", Host: "db3", Port: "3306", IP: "10.0.0.3"}
+	tests := []struct {
+		servers  serverList
+		expected [][]*ServerMonitor
+	}{
+		{serverList{host, other}, nil},
+		{serverList{host, otherPort, other}, nil},
+		{serverList{host, ip, fqdn, otherPort, other}, [][]*ServerMonitor{{host, ip, fqdn}}},
+		{serverList{host, unresolved, ip, unresolvedCase}, [][]*ServerMonitor{{host, ip}, {unresolved, unresolvedCase}}},
+	}
+	for _, test := range tests {
+		cluster := &Cluster{Servers: test.servers}
+		if res := cluster.FindDuplicateServers(); !reflect.DeepEqual(res, test.expected) {
+			t.Fatalf("Got %v, expected %v", res, test.expected)
+		}
+		if cluster.HasDuplicateServers() != (test.expected != nil) {
+			t.Fatalf("Unexpected HasDuplicateServers for %v", test.servers)
+		}
+	}
+}
+
+func TestMaintenanceWindows(t *testing.T) {
+	cluster := &Cluster{}
+	now := time.Now()
+	if err := cluster.SetMaintenanceWindow(now, now.Add(-time.Minute)); err == nil {
+		t.Fatal("Expected a window ending before its start refused")
+	}
+	if err := cluster.SetMaintenanceWindow(now.Add(time.Hour), now.Add(2*time.Hour)); err != nil {
+		t.Fatal(err)
+	}
+	if cluster.IsInMaintenanceWindow() {
+		t.Fatal("Unexpected maintenance window before its start")
+	}
+	// overlapping windows are kept side by side
+	cluster.SetMaintenanceWindow(now.Add(-time.Minute), now.Add(90*time.Minute))
+	if !cluster.IsInMaintenanceWindow() || len(cluster.MaintenanceWindows) != 2 {
+		t.Fatalf("Expected in the overlapping window, got %d windows", len(cluster.MaintenanceWindows))
+	}
+	// an expired window no more suppress alerts and is purged by the next window set
+	cluster.MaintenanceWindows = []MaintenanceWindow{{Start: now.Add(-2 * time.Hour), End: now.Add(-time.Hour)}}
+	if cluster.IsInMaintenanceWindow() {
+		t.Fatal("Unexpected maintenance window after its end")
+	}
+	cluster.SetMaintenanceWindow(now.Add(time.Hour), now.Add(2*time.Hour))
+	if len(cluster.MaintenanceWindows) != 1 || !cluster.MaintenanceWindows[0].Start.After(now) {
+		t.Fatalf("Expected the expired window purged, got %v", cluster.MaintenanceWindows)
+	}
+	windows := cluster.GetMaintenanceWindows()
+	windows[0].End = now
+	if !cluster.MaintenanceWindows[0].End.After(now) {
+		t.Fatal("Expected a copy of the maintenance windows")
+	}
+
+	// windows survive a restart through the cluster state file
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster.sme = sme
+	cluster.Name = "c1"
+	cluster.Conf.WorkingDir = t.TempDir()
+	if err := os.MkdirAll(cluster.Conf.WorkingDir+"/c1", 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := cluster.Save(); err != nil {
+		t.Fatal(err)
+	}
+	restored := &Cluster{sme: new(state.StateMachine), WorkingDir: cluster.Conf.WorkingDir + "/c1"}
+	restored.sme.Init()
+	if err := restored.GetPersitentState(); err != nil {
+		t.Fatal(err)
+	}
+	if w := restored.GetMaintenanceWindows(); len(w) != 1 || !w[0].End.Equal(cluster.MaintenanceWindows[0].End) {
+		t.Fatalf("Expected the maintenance window restored, got %v", w)
+	}
+}
+
+func TestReadWriteSplitAdvice(t *testing.T) {
+	server := func(url string, state string, delay int64, prev map[string]string, cur map[string]string) *ServerMonitor {
+		return &ServerMonitor{URL: url, State: state, PrevStatus: prev, Status: cur, ReplicationStatus: replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}}}}
+	}
+	master := server("db1:3306", stateMaster, 0, map[string]string{"COM_SELECT": "1000", "COM_INSERT": "100", "COM_UPDATE": "50"}, map[string]string{"COM_SELECT": "1600", "COM_INSERT": "250", "COM_UPDATE": "100"})
+	lagging := server("db2:3306", stateSlave, 30, map[string]string{"COM_SELECT": "10"}, map[string]string{"COM_SELECT": "110"})
+	lagging.ReadLagState.Alerting = true
+	near := server("db3:3306", stateSlave, 2, nil, map[string]string{"COM_SELECT": "500"})
+	fresh := server("db4:3306", stateSlave, 0, map[string]string{"COM_SELECT": "0"}, map[string]string{"COM_SELECT": "100"})
+	cluster := &Cluster{master: master, Servers: serverList{master, lagging, near, fresh}, slaves: serverList{lagging, near, fresh}}
+	advice := cluster.GetReadWriteSplitAdvice()
+	if advice.Reads != 800 || advice.Writes != 200 || advice.OffloadableReads != 600 {
+		t.Fatalf("Got reads %d writes %d offloadable %d, expected 800 200 600", advice.Reads, advice.Writes, advice.OffloadableReads)
+	}
+	if advice.ReadRatio != 0.8 || advice.OffloadRatio != 0.6 {
+		t.Fatalf("Got read ratio %f offload ratio %f", advice.ReadRatio, advice.OffloadRatio)
+	}
+	if strings.Join(advice.ReadPool, ",") != "db4:3306,db3:3306" {
+		t.Fatalf("Got read pool %v", advice.ReadPool)
+	}
+	near.ReadLagState.Alerting = true
+	fresh.ReadLagState.Alerting = true
+	if advice = cluster.GetReadWriteSplitAdvice(); advice.OffloadableReads != 0 || len(advice.ReadPool) != 0 {
+		t.Fatalf("Expected nothing offloadable without eligible replica, got %v", advice)
+	}
+}
+
+func TestBestNewSourceForOrphan(t *testing.T) {
+	cluster := &Cluster{rplUser: "repl", Conf: config.Config{ForceSlaveHeartbeatRetry: 10, ForceSlaveHeartbeatTime: 3}}
+	server := func(host string, state string, source string, pos string, delay int64) *ServerMonitor {
+		s := &ServerMonitor{URL: host + ":3306", Host: host, Port: "3306", State: state, ClusterGroup: cluster, DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", ""), CurrentGtid: gtid.NewList(pos), HaveBinlog: true, HaveBinlogSlaveUpdates: true}
+		if source != "" {
+			s.ReplicationStatus = replicationStatusFixture{{MasterHost: sql.NullString{String: source, Valid: true}, MasterPort: sql.NullString{String: "3306", Valid: true}, SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}}}
+		}
+		return s
+	}
+	master := server("db1", stateMaster, "", "0-1-200", 0)
+	relay := server("db2", stateFailed, "", "0-1-150", 0)
+	relay.LastSeenReplications = replicationStatusFixture{{MasterHost: sql.NullString{String: "db1", Valid: true}, MasterPort: sql.NullString{String: "3306", Valid: true}}}
+	leaf := server("db3", stateSlave, "db2", "0-1-150", 0)
+	uncle := server("db4", stateSlave, "db1", "0-1-190", 5)
+	cluster.master = master
+	cluster.Servers = serverList{master, relay, leaf, uncle}
+	orphans := cluster.GetOrphanedReplicas()
+	if len(orphans) != 1 || orphans[0] != leaf {
+		t.Fatalf("Expected db3 orphaned, got %v", orphans)
+	}
+	advice, err := cluster.GetBestNewSourceFor(leaf)
+	if err != nil || advice.Source != "db1:3306" || advice.FailedSource != "db2:3306" || advice.Mode != "SLAVE_POS" {
+		t.Fatalf("Unexpected advice %v %v", advice, err)
+	}
+	if !strings.Contains(advice.ChangeMaster, "master_host='db1', master_port=3306, master_user='repl'") || !strings.Contains(advice.ChangeMaster, "MASTER_USE_GTID=SLAVE_POS") {
+		t.Fatalf("Unexpected change master %s", advice.ChangeMaster)
+	}
+	master.State = stateFailed
+	if advice, err = cluster.GetBestNewSourceFor(leaf); err != nil || advice.Source != "db4:3306" {
+		t.Fatalf("Expected sibling of the relay, got %v %v", advice, err)
+	}
+	uncle.CurrentGtid = gtid.NewList("0-1-100")
+	if _, err = cluster.GetBestNewSourceFor(leaf); err == nil {
+		t.Fatal("Expected no source behind the replica position")
+	}
+}
+
+func TestMasterConsistencyWarnings(t *testing.T) {
+	replicating := func(delay int64) replicationStatusFixture {
+		return replicationStatusFixture{{MasterHost: sql.NullString{String: "db2", Valid: true}, MasterPort: sql.NullString{String: "3306", Valid: true}, SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}}}
+	}
+	sme := new(state.StateMachine)
+	sme.Init()
+	master := &ServerMonitor{URL: "db1:3306", State: stateMaster}
+	slave := &ServerMonitor{URL: "db2:3306", State: stateSlave, HaveReadOnly: true, ReplicationStatus: replicating(0)}
+	cluster := &Cluster{sme: sme, master: master, Conf: config.Config{FailMaxDelay: 30}}
+	cluster.Servers = serverList{master, slave}
+	if w := cluster.GetMasterConsistencyWarnings(); len(w) != 0 {
+		t.Fatalf("Expected consistent master, got %v", w)
+	}
+	master.ReplicationStatus = replicating(2)
+	standalone := &ServerMonitor{URL: "db3:3306", State: stateSlave}
+	cluster.Servers = append(cluster.Servers, standalone)
+	cluster.CheckMasterConsistency()
+	expected := []string{"Master db1:3306 replicates from db2:3306 with delay 2, master designation may be stale", "Server db3:3306 is writable without replication, cluster may have a second master besides db1:3306"}
+	if w := cluster.GetMasterConsistencyWarnings(); !reflect.DeepEqual(w, expected) || !sme.CurState.Search("WARN0107") || !sme.CurState.Search("WARN0108") {
+		t.Fatalf("Got %v, expected %v", w, expected)
+	}
+	master.ReplicationStatus = replicating(3600)
+	cluster.Conf.MultiMasterRing = true
+	if w := cluster.GetMasterConsistencyWarnings(); len(w) != 0 {
+		t.Fatalf("Expected multi master ring not checked, got %v", w)
+	}
+	cluster.Conf.MultiMasterRing = false
+	if w := cluster.GetMasterConsistencyWarnings(); len(w) != 1 {
+		t.Fatalf("Expected lagging source not flagged on master, got %v", w)
+	}
+}
+
+func TestReplicaReadWeights(t *testing.T) {
+	cluster := &Cluster{Conf: config.Config{MaxReadLag: 20, ReadWeightDecay: ReadWeightDecayLinear}}
+	slave := func(url string, delay int64, io string) *ServerMonitor {
+		return &ServerMonitor{URL: url, State: stateSlave, IsSlave: true, ClusterGroup: cluster, ReplicationStatus: replicationStatusFixture{{
+			SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: io == "Yes"},
+			SlaveIORunning:      sql.NullString{String: io, Valid: true},
+			SlaveSQLRunning:     sql.NullString{String: "Yes", Valid: true},
+		}}}
+	}
+	cluster.slaves = serverList{slave("db2:3306", 0, "Yes"), slave("db3:3306", 10, "Yes"), slave("db4:3306", 30, "Yes"), slave("db5:3306", 0, "Connecting")}
+	weights := cluster.GetReplicaReadWeights()
+	got := []int{}
+	for _, w := range weights {
+		got = append(got, w.Weight)
+	}
+	if !reflect.DeepEqual(got, []int{67, 33, 0, 0}) || weights[2].Eligible || weights[3].Reason != "replication not running" {
+		t.Fatalf("Unexpected linear weights %+v", weights)
+	}
+	cluster.Conf.ReadWeightDecay = ReadWeightDecayExponential
+	cluster.Conf.ReadWeightHalfLife = 10
+	cluster.Conf.MaxReadLag = 0
+	got = []int{}
+	for _, w := range cluster.GetReplicaReadWeights() {
+		got = append(got, w.Weight)
+	}
+	if !reflect.DeepEqual(got, []int{61, 31, 8, 0}) {
+		t.Fatalf("Unexpected exponential weights %v", got)
+	}
+	cluster.slaves = serverList{slave("db2:3306", 0, "Connecting")}
+	if w := cluster.GetReplicaReadWeights(); w[0].Weight != 0 || w[0].Eligible {
+		t.Fatalf("Expected no weight without eligible slave, got %+v", w)
+	}
+}
+
+func TestGroupReplicationSummary(t *testing.T) {
+	member := func(name string, group string, masterState string, delay int64, io string) *Cluster {
+		sme := new(state.StateMachine)
+		sme.Init()
+		c := &Cluster{Name: name, sme: sme, master: &ServerMonitor{URL: name + "-db1:3306", State: masterState}, Conf: config.Config{ClusterGroup: group}}
+		c.slaves = serverList{&ServerMonitor{URL: name + "-db2:3306", State: stateSlave, ClusterGroup: c, ReplicationStatus: replicationStatusFixture{{
+			SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: io == "Yes"},
+			SlaveIORunning:      sql.NullString{String: io, Valid: true},
+			SlaveSQLRunning:     sql.NullString{String: "Yes", Valid: true},
+		}}}}
+		return c
+	}
+	clusters := map[string]*Cluster{
+		"paris":  member("paris", "emea/prod", stateMaster, 4, "Yes"),
+		"london": member("london", "emea/prod", stateMaster, 12, "Yes"),
+		"berlin": member("berlin", " emea /", stateFailed, 0, "Connecting"),
+		"alone":  member("alone", "", stateMaster, 300, "Yes"),
+	}
+	for _, c := range clusters {
+		c.SetClusterList(clusters)
+	}
+	summaries := GetGroupReplicationSummaries(clusters)
+	if len(summaries) != 2 || summaries[0].Group != "emea" || summaries[1].Group != "emea/prod" {
+		t.Fatalf("Unexpected groups %+v", summaries)
+	}
+	emea := summaries[0]
+	if !reflect.DeepEqual(emea.Clusters, []string{"berlin", "london", "paris"}) || emea.Healthy != 2 || emea.MasterFailed != 1 || emea.MaxDelay != 12 || emea.MaxDelayCluster != "london" || !reflect.DeepEqual(emea.DelayUnknown, []string{"berlin"}) {
+		t.Fatalf("Unexpected emea roll up %+v", emea)
+	}
+	if prod := summaries[1]; len(prod.Clusters) != 2 || prod.Healthy != 2 || len(prod.DelayUnknown) != 0 {
+		t.Fatalf("Unexpected emea/prod roll up %+v", prod)
+	}
+	if s := clusters["paris"].GetGroupReplicationSummary(); len(s) != 2 || s[1].Group != "emea/prod" {
+		t.Fatalf("Expected paris in emea and emea/prod, got %+v", s)
+	}
+	if s := clusters["paris"].GetGroupReplicationSummaryOf(map[string]*Cluster{"paris": clusters["paris"]}); len(s) != 2 || !reflect.DeepEqual(s[0].Clusters, []string{"paris"}) {
+		t.Fatalf("Expected roll up restricted to paris, got %+v", s)
+	}
+	if s := clusters["alone"].GetGroupReplicationSummary(); s != nil {
+		t.Fatalf("Expected no roll up without group, got %+v", s)
+	}
+	metrics := GetGroupPrometheusMetrics(summaries)
+	if !strings.Contains(metrics, "group_replication_delay_max_seconds{group=\"emea\"} 12\n") || !strings.Contains(metrics, "group_clusters_healthy{group=\"emea/prod\"} 2\n") {
+		t.Fatalf("Unexpected metrics %s", metrics)
+	}
+}
@@ -8,6 +8,7 @@ package cluster
 
 import (
 	"strings"
+	"time"
 
 	"github.com/signal18/replication-manager/config"
 )
@@ -23,6 +24,34 @@ func (cluster *Cluster) HasServer(srv *ServerMonitor) bool {
 	return false
 }
 
+// FindDuplicateServers returns groups of monitored servers resolving to the same endpoint,
+// hosts are compared by resolved IP when available and case insensitive name otherwise
+func (cluster *Cluster) FindDuplicateServers() [][]*ServerMonitor {
+	var keys []string
+	groups := make(map[string][]*ServerMonitor)
+	for _, sv := range cluster.Servers {
+		if sv == nil {
+			continue
+		}
+		key := sv.GetEndpoint()
+		if _, ok := groups[key]; !ok {
+			keys = append(keys, key)
+		}
+		groups[key] = append(groups[key], sv)
+	}
+	var duplicates [][]*ServerMonitor
+	for _, key := range keys {
+		if len(groups[key]) > 1 {
+			duplicates = append(duplicates, groups[key])
+		}
+	}
+	return duplicates
+}
+
+func (cluster *Cluster) HasDuplicateServers() bool {
+	return len(cluster.FindDuplicateServers()) > 0
+}
+
 func (cluster *Cluster) HasSchedulerEntry(myname string) bool {
 	if _, ok := cluster.Schedule[myname]; ok {
 		return true
@@ -232,6 +261,18 @@ func (cluster *Cluster) IsInFailover() bool {
 	return cluster.sme.IsInFailover()
 }
 
+func (cluster *Cluster) IsInMaintenanceWindow() bool {
+	now := time.Now()
+	cluster.Lock()
+	defer cluster.Unlock()
+	for _, w := range cluster.MaintenanceWindows {
+		if !now.Before(w.Start) && now.Before(w.End) {
+			return true
+		}
+	}
+	return false
+}
+
 func (cluster *Cluster) IsDiscovered() bool {
 	return cluster.sme.IsDiscovered()
 }
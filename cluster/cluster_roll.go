@@ -87,8 +87,10 @@ func (cluster *Cluster) RollingRestart() error {
 	saveFailoverMode := cluster.Conf.FailSync
 	cluster.SetFailSync(false)
 	defer cluster.SetFailSync(saveFailoverMode)
-	for _, slave := range cluster.slaves {
-
+	for _, slave := range cluster.GetRollingRestartOrder() {
+		if slave == cluster.master {
+			continue
+		}
 		if !slave.IsDown() {
 			//slave.SetMaintenance()
 			//proxy.
@@ -112,6 +114,11 @@ func (cluster *Cluster) RollingRestart() error {
 				cluster.LogPrintf(LvlErr, "Cancel rolling restart slave does not restart %s %s", slave.URL, err)
 				return err
 			}
+			err = cluster.WaitReplicationCatchUp(slave)
+			if err != nil {
+				cluster.LogPrintf(LvlErr, "Cancel rolling restart slave does not catch up replication %s %s", slave.URL, err)
+				return err
+			}
 		}
 		slave.WaitSyncToMaster(cluster.master)
 		slave.SwitchMaintenance()
@@ -143,6 +150,11 @@ func (cluster *Cluster) RollingRestart() error {
 		cluster.LogPrintf(LvlErr, "Cancel rolling restart old master does not restart %s %s", master.URL, err)
 		return err
 	}
+	err = cluster.WaitReplicationCatchUp(master)
+	if err != nil {
+		cluster.LogPrintf(LvlErr, "Cancel rolling restart old master does not catch up replication %s %s", master.URL, err)
+		return err
+	}
 	master.WaitSyncToMaster(cluster.master)
 	master.SwitchMaintenance()
 	cluster.SwitchOver()
@@ -99,12 +99,12 @@ func (cluster *Cluster) SetSchedulerBackupLogical() {
 		var err error
 		cluster.LogPrintf(LvlInfo, "Schedule logical backup time at: %s", cluster.Conf.BackupLogicalCron)
 		cluster.idSchedulerLogicalBackup, err = cluster.scheduler.AddFunc(cluster.Conf.BackupLogicalCron, func() {
-			mysrv := cluster.GetBackupServer()
-			if mysrv != nil {
-				mysrv.JobBackupLogical()
-			} else {
-				cluster.master.JobBackupLogical()
+			mysrv, err := cluster.GetBackupServer()
+			if err != nil {
+				cluster.LogPrintf(LvlErr, "Cancel scheduled logical backup: %s", err)
+				return
 			}
+			mysrv.JobBackupLogical()
 		})
 		if err == nil {
 			cluster.Schedule["backuplogical"] = cluster.scheduler.Entry(cluster.idSchedulerPhysicalBackup)
@@ -121,7 +121,12 @@ func (cluster *Cluster) SetSchedulerBackupPhysical() {
 		var err error
 		cluster.LogPrintf(LvlInfo, "Schedule Physical backup time at: %s", cluster.Conf.BackupPhysicalCron)
 		cluster.idSchedulerPhysicalBackup, err = cluster.scheduler.AddFunc(cluster.Conf.BackupPhysicalCron, func() {
-			cluster.master.JobBackupPhysical()
+			mysrv, err := cluster.GetBackupServer()
+			if err != nil {
+				cluster.LogPrintf(LvlErr, "Cancel scheduled physical backup: %s", err)
+				return
+			}
+			mysrv.JobBackupPhysical()
 		})
 		if err == nil {
 			cluster.Schedule["backupphysical"] = cluster.scheduler.Entry(cluster.idSchedulerPhysicalBackup)
@@ -214,6 +219,51 @@ func (cluster *Cluster) SetSchedulerRollingReprov() {
 	}
 }
 
+func (cluster *Cluster) SetSchedulerConsistencyCheck() {
+	if cluster.HasSchedulerEntry("consistencycheck") {
+		cluster.LogPrintf(LvlInfo, "Disable consistency check")
+		cluster.scheduler.Remove(cluster.idSchedulerConsistencyCheck)
+		delete(cluster.Schedule, "consistencycheck")
+	}
+	if cluster.Conf.SchedulerConsistencyCheckSchemas != "" {
+		var err error
+		cluster.LogPrintf(LvlInfo, "Schedule consistency check of %s at: %s", cluster.Conf.SchedulerConsistencyCheckSchemas, cluster.Conf.SchedulerConsistencyCheckCron)
+		cluster.idSchedulerConsistencyCheck, err = cluster.scheduler.AddFunc(cluster.Conf.SchedulerConsistencyCheckCron, func() {
+			cluster.Lock()
+			schemas := cluster.Conf.SchedulerConsistencyCheckSchemas
+			cluster.Unlock()
+			for _, schema := range strings.Split(schemas, ",") {
+				cluster.CheckSchemaChecksum(schema)
+			}
+		})
+		if err == nil {
+			cluster.Schedule["consistencycheck"] = cluster.scheduler.Entry(cluster.idSchedulerConsistencyCheck)
+		}
+	}
+}
+
+// ScheduleConsistencyCheck add a schema to the scheduled chunked checksum of scheduler-consistency-check-cron
+func (cluster *Cluster) ScheduleConsistencyCheck(schema string) error {
+	if cluster.scheduler == nil {
+		return errors.New("Scheduler is disabled")
+	}
+	cluster.Lock()
+	schemas := strings.Split(cluster.Conf.SchedulerConsistencyCheckSchemas, ",")
+	for _, s := range schemas {
+		if s == schema {
+			cluster.Unlock()
+			return nil
+		}
+	}
+	if cluster.Conf.SchedulerConsistencyCheckSchemas == "" {
+		schemas = nil
+	}
+	cluster.Conf.SchedulerConsistencyCheckSchemas = strings.Join(append(schemas, schema), ",")
+	cluster.Unlock()
+	cluster.SetSchedulerConsistencyCheck()
+	return nil
+}
+
 func (cluster *Cluster) SetSchedulerSlaRotate() {
 	if cluster.HasSchedulerEntry("slarotate") {
 		cluster.LogPrintf(LvlInfo, "Disable rotate Sla ")
@@ -588,7 +638,7 @@ func (cluster *Cluster) SetDBDynamicConfig() {
 		if !srv.IsMariaDB() {
 			cmd = "mysql_command"
 		}
-		srv.GetDatabaseConfig()
+		srv.GetDatabaseConfigManifest()
 		srv.ExecScriptSQL(strings.Split(srv.GetDatabaseDynamicConfig("", cmd), ";"))
 	}
 }
@@ -1031,3 +1081,23 @@ func (cluster *Cluster) SetProvProxyServiceType(value string) error {
 	cluster.SetProxiesReprovCookie()
 	return nil
 }
+
+// SetMaintenanceWindow register a window during which replication delay does not trigger alerts and late states,
+// expired windows are purged
+func (cluster *Cluster) SetMaintenanceWindow(start time.Time, end time.Time) error {
+	if !end.After(start) {
+		return errors.New("Maintenance window end must be after start")
+	}
+	now := time.Now()
+	var windows []MaintenanceWindow
+	cluster.Lock()
+	for _, w := range cluster.MaintenanceWindows {
+		if w.End.After(now) {
+			windows = append(windows, w)
+		}
+	}
+	cluster.MaintenanceWindows = append(windows, MaintenanceWindow{Start: start, End: end})
+	cluster.Unlock()
+	cluster.LogPrintf(LvlInfo, "Set maintenance window from %s to %s", start.Format(time.RFC3339), end.Format(time.RFC3339))
+	return nil
+}
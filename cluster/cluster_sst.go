@@ -26,6 +26,8 @@ type SST struct {
 	outresticreader io.WriteCloser
 	cluster         *Cluster
 	port            int
+	err             error       // read or write error of the stream copy
+	onEnd           func(error) // called once the stream is received, with the error that interrupted it
 }
 
 type ProtectedSSTconnections struct {
@@ -108,9 +110,12 @@ func (cluster *Cluster) SSTRunReceiverToRestic(filename string) (string, error)
 	return strconv.Itoa(destinationPort), nil
 }
 
-func (cluster *Cluster) SSTRunReceiverToFile(filename string, openfile string) (string, error) {
+// SSTRunReceiverToFile listens for a stream written to filename, onEnd when not nil is called once the stream
+// ends, with an error when no connection was received or the stream was interrupted
+func (cluster *Cluster) SSTRunReceiverToFile(filename string, openfile string, onEnd func(error)) (string, error) {
 	sst := new(SST)
 	sst.cluster = cluster
+	sst.onEnd = onEnd
 	var writers []io.Writer
 
 	var err error
@@ -161,6 +166,9 @@ func (sst *SST) tcp_con_handle_to_file() {
 		SSTs.Lock()
 		delete(SSTs.SSTconnections, port)
 		SSTs.Unlock()
+		if sst.onEnd != nil {
+			sst.onEnd(err)
+		}
 	}()
 
 	sst.in, err = sst.listener.Accept()
@@ -179,6 +187,7 @@ func (sst *SST) tcp_con_handle_to_file() {
 			sst.cluster.LogPrintf(LvlInfo, "Chan SST out for %d", sst.listener.Addr().(*net.TCPAddr).Port)
 		}
 	}
+	err = sst.err
 }
 
 func (sst *SST) tcp_con_handle_to_restic() {
@@ -240,12 +249,14 @@ func (sst *SST) stream_copy_to_file() <-chan int {
 			if err != nil {
 				if err != io.EOF {
 					sst.cluster.LogPrintf(LvlErr, "Read error: %s", err)
+					sst.err = err
 				}
 				break
 			}
 			_, err = sst.outfilewriter.Write(buf[0:nBytes])
 			if err != nil {
 				sst.cluster.LogPrintf(LvlErr, "Write error: %s", err)
+				sst.err = err
 			}
 		}
 	}()
@@ -9,12 +9,31 @@ package cluster
 import (
 	"archive/tar"
 	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
+	"path/filepath"
 	"strings"
 )
 
+// ConfigManifestFile is a regular file of a config tarball
+type ConfigManifestFile struct {
+	Path   string `json:"path"`
+	Size   int64  `json:"size"`
+	Sha256 string `json:"sha256"`
+}
+
+// ConfigManifest lists the files of a config tarball with their checksum, Sha256 is the checksum of the tarball
+type ConfigManifest struct {
+	Tarball string               `json:"tarball"`
+	Sha256  string               `json:"sha256"`
+	Files   []ConfigManifestFile `json:"files"`
+}
+
 func (cluster *Cluster) TarGzWrite(_path string, tw *tar.Writer, fi os.FileInfo, trimprefix string) {
 	fr, err := os.Open(_path)
 	if err != nil {
@@ -93,3 +112,57 @@ func (cluster *Cluster) TarGz(outFilePath string, inPath string) {
 
 	fmt.Println("tar.gz ok")
 }
+
+// GetTarGzManifest reads back a tarball to list the size and sha256 of each regular file it contains
+func GetTarGzManifest(tarPath string) (ConfigManifest, error) {
+	m := ConfigManifest{Tarball: filepath.Base(tarPath), Files: []ConfigManifestFile{}}
+	f, err := os.Open(tarPath)
+	if err != nil {
+		return m, err
+	}
+	defer f.Close()
+	archive := sha256.New()
+	gr, err := gzip.NewReader(io.TeeReader(f, archive))
+	if err != nil {
+		return m, err
+	}
+	defer gr.Close()
+	tr := tar.NewReader(gr)
+	for {
+		h, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return m, err
+		}
+		if h.Typeflag != tar.TypeReg {
+			continue
+		}
+		fh := sha256.New()
+		size, err := io.Copy(fh, tr)
+		if err != nil {
+			return m, err
+		}
+		m.Files = append(m.Files, ConfigManifestFile{Path: h.Name, Size: size, Sha256: hex.EncodeToString(fh.Sum(nil))})
+	}
+	// drain the gzip trailer so the archive checksum covers the whole file
+	if _, err = io.Copy(ioutil.Discard, f); err != nil {
+		return m, err
+	}
+	m.Sha256 = hex.EncodeToString(archive.Sum(nil))
+	return m, nil
+}
+
+// WriteTarGzManifest writes the manifest of a tarball as manifest.json in the tarball directory
+func (cluster *Cluster) WriteTarGzManifest(tarPath string) (ConfigManifest, error) {
+	m, err := GetTarGzManifest(tarPath)
+	if err != nil {
+		return m, err
+	}
+	content, err := json.MarshalIndent(m, "", "\t")
+	if err != nil {
+		return m, err
+	}
+	return m, ioutil.WriteFile(filepath.Join(filepath.Dir(tarPath), "manifest.json"), content, 0644)
+}
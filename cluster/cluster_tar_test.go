@@ -0,0 +1,48 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestTarGzManifest(t *testing.T) {
+	dir := t.TempDir()
+	os.MkdirAll(filepath.Join(dir, "init", "etc", "mysql"), 0775)
+	ioutil.WriteFile(filepath.Join(dir, "init", "etc", "mysql", "my.cnf"), []byte("[mysqld]\n"), 0644)
+	os.Symlink("my.cnf", filepath.Join(dir, "init", "etc", "mysql", "link.cnf"))
+	cluster := &Cluster{}
+	tarPath := filepath.Join(dir, "config.tar.gz")
+	cluster.TarGz(tarPath, filepath.Join(dir, "init"))
+	m, err := cluster.WriteTarGzManifest(tarPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := sha256.Sum256([]byte("[mysqld]\n"))
+	if len(m.Files) != 1 || m.Files[0].Path != "etc/mysql/my.cnf" || m.Files[0].Size != 9 || m.Files[0].Sha256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("Unexpected manifest files %+v", m.Files)
+	}
+	tarball, _ := ioutil.ReadFile(tarPath)
+	sum = sha256.Sum256(tarball)
+	if m.Tarball != "config.tar.gz" || m.Sha256 != hex.EncodeToString(sum[:]) {
+		t.Fatalf("Unexpected tarball checksum %s", m.Sha256)
+	}
+	var written ConfigManifest
+	content, err := ioutil.ReadFile(filepath.Join(dir, "manifest.json"))
+	if err != nil || json.Unmarshal(content, &written) != nil || written.Sha256 != m.Sha256 {
+		t.Fatalf("Unexpected manifest.json %s %s", content, err)
+	}
+}
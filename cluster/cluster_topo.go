@@ -12,8 +12,12 @@ package cluster
 import (
 	"errors"
 	"fmt"
+	"strings"
 	"sync"
+	"sync/atomic"
+	"time"
 
+	"github.com/jmoiron/sqlx"
 	"github.com/signal18/replication-manager/utils/state"
 )
 
@@ -65,6 +69,13 @@ func (cluster *Cluster) newServerList() error {
 		}
 	}
 	cluster.Unlock()
+	for _, duplicates := range cluster.FindDuplicateServers() {
+		var urls []string
+		for _, sv := range duplicates {
+			urls = append(urls, sv.URL)
+		}
+		cluster.LogPrintf(LvlWarn, "Duplicate database servers %s resolving to %s", strings.Join(urls, ","), duplicates[0].GetEndpoint())
+	}
 	return nil
 }
 
@@ -94,32 +105,97 @@ func (cluster *Cluster) AddChildServers() error {
 	// End  child clusters  same multi source server discorvery
 }
 
+// pingServers refreshes the servers with at most monitoring-refresh-workers pings at a time. With
+// monitoring-refresh-timeout a connection check that does not return in time is recorded as a failed ping, so
+// that a hanging server is still detected after failcount loops. A slow refresh of a reachable server is never
+// recorded as a failure, it only releases its worker and the server is skipped until its refresh returns, so
+// that two pings never update the state of a server concurrently
+func (cluster *Cluster) pingServers(servers serverList) {
+	workers := cluster.Conf.MonitorRefreshWorkers
+	if workers <= 0 || workers > len(servers) {
+		workers = len(servers)
+	}
+	timeout := time.Duration(cluster.Conf.MonitorRefreshTimeout) * time.Second
+	sem := make(chan bool, workers)
+	wg := new(sync.WaitGroup)
+	for _, server := range servers {
+		if !atomic.CompareAndSwapInt32(&server.refreshing, 0, 1) {
+			cluster.LogPrintf(LvlWarn, "Skipping refresh of server %s, previous refresh still running", server.URL)
+			continue
+		}
+		wg.Add(1)
+		sem <- true
+		go func(server *ServerMonitor) {
+			defer wg.Done()
+			defer func() { <-sem }()
+			cluster.pingServer(server, timeout, server.checkConnection, server.processPing)
+		}(server)
+	}
+	wg.Wait()
+}
+
+// pingServer runs check then process for a server claimed by pingServers and clears its refreshing flag once
+// done. The timeout only applies to check, when it expires first the failed ping is recorded and the late result
+// of check is dropped, only one of them ever updates the server state
+func (cluster *Cluster) pingServer(server *ServerMonitor, timeout time.Duration, check func() (*sqlx.DB, error), process func(*sqlx.DB, error)) {
+	// claim is 1 when the result of check is processed, 2 when the timeout is
+	var claim int32
+	checked := make(chan bool)
+	done := make(chan bool)
+	go func() {
+		conn, err := check()
+		if !atomic.CompareAndSwapInt32(&claim, 0, 1) {
+			if conn != nil {
+				conn.Close()
+			}
+			return
+		}
+		close(checked)
+		process(conn, err)
+		atomic.StoreInt32(&server.refreshing, 0)
+		close(done)
+	}()
+	if timeout <= 0 {
+		<-done
+		return
+	}
+	select {
+	case <-checked:
+	case <-time.After(timeout):
+		if atomic.CompareAndSwapInt32(&claim, 0, 2) {
+			cluster.LogPrintf(LvlWarn, "Connection check of server %s exceeds %s", server.URL, timeout)
+			server.failPing(fmt.Errorf("Connection check of server %s exceeds %s", server.URL, timeout))
+			atomic.StoreInt32(&server.refreshing, 0)
+			return
+		}
+		<-checked
+	}
+	select {
+	case <-done:
+	case <-time.After(timeout):
+		cluster.LogPrintf(LvlWarn, "Refresh of server %s exceeds %s, not waiting for it", server.URL, timeout)
+	}
+}
+
 // Start of topology detection
 // Create a connection to each host and build list of slaves.
 func (cluster *Cluster) TopologyDiscover(wcg *sync.WaitGroup) error {
 	defer wcg.Done()
 	cluster.AddChildServers()
 	//monitor ignored server fist so that their replication position get oldest
-	wg := new(sync.WaitGroup)
 	if cluster.Conf.Hosts == "" {
 		return errors.New("Can not discover empty cluster")
 	}
+	var ignored, monitored serverList
 	for _, server := range cluster.Servers {
 		if server.IsIgnored() {
-			wg.Add(1)
-			go server.Ping(wg)
-		}
-	}
-	wg.Wait()
-
-	wg = new(sync.WaitGroup)
-	for _, server := range cluster.Servers {
-		if !server.IsIgnored() {
-			wg.Add(1)
-			go server.Ping(wg)
+			ignored = append(ignored, server)
+		} else {
+			monitored = append(monitored, server)
 		}
 	}
-	wg.Wait()
+	cluster.pingServers(ignored)
+	cluster.pingServers(monitored)
 
 	//	cluster.pingServerList()
 	if cluster.sme.IsInFailover() {
@@ -240,6 +240,28 @@ func (cluster *Cluster) WaitDatabaseSuspect(server *ServerMonitor) error {
 	return nil
 }
 
+// WaitReplicationCatchUp wait for the slave threads to run with a replication delay under one second,
+// fails after rolling-restart-catchup-timeout
+func (cluster *Cluster) WaitReplicationCatchUp(server *ServerMonitor) error {
+	cluster.LogPrintf(LvlInfo, "Wait replication catch up on %s", server.URL)
+	ticker := time.NewTicker(time.Second)
+	defer ticker.Stop()
+	timeout := time.After(time.Duration(cluster.Conf.RollingRestartCatchUpTimeout) * time.Second)
+	for {
+		select {
+		case <-ticker.C:
+			if !server.IsReplicationBroken() && server.GetReplicationDelay() <= 1 {
+				cluster.LogPrintf(LvlInfo, "Replication caught up on %s", server.URL)
+				return nil
+			}
+			cluster.LogPrintf(LvlInfo, "Waiting replication catch up on %s delay %d", server.URL, server.GetReplicationDelay())
+		case <-timeout:
+			cluster.LogPrintf(LvlInfo, "Wait replication catch up timeout on %s", server.URL)
+			return errors.New("Failed to wait replication catch up")
+		}
+	}
+}
+
 func (cluster *Cluster) WaitDatabaseFailed(server *ServerMonitor) error {
 	cluster.LogPrintf(LvlInfo, "Wait state failed on %s", server.URL)
 	exitloop := 0
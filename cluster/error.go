@@ -92,6 +92,8 @@ var clusterError = map[string]string{
 	"ERR00082": "Could not get agents from orchestrator %s",
 	"ERR00083": "Different cluster uuid found on %s:%s %s:%s",
 	"ERR00084": "Cluster have no master when slave %s was started",
+	"ERR00085": "Skip slave in election %s has replication filters %s",
+	"ERR00086": "Master %s is not fenced, confidence %s with %d of %d slaves that lost the master",
 	"WARN0022": "Rejoining standalone server %s to master %s",
 	"WARN0023": "Number of failed master ping has been reached",
 	"WARN0045": "Provision task is in queue",
@@ -149,4 +151,18 @@ var clusterError = map[string]string{
 	"WARN0098": "ProxySQL could not load global variables from runtime (%s)",
 	"WARN0099": "MariaDB version as replication issue https://jira.mariadb.org/browse/MDEV-20821",
 	"WARN0100": "No space left on device pn %s",
+	"WARN0101": "Replication delay over failover-max-slave-delay for %d monitoring polls on %s",
+	"WARN0102": "Slave %s replicates from %s instead of elected master %s",
+	"WARN0103": "Schema %s on %s has charset %s collation %s, master has charset %s collation %s",
+	"WARN0104": "Write circuit breaker open, no healthy slave within write-circuit-breaker-max-slave-delay %d",
+	"WARN0105": "Server %s runs %s older than cluster newest %s",
+	"WARN0106": "Server %s runs %s and can not replicate from master %s: %s",
+	"WARN0107": "Master %s replicates from %s with delay %d, master designation may be stale",
+	"WARN0108": "Server %s is writable without replication, cluster may have a second master besides %s",
+	"WARN0109": "User %s over quota on %s: %s",
+	"WARN0110": "Connection storm on %s, %d new connections at %.1f per second",
+	"WARN0111": "Replication delay over alert-replication-delay-warning for %d monitoring polls on %s",
+	"WARN0112": "Newest backup %s, over backup-freshness-max-age %d",
+	"WARN0113": "Could not connect to %s after %d attempts down the TLS fallback ladder: %s",
+	"WARN0114": "Replication stopped on error on %s, automatic skip disabled after %d events skipped in the last hour",
 }
@@ -0,0 +1,142 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"encoding/json"
+	"strconv"
+	"time"
+)
+
+// ServersExportSchemaVersion is increased on every incompatible change of the servers export, fields can be
+// added without changing the version, consumers must ignore unknown fields
+const ServersExportSchemaVersion = 1
+
+// ServersExport is the versioned servers document of a cluster, decoupled from the ServerMonitor struct
+type ServersExport struct {
+	SchemaVersion int            `json:"schemaVersion"`
+	Cluster       string         `json:"cluster"`
+	Timestamp     int64          `json:"timestamp"`
+	Servers       []ServerExport `json:"servers"`
+}
+
+// ServerExport is the monitored state of a server, Delay is null when it is not a slave or the delay can not
+// be measured
+type ServerExport struct {
+	Id          string              `json:"id"`
+	URL         string              `json:"url"`
+	Host        string              `json:"host"`
+	Port        string              `json:"port"`
+	State       string              `json:"state"`
+	Version     string              `json:"version"`
+	Flavor      string              `json:"flavor"`
+	Delay       *int64              `json:"delay"`
+	Flags       ServerExportFlags   `json:"flags"`
+	Replication ServerExportReplica `json:"replication"`
+	Status      ServerExportStatus  `json:"status"`
+}
+
+// ServerExportFlags are the booleans describing the role and administrative state of a server, Delayed is set
+// for a slave of replication-delayed-hosts or with a MASTER_DELAY
+type ServerExportFlags struct {
+	Up          bool `json:"up"`
+	Master      bool `json:"master"`
+	Slave       bool `json:"slave"`
+	ReadOnly    bool `json:"readOnly"`
+	Maintenance bool `json:"maintenance"`
+	Ignored     bool `json:"ignored"`
+	Preferred   bool `json:"preferred"`
+	Delayed     bool `json:"delayed"`
+}
+
+// ServerExportReplica summarizes the replication health of a slave
+type ServerExportReplica struct {
+	Source          string `json:"source"`
+	IOThread        bool   `json:"ioThread"`
+	SQLThread       bool   `json:"sqlThread"`
+	Error           bool   `json:"error"`
+	DelayAlert      bool   `json:"delayAlert"`
+	DelayAlertLevel string `json:"delayAlertLevel"`
+	ReadEligible    bool   `json:"readEligible"`
+	ConfiguredDelay int64  `json:"configuredDelay"`
+	RemainingDelay  int64  `json:"remainingDelay"`
+}
+
+// ServerExportStatus is a summary of the global status
+type ServerExportStatus struct {
+	Uptime           int64 `json:"uptime"`
+	ThreadsConnected int64 `json:"threadsConnected"`
+	ThreadsRunning   int64 `json:"threadsRunning"`
+}
+
+// GetServersExport returns the versioned servers document built from the server accessors
+func (cluster *Cluster) GetServersExport() ServersExport {
+	doc := ServersExport{SchemaVersion: ServersExportSchemaVersion, Cluster: cluster.Name, Timestamp: time.Now().Unix(), Servers: []ServerExport{}}
+	for _, s := range cluster.Servers {
+		if s == nil {
+			continue
+		}
+		doc.Servers = append(doc.Servers, s.getServerExport())
+	}
+	return doc
+}
+
+// ExportServersJSON returns the versioned servers document as JSON
+func (cluster *Cluster) ExportServersJSON() ([]byte, error) {
+	return json.Marshal(cluster.GetServersExport())
+}
+
+func (server *ServerMonitor) getServerExport() ServerExport {
+	e := ServerExport{
+		Id:    server.Id,
+		URL:   server.URL,
+		Host:  server.Host,
+		Port:  server.Port,
+		State: server.State,
+		Flags: ServerExportFlags{
+			Up:          !server.IsDown(),
+			Master:      server.IsMaster(),
+			Slave:       server.IsSlave,
+			ReadOnly:    server.IsReadOnly(),
+			Maintenance: server.IsMaintenance,
+			Ignored:     server.IsIgnored(),
+			Preferred:   server.IsPrefered(),
+			Delayed:     server.IsDelayed,
+		},
+	}
+	if server.DBVersion != nil {
+		e.Version = strconv.Itoa(server.DBVersion.Major) + "." + strconv.Itoa(server.DBVersion.Minor) + "." + strconv.Itoa(server.DBVersion.Release)
+		e.Flavor = server.DBVersion.Flavor
+	}
+	if server.IsSlave {
+		if server.HasReplicationDelay() {
+			delay := server.GetReplicationDelay()
+			e.Delay = &delay
+		}
+		e.Replication = ServerExportReplica{
+			IOThread:        server.IsIOThreadRunning(),
+			SQLThread:       server.IsSQLThreadRunning(),
+			Error:           server.HasReplicationError(),
+			DelayAlert:      server.IsReplicationDelayAlerting(),
+			DelayAlertLevel: server.GetReplicationDelayAlertLevel(),
+			ReadEligible:    server.IsReadEligible(),
+		}
+		if ss, err := server.GetSlaveStatus(server.ReplicationSourceName); err == nil {
+			e.Replication.Source = ss.MasterHost.String + ":" + ss.MasterPort.String
+			e.Replication.ConfiguredDelay = ss.SQLDelay.Int64
+			e.Replication.RemainingDelay = ss.SQLRemainingDelay.Int64
+			e.Flags.Delayed = e.Flags.Delayed || ss.SQLDelay.Int64 > 0
+		}
+	}
+	e.Status.Uptime, _ = strconv.ParseInt(server.Status["UPTIME"], 10, 64)
+	e.Status.ThreadsConnected, _ = strconv.ParseInt(server.Status["THREADS_CONNECTED"], 10, 64)
+	e.Status.ThreadsRunning, _ = strconv.ParseInt(server.Status["THREADS_RUNNING"], 10, 64)
+	return e
+}
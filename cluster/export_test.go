@@ -0,0 +1,50 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"encoding/json"
+	"testing"
+
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
+
+func TestExportServersJSON(t *testing.T) {
+	master := &ServerMonitor{Id: "db1", URL: "db1:3306", Host: "db1", Port: "3306", State: stateMaster, Pass: "secret", DBVersion: &dbhelper.MySQLVersion{Flavor: "MariaDB", Major: 10, Minor: 6, Release: 12}, Status: map[string]string{"UPTIME": "3600", "THREADS_RUNNING": "4"}}
+	slave := &ServerMonitor{Id: "db2", URL: "db2:3306", Host: "db2", Port: "3306", State: stateSlave, IsSlave: true, HaveReadOnly: true, ReplicationStatus: replicationStatusFixture{{MasterHost: sql.NullString{String: "db1", Valid: true}, MasterPort: sql.NullString{String: "3306", Valid: true}, SecondsBehindMaster: sql.NullInt64{Int64: 3, Valid: true}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}}}}
+	cluster := &Cluster{Name: "c1", master: master}
+	cluster.Servers = serverList{master, slave}
+	master.ClusterGroup = cluster
+	slave.ClusterGroup = cluster
+	data, err := cluster.ExportServersJSON()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		t.Fatal(err)
+	}
+	if doc["schemaVersion"] != float64(ServersExportSchemaVersion) || doc["cluster"] != "c1" {
+		t.Fatalf("Unexpected document header %s", data)
+	}
+	var export ServersExport
+	json.Unmarshal(data, &export)
+	m, s := export.Servers[0], export.Servers[1]
+	if !m.Flags.Master || m.Version != "10.6.12" || m.Flavor != "MariaDB" || m.Delay != nil || m.Status.Uptime != 3600 || m.Status.ThreadsRunning != 4 {
+		t.Fatalf("Unexpected master export %+v", m)
+	}
+	if s.Flags.Master || !s.Flags.Slave || !s.Flags.ReadOnly || s.Delay == nil || *s.Delay != 3 || s.Replication.Source != "db1:3306" || !s.Replication.IOThread || !s.Replication.SQLThread {
+		t.Fatalf("Unexpected slave export %+v", s)
+	}
+	if _, ok := doc["servers"].([]interface{})[0].(map[string]interface{})["pass"]; ok {
+		t.Fatalf("Export leaks internal fields %s", data)
+	}
+}
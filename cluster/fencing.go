@@ -0,0 +1,103 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"fmt"
+
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+const (
+	FencingNone   string = "none"
+	FencingLow    string = "low"
+	FencingMedium string = "medium"
+	FencingHigh   string = "high"
+)
+
+// MasterFencingState is how confident the monitor is that a failed master is cut from the topology. The
+// replicating slaves are the other vantage points: a slave is lost when its IO thread is not running or has an
+// IO error, and connected when it still replicates from the master
+type MasterFencingState struct {
+	Master          string `json:"master"`
+	Failed          bool   `json:"failed"`
+	FailCount       int    `json:"failCount"`
+	MaxFail         int    `json:"maxFail"`
+	Slaves          int    `json:"slaves"`
+	SlavesLost      int    `json:"slavesLost"`
+	SlavesConnected int    `json:"slavesConnected"`
+	Confidence      string `json:"confidence"`
+	Fenced          bool   `json:"fenced"`
+}
+
+// GetMasterFencingState returns the fencing confidence of the master from the consecutive failed pings of the
+// monitor and the replication status of its slaves, the master is fenced at high confidence only
+func (cluster *Cluster) GetMasterFencingState() MasterFencingState {
+	fs := MasterFencingState{MaxFail: cluster.Conf.MaxFail}
+	if cluster.master == nil {
+		fs.Confidence = FencingNone
+		return fs
+	}
+	fs.Master = cluster.master.URL
+	fs.Failed = cluster.master.State == stateFailed
+	fs.FailCount = cluster.master.FailCount
+	for _, s := range cluster.slaves {
+		if s == nil || s.IsDown() {
+			continue
+		}
+		if m, _ := cluster.GetMasterFromReplication(s); m != cluster.master {
+			continue
+		}
+		fs.Slaves++
+		if s.IsIOThreadRunning() && !s.hasIOError() {
+			fs.SlavesConnected++
+		} else {
+			fs.SlavesLost++
+		}
+	}
+	fs.Confidence = fs.getConfidence()
+	fs.Fenced = fs.Confidence == FencingHigh
+	return fs
+}
+
+// getConfidence is low when the failure is not confirmed by the ping counter or when a slave still replicates
+// from the master, medium when no slave can corroborate the failure and high when all slaves lost the master
+func (fs MasterFencingState) getConfidence() string {
+	switch {
+	case !fs.Failed:
+		return FencingNone
+	case fs.FailCount < fs.MaxFail || fs.SlavesConnected > 0:
+		return FencingLow
+	case fs.SlavesLost == 0:
+		return FencingMedium
+	}
+	return FencingHigh
+}
+
+func (server *ServerMonitor) hasIOError() bool {
+	ss, err := server.GetSlaveStatus(server.ReplicationSourceName)
+	if err != nil {
+		return false
+	}
+	return ss.LastIOErrno.String != "" && ss.LastIOErrno.String != "0"
+}
+
+// isMasterNotFenced cancel the failover when failover-falsepositive-fencing is set and the master is not fenced
+func (cluster *Cluster) isMasterNotFenced() bool {
+	if cluster.Conf.CheckFalsePositiveFencing == false {
+		return false
+	}
+	fs := cluster.GetMasterFencingState()
+	if fs.Fenced {
+		return false
+	}
+	cluster.sme.AddState("ERR00086", state.State{ErrType: LvlErr, ErrDesc: fmt.Sprintf(clusterError["ERR00086"], fs.Master, fs.Confidence, fs.SlavesLost, fs.Slaves), ErrFrom: "CHECK"})
+	return true
+}
@@ -0,0 +1,75 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/dbhelper"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+func fencingSlaveStatus(io string, errno string) []dbhelper.SlaveStatus {
+	return []dbhelper.SlaveStatus{{
+		MasterHost:     sql.NullString{String: "db1", Valid: true},
+		MasterPort:     sql.NullString{String: "3306", Valid: true},
+		MasterServerID: 1,
+		SlaveIORunning: sql.NullString{String: io, Valid: true},
+		LastIOErrno:    sql.NullString{String: errno, Valid: true},
+	}}
+}
+
+func TestMasterFencingState(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{sme: sme, Conf: config.Config{MaxFail: 3, CheckFalsePositiveFencing: true}}
+	master := &ServerMonitor{URL: "db1:3306", Host: "db1", Port: "3306", ServerID: 1, State: stateFailed, ClusterGroup: cluster}
+	db2 := &ServerMonitor{URL: "db2:3306", ServerID: 2, State: stateSlave, ClusterGroup: cluster}
+	db3 := &ServerMonitor{URL: "db3:3306", ServerID: 3, State: stateSlave, ClusterGroup: cluster}
+	cluster.master = master
+	cluster.Servers = serverList{master, db2, db3}
+	cluster.slaves = serverList{db2, db3}
+	setStatus := func(s *ServerMonitor, ss []dbhelper.SlaveStatus) {
+		s.Replications = ss
+		s.ReplicationStatus = replicationStatusFixture(ss)
+	}
+	tests := []struct {
+		failCount  int
+		db2, db3   []dbhelper.SlaveStatus
+		confidence string
+		lost       int
+	}{
+		{1, fencingSlaveStatus("Connecting", "2003"), fencingSlaveStatus("Connecting", "2003"), FencingLow, 2},
+		{3, fencingSlaveStatus("Yes", "0"), fencingSlaveStatus("Connecting", "2003"), FencingLow, 1},
+		{3, fencingSlaveStatus("Yes", "2013"), fencingSlaveStatus("No", "0"), FencingHigh, 2},
+		{3, nil, nil, FencingMedium, 0},
+	}
+	for _, test := range tests {
+		master.FailCount = test.failCount
+		setStatus(db2, test.db2)
+		setStatus(db3, test.db3)
+		fs := cluster.GetMasterFencingState()
+		if fs.Confidence != test.confidence || fs.SlavesLost != test.lost || fs.Fenced != (test.confidence == FencingHigh) {
+			t.Fatalf("Got %+v, expected confidence %s with %d slaves lost", fs, test.confidence, test.lost)
+		}
+		if cluster.isMasterNotFenced() == fs.Fenced {
+			t.Fatalf("Failover fencing check does not match %+v", fs)
+		}
+	}
+	if !sme.CurState.Search("ERR00086") {
+		t.Fatal("Expected ERR00086 for a master not fenced")
+	}
+	master.State = stateMaster
+	if fs := cluster.GetMasterFencingState(); fs.Confidence != FencingNone {
+		t.Fatalf("Got confidence %s for a running master", fs.Confidence)
+	}
+}
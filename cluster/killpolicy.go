@@ -0,0 +1,271 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
+
+// KillPolicy kills the queries running longer than MinDuration seconds, User, Schema, Host, Digest and Statement
+// restrict the match when set, at least one of User, Schema, Host or Digest is required. Host is the client host
+// without port, Statement is the first keyword of the query like SELECT. Schedule restricts the policy to a time
+// window like "09:00-18:00", "1-5 09:00-18:00" for monday to friday or "5-1 20:00-06:00" over the weekend, empty
+// for always
+type KillPolicy struct {
+	Name        string `json:"name"`
+	User        string `json:"user"`
+	Host        string `json:"host"`
+	Digest      string `json:"digest"`
+	Statement   string `json:"statement"`
+	MinDuration int64  `json:"minDuration"`
+	Schema      string `json:"schema"`
+	Schedule    string `json:"schedule"`
+	DryRun      bool   `json:"dryRun"`
+}
+
+// KillPolicyAction is a query killed, or that would be killed in dry run, by a policy
+type KillPolicyAction struct {
+	Policy string `json:"policy"`
+	URL    string `json:"url"`
+	Id     uint64 `json:"id"`
+	User   string `json:"user"`
+	Time   int64  `json:"time"`
+	DryRun bool   `json:"dryRun"`
+}
+
+// Validate checks that the policy can be evaluated
+func (p KillPolicy) Validate() error {
+	if p.Name == "" {
+		return errors.New("Kill policy without name")
+	}
+	if p.MinDuration <= 0 {
+		return fmt.Errorf("Kill policy %s minimum duration must be positive", p.Name)
+	}
+	if p.User == "" && p.Schema == "" && p.Host == "" && p.Digest == "" {
+		return fmt.Errorf("Kill policy %s requires a user, schema, host or digest filter", p.Name)
+	}
+	if _, err := p.inSchedule(time.Now()); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (p KillPolicy) inSchedule(now time.Time) (bool, error) {
+	if p.Schedule == "" {
+		return true, nil
+	}
+	fields := strings.Fields(p.Schedule)
+	window := fields[len(fields)-1]
+	if len(fields) == 2 {
+		first, last, err := parseKillPolicyRange(fields[0], strconv.Atoi)
+		if err != nil || first < 0 || last > 6 {
+			return false, fmt.Errorf("Kill policy %s invalid days %s", p.Name, fields[0])
+		}
+		day := int(now.Weekday())
+		if first <= last && (day < first || day > last) {
+			return false, nil
+		}
+		// days over the end of the week
+		if first > last && day < first && day > last {
+			return false, nil
+		}
+	} else if len(fields) != 1 {
+		return false, fmt.Errorf("Kill policy %s invalid schedule %s", p.Name, p.Schedule)
+	}
+	start, end, err := parseKillPolicyRange(window, func(s string) (int, error) {
+		t, err := time.Parse("15:04", s)
+		return t.Hour()*60 + t.Minute(), err
+	})
+	if err != nil {
+		return false, fmt.Errorf("Kill policy %s invalid time window %s", p.Name, window)
+	}
+	minute := now.Hour()*60 + now.Minute()
+	if start <= end {
+		return minute >= start && minute < end, nil
+	}
+	// window over midnight
+	return minute >= start || minute < end, nil
+}
+
+func parseKillPolicyRange(s string, parse func(string) (int, error)) (int, int, error) {
+	bounds := strings.SplitN(s, "-", 2)
+	first, err := parse(bounds[0])
+	if err != nil {
+		return 0, 0, err
+	}
+	if len(bounds) == 1 {
+		return first, first, nil
+	}
+	last, err := parse(bounds[1])
+	return first, last, err
+}
+
+func (p KillPolicy) match(q dbhelper.Processlist) bool {
+	if !q.Time.Valid || q.Time.Float64 < float64(p.MinDuration) {
+		return false
+	}
+	if p.User != "" && q.User != p.User {
+		return false
+	}
+	if p.Schema != "" && q.Db.String != p.Schema {
+		return false
+	}
+	if p.Host != "" && getProcessListClientHost(q.Host) != getProcessListClientHost(p.Host) {
+		return false
+	}
+	if p.Digest != "" {
+		d := q.Digest
+		if d == "" {
+			d = dbhelper.GetQueryDigest(q.Info.String)
+		}
+		if d != p.Digest {
+			return false
+		}
+	}
+	if p.Statement != "" && !strings.HasPrefix(strings.ToUpper(strings.TrimLeft(q.Info.String, " \t\r\n(")), strings.ToUpper(p.Statement)) {
+		return false
+	}
+	return true
+}
+
+// getKillPolicyCandidates returns the client queries a policy may kill, monitoring, system and replication threads
+// are never candidates
+func (server *ServerMonitor) getKillPolicyCandidates() []dbhelper.Processlist {
+	var pl []dbhelper.Processlist
+	commands := server.GetProcessListReplicationCommands()
+	for _, q := range server.FullProcessList {
+		if q.User == server.User || q.User == "system user" || q.User == "event_scheduler" || q.User == server.ClusterGroup.rplUser {
+			continue
+		}
+		if (q.Command != "Query" && q.Command != "Execute") || !q.Info.Valid || q.Info.String == "" {
+			continue
+		}
+		if server.isReplicationApplierThread(q, commands) {
+			continue
+		}
+		pl = append(pl, q)
+	}
+	return pl
+}
+
+// LoadKillPolicies reads the JSON array of monitoring-kill-policies
+func (cluster *Cluster) LoadKillPolicies() {
+	cluster.KillPolicies = []KillPolicy{}
+	if cluster.Conf.KillPolicies == "" {
+		return
+	}
+	var policies []KillPolicy
+	if err := json.Unmarshal([]byte(cluster.Conf.KillPolicies), &policies); err != nil {
+		cluster.LogPrintf(LvlErr, "Can't parse kill policies: %s", err)
+		return
+	}
+	for _, p := range policies {
+		if err := cluster.AddKillPolicy(p); err != nil {
+			cluster.LogPrintf(LvlErr, "Skip kill policy: %s", err)
+		}
+	}
+}
+
+// AddKillPolicy adds or replaces the policy of the same name
+func (cluster *Cluster) AddKillPolicy(p KillPolicy) error {
+	if err := p.Validate(); err != nil {
+		return err
+	}
+	cluster.Lock()
+	defer cluster.Unlock()
+	for i := range cluster.KillPolicies {
+		if cluster.KillPolicies[i].Name == p.Name {
+			cluster.KillPolicies[i] = p
+			return nil
+		}
+	}
+	cluster.KillPolicies = append(cluster.KillPolicies, p)
+	return nil
+}
+
+// DropKillPolicy removes a policy, false is returned when no policy has this name
+func (cluster *Cluster) DropKillPolicy(name string) bool {
+	cluster.Lock()
+	defer cluster.Unlock()
+	for i := range cluster.KillPolicies {
+		if cluster.KillPolicies[i].Name == name {
+			cluster.KillPolicies = append(cluster.KillPolicies[:i], cluster.KillPolicies[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+func (cluster *Cluster) GetKillPolicies() []KillPolicy {
+	cluster.Lock()
+	defer cluster.Unlock()
+	return append([]KillPolicy{}, cluster.KillPolicies...)
+}
+
+// ApplyKillPolicies kills on every running server the queries matching a policy in its schedule, every kill is
+// logged, nothing is killed when the policy or monitoring-kill-policies-dry-run is in dry run
+func (cluster *Cluster) ApplyKillPolicies() []KillPolicyAction {
+	return cluster.applyKillPolicies(time.Now())
+}
+
+// getLoggedQuery returns the query of a killed thread as written to the logs, readable by any API user, the
+// literals are replaced by placeholders when monitoring-processlist-redact is set
+func (cluster *Cluster) getLoggedQuery(q dbhelper.Processlist) string {
+	if cluster.Conf.MonitorProcessListRedact {
+		return redactQuery(q.Info.String)
+	}
+	return q.Info.String
+}
+
+func (cluster *Cluster) applyKillPolicies(now time.Time) []KillPolicyAction {
+	var actions []KillPolicyAction
+	for _, p := range cluster.GetKillPolicies() {
+		if ok, _ := p.inSchedule(now); !ok {
+			continue
+		}
+		dryRun := p.DryRun || cluster.Conf.KillPoliciesDryRun
+		for _, s := range cluster.Servers {
+			if s == nil || s.IsDown() {
+				continue
+			}
+			for _, q := range s.getKillPolicyCandidates() {
+				if !p.match(q) {
+					continue
+				}
+				a := KillPolicyAction{Policy: p.Name, URL: s.URL, Id: q.Id, User: q.User, Time: int64(q.Time.Float64), DryRun: dryRun}
+				if dryRun {
+					cluster.LogPrintf(LvlInfo, "Kill policy %s dry run, would kill query %d of %s on %s running %ds: %s", p.Name, q.Id, q.User, s.URL, a.Time, cluster.getLoggedQuery(q))
+					actions = append(actions, a)
+					continue
+				}
+				// the thread may have ended or run another query since the process list was read
+				if cur, ok := s.getCurrentThread(q); !ok || !p.match(cur) {
+					cluster.LogPrintf(LvlInfo, "Kill policy %s skip query %d on %s, no longer running", p.Name, q.Id, s.URL)
+					continue
+				}
+				logs, err := s.KillQuery(strconv.FormatUint(q.Id, 10))
+				cluster.LogSQL(logs, err, s.URL, "KillPolicy", LvlErr, "Kill policy %s could not kill query %d on %s: %s", p.Name, q.Id, s.URL, err)
+				if err != nil {
+					continue
+				}
+				cluster.LogPrintf(LvlInfo, "Kill policy %s killed query %d of %s on %s running %ds: %s", p.Name, q.Id, q.User, s.URL, a.Time, cluster.getLoggedQuery(q))
+				actions = append(actions, a)
+			}
+		}
+	}
+	return actions
+}
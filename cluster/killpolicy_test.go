@@ -0,0 +1,117 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
+
+func TestKillPolicies(t *testing.T) {
+	query := func(id uint64, user string, command string, db string, info string, seconds float64) dbhelper.Processlist {
+		return dbhelper.Processlist{Id: id, User: user, Command: command, Db: sql.NullString{String: db, Valid: true}, Info: sql.NullString{String: info, Valid: true}, Time: sql.NullFloat64{Float64: seconds, Valid: true}}
+	}
+	cluster := &Cluster{Conf: config.Config{KillPolicies: `[{"name":"report","user":"report","statement":"select","minDuration":300,"schedule":"1-5 09:00-18:00","dryRun":true},{"name":"bad","minDuration":10,"schedule":"25:00-26:00"}]`}}
+	server := &ServerMonitor{URL: "db1:3306", User: "repman", State: stateSlave, ClusterGroup: cluster, DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", "")}
+	server.FullProcessList = []dbhelper.Processlist{
+		query(1, "report", "Query", "sales", "SELECT sum(total) FROM orders", 400),
+		query(2, "report", "Query", "sales", "SELECT 1", 20),
+		query(3, "report", "Query", "sales", "UPDATE orders SET total=0", 900),
+		query(4, "app", "Query", "sales", "SELECT * FROM orders", 900),
+		query(5, "repman", "Query", "", "SELECT sleep(1000)", 1000),
+		query(6, "system user", "Slave_worker", "", "SELECT 1", 1000),
+	}
+	cluster.Servers = serverList{server}
+	cluster.LoadKillPolicies()
+	if policies := cluster.GetKillPolicies(); len(policies) != 1 || policies[0].Name != "report" {
+		t.Fatalf("Expected invalid schedule policy skipped, got %v", policies)
+	}
+	wednesday := time.Date(2026, 10, 14, 10, 30, 0, 0, time.Local)
+	actions := cluster.applyKillPolicies(wednesday)
+	if len(actions) != 1 || actions[0].Id != 1 || !actions[0].DryRun || actions[0].Time != 400 {
+		t.Fatalf("Expected query 1 matched in dry run, got %v", actions)
+	}
+	if actions = cluster.applyKillPolicies(wednesday.Add(9 * time.Hour)); len(actions) != 0 {
+		t.Fatalf("Expected no match out of business hours, got %v", actions)
+	}
+	if actions = cluster.applyKillPolicies(wednesday.AddDate(0, 0, 3)); len(actions) != 0 {
+		t.Fatalf("Expected no match on saturday, got %v", actions)
+	}
+	night := KillPolicy{Name: "night", User: "batch", MinDuration: 60, Schedule: "22:00-06:00"}
+	if ok, _ := night.inSchedule(wednesday.Add(14 * time.Hour)); !ok {
+		t.Fatal("Expected window over midnight to match at 00:30")
+	}
+	weekend := KillPolicy{Name: "weekend", User: "batch", MinDuration: 60, Schedule: "5-1 00:00-23:59"}
+	for days, expected := range map[int]bool{0: false, 1: false, 2: true, 3: true, 4: true, 5: true, 6: false} {
+		if ok, err := weekend.inSchedule(wednesday.AddDate(0, 0, days)); err != nil || ok != expected {
+			t.Fatalf("Expected weekend schedule %v on %s, got %v %v", expected, wednesday.AddDate(0, 0, days).Weekday(), ok, err)
+		}
+	}
+	if err := (KillPolicy{Name: "all", MinDuration: 60}).Validate(); err == nil {
+		t.Fatal("Expected policy without user, schema, host or digest filter rejected")
+	}
+	if !cluster.DropKillPolicy("report") || len(cluster.GetKillPolicies()) != 0 {
+		t.Fatal("Expected policy dropped")
+	}
+}
+
+func TestKillPoliciesRecheckThread(t *testing.T) {
+	query := func(id uint64, user string, host string, info string, seconds float64) dbhelper.Processlist {
+		return dbhelper.Processlist{Id: id, User: user, Host: host, Command: "Query", Info: sql.NullString{String: info, Valid: true}, Time: sql.NullFloat64{Float64: seconds, Valid: true}}
+	}
+	// the threads as read again before the kill, thread 2 runs another query and thread 3 ended
+	current := map[int64][]driver.Value{
+		1: {int64(1), "app", "10.0.0.5:5000", nil, "Query", int64(130), nil, "SELECT * FROM orders", int64(0)},
+		2: {int64(2), "app", "10.0.0.5:5001", nil, "Query", int64(1), nil, "SELECT 1", int64(0)},
+	}
+	log := &execLog{}
+	name := fmt.Sprintf("killpolicy%d", time.Now().UnixNano())
+	sql.Register(name, execDriver{log: log, rows: func(query string, args []driver.Value) *pkRows {
+		if !strings.Contains(query, "WHERE ID = ?") {
+			return nil
+		}
+		r := &pkRows{cols: []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info", "Progress"}}
+		if row, ok := current[args[0].(int64)]; ok {
+			r.values = [][]driver.Value{row}
+		}
+		return r
+	}})
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cluster := &Cluster{}
+	server := &ServerMonitor{URL: "db1:3306", User: "repman", State: stateSlave, Conn: db, ClusterGroup: cluster, DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", "")}
+	server.FullProcessList = []dbhelper.Processlist{
+		query(1, "app", "10.0.0.5:5000", "SELECT * FROM orders", 120),
+		query(2, "app", "10.0.0.5:5001", "SELECT * FROM orders", 120),
+		query(3, "app", "10.0.0.5:5002", "SELECT * FROM orders", 120),
+		query(4, "app", "10.0.0.6:5000", "SELECT * FROM orders", 120),
+	}
+	cluster.Servers = serverList{server}
+	if err := cluster.AddKillPolicy(KillPolicy{Name: "app", Host: "10.0.0.5", MinDuration: 60}); err != nil {
+		t.Fatal(err)
+	}
+	actions := cluster.applyKillPolicies(time.Now())
+	if len(actions) != 1 || actions[0].Id != 1 || actions[0].DryRun {
+		t.Fatalf("Expected only query 1 killed, got %v", actions)
+	}
+	if stmts := strings.Join(log.stmts, ";"); stmts != "KILL QUERY ? [1]" {
+		t.Fatalf("Unexpected kill statements %s", stmts)
+	}
+}
@@ -0,0 +1,62 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"encoding/json"
+	"os"
+	"sync"
+)
+
+// DelaySample is a replication delay measure of a replication channel at a monitoring poll
+type DelaySample struct {
+	Cluster    string `json:"cluster"`
+	Server     string `json:"server"`
+	Channel    string `json:"channel"`
+	LagSeconds int64  `json:"lagSeconds"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// MetricSink receives replication delay samples for long term analysis
+type MetricSink interface {
+	RecordReplicationDelay(sample DelaySample) error
+	Close() error
+}
+
+// fileMetricSink appends samples as JSON lines to a file
+type fileMetricSink struct {
+	sync.Mutex
+	Handle *os.File
+}
+
+func newFileMetricSink(name string) (*fileMetricSink, error) {
+	f, err := os.OpenFile(name, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return nil, err
+	}
+	return &fileMetricSink{Handle: f}, nil
+}
+
+func (fs *fileMetricSink) RecordReplicationDelay(sample DelaySample) error {
+	line, err := json.Marshal(sample)
+	if err != nil {
+		return err
+	}
+	fs.Lock()
+	defer fs.Unlock()
+	_, err = fs.Handle.Write(append(line, '\n'))
+	return err
+}
+
+func (fs *fileMetricSink) Close() error {
+	fs.Lock()
+	defer fs.Unlock()
+	return fs.Handle.Close()
+}
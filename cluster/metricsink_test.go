@@ -0,0 +1,68 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFileMetricSink(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "delay.json")
+	sink, err := newFileMetricSink(name)
+	if err != nil {
+		t.Fatal("Error creating sink: ", err)
+	}
+	for _, lag := range []int64{0, 12} {
+		err = sink.RecordReplicationDelay(DelaySample{Cluster: "c1", Server: "db1:3306", LagSeconds: lag, Timestamp: 1700000000})
+		if err != nil {
+			t.Fatal("Error recording sample: ", err)
+		}
+	}
+	sink.Close()
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal("Error reading sink: ", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Read %d lines, expected 2", len(lines))
+	}
+	if lines[1] != `{"cluster":"c1","server":"db1:3306","channel":"","lagSeconds":12,"timestamp":1700000000}` {
+		t.Fatalf("Unexpected sample %s", lines[1])
+	}
+}
+
+func TestRecordReplicationDelaySkipsUnknown(t *testing.T) {
+	name := filepath.Join(t.TempDir(), "delay.json")
+	sink, err := newFileMetricSink(name)
+	if err != nil {
+		t.Fatal("Error creating sink: ", err)
+	}
+	server := &ServerMonitor{URL: "db2:3306", ClusterGroup: &Cluster{Name: "c1", MetricSink: sink}, ReplicationStatus: replicationStatusFixture{
+		{ConnectionName: sql.NullString{String: "stopped", Valid: true}},
+		{ConnectionName: sql.NullString{String: "running", Valid: true}, SecondsBehindMaster: sql.NullInt64{Int64: 3, Valid: true}},
+	}}
+	if err = server.RecordReplicationDelay(); err != nil {
+		t.Fatal("Error recording delay: ", err)
+	}
+	sink.Close()
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal("Error reading sink: ", err)
+	}
+	lines := strings.Split(strings.TrimSpace(string(content)), "\n")
+	if len(lines) != 1 || !strings.Contains(lines[0], `"channel":"running","lagSeconds":3`) {
+		t.Fatalf("Expected only the running channel recorded, got %s", content)
+	}
+}
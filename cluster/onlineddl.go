@@ -0,0 +1,544 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
+
+const (
+	DDLAlgorithmInstant string = "INSTANT"
+	DDLAlgorithmInplace string = "INPLACE"
+	DDLAlgorithmCopy    string = "COPY"
+)
+
+var ddlAlgorithmRank = map[string]int{DDLAlgorithmInstant: 0, DDLAlgorithmInplace: 1, DDLAlgorithmCopy: 2}
+
+// OnlineDDLFeasibility is the expected InnoDB algorithm of an ALTER TABLE, the slowest of its operations.
+// Rebuild is set when the table is copied in place or not, Online when concurrent DML is permitted
+type OnlineDDLFeasibility struct {
+	Schema     string               `json:"schema"`
+	Table      string               `json:"table"`
+	Algorithm  string               `json:"algorithm"`
+	Rebuild    bool                 `json:"rebuild"`
+	Online     bool                 `json:"online"`
+	Operations []OnlineDDLOperation `json:"operations"`
+	Warnings   []string             `json:"warnings"`
+}
+
+type OnlineDDLOperation struct {
+	Clause    string `json:"clause"`
+	Operation string `json:"operation"`
+	Algorithm string `json:"algorithm"`
+	Rebuild   bool   `json:"rebuild"`
+	Online    bool   `json:"online"`
+}
+
+type onlineDDLTable struct {
+	Columns     []dbhelper.TableColumn
+	PrimaryKey  []string
+	ForeignKeys []dbhelper.ForeignKey
+	Indexes     []dbhelper.TableIndexColumn
+	Engine      string
+	RowFormat   string
+	DataLength  int64
+}
+
+var (
+	alterTableRegexp   = regexp.MustCompile(`(?is)^\s*ALTER\s+(ONLINE\s+)?(IGNORE\s+)?TABLE\s+\S+\s*`)
+	ddlIntegerRegexp   = regexp.MustCompile(`^(tinyint|smallint|mediumint|int|integer|bigint)\(\d+\)`)
+	ddlVarcharRegexp   = regexp.MustCompile(`^varchar\((\d+)\)$`)
+	ddlAddPKRegexp     = regexp.MustCompile(`(?i)^ADD\s+(CONSTRAINT\s+(\S+\s+)?)?PRIMARY\s+KEY`)
+	ddlAlgorithmRegexp = regexp.MustCompile(`(?i)^(ALGORITHM|LOCK)\s*=?\s*(\w+)$`)
+)
+
+// CheckOnlineDDLFeasibility returns the algorithm an ALTER of the table is expected to use with its warnings,
+// from the table definition and the server version without running the ALTER. alterSQL is the full ALTER
+// TABLE statement or its list of operations
+func (server *ServerMonitor) CheckOnlineDDLFeasibility(schema string, table string, alterSQL string) (OnlineDDLFeasibility, error) {
+	if server.DBVersion.IsPPostgreSQL() {
+		return OnlineDDLFeasibility{}, errors.New("Online DDL feasibility is not available on PostgreSQL")
+	}
+	var t onlineDDLTable
+	var err error
+	t.Columns, err = server.GetTableColumns(schema, table)
+	if err != nil {
+		return OnlineDDLFeasibility{}, err
+	}
+	if len(t.Columns) == 0 {
+		return OnlineDDLFeasibility{}, fmt.Errorf("Table %s.%s not found", schema, table)
+	}
+	pks, err := server.GetTablePKs(schema)
+	if err != nil {
+		return OnlineDDLFeasibility{}, err
+	}
+	t.PrimaryKey = pks[table]
+	fks, err := server.GetTableForeignKeys(schema)
+	if err != nil {
+		return OnlineDDLFeasibility{}, err
+	}
+	for _, fk := range fks {
+		if fk.Table == table || (fk.RefSchema == schema && fk.RefTable == table) {
+			t.ForeignKeys = append(t.ForeignKeys, fk)
+		}
+	}
+	var logs string
+	t.Indexes, logs, err = dbhelper.GetTableIndexColumns(server.Conn, server.DBVersion, schema, table)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get indexes of %s.%s %s %s", schema, table, server.URL, err)
+	if err != nil {
+		return OnlineDDLFeasibility{}, err
+	}
+	storage, logs, err := dbhelper.GetTableStorage(server.Conn, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get table storage of %s %s %s", schema, server.URL, err)
+	if err != nil {
+		return OnlineDDLFeasibility{}, err
+	}
+	for _, s := range storage {
+		if s.Table == table {
+			t.Engine = s.Engine.String
+			t.RowFormat = s.Row_format.String
+			t.DataLength = s.Data_length
+		}
+	}
+	f := getOnlineDDLFeasibility(server.DBVersion, alterSQL, t)
+	f.Schema = schema
+	f.Table = table
+	return f, nil
+}
+
+func getOnlineDDLFeasibility(version *dbhelper.MySQLVersion, alter string, t onlineDDLTable) OnlineDDLFeasibility {
+	f := OnlineDDLFeasibility{Algorithm: DDLAlgorithmInstant, Online: true, Operations: []OnlineDDLOperation{}, Warnings: []string{}}
+	clauses := splitDDLClauses(alterTableRegexp.ReplaceAllString(strings.TrimRight(strings.TrimSpace(alter), ";"), ""))
+	replacePK := false
+	for _, c := range clauses {
+		replacePK = replacePK || ddlAddPKRegexp.MatchString(c)
+	}
+	requested := make(map[string]string)
+	for _, c := range clauses {
+		if m := ddlAlgorithmRegexp.FindStringSubmatch(c); m != nil {
+			requested[strings.ToUpper(m[1])] = strings.ToUpper(m[2])
+			continue
+		}
+		op := getOnlineDDLOperation(version, c, t, replacePK, &f.Warnings)
+		if t.Engine != "" && !strings.EqualFold(t.Engine, "InnoDB") && op.Operation != "rename table" {
+			op.Algorithm, op.Rebuild, op.Online = DDLAlgorithmCopy, true, false
+		}
+		f.Operations = append(f.Operations, op)
+		if ddlAlgorithmRank[op.Algorithm] > ddlAlgorithmRank[f.Algorithm] {
+			f.Algorithm = op.Algorithm
+		}
+		f.Rebuild = f.Rebuild || op.Rebuild
+		f.Online = f.Online && op.Online
+	}
+	if len(f.Operations) == 0 {
+		f.Warnings = append(f.Warnings, "No operation found in the ALTER")
+		return f
+	}
+	if t.Engine != "" && !strings.EqualFold(t.Engine, "InnoDB") {
+		f.Warnings = append(f.Warnings, fmt.Sprintf("Engine %s supports no online DDL, the ALTER copies the table", t.Engine))
+	}
+	if r, ok := requested["ALGORITHM"]; ok && r != "DEFAULT" && ddlAlgorithmRank[r] < ddlAlgorithmRank[f.Algorithm] {
+		f.Warnings = append(f.Warnings, fmt.Sprintf("ALGORITHM=%s is not supported by the ALTER, it will fail, expected %s", r, f.Algorithm))
+	}
+	if r, ok := requested["LOCK"]; ok && r == "NONE" && !f.Online {
+		f.Warnings = append(f.Warnings, "LOCK=NONE is not supported by the ALTER, it will fail")
+	}
+	if f.Rebuild {
+		if len(t.PrimaryKey) == 0 {
+			f.Warnings = append(f.Warnings, "Table has no primary key, the rebuild uses the hidden clustered index")
+		}
+		if t.DataLength > 0 {
+			f.Warnings = append(f.Warnings, fmt.Sprintf("Table rebuild rewrites %d MB of data", t.DataLength/1024/1024))
+		}
+		for _, col := range t.Columns {
+			if col.Generated == "STORED" {
+				f.Warnings = append(f.Warnings, fmt.Sprintf("Stored generated column %s is computed again for every row", col.Name))
+			}
+		}
+		f.Warnings = append(f.Warnings, "Slaves apply the ALTER after the master, replication delay grows for its whole duration")
+	}
+	if f.Algorithm == DDLAlgorithmCopy && len(t.ForeignKeys) > 0 {
+		f.Warnings = append(f.Warnings, "Table has foreign keys, the copy checks them for every row")
+	}
+	if !f.Online {
+		f.Warnings = append(f.Warnings, "Concurrent writes to the table are blocked during the ALTER")
+	}
+	seen := make(map[string]bool)
+	warnings := []string{}
+	for _, w := range f.Warnings {
+		if !seen[w] {
+			seen[w] = true
+			warnings = append(warnings, w)
+		}
+	}
+	f.Warnings = warnings
+	return f
+}
+
+func getOnlineDDLOperation(version *dbhelper.MySQLVersion, clause string, t onlineDDLTable, replacePK bool, warnings *[]string) OnlineDDLOperation {
+	tokens := ddlTokens(clause)
+	up := make([]string, len(tokens)+3)
+	for i, tk := range tokens {
+		up[i] = strings.ToUpper(tk)
+	}
+	op := OnlineDDLOperation{Clause: clause}
+	set := func(name string, algorithm string, rebuild bool, online bool) OnlineDDLOperation {
+		op.Operation, op.Algorithm, op.Rebuild, op.Online = name, algorithm, rebuild, online
+		return op
+	}
+	metadata := ddlMetadataAlgorithm(version)
+	switch up[0] {
+	case "ADD":
+		kind := up[1]
+		if kind == "CONSTRAINT" {
+			kind = up[2]
+			if kind != "PRIMARY" && kind != "UNIQUE" && kind != "FOREIGN" && kind != "CHECK" {
+				kind = up[3]
+			}
+		}
+		switch kind {
+		case "INDEX", "KEY", "UNIQUE":
+			return set("add index", DDLAlgorithmInplace, false, true)
+		case "FULLTEXT":
+			if hasDDLIndexType(t, "FULLTEXT") {
+				return set("add fulltext index", DDLAlgorithmInplace, false, false)
+			}
+			return set("add first fulltext index", DDLAlgorithmInplace, true, false)
+		case "SPATIAL":
+			return set("add spatial index", DDLAlgorithmInplace, false, false)
+		case "PRIMARY":
+			return set("add primary key", DDLAlgorithmInplace, true, true)
+		case "FOREIGN":
+			*warnings = append(*warnings, "Adding a foreign key uses INPLACE only with foreign_key_checks disabled")
+			return set("add foreign key", DDLAlgorithmCopy, true, false)
+		case "CHECK":
+			return set("add check constraint", DDLAlgorithmCopy, true, false)
+		case "PARTITION":
+			return set("add partition", DDLAlgorithmInplace, false, true)
+		}
+		def := tokens[1:]
+		if up[1] == "COLUMN" {
+			def = tokens[2:]
+		}
+		return getOnlineDDLAddColumn(version, t, def, set, warnings)
+	case "DROP":
+		switch up[1] {
+		case "INDEX", "KEY":
+			return set("drop index", DDLAlgorithmInplace, false, true)
+		case "PRIMARY":
+			if replacePK {
+				return set("drop primary key", DDLAlgorithmInplace, true, true)
+			}
+			return set("drop primary key", DDLAlgorithmCopy, true, false)
+		case "FOREIGN", "CONSTRAINT", "CHECK":
+			return set("drop constraint", DDLAlgorithmInplace, false, true)
+		case "PARTITION":
+			return set("drop partition", DDLAlgorithmInplace, false, false)
+		}
+		name := ""
+		if up[1] == "COLUMN" && len(tokens) > 2 {
+			name = unquoteDDLName(tokens[2])
+		} else if len(tokens) > 1 {
+			name = unquoteDDLName(tokens[1])
+		}
+		checkDDLColumn(t, name, warnings)
+		if ddlVersionAtLeast(version, 8, 0, 29, 10, 4, 0) && !isDDLInstantBlocked(version, t, warnings) {
+			return set("drop column", DDLAlgorithmInstant, false, true)
+		}
+		return set("drop column", DDLAlgorithmInplace, true, true)
+	case "MODIFY", "CHANGE":
+		def := tokens[1:]
+		if up[1] == "COLUMN" {
+			def = tokens[2:]
+		}
+		if len(def) == 0 {
+			break
+		}
+		name, newName := unquoteDDLName(def[0]), unquoteDDLName(def[0])
+		if up[0] == "CHANGE" && len(def) > 1 {
+			newName = unquoteDDLName(def[1])
+			def = def[1:]
+		}
+		return getOnlineDDLColumnChange(version, t, name, newName, def[1:], set, warnings)
+	case "ALTER":
+		if up[1] == "INDEX" {
+			return set("index visibility", metadata, false, true)
+		}
+		return set("column default or visibility", metadata, false, true)
+	case "RENAME":
+		switch up[1] {
+		case "COLUMN":
+			if ddlVersionAtLeast(version, 8, 0, 28, 10, 3, 0) {
+				return set("rename column", DDLAlgorithmInstant, false, true)
+			}
+			return set("rename column", DDLAlgorithmInplace, false, true)
+		case "INDEX", "KEY":
+			return set("rename index", metadata, false, true)
+		}
+		return set("rename table", DDLAlgorithmInstant, false, true)
+	case "ENGINE":
+		if len(tokens) > 1 && strings.EqualFold(tokens[len(tokens)-1], "InnoDB") && (t.Engine == "" || strings.EqualFold(t.Engine, "InnoDB")) {
+			return set("rebuild table", DDLAlgorithmInplace, true, true)
+		}
+		return set("change engine", DDLAlgorithmCopy, true, false)
+	case "FORCE", "ROW_FORMAT", "KEY_BLOCK_SIZE":
+		return set("rebuild table", DDLAlgorithmInplace, true, true)
+	case "CONVERT":
+		return set("convert character set", DDLAlgorithmCopy, true, false)
+	case "DEFAULT", "CHARACTER", "CHARSET", "COLLATE":
+		return set("table default character set", DDLAlgorithmInplace, false, true)
+	case "AUTO_INCREMENT":
+		return set("auto increment value", DDLAlgorithmInplace, false, true)
+	case "COMMENT":
+		return set("table comment", metadata, false, true)
+	case "PARTITION", "REMOVE", "ORDER":
+		return set("repartition or reorder table", DDLAlgorithmCopy, true, false)
+	}
+	*warnings = append(*warnings, fmt.Sprintf("Unrecognized operation %s, expecting a table copy", clause))
+	return set("unknown", DDLAlgorithmCopy, true, false)
+}
+
+func getOnlineDDLAddColumn(version *dbhelper.MySQLVersion, t onlineDDLTable, def []string, set func(string, string, bool, bool) OnlineDDLOperation, warnings *[]string) OnlineDDLOperation {
+	positioned, generated, stored, autoIncrement := false, false, false, false
+	for _, tk := range def {
+		switch strings.ToUpper(tk) {
+		case "FIRST", "AFTER":
+			positioned = true
+		case "AS", "GENERATED":
+			generated = true
+		case "STORED", "PERSISTENT":
+			stored = true
+		case "AUTO_INCREMENT":
+			autoIncrement = true
+		}
+	}
+	if len(def) > 0 && strings.HasPrefix(def[0], "(") {
+		positioned = false
+	}
+	switch {
+	case generated && stored:
+		return set("add stored generated column", DDLAlgorithmCopy, true, false)
+	case generated:
+		return set("add virtual column", ddlMetadataAlgorithm(version), false, true)
+	case autoIncrement:
+		return set("add auto increment column", DDLAlgorithmInplace, true, false)
+	}
+	instant := ddlVersionAtLeast(version, 8, 0, 29, 10, 4, 0) || (!positioned && ddlVersionAtLeast(version, 8, 0, 12, 10, 3, 2))
+	if instant && !isDDLInstantBlocked(version, t, warnings) {
+		if version.IsMySQLOrPercona() && ddlVersionAtLeast(version, 8, 0, 29, 10, 4, 0) {
+			*warnings = append(*warnings, "INSTANT is refused after 64 row versions of the table, it is then rebuilt")
+		}
+		return set("add column", DDLAlgorithmInstant, false, true)
+	}
+	return set("add column", DDLAlgorithmInplace, true, true)
+}
+
+func getOnlineDDLColumnChange(version *dbhelper.MySQLVersion, t onlineDDLTable, name string, newName string, def []string, set func(string, string, bool, bool) OnlineDDLOperation, warnings *[]string) OnlineDDLOperation {
+	col, found := checkDDLColumn(t, name, warnings)
+	if !found || len(def) == 0 {
+		return set("modify column", DDLAlgorithmCopy, true, false)
+	}
+	newType := strings.ToLower(def[0])
+	notNull := false
+	for i, tk := range def[1:] {
+		switch strings.ToUpper(tk) {
+		case "UNSIGNED", "ZEROFILL":
+			newType += " " + strings.ToLower(tk)
+		case "CHARACTER", "CHARSET", "COLLATE":
+			return set("change column character set", DDLAlgorithmCopy, true, false)
+		case "NULL":
+			notNull = i > 0 && strings.EqualFold(def[i], "NOT")
+		}
+	}
+	oldType := strings.ToLower(col.Type)
+	renamed := !strings.EqualFold(name, newName)
+	normalize := func(s string) string { return ddlIntegerRegexp.ReplaceAllString(s, "$1") }
+	if normalize(newType) == normalize(oldType) {
+		if notNull == col.Nullable {
+			return set("change column nullability", DDLAlgorithmInplace, true, true)
+		}
+		if renamed {
+			if ddlVersionAtLeast(version, 8, 0, 28, 10, 3, 0) {
+				return set("rename column", DDLAlgorithmInstant, false, true)
+			}
+			return set("rename column", DDLAlgorithmInplace, false, true)
+		}
+		return set("change column attributes", ddlMetadataAlgorithm(version), false, true)
+	}
+	if o, n := ddlVarcharRegexp.FindStringSubmatch(oldType), ddlVarcharRegexp.FindStringSubmatch(newType); o != nil && n != nil && notNull != col.Nullable {
+		oldLen, _ := strconv.Atoi(o[1])
+		newLen, _ := strconv.Atoi(n[1])
+		mb := getCollationMaxBytes(col.Collation)
+		if newLen >= oldLen && (oldLen*mb < 256) == (newLen*mb < 256) {
+			return set("extend varchar column", DDLAlgorithmInplace, false, true)
+		}
+		*warnings = append(*warnings, fmt.Sprintf("Column %s length prefix grows from 1 to 2 bytes or shrinks, the table is copied", name))
+	}
+	for _, kind := range []string{"enum(", "set("} {
+		if strings.HasPrefix(oldType, kind) && strings.HasPrefix(newType, kind) && notNull != col.Nullable {
+			oldValues := splitColumnTypeValues(oldType[len(kind) : len(oldType)-1])
+			newValues := splitColumnTypeValues(newType[len(kind) : len(newType)-1])
+			appended := len(newValues) >= len(oldValues) && (kind == "set(" && len(newValues) <= 8*((len(oldValues)+7)/8) || kind == "enum(" && (len(oldValues) > 255 || len(newValues) <= 255))
+			for i := range oldValues {
+				appended = appended && i < len(newValues) && oldValues[i] == newValues[i]
+			}
+			if appended {
+				return set("append enum or set values", ddlMetadataAlgorithm(version), false, true)
+			}
+		}
+	}
+	return set("change column type", DDLAlgorithmCopy, true, false)
+}
+
+// ddlMetadataAlgorithm is the algorithm of the operations changing the data dictionary only
+func ddlMetadataAlgorithm(version *dbhelper.MySQLVersion) string {
+	if ddlVersionAtLeast(version, 8, 0, 0, 10, 3, 0) {
+		return DDLAlgorithmInstant
+	}
+	return DDLAlgorithmInplace
+}
+
+// ddlVersionAtLeast compares the server version to the first MySQL or MariaDB release supporting a feature
+func ddlVersionAtLeast(version *dbhelper.MySQLVersion, major int, minor int, release int, mariadbMajor int, mariadbMinor int, mariadbRelease int) bool {
+	if version == nil {
+		return false
+	}
+	if version.IsMariaDB() {
+		return version.Compare(&dbhelper.MySQLVersion{Major: mariadbMajor, Minor: mariadbMinor, Release: mariadbRelease}) >= 0
+	}
+	return version.Compare(&dbhelper.MySQLVersion{Major: major, Minor: minor, Release: release}) >= 0
+}
+
+func isDDLInstantBlocked(version *dbhelper.MySQLVersion, t onlineDDLTable, warnings *[]string) bool {
+	if strings.EqualFold(t.RowFormat, "Compressed") {
+		*warnings = append(*warnings, "ROW_FORMAT=COMPRESSED prevents INSTANT column changes")
+		return true
+	}
+	if version.IsMySQLOrPercona() && hasDDLIndexType(t, "FULLTEXT") {
+		*warnings = append(*warnings, "FULLTEXT index prevents INSTANT column changes")
+		return true
+	}
+	return false
+}
+
+func hasDDLIndexType(t onlineDDLTable, kind string) bool {
+	for _, idx := range t.Indexes {
+		if strings.EqualFold(idx.Type, kind) {
+			return true
+		}
+	}
+	return false
+}
+
+// checkDDLColumn returns the column and warns when it is missing or part of a foreign key
+func checkDDLColumn(t onlineDDLTable, name string, warnings *[]string) (dbhelper.TableColumn, bool) {
+	for _, fk := range t.ForeignKeys {
+		if strings.EqualFold(fk.Column, name) || strings.EqualFold(fk.RefColumn, name) {
+			*warnings = append(*warnings, fmt.Sprintf("Column %s is part of foreign key %s", name, fk.Constraint))
+		}
+	}
+	for _, col := range t.Columns {
+		if strings.EqualFold(col.Name, name) {
+			return col, true
+		}
+	}
+	*warnings = append(*warnings, fmt.Sprintf("Column %s not found", name))
+	return dbhelper.TableColumn{}, false
+}
+
+func getCollationMaxBytes(collation string) int {
+	switch {
+	case strings.HasPrefix(collation, "utf8mb4"), strings.HasPrefix(collation, "utf16"), strings.HasPrefix(collation, "utf32"):
+		return 4
+	case strings.HasPrefix(collation, "utf8"):
+		return 3
+	case strings.HasPrefix(collation, "ucs2"), strings.HasPrefix(collation, "gbk"), strings.HasPrefix(collation, "big5"), strings.HasPrefix(collation, "sjis"):
+		return 2
+	}
+	return 1
+}
+
+func unquoteDDLName(name string) string {
+	return strings.Trim(name, "`\"")
+}
+
+// splitDDLClauses splits the operations of an ALTER on the commas out of parenthesis and quotes
+func splitDDLClauses(alter string) []string {
+	var clauses []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(alter); i++ {
+		c := alter[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			clauses = append(clauses, strings.TrimSpace(alter[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(alter[start:]); last != "" {
+		clauses = append(clauses, last)
+	}
+	return clauses
+}
+
+// ddlTokens splits an operation on spaces out of parenthesis and quotes, an equal sign is a token
+func ddlTokens(clause string) []string {
+	var tokens []string
+	var cur strings.Builder
+	depth := 0
+	var quote byte
+	flush := func() {
+		if cur.Len() > 0 {
+			tokens = append(tokens, cur.String())
+			cur.Reset()
+		}
+	}
+	for i := 0; i < len(clause); i++ {
+		c := clause[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case depth == 0 && (c == ' ' || c == '\t' || c == '\n' || c == '\r'):
+			flush()
+			continue
+		case depth == 0 && c == '=':
+			flush()
+			tokens = append(tokens, "=")
+			continue
+		}
+		cur.WriteByte(c)
+	}
+	flush()
+	return tokens
+}
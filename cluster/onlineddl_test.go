@@ -0,0 +1,90 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
+
+func TestOnlineDDLFeasibility(t *testing.T) {
+	table := onlineDDLTable{
+		Columns: []dbhelper.TableColumn{
+			{Name: "id", Type: "bigint(20)"},
+			{Name: "name", Type: "varchar(50)", Nullable: true, Collation: "utf8mb4_general_ci"},
+			{Name: "status", Type: "enum('new','done')"},
+			{Name: "customer", Type: "int(11)"},
+		},
+		PrimaryKey:  []string{"id"},
+		ForeignKeys: []dbhelper.ForeignKey{{Constraint: "fk_customer", Table: "orders", Column: "customer", RefTable: "customers", RefColumn: "id"}},
+		Engine:      "InnoDB",
+		DataLength:  100 * 1024 * 1024,
+	}
+	mysql80 := dbhelper.NewMySQLVersion("8.0.32", "")
+	mysql57 := dbhelper.NewMySQLVersion("5.7.40", "")
+	mariadb := dbhelper.NewMySQLVersion("10.6.4-MariaDB", "")
+	tests := []struct {
+		version   *dbhelper.MySQLVersion
+		alter     string
+		algorithm string
+		rebuild   bool
+		online    bool
+		warning   string
+	}{
+		{mysql80, "ALTER TABLE orders ADD COLUMN note varchar(10) AFTER name", DDLAlgorithmInstant, false, true, "64 row versions"},
+		{mysql57, "ALTER TABLE orders ADD COLUMN note varchar(10)", DDLAlgorithmInplace, true, true, "Slaves apply the ALTER"},
+		{mariadb, "ADD INDEX ix_name (name), ALTER COLUMN status SET DEFAULT 'new'", DDLAlgorithmInplace, false, true, ""},
+		{mysql80, "MODIFY name varchar(60)", DDLAlgorithmInplace, false, true, ""},
+		{mysql80, "MODIFY name varchar(70)", DDLAlgorithmCopy, true, false, "length prefix"},
+		{mysql80, "MODIFY name varchar(50) NOT NULL", DDLAlgorithmInplace, true, true, ""},
+		{mysql80, "MODIFY status enum('new','done','late') NOT NULL", DDLAlgorithmInstant, false, true, ""},
+		{mysql80, "MODIFY customer bigint NOT NULL, ALGORITHM=INPLACE, LOCK=NONE", DDLAlgorithmCopy, true, false, "ALGORITHM=INPLACE is not supported"},
+		{mysql80, "DROP COLUMN customer", DDLAlgorithmInstant, false, true, "foreign key fk_customer"},
+		{mysql80, "DROP PRIMARY KEY, ADD PRIMARY KEY (id, customer)", DDLAlgorithmInplace, true, true, "100 MB"},
+		{mysql80, "DROP PRIMARY KEY", DDLAlgorithmCopy, true, false, "foreign keys"},
+		{mysql80, "ADD FOREIGN KEY (customer) REFERENCES customers (id)", DDLAlgorithmCopy, true, false, "foreign_key_checks"},
+		{mariadb, "ENGINE=InnoDB", DDLAlgorithmInplace, true, true, ""},
+		{mysql80, "CONVERT TO CHARACTER SET utf8mb4", DDLAlgorithmCopy, true, false, ""},
+		{mysql80, "RENAME TO orders_old", DDLAlgorithmInstant, false, true, ""},
+		{mysql80, "DISCARD TABLESPACE", DDLAlgorithmCopy, true, false, "Unrecognized operation"},
+	}
+	for _, test := range tests {
+		f := getOnlineDDLFeasibility(test.version, test.alter, table)
+		if f.Algorithm != test.algorithm || f.Rebuild != test.rebuild || f.Online != test.online {
+			t.Fatalf("Got %s rebuild %t online %t for %s, expected %s %t %t: %+v", f.Algorithm, f.Rebuild, f.Online, test.alter, test.algorithm, test.rebuild, test.online, f.Operations)
+		}
+		if test.warning != "" && !strings.Contains(strings.Join(f.Warnings, "\n"), test.warning) {
+			t.Fatalf("Expected warning %q for %s, got %v", test.warning, test.alter, f.Warnings)
+		}
+	}
+	table.RowFormat = "Compressed"
+	if f := getOnlineDDLFeasibility(mysql80, "ADD COLUMN note int", table); f.Algorithm != DDLAlgorithmInplace || !f.Rebuild {
+		t.Fatalf("Expected compressed table rebuilt on add column, got %+v", f)
+	}
+	table.Engine = "MyISAM"
+	if f := getOnlineDDLFeasibility(mysql80, "ADD INDEX ix_name (name)", table); f.Algorithm != DDLAlgorithmCopy || f.Online {
+		t.Fatalf("Expected MyISAM table copied, got %+v", f)
+	}
+}
+
+func TestDDLTokens(t *testing.T) {
+	clauses := splitDDLClauses("ADD COLUMN c enum('a,b','c') DEFAULT 'a,b', ENGINE=InnoDB")
+	if len(clauses) != 2 || clauses[1] != "ENGINE=InnoDB" {
+		t.Fatalf("Unexpected clauses %q", clauses)
+	}
+	if tokens := ddlTokens(clauses[1]); len(tokens) != 3 || tokens[1] != "=" {
+		t.Fatalf("Unexpected tokens %q", tokens)
+	}
+	if tokens := ddlTokens(clauses[0]); len(tokens) != 6 || tokens[3] != "enum('a,b','c')" {
+		t.Fatalf("Unexpected tokens %q", tokens)
+	}
+}
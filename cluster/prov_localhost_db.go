@@ -71,7 +71,7 @@ func (cluster *Cluster) LocalhostProvisionDatabaseService(server *ServerMonitor)
 			return err
 		}
 	cluster.LogPrintf(LvlInfo, "Remove datadir done: %s", out.Bytes())*/
-	server.GetDatabaseConfig()
+	server.GenerateDatabaseConfig(true)
 	///	os.Symlink(server.Datadir+"/init/data", path)
 
 	/*cmd = exec.Command("cp", "-rp", cluster.Conf.ShareDir+"/tests/data"+cluster.Conf.ProvDatadirVersion, path)
@@ -285,7 +285,7 @@ func (cluster *Cluster) LocalhostStartDatabaseServiceFistTime(server *ServerMoni
 }
 
 func (cluster *Cluster) LocalhostStartDatabaseService(server *ServerMonitor) error {
-	server.GetDatabaseConfig()
+	server.GetDatabaseConfigManifest()
 	if server.Id == "" {
 		_, err := os.Stat(server.Id)
 		if err != nil {
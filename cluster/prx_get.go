@@ -80,10 +80,9 @@ func (proxy *Proxy) GetProxyConfig() string {
 		if proxy.ShardProxy == nil {
 			proxy.ClusterGroup.LogPrintf(LvlErr, "Can't get shard proxy config start monitoring")
 			proxy.ClusterGroup.ShardProxyBootstrap(proxy)
-			return proxy.ShardProxy.GetDatabaseConfig()
-		} else {
-			return proxy.ShardProxy.GetDatabaseConfig()
 		}
+		proxy.ShardProxy.GetDatabaseConfigManifest()
+		return ""
 	}
 	type File struct {
 		Path    string `json:"path"`
@@ -145,10 +144,12 @@ func (proxy *Proxy) GetProxyConfig() string {
 						var f Link
 						json.Unmarshal([]byte(variable.Value), &f)
 						fpath := strings.Replace(f.Symlink, "%%ENV:SVC_CONF_ENV_BASE_DIR%%/%%ENV:POD%%", proxy.Datadir+"/init", -1)
-						if proxy.ClusterGroup.Conf.LogLevel > 2 {
-							proxy.ClusterGroup.LogPrintf(LvlInfo, "Config symlink %s", fpath)
+						action, err := misc.Symlink(f.Target, fpath)
+						if err != nil {
+							proxy.ClusterGroup.LogPrintf(LvlErr, "Config symlink %s skipped: %s", fpath, err)
+						} else if proxy.ClusterGroup.Conf.LogLevel > 2 {
+							proxy.ClusterGroup.LogPrintf(LvlInfo, "Config symlink %s %s to %s", fpath, action, f.Target)
 						}
-						os.Symlink(f.Target, fpath)
 						//	keys := strings.Split(variable.Value, " ")
 					}
 				}
@@ -0,0 +1,237 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
+
+// SchemaExportFormatVersion is increased on any incompatible change of the exported document
+const SchemaExportFormatVersion = 1
+
+// SchemaExport is the neutral description of the base tables of a schema for ORM and diagram generators, tables
+// are ordered by name. The DDL is not part of the document, it is still given by GetTableDefinition
+type SchemaExport struct {
+	FormatVersion int           `json:"formatVersion"`
+	Schema        string        `json:"schema"`
+	Tables        []SchemaTable `json:"tables"`
+}
+
+// SchemaTable lists the columns in ordinal order, the primary key columns in key order, the indexes the
+// primary key included and the foreign keys with their columns in constraint order
+type SchemaTable struct {
+	Name        string             `json:"name"`
+	Columns     []SchemaColumn     `json:"columns"`
+	PrimaryKey  []string           `json:"primaryKey"`
+	Indexes     []SchemaIndex      `json:"indexes"`
+	ForeignKeys []SchemaForeignKey `json:"foreignKeys"`
+}
+
+// SchemaColumn Type is the canonical type among boolean, integer, bigint, decimal, float, double, bit, string,
+// text, binary, blob, date, datetime, timestamp, time, year, json, enum, set, uuid, geometry and unknown,
+// NativeType is the column type of the server. Length is set for string, binary and bit, Precision and Scale for
+// decimal, Precision for the fractional seconds of temporal types and Values for enum and set. Default is null
+// when the column has no default, the server expression otherwise
+type SchemaColumn struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	NativeType    string   `json:"nativeType"`
+	Length        int64    `json:"length,omitempty"`
+	Precision     int64    `json:"precision,omitempty"`
+	Scale         int64    `json:"scale,omitempty"`
+	Unsigned      bool     `json:"unsigned,omitempty"`
+	Values        []string `json:"values,omitempty"`
+	Nullable      bool     `json:"nullable"`
+	Default       *string  `json:"default"`
+	AutoIncrement bool     `json:"autoIncrement"`
+	Generated     string   `json:"generated,omitempty"`
+	Expression    string   `json:"expression,omitempty"`
+	Collation     string   `json:"collation,omitempty"`
+}
+
+type SchemaIndex struct {
+	Name    string   `json:"name"`
+	Columns []string `json:"columns"`
+	Unique  bool     `json:"unique"`
+	Primary bool     `json:"primary"`
+	Type    string   `json:"type"`
+}
+
+type SchemaForeignKey struct {
+	Name       string   `json:"name"`
+	Columns    []string `json:"columns"`
+	RefSchema  string   `json:"refSchema"`
+	RefTable   string   `json:"refTable"`
+	RefColumns []string `json:"refColumns"`
+	OnDelete   string   `json:"onDelete"`
+	OnUpdate   string   `json:"onUpdate"`
+}
+
+var canonicalColumnTypes = map[string]string{
+	"tinyint": "integer", "smallint": "integer", "mediumint": "integer", "int": "integer", "integer": "integer",
+	"bigint": "bigint", "decimal": "decimal", "numeric": "decimal", "float": "float", "double": "double", "real": "double",
+	"bit": "bit", "char": "string", "varchar": "string", "inet4": "string", "inet6": "string",
+	"tinytext": "text", "text": "text", "mediumtext": "text", "longtext": "text",
+	"binary": "binary", "varbinary": "binary", "tinyblob": "blob", "blob": "blob", "mediumblob": "blob", "longblob": "blob",
+	"date": "date", "datetime": "datetime", "timestamp": "timestamp", "time": "time", "year": "year",
+	"json": "json", "enum": "enum", "set": "set", "uuid": "uuid",
+	"geometry": "geometry", "point": "geometry", "linestring": "geometry", "polygon": "geometry", "multipoint": "geometry",
+	"multilinestring": "geometry", "multipolygon": "geometry", "geometrycollection": "geometry",
+}
+
+// ExportSchemaJSON returns the JSON document describing the base tables of a schema
+func (server *ServerMonitor) ExportSchemaJSON(schema string) ([]byte, error) {
+	tables, logs, err := dbhelper.GetSchemaTableNames(server.Conn, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get tables of %s %s %s", schema, server.URL, err)
+	if err != nil {
+		return nil, err
+	}
+	pks, err := server.GetTablePKs(schema)
+	if err != nil {
+		return nil, err
+	}
+	fks, err := server.GetTableForeignKeys(schema)
+	if err != nil {
+		return nil, err
+	}
+	schemaCols, logs, err := dbhelper.GetSchemaColumns(server.Conn, server.DBVersion, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get columns of %s %s %s", schema, server.URL, err)
+	if err != nil {
+		return nil, err
+	}
+	schemaIdx, logs, err := dbhelper.GetSchemaIndexColumns(server.Conn, server.DBVersion, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get indexes of %s %s %s", schema, server.URL, err)
+	if err != nil {
+		return nil, err
+	}
+	cols := make(map[string][]dbhelper.TableColumn)
+	for _, col := range schemaCols {
+		cols[col.Table] = append(cols[col.Table], col)
+	}
+	idx := make(map[string][]dbhelper.TableIndexColumn)
+	for _, ic := range schemaIdx {
+		idx[ic.Table] = append(idx[ic.Table], ic)
+	}
+	return json.MarshalIndent(buildSchemaExport(schema, tables, cols, pks, idx, fks), "", "\t")
+}
+
+func buildSchemaExport(schema string, tables []string, cols map[string][]dbhelper.TableColumn, pks map[string][]string, idx map[string][]dbhelper.TableIndexColumn, fks []dbhelper.ForeignKey) SchemaExport {
+	export := SchemaExport{FormatVersion: SchemaExportFormatVersion, Schema: schema, Tables: []SchemaTable{}}
+	for _, t := range tables {
+		st := SchemaTable{Name: t, Columns: []SchemaColumn{}, PrimaryKey: []string{}, Indexes: []SchemaIndex{}, ForeignKeys: []SchemaForeignKey{}}
+		for _, col := range cols[t] {
+			st.Columns = append(st.Columns, getSchemaColumn(col))
+		}
+		st.PrimaryKey = append(st.PrimaryKey, pks[t]...)
+		for _, ic := range idx[t] {
+			if n := len(st.Indexes); n > 0 && st.Indexes[n-1].Name == ic.Index {
+				st.Indexes[n-1].Columns = append(st.Indexes[n-1].Columns, ic.Column)
+				continue
+			}
+			st.Indexes = append(st.Indexes, SchemaIndex{Name: ic.Index, Columns: []string{ic.Column}, Unique: ic.Unique, Primary: ic.Index == "PRIMARY", Type: ic.Type})
+		}
+		for _, fk := range fks {
+			if fk.Table != t {
+				continue
+			}
+			if n := len(st.ForeignKeys); n > 0 && st.ForeignKeys[n-1].Name == fk.Constraint {
+				st.ForeignKeys[n-1].Columns = append(st.ForeignKeys[n-1].Columns, fk.Column)
+				st.ForeignKeys[n-1].RefColumns = append(st.ForeignKeys[n-1].RefColumns, fk.RefColumn)
+				continue
+			}
+			st.ForeignKeys = append(st.ForeignKeys, SchemaForeignKey{Name: fk.Constraint, Columns: []string{fk.Column}, RefSchema: fk.RefSchema, RefTable: fk.RefTable, RefColumns: []string{fk.RefColumn}, OnDelete: fk.OnDelete, OnUpdate: fk.OnUpdate})
+		}
+		export.Tables = append(export.Tables, st)
+	}
+	return export
+}
+
+func getSchemaColumn(col dbhelper.TableColumn) SchemaColumn {
+	sc := SchemaColumn{
+		Name:          col.Name,
+		NativeType:    col.Type,
+		Nullable:      col.Nullable,
+		AutoIncrement: strings.Contains(strings.ToLower(col.Extra), "auto_increment"),
+		Generated:     col.Generated,
+		Expression:    col.GenerationExpression,
+		Collation:     col.Collation,
+	}
+	if col.HasDefault {
+		def := col.Default
+		sc.Default = &def
+	}
+	native := strings.ToLower(strings.TrimSpace(col.Type))
+	base, args := native, ""
+	if i := strings.Index(native, "("); i >= 0 {
+		base = native[:i]
+		if j := strings.LastIndex(native, ")"); j > i {
+			args = native[i+1 : j]
+		}
+	} else if i := strings.Index(native, " "); i >= 0 {
+		base = native[:i]
+	}
+	sc.Unsigned = strings.Contains(native, " unsigned")
+	sc.Type = canonicalColumnTypes[base]
+	if sc.Type == "" {
+		sc.Type = "unknown"
+	}
+	switch sc.Type {
+	case "enum", "set":
+		// values are read from the original type to keep their case
+		if i, j := strings.Index(col.Type, "("), strings.LastIndex(col.Type, ")"); i >= 0 && j > i {
+			sc.Values = splitColumnTypeValues(col.Type[i+1 : j])
+		}
+	case "decimal":
+		p := strings.SplitN(args, ",", 2)
+		sc.Precision, _ = strconv.ParseInt(p[0], 10, 64)
+		if len(p) == 2 {
+			sc.Scale, _ = strconv.ParseInt(p[1], 10, 64)
+		}
+	case "datetime", "timestamp", "time":
+		sc.Precision, _ = strconv.ParseInt(args, 10, 64)
+	case "string", "binary", "bit":
+		sc.Length, _ = strconv.ParseInt(args, 10, 64)
+	case "integer":
+		if base == "tinyint" && args == "1" && !sc.Unsigned {
+			sc.Type = "boolean"
+		}
+	}
+	return sc
+}
+
+// splitColumnTypeValues returns the unquoted values of an enum or set type definition, a quote doubled in a
+// value is a single quote
+func splitColumnTypeValues(def string) []string {
+	var values []string
+	var cur strings.Builder
+	quoted := false
+	for i := 0; i < len(def); i++ {
+		c := def[i]
+		switch {
+		case c == '\'' && quoted && i+1 < len(def) && def[i+1] == '\'':
+			cur.WriteByte(c)
+			i++
+		case c == '\'':
+			quoted = !quoted
+		case c == ',' && !quoted:
+			values = append(values, cur.String())
+			cur.Reset()
+		default:
+			if quoted {
+				cur.WriteByte(c)
+			}
+		}
+	}
+	return append(values, cur.String())
+}
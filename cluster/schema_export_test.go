@@ -0,0 +1,131 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
+
+func TestSchemaColumnTypes(t *testing.T) {
+	tests := []struct {
+		native    string
+		canonical string
+		length    int64
+		precision int64
+		scale     int64
+		unsigned  bool
+		values    []string
+	}{
+		{"int(10) unsigned", "integer", 0, 0, 0, true, nil},
+		{"tinyint(1)", "boolean", 0, 0, 0, false, nil},
+		{"bigint(20)", "bigint", 0, 0, 0, false, nil},
+		{"varchar(255)", "string", 255, 0, 0, false, nil},
+		{"decimal(10,2)", "decimal", 0, 10, 2, false, nil},
+		{"datetime(6)", "datetime", 0, 6, 0, false, nil},
+		{"longblob", "blob", 0, 0, 0, false, nil},
+		{"enum('Small','it''s','a,b')", "enum", 0, 0, 0, false, []string{"Small", "it's", "a,b"}},
+		{"vector(3)", "unknown", 0, 0, 0, false, nil},
+	}
+	for _, test := range tests {
+		sc := getSchemaColumn(dbhelper.TableColumn{Name: "c", Type: test.native})
+		if sc.Type != test.canonical || sc.Length != test.length || sc.Precision != test.precision || sc.Scale != test.scale || sc.Unsigned != test.unsigned || !reflect.DeepEqual(sc.Values, test.values) {
+			t.Fatalf("Got %+v for %s", sc, test.native)
+		}
+	}
+}
+
+func TestBuildSchemaExport(t *testing.T) {
+	cols := map[string][]dbhelper.TableColumn{
+		"orders": {
+			{Name: "id", Type: "bigint(20)", Extra: "auto_increment"},
+			{Name: "customer", Type: "int(11)", Nullable: true},
+			{Name: "status", Type: "varchar(10)", HasDefault: true, Default: "new"},
+		},
+	}
+	idx := map[string][]dbhelper.TableIndexColumn{
+		"orders": {
+			{Index: "PRIMARY", Column: "id", Position: 1, Unique: true, Type: "BTREE"},
+			{Index: "ix_customer", Column: "customer", Position: 1, Type: "BTREE"},
+			{Index: "ix_customer", Column: "status", Position: 2, Type: "BTREE"},
+		},
+	}
+	fks := []dbhelper.ForeignKey{
+		{Constraint: "fk_customer", Table: "orders", Column: "customer", RefSchema: "shop", RefTable: "customers", RefColumn: "id", OnDelete: "CASCADE", OnUpdate: "RESTRICT"},
+	}
+	export := buildSchemaExport("shop", []string{"customers", "orders"}, cols, map[string][]string{"orders": {"id"}}, idx, fks)
+	if export.FormatVersion != SchemaExportFormatVersion || len(export.Tables) != 2 {
+		t.Fatalf("Unexpected export %+v", export)
+	}
+	if c := export.Tables[0]; len(c.Columns) != 0 || c.PrimaryKey == nil || c.Indexes == nil || c.ForeignKeys == nil {
+		t.Fatalf("Expected empty lists for table without metadata, got %+v", c)
+	}
+	o := export.Tables[1]
+	if !o.Columns[0].AutoIncrement || o.Columns[0].Default != nil || *o.Columns[2].Default != "new" || !o.Columns[1].Nullable {
+		t.Fatalf("Unexpected columns %+v", o.Columns)
+	}
+	if !reflect.DeepEqual(o.PrimaryKey, []string{"id"}) || len(o.Indexes) != 2 || !o.Indexes[0].Primary || !reflect.DeepEqual(o.Indexes[1].Columns, []string{"customer", "status"}) || o.Indexes[1].Unique {
+		t.Fatalf("Unexpected keys %+v %+v", o.PrimaryKey, o.Indexes)
+	}
+	if len(o.ForeignKeys) != 1 || o.ForeignKeys[0].RefTable != "customers" || o.ForeignKeys[0].OnDelete != "CASCADE" {
+		t.Fatalf("Unexpected foreign keys %+v", o.ForeignKeys)
+	}
+}
+
+func TestExportSchemaJSONQueries(t *testing.T) {
+	queries := make(map[string]int)
+	name := fmt.Sprintf("schemaexport%d", time.Now().UnixNano())
+	sql.Register(name, execDriver{log: &execLog{}, rows: func(query string, args []driver.Value) *pkRows {
+		switch {
+		case strings.Contains(query, "information_schema.TABLES"):
+			return &pkRows{cols: []string{"TABLE_NAME"}, values: [][]driver.Value{{"customers"}, {"orders"}}}
+		case strings.Contains(query, "information_schema.COLUMNS"):
+			queries["COLUMNS"]++
+			return &pkRows{cols: []string{"Table_name", "Column_name", "Ordinal_position", "Column_type", "Is_nullable", "Has_default", "Column_default", "Collation_name", "Extra", "Generation_expression"},
+				values: [][]driver.Value{{"customers", "email", int64(1), "varchar(255)", int64(0), int64(0), "", "", "", ""}, {"orders", "id", int64(1), "bigint(20)", int64(0), int64(0), "", "", "", ""}}}
+		case strings.Contains(query, "information_schema.STATISTICS"):
+			queries["STATISTICS"]++
+			if !strings.Contains(query, "EXPRESSION") {
+				t.Errorf("Expected functional index expressions read on MySQL 8, got %s", query)
+			}
+			return &pkRows{cols: []string{"Table_name", "Index_name", "Column_name", "Seq_in_index", "Is_unique", "Index_type"},
+				values: [][]driver.Value{{"customers", "uk_email", "(lower(`email`))", int64(1), int64(1), "BTREE"}, {"orders", "PRIMARY", "id", int64(1), int64(1), "BTREE"}}}
+		}
+		return nil
+	}})
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &ServerMonitor{URL: "db1:3306", Conn: db, ClusterGroup: &Cluster{}, DBVersion: dbhelper.NewMySQLVersion("8.0.32", "")}
+	doc, err := server.ExportSchemaJSON("app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if queries["COLUMNS"] != 1 || queries["STATISTICS"] != 1 {
+		t.Fatalf("Expected columns and indexes read once per schema, got %v", queries)
+	}
+	var export SchemaExport
+	if err := json.Unmarshal(doc, &export); err != nil {
+		t.Fatal(err)
+	}
+	if len(export.Tables) != 2 || len(export.Tables[0].Columns) != 1 || export.Tables[0].Indexes[0].Columns[0] != "(lower(`email`))" || export.Tables[1].Columns[0].Name != "id" || !export.Tables[1].Indexes[0].Primary {
+		t.Fatalf("Unexpected export %s", doc)
+	}
+}
@@ -12,6 +12,7 @@ package cluster
 import (
 	"fmt"
 	"log"
+	"regexp"
 	"strings"
 
 	"github.com/signal18/replication-manager/utils/dbhelper"
@@ -47,6 +48,230 @@ func (cluster *Cluster) SpiderShardsDiscovery() {
 	}
 }
 
+// ShardKeyAlignment tells whether the primary key of a spider table routes lookups to a single shard, Shards
+// counts the distinct backends of the partitions and Aligned is true when the shard columns are the leading
+// columns of the primary key, lookups on the key or its leading columns then prune to one shard
+type ShardKeyAlignment struct {
+	Schema              string   `json:"schema"`
+	Table               string   `json:"table"`
+	Engine              string   `json:"engine"`
+	PrimaryKey          []string `json:"primaryKey"`
+	PartitionMethod     string   `json:"partitionMethod"`
+	PartitionExpression string   `json:"partitionExpression"`
+	ShardColumns        []string `json:"shardColumns"`
+	Shards              int      `json:"shards"`
+	Aligned             bool     `json:"aligned"`
+	FanOut              bool     `json:"fanOut"`
+	Diagnostics         []string `json:"diagnostics"`
+}
+
+var (
+	spiderBackendRegexp    = regexp.MustCompile(`(?i)\b(srv|host|port)\s+["']([^"']*)["']`)
+	spiderOptionRegexp     = regexp.MustCompile(`(?i)\b(COMMENT|CONNECTION)\s*=\s*'((?:[^'\\]|\\.|'')*)'`)
+	spiderPartitionRegexp  = regexp.MustCompile("\\bPARTITION `((?:[^`]|``)+)`")
+	partitionColumnRegexp  = regexp.MustCompile("`((?:[^`]|``)+)`")
+	partitionIdentRegexp   = regexp.MustCompile(`[A-Za-z_][A-Za-z0-9_$]*\s*\(?`)
+	partitionExprOperators = map[string]bool{"DIV": true, "MOD": true, "AND": true, "OR": true, "XOR": true, "NOT": true}
+)
+
+// AnalyzeShardKeyAlignment compares the primary key of a table with the spider partitioning expression and flags
+// the tables whose primary key lookups fan out to all the shards
+func (server *ServerMonitor) AnalyzeShardKeyAlignment(schema string, table string) (ShardKeyAlignment, error) {
+	sa := ShardKeyAlignment{Schema: schema, Table: table}
+	storage, logs, err := dbhelper.GetTableStorage(server.Conn, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get table storage of %s %s %s", schema, server.URL, err)
+	if err != nil {
+		return sa, err
+	}
+	found := false
+	for _, s := range storage {
+		if s.Table == table {
+			sa.Engine = s.Engine.String
+			found = true
+		}
+	}
+	if !found {
+		return sa, fmt.Errorf("Table %s.%s not found", schema, table)
+	}
+	pks, err := server.GetTablePKs(schema)
+	if err != nil {
+		return sa, err
+	}
+	sa.PrimaryKey = pks[table]
+	partitions, err := server.GetTablePartitions(schema, table)
+	if err != nil {
+		return sa, err
+	}
+	connections := make(map[string]string)
+	if strings.EqualFold(sa.Engine, "SPIDER") && len(partitions) > 0 {
+		ddl, err := server.GetTableDefinition(schema, table)
+		if err != nil {
+			return sa, err
+		}
+		connections = getSpiderConnections(ddl)
+	}
+	return getShardKeyAlignment(sa, server.IsCompute, partitions, connections), nil
+}
+
+// getShardKeyAlignment counts the backends of the partitions from their comment or from the CONNECTION and
+// COMMENT options of connections, keyed by partition name and by "" for the table
+func getShardKeyAlignment(sa ShardKeyAlignment, compute bool, partitions []dbhelper.TablePartition, connections map[string]string) ShardKeyAlignment {
+	sa.ShardColumns = []string{}
+	sa.Diagnostics = []string{}
+	if sa.PrimaryKey == nil {
+		sa.PrimaryKey = []string{}
+	}
+	if !compute {
+		sa.Diagnostics = append(sa.Diagnostics, "Server is not a spider compute node")
+	}
+	if !strings.EqualFold(sa.Engine, "SPIDER") {
+		sa.Shards = 1
+		sa.Aligned = true
+		sa.Diagnostics = append(sa.Diagnostics, fmt.Sprintf("Table engine %s is not SPIDER, rows are local to the server", sa.Engine))
+		return sa
+	}
+	if len(partitions) == 0 {
+		sa.Shards = 1
+		sa.Aligned = true
+		sa.Diagnostics = append(sa.Diagnostics, "Table is not partitioned, all rows are on the backend of the table comment")
+		return sa
+	}
+	backends := make(map[string]bool)
+	var unknown []string
+	for _, p := range partitions {
+		sa.PartitionMethod = p.Partition_method
+		sa.PartitionExpression = p.Partition_expression
+		backend := getSpiderBackend(p.Partition_comment)
+		if backend == "" {
+			backend = getSpiderBackend(connections[p.Partition_name])
+		}
+		if backend == "" {
+			backend = getSpiderBackend(connections[""])
+		}
+		if backend == "" {
+			unknown = append(unknown, p.Partition_name)
+			continue
+		}
+		backends[backend] = true
+	}
+	sa.ShardColumns = getPartitionColumns(sa.PartitionMethod, sa.PartitionExpression, sa.PrimaryKey)
+	if len(unknown) > 0 {
+		sa.Diagnostics = append(sa.Diagnostics, fmt.Sprintf("Backend unknown for partitions %s, shards and alignment can not be computed", strings.Join(unknown, ",")))
+		return sa
+	}
+	sa.Shards = len(backends)
+	if sa.Shards == 1 {
+		sa.Aligned = true
+		sa.Diagnostics = append(sa.Diagnostics, "All partitions use the same backend, the table is not sharded")
+		return sa
+	}
+	if len(sa.PrimaryKey) == 0 {
+		sa.FanOut = true
+		sa.Diagnostics = append(sa.Diagnostics, fmt.Sprintf("Table has no primary key, row lookups fan out to all %d shards", sa.Shards))
+		return sa
+	}
+	sa.Aligned = len(sa.ShardColumns) > 0 && len(sa.ShardColumns) <= len(sa.PrimaryKey)
+	if sa.Aligned {
+		for _, c := range sa.ShardColumns {
+			leading := false
+			for _, k := range sa.PrimaryKey[:len(sa.ShardColumns)] {
+				leading = leading || strings.EqualFold(c, k)
+			}
+			sa.Aligned = sa.Aligned && leading
+		}
+	}
+	if !sa.Aligned {
+		sa.FanOut = true
+		sa.Diagnostics = append(sa.Diagnostics, fmt.Sprintf("Shard columns %s are not the leading columns of primary key %s, lookups on the leading primary key columns fan out to all %d shards", strings.Join(sa.ShardColumns, ","), strings.Join(sa.PrimaryKey, ","), sa.Shards))
+	}
+	method := strings.ToUpper(sa.PartitionMethod)
+	if strings.Contains(method, "HASH") || strings.Contains(method, "KEY") {
+		sa.Diagnostics = append(sa.Diagnostics, fmt.Sprintf("%s partitioning spreads ranges of %s over the shards, range scans fan out to all %d shards", method, strings.Join(sa.ShardColumns, ","), sa.Shards))
+	}
+	return sa
+}
+
+// getSpiderBackend returns the server of a spider connection string, srv or host and port, empty when the string
+// names no backend
+func getSpiderBackend(comment string) string {
+	params := make(map[string]string)
+	for _, m := range spiderBackendRegexp.FindAllStringSubmatch(comment, -1) {
+		params[strings.ToLower(m[1])] = m[2]
+	}
+	if params["srv"] != "" {
+		return params["srv"]
+	}
+	if params["host"] == "" {
+		return ""
+	}
+	if params["port"] == "" {
+		return params["host"]
+	}
+	return params["host"] + ":" + params["port"]
+}
+
+// getSpiderConnections returns the CONNECTION and COMMENT options of a SHOW CREATE TABLE output by partition name,
+// the table ones under "", column comments have no = and are not options
+func getSpiderConnections(ddl string) map[string]string {
+	connections := make(map[string]string)
+	options := func(s string) string {
+		var res []string
+		for _, m := range spiderOptionRegexp.FindAllStringSubmatch(s, -1) {
+			res = append(res, strings.Replace(strings.Replace(m[2], "''", "'", -1), "\\'", "'", -1))
+		}
+		return strings.Join(res, " ")
+	}
+	pos := strings.Index(ddl, "PARTITION BY ")
+	if pos < 0 {
+		connections[""] = options(ddl)
+		return connections
+	}
+	connections[""] = options(ddl[:pos])
+	parts := spiderPartitionRegexp.FindAllStringSubmatchIndex(ddl[pos:], -1)
+	for i, m := range parts {
+		end := len(ddl) - pos
+		if i+1 < len(parts) {
+			end = parts[i+1][0]
+		}
+		name := strings.Replace(ddl[pos+m[2]:pos+m[3]], "``", "`", -1)
+		connections[name] = options(ddl[pos+m[1] : pos+end])
+	}
+	return connections
+}
+
+// getPartitionColumns returns the columns of a partitioning expression, KEY() without columns uses the primary
+// key and function names of an expression like YEAR(`d`) are not columns
+func getPartitionColumns(method string, expression string, pk []string) []string {
+	columns := []string{}
+	if strings.TrimSpace(expression) == "" {
+		if strings.Contains(strings.ToUpper(method), "KEY") {
+			columns = append(columns, pk...)
+		}
+		return columns
+	}
+	seen := make(map[string]bool)
+	add := func(c string) {
+		if !seen[strings.ToLower(c)] {
+			seen[strings.ToLower(c)] = true
+			columns = append(columns, c)
+		}
+	}
+	if quoted := partitionColumnRegexp.FindAllStringSubmatch(expression, -1); len(quoted) > 0 {
+		for _, m := range quoted {
+			add(strings.Replace(m[1], "``", "`", -1))
+		}
+		return columns
+	}
+	for _, m := range partitionIdentRegexp.FindAllString(expression, -1) {
+		m = strings.TrimSpace(m)
+		if strings.HasSuffix(m, "(") || partitionExprOperators[strings.ToUpper(m)] {
+			continue
+		}
+		add(m)
+	}
+	return columns
+}
+
 func (cluster *Cluster) SpiderSetShardsRepl() {
 	for k, s := range cluster.Servers {
 		url := s.URL
@@ -0,0 +1,72 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
+
+func TestShardKeyAlignment(t *testing.T) {
+	shards := func(method string, expression string, backends ...string) []dbhelper.TablePartition {
+		var partitions []dbhelper.TablePartition
+		for i, b := range backends {
+			partitions = append(partitions, dbhelper.TablePartition{Partition_name: "pt" + string(rune('0'+i)), Partition_method: method, Partition_expression: expression, Partition_comment: b})
+		}
+		return partitions
+	}
+	orders := ShardKeyAlignment{Schema: "shop", Table: "orders", Engine: "SPIDER", PrimaryKey: []string{"tenant_id", "id"}}
+
+	sa := getShardKeyAlignment(orders, true, shards("KEY", "`tenant_id`", `srv "RW1", tbl "orders"`, `srv "RW2", tbl "orders"`), nil)
+	if !sa.Aligned || sa.FanOut || sa.Shards != 2 || !reflect.DeepEqual(sa.ShardColumns, []string{"tenant_id"}) || len(sa.Diagnostics) != 1 {
+		t.Fatalf("Expected tenant sharding aligned with a range scan warning, got %+v", sa)
+	}
+	sa = getShardKeyAlignment(orders, true, shards("HASH", "crc32(id) MOD 4", `srv "RW1"`, `srv "RW2"`, `srv "RW3"`), nil)
+	if sa.Aligned || !sa.FanOut || sa.Shards != 3 || !reflect.DeepEqual(sa.ShardColumns, []string{"id"}) {
+		t.Fatalf("Expected sharding on the second key column to fan out, got %+v", sa)
+	}
+	sa = getShardKeyAlignment(orders, true, shards("KEY", "", `host "10.0.0.1", port "3306"`, `host "10.0.0.2", port "3306"`), nil)
+	if !sa.Aligned || !reflect.DeepEqual(sa.ShardColumns, []string{"tenant_id", "id"}) || sa.Shards != 2 {
+		t.Fatalf("Expected KEY() to shard on the primary key, got %+v", sa)
+	}
+	sa = getShardKeyAlignment(orders, true, shards("RANGE COLUMNS", "`tenant_id`", `srv "RW1"`, `srv 'RW1'`), nil)
+	if !sa.Aligned || sa.FanOut || sa.Shards != 1 {
+		t.Fatalf("Expected partitions on one backend not sharded, got %+v", sa)
+	}
+	ddl := "CREATE TABLE `orders` (\n  `tenant_id` int(11) NOT NULL COMMENT 'srv \"RW9\"',\n  `id` int(11) NOT NULL,\n  PRIMARY KEY (`tenant_id`,`id`)\n) ENGINE=SPIDER DEFAULT CHARSET=latin1 CONNECTION='wrapper \"mysql\", table \"orders\"'\n" +
+		" PARTITION BY KEY (`tenant_id`)\n(PARTITION `pt1` CONNECTION = 'srv \"RW1\"' ENGINE = SPIDER,\n PARTITION `pt2` COMMENT = 'host \"10.0.0.2\", port \"3307\"' ENGINE = SPIDER)"
+	connections := getSpiderConnections(ddl)
+	if connections["pt1"] != `srv "RW1"` || connections["pt2"] != `host "10.0.0.2", port "3307"` || connections[""] != `wrapper "mysql", table "orders"` {
+		t.Fatalf("Unexpected connections %v", connections)
+	}
+	sa = getShardKeyAlignment(orders, true, []dbhelper.TablePartition{{Partition_name: "pt1", Partition_method: "KEY", Partition_expression: "`tenant_id`"}, {Partition_name: "pt2", Partition_method: "KEY", Partition_expression: "`tenant_id`"}}, connections)
+	if !sa.Aligned || sa.Shards != 2 {
+		t.Fatalf("Expected partitions backends read from the CONNECTION and COMMENT options, got %+v", sa)
+	}
+	sa = getShardKeyAlignment(orders, true, shards("KEY", "`tenant_id`", `srv "RW1"`, `wrapper "mysql"`), nil)
+	if sa.Aligned || sa.FanOut || sa.Shards != 0 || !strings.Contains(strings.Join(sa.Diagnostics, ","), "Backend unknown for partitions pt1") {
+		t.Fatalf("Expected backend unknown reported, got %+v", sa)
+	}
+	connections = map[string]string{"": `srv "RW1"`}
+	if sa = getShardKeyAlignment(orders, true, shards("KEY", "`tenant_id`", "", ""), connections); !sa.Aligned || sa.Shards != 1 {
+		t.Fatalf("Expected partitions without connection on the table backend, got %+v", sa)
+	}
+	orders.PrimaryKey = nil
+	if sa = getShardKeyAlignment(orders, true, shards("HASH", "`id`", `srv "RW1"`, `srv "RW2"`), nil); !sa.FanOut || sa.PrimaryKey == nil {
+		t.Fatalf("Expected table without primary key to fan out, got %+v", sa)
+	}
+	orders.Engine = "InnoDB"
+	if sa = getShardKeyAlignment(orders, false, nil, nil); sa.FanOut || sa.Shards != 1 || len(sa.Diagnostics) != 2 {
+		t.Fatalf("Expected local table on a non compute node, got %+v", sa)
+	}
+}
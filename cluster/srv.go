@@ -129,6 +129,7 @@ type ServerMonitor struct {
 	ProxysqlHostgroup           string                       `json:"proxysqlHostgroup"`
 	RelayLogSize                uint64                       `json:"relayLogSize"`
 	Replications                []dbhelper.SlaveStatus       `json:"replications"`
+	ReplicationFilters          []dbhelper.ReplicationFilter `json:"replicationFilters"`
 	LastSeenReplications        []dbhelper.SlaveStatus       `json:"lastSeenReplications"`
 	MasterStatus                dbhelper.MasterStatus        `json:"masterStatus"`
 	SlaveStatus                 *dbhelper.SlaveStatus        `json:"-"`
@@ -141,6 +142,8 @@ type ServerMonitor struct {
 	FullProcessList             []dbhelper.Processlist       `json:"-"`
 	Variables                   map[string]string            `json:"-"`
 	EngineInnoDB                map[string]string            `json:"engineInnodb"`
+	BufferPoolStats             []dbhelper.BufferPoolStats   `json:"bufferPoolStats"`
+	ThreadPool                  ThreadPoolStats              `json:"threadPool"`
 	ErrorLog                    s18log.HttpLog               `json:"errorLog"`
 	SlowLog                     s18log.SlowLog               `json:"-"`
 	Status                      map[string]string            `json:"-"`
@@ -166,6 +169,313 @@ type ServerMonitor struct {
 	SSTPort                     string                       `json:"sstPort"`       //used to send data to dbjobs
 	Agent                       string                       `json:"agent"`         //used to provision service in orchestrator
 	BinaryLogFiles              map[string]uint              `json:"binaryLogFiles"`
+	ReplicationDelayAlertState  DelayAlertState              `json:"replicationDelayAlertState"`
+	ReplicationDelayWarnState   DelayAlertState              `json:"replicationDelayWarnState"`
+	ReadLagState                DelayAlertState              `json:"readLagState"`             // alerting when the slave is too late to serve reads
+	SmoothedReplicationDelay    float64                      `json:"smoothedReplicationDelay"` // exponentially weighted moving average of the replication delay
+	ReplicationsTimestamp       int64                        `json:"replicationsTimestamp"`    // unix time of the last successful slave status fetch
+	replicationSLOBuckets       []sloBucket                  // per minute polls within failover-max-slave-delay over the last day
+	lastSLOSample               sloSample                    // last poll counted in the replication SLO
+	restartTime                 time.Time                    // restart detected from uptime, zero once the slave caught up
+	refreshing                  int32                        // set while a ping runs, a server is never pinged twice at a time
+	BinlogWriteRate             float64                      `json:"binlogWriteRate"`      // bytes per second written to the binary log between the last two polls
+	ReplicationApplyRate        float64                      `json:"replicationApplyRate"` // bytes per second of master binary log applied between the last two polls
+	ReplicationStatus           ReplicationStatusProvider    `json:"-"`                    // used to inject replication status in place of the monitored one
+	DeadlockHistory             []dbhelper.Deadlock          `json:"-"`                    // ring buffer of deadlocks seen in innodb status
+	historyListLengths          []int64                      // innodb history list length of the last polls, oldest first
+	connectionSamples           []ConnectionSample           // connected and running threads of the last polls, oldest first
+	HeavyMonitoringSkipped      bool                         `json:"heavyMonitoringSkipped"` // expensive gathering skipped while the slave is lagging
+	delayWebhookState           string                       // last replication delay alert state posted to the webhook
+	delayWarningWebhookState    string                       // last replication delay warning state posted to the warning webhook
+	delayWebhookQueues          webhookQueues                // alerts waiting to be posted in order per webhook url
+	processListDigests          map[string]string            // query text to digest cache of the previous process list
+	DatabaseConfigHash          string                       `json:"-"` // hash of the last generated config tarball
+	binlogWriteSample           binlogCoordinate             // master binary log coordinates of the previous poll
+	replicationApplySample      binlogCoordinate             // executed master binary log coordinates of the previous poll
+	processListStates           map[uint64]threadState       // current state and state start time of each thread of the process list
+	monitorConnectionIds        map[uint64]bool              // thread ids of the monitoring connections still in the process list
+	processListSnapshots        []*ProcessListSnapshot       // named process list snapshots, oldest first
+	channelDelayStats           channelDelayStats            // per replication channel delay max and breach time between scrapes
+	smoothingMasterHost         string                       // master host:port the smoothed delay was computed for
+	replicationErrorSkips       []time.Time                  // time of the replication errors skipped automatically in the last hour
+}
+
+// ReplicationStatusProvider feed the replication channels status, when not set the monitored SHOW SLAVE STATUS is used
+type ReplicationStatusProvider interface {
+	GetReplications() []dbhelper.SlaveStatus
+}
+
+// ProcessListSnapshot is a copy of the process list at a point in time to be diffed with DiffProcessListSnapshots
+type ProcessListSnapshot struct {
+	Name    string    `json:"name"`
+	Time    time.Time `json:"time"`
+	threads map[uint64]dbhelper.Processlist
+}
+
+type ProcessListChange struct {
+	Before dbhelper.Processlist `json:"before"`
+	After  dbhelper.Processlist `json:"after"`
+}
+
+type ProcessListDiff struct {
+	Appeared    []dbhelper.Processlist `json:"appeared"`
+	Disappeared []dbhelper.Processlist `json:"disappeared"`
+	Changed     []ProcessListChange    `json:"changed"`
+}
+
+type channelDelayStat struct {
+	Max           int64
+	BreachSeconds float64
+	LastSample    time.Time
+}
+
+type channelDelayStats struct {
+	sync.Mutex
+	channels map[string]*channelDelayStat
+}
+
+type threadState struct {
+	State string
+	Since time.Time
+}
+
+type binlogCoordinate struct {
+	File string
+	Pos  uint64
+	Time time.Time
+}
+
+// sloBucket count the polls and the polls within failover-max-slave-delay of a minute
+type sloBucket struct {
+	Minute int64
+	Total  int
+	Good   int
+}
+
+type sloSample struct {
+	Minute int64
+	Good   bool
+	Set    bool
+}
+
+// ReplicationSLOWindow is the replication delay SLO compliance over a rolling window, a burn rate of 1 consumes
+// the error budget exactly at the end of the window
+type ReplicationSLOWindow struct {
+	Window     string  `json:"window"`
+	Polls      int     `json:"polls"`
+	Compliance float64 `json:"compliance"`
+	BurnRate   float64 `json:"burnRate"`
+}
+
+// PurgeLagStatus is the innodb undo backlog, a growing history list length means a long running transaction
+// is blocking purge
+type PurgeLagStatus struct {
+	HistoryListLength      int64   `json:"historyListLength"`
+	Trend                  int64   `json:"trend"` // history list length growth over the kept polls
+	History                []int64 `json:"history"`
+	OldestActiveTrxID      string  `json:"oldestActiveTrxId"`
+	OldestActiveTrxSeconds int64   `json:"oldestActiveTrxSeconds"`
+	Threshold              int64   `json:"threshold"`
+	Alert                  bool    `json:"alert"`
+}
+
+// ConnectionSample is the connected and running threads of a poll, Time is in unix milliseconds
+type ConnectionSample struct {
+	Time      int64 `json:"time"`
+	Connected int64 `json:"connected"`
+	Running   int64 `json:"running"`
+}
+
+// ConnectionStorm is the change of connected threads between the last two polls, Storm is set when the new
+// connections or their rate per second are over monitoring-connection-storm-jump or monitoring-connection-storm-rate
+type ConnectionStorm struct {
+	Connected     int64              `json:"connected"`
+	Running       int64              `json:"running"`
+	Jump          int64              `json:"jump"`
+	Rate          float64            `json:"rate"`
+	JumpThreshold int64              `json:"jumpThreshold"`
+	RateThreshold int64              `json:"rateThreshold"`
+	Storm         bool               `json:"storm"`
+	History       []ConnectionSample `json:"history"`
+}
+
+// ThreadPoolStats is the MariaDB thread pool usage, a queue growing while groups are stalled shows a saturated
+// pool, Groups is empty before MariaDB 10.5
+type ThreadPoolStats struct {
+	Enabled       bool                       `json:"enabled"`
+	Size          int64                      `json:"size"`
+	Threads       int64                      `json:"threads"`
+	IdleThreads   int64                      `json:"idleThreads"`
+	ActiveThreads int64                      `json:"activeThreads"`
+	QueueLength   int64                      `json:"queueLength"`
+	StalledGroups int                        `json:"stalledGroups"`
+	Groups        []dbhelper.ThreadPoolGroup `json:"groups"`
+}
+
+// ReplicationApplyBottleneck is the statement an applier thread of a late slave is running, Elapsed is the time
+// spent on it in seconds and Delay the replication delay
+type ReplicationApplyBottleneck struct {
+	Id        uint64  `json:"id"`
+	Statement string  `json:"statement"`
+	State     string  `json:"state"`
+	Elapsed   float64 `json:"elapsed"`
+	Delay     int64   `json:"delay"`
+}
+
+// LockWaitChain is a path of InnoDB lock waits starting at the head-of-line Blocker, the thread to kill to
+// release the chain, Waiters counts the threads waiting directly or indirectly on the blocker
+type LockWaitChain struct {
+	Blocker uint64           `json:"blocker"`
+	Waiters int              `json:"waiters"`
+	Threads []LockWaitThread `json:"threads"`
+}
+
+// LockWaitThread is a thread of a lock wait chain with its current query, LockedTable is the table it waits
+// on, empty for the blocker
+type LockWaitThread struct {
+	Id          uint64  `json:"id"`
+	User        string  `json:"user"`
+	Host        string  `json:"host"`
+	Db          string  `json:"db"`
+	Command     string  `json:"command"`
+	Time        float64 `json:"time"`
+	State       string  `json:"state"`
+	Info        string  `json:"info"`
+	LockedTable string  `json:"lockedTable"`
+}
+
+// ReplicationErrorSkip is a replication event skipped or to skip in dry run, Statement is the failed statement
+// when the error gives it, the error message otherwise
+type ReplicationErrorSkip struct {
+	Channel   string `json:"channel"`
+	Errno     int    `json:"errno"`
+	Error     string `json:"error"`
+	Statement string `json:"statement"`
+	Method    string `json:"method"`
+	GTID      string `json:"gtid"`
+	DryRun    bool   `json:"dryRun"`
+}
+
+// MDLReport is the table metadata lock waits of a server, Available is false with the Reason when the locks can
+// not be read, metadata_lock_info on MariaDB or performance_schema.metadata_locks otherwise
+type MDLReport struct {
+	Available bool      `json:"available"`
+	Reason    string    `json:"reason"`
+	Waiters   []MDLWait `json:"waiters"`
+}
+
+// MDLWait is a thread waiting for a table metadata lock, Waiter.LockedTable is the comma separated tables it waits
+// for, blockers are the other threads holding or waiting for an exclusive lock on those tables, idle in transaction
+// ones included
+type MDLWait struct {
+	Waiter   LockWaitThread `json:"waiter"`
+	Blockers []MDLBlocker   `json:"blockers"`
+}
+
+type MDLBlocker struct {
+	LockWaitThread
+	LockMode          string `json:"lockMode"`
+	LockDuration      string `json:"lockDuration"`
+	IdleInTransaction bool   `json:"idleInTransaction"`
+}
+
+// ReplicationThreads groups the replication threads of the processlist, one IO thread and one SQL thread per
+// channel, SQL is the coordinator of the workers when replication is parallel
+type ReplicationThreads struct {
+	IO      []ReplicationThread `json:"io"`
+	SQL     []ReplicationThread `json:"sql"`
+	Workers []ReplicationThread `json:"workers"`
+}
+
+// ReplicationThread is a replication thread with the statement it applies in Info, Idle is true when its state
+// is one of monitoring-processlist-replication-idle-states
+type ReplicationThread struct {
+	Id      uint64  `json:"id"`
+	Command string  `json:"command"`
+	Time    float64 `json:"time"`
+	State   string  `json:"state"`
+	Info    string  `json:"info"`
+	Idle    bool    `json:"idle"`
+}
+
+// ThreadTempReport lists the client threads creating temporary tables, Available is false with the Reason when
+// performance_schema or its statement consumers are disabled
+type ThreadTempReport struct {
+	Available bool              `json:"available"`
+	Reason    string            `json:"reason"`
+	Threads   []ThreadTempUsage `json:"threads"`
+}
+
+// ThreadTempUsage is a running thread with the temporary tables of its current statement, OnDisk is true when
+// its state shows a temporary table written or converted to disk, Worst flags the threads spilling the most
+type ThreadTempUsage struct {
+	Id            uint64  `json:"id"`
+	User          string  `json:"user"`
+	Host          string  `json:"host"`
+	Db            string  `json:"db"`
+	Time          float64 `json:"time"`
+	State         string  `json:"state"`
+	Info          string  `json:"info"`
+	TmpTables     uint64  `json:"tmpTables"`
+	TmpDiskTables uint64  `json:"tmpDiskTables"`
+	OnDisk        bool    `json:"onDisk"`
+	Worst         bool    `json:"worst"`
+}
+
+// PrimaryKeySuggestion is a read only proposal to give a primary key to a table, Index is the unique index
+// promoted to primary key, empty when a synthetic auto increment column is added
+type PrimaryKeySuggestion struct {
+	Schema    string   `json:"schema"`
+	Table     string   `json:"table"`
+	Index     string   `json:"index"`
+	Columns   []string `json:"columns"`
+	Statement string   `json:"statement"`
+}
+
+// TableStorageInfo is the row format and compression of a table, PageCompressed is set for MariaDB
+// PAGE_COMPRESSED and MySQL transparent page COMPRESSION, SuggestPageCompression flags a ROW_FORMAT=COMPRESSED
+// table that could use page compression instead
+type TableStorageInfo struct {
+	Schema                 string `json:"schema"`
+	Table                  string `json:"table"`
+	Engine                 string `json:"engine"`
+	RowFormat              string `json:"rowFormat"`
+	KeyBlockSize           int64  `json:"keyBlockSize"`
+	PageCompressed         bool   `json:"pageCompressed"`
+	Compression            string `json:"compression"`
+	DataLength             int64  `json:"dataLength"`
+	SuggestPageCompression bool   `json:"suggestPageCompression"`
+}
+
+type StatusAnomaly struct {
+	Name      string  `json:"name"`
+	Rate      float64 `json:"rate"`
+	Threshold float64 `json:"threshold"`
+	Anomalous bool    `json:"anomalous"`
+}
+
+type ColumnCollation struct {
+	Name      string `json:"name"`
+	Collation string `json:"collation"`
+}
+
+type TableCollationMismatch struct {
+	Table     string            `json:"table"`
+	Collation string            `json:"collation"`
+	Columns   []ColumnCollation `json:"columns"`
+}
+
+const (
+	DelayAlertLevelOK       string = "OK"
+	DelayAlertLevelWarning  string = "Warning"
+	DelayAlertLevelCritical string = "Critical"
+)
+
+// DelayAlertState track consecutive polls above or below failover-max-slave-delay
+type DelayAlertState struct {
+	Alerting   bool `json:"alerting"`
+	AboveCount int  `json:"aboveCount"`
+	BelowCount int  `json:"belowCount"`
 }
 
 type serverList []*ServerMonitor
@@ -287,13 +597,12 @@ func (server *ServerMonitor) Ping(wg *sync.WaitGroup) {
 
 	defer wg.Done()
 
-	if server.ClusterGroup.vmaster != nil {
-		if server.ClusterGroup.vmaster.ServerID == server.ServerID {
-			server.IsVirtualMaster = true
-		} else {
-			server.IsVirtualMaster = false
-		}
-	}
+	conn, err := server.checkConnection()
+	server.processPing(conn, err)
+}
+
+// checkConnection opens a new connection to the server, or checks its agent, it does not update the server state
+func (server *ServerMonitor) checkConnection() (*sqlx.DB, error) {
 	var conn *sqlx.DB
 	var err error
 	switch server.ClusterGroup.Conf.CheckType {
@@ -307,83 +616,23 @@ func (server *ServerMonitor) Ping(wg *sync.WaitGroup) {
 			err = fmt.Errorf("HTTP Response Code Error: %d", resp.StatusCode)
 		}
 	}
+	return conn, err
+}
+
+// processPing updates the server state from the result of checkConnection and refreshes the server
+func (server *ServerMonitor) processPing(conn *sqlx.DB, err error) {
+	if server.ClusterGroup.vmaster != nil {
+		if server.ClusterGroup.vmaster.ServerID == server.ServerID {
+			server.IsVirtualMaster = true
+		} else {
+			server.IsVirtualMaster = false
+		}
+	}
 	// manage IP based DNS may failed if backend server as changed IP  try to resolv it and recreate new DSN
 	//server.SetCredential(server.URL, server.User, server.Pass)
 	// Handle failure cases here
 	if err != nil {
-		// Copy the last known server states or they will be cleared at next monitoring loop
-		if server.State != stateFailed {
-			server.ClusterGroup.sme.CopyOldStateFromUnknowServer(server.URL)
-		}
-		// server.ClusterGroup.LogPrintf(LvlDbg, "Failure detection handling for server %s %s", server.URL, err)
-		// server.ClusterGroup.LogPrintf(LvlErr, "Failure detection handling for server %s %s", server.URL, err)
-
-		if driverErr, ok := err.(*mysql.MySQLError); ok {
-			//	server.ClusterGroup.LogPrintf(LvlDbg, "Driver Error %s %d ", server.URL, driverErr.Number)
-
-			// access denied
-			if driverErr.Number == 1045 {
-				server.State = stateErrorAuth
-				server.ClusterGroup.SetState("ERR00004", state.State{ErrType: LvlErr, ErrDesc: fmt.Sprintf(clusterError["ERR00004"], server.URL, err.Error()), ErrFrom: "SRV"})
-				return
-			} else {
-				server.ClusterGroup.LogPrintf(LvlErr, "Driver Error %s %d ", server.URL, driverErr.Number)
-			}
-		}
-		if err != sql.ErrNoRows {
-			server.FailCount++
-			if server.ClusterGroup.master == nil {
-				server.ClusterGroup.LogPrintf(LvlDbg, "Master not defined")
-			}
-			if server.ClusterGroup.master != nil && server.URL == server.ClusterGroup.master.URL {
-				server.FailSuspectHeartbeat = server.ClusterGroup.sme.GetHeartbeats()
-				if server.ClusterGroup.master.FailCount <= server.ClusterGroup.Conf.MaxFail {
-					server.ClusterGroup.LogPrintf("INFO", "Master Failure detected! Retry %d/%d", server.ClusterGroup.master.FailCount, server.ClusterGroup.Conf.MaxFail)
-				}
-				if server.FailCount >= server.ClusterGroup.Conf.MaxFail {
-					if server.FailCount == server.ClusterGroup.Conf.MaxFail {
-						server.ClusterGroup.LogPrintf("INFO", "Declaring db master as failed %s", server.URL)
-					}
-					server.ClusterGroup.master.State = stateFailed
-					server.DelWaitStopCookie()
-				} else {
-					server.ClusterGroup.master.State = stateSuspect
-
-				}
-			} else {
-				// not the master
-				server.ClusterGroup.LogPrintf(LvlDbg, "Failure detection of no master FailCount %d MaxFail %d", server.FailCount, server.ClusterGroup.Conf.MaxFail)
-				if server.FailCount >= server.ClusterGroup.Conf.MaxFail {
-					if server.FailCount == server.ClusterGroup.Conf.MaxFail {
-						server.ClusterGroup.LogPrintf("INFO", "Declaring slave db %s as failed", server.URL)
-						server.State = stateFailed
-						server.DelWaitStopCookie()
-						// remove from slave list
-						server.delete(&server.ClusterGroup.slaves)
-						if server.Replications != nil {
-							server.LastSeenReplications = server.Replications
-						}
-						server.Replications = nil
-					}
-				} else {
-					server.State = stateSuspect
-				}
-			}
-		}
-		// Send alert if state has changed
-		if server.PrevState != server.State {
-			//if cluster.Conf.Verbose {
-			server.ClusterGroup.LogPrintf(LvlDbg, "Server %s state changed from %s to %s", server.URL, server.PrevState, server.State)
-			if server.State != stateSuspect {
-				server.ClusterGroup.LogPrintf("ALERT", "Server %s state changed from %s to %s", server.URL, server.PrevState, server.State)
-				server.ClusterGroup.backendStateChangeProxies()
-				server.SendAlert()
-				server.ProcessFailedSlave()
-			}
-		}
-		if server.PrevState != server.State {
-			server.PrevState = server.State
-		}
+		server.failPing(err)
 		return
 	}
 
@@ -492,6 +741,84 @@ func (server *ServerMonitor) Ping(wg *sync.WaitGroup) {
 	}
 }
 
+// failPing records a failed ping, the server turns suspect then failed after failcount pings and the master
+// failure is detected
+func (server *ServerMonitor) failPing(err error) {
+	// Copy the last known server states or they will be cleared at next monitoring loop
+	if server.State != stateFailed {
+		server.ClusterGroup.sme.CopyOldStateFromUnknowServer(server.URL)
+	}
+	// server.ClusterGroup.LogPrintf(LvlDbg, "Failure detection handling for server %s %s", server.URL, err)
+	// server.ClusterGroup.LogPrintf(LvlErr, "Failure detection handling for server %s %s", server.URL, err)
+
+	if driverErr, ok := err.(*mysql.MySQLError); ok {
+		//	server.ClusterGroup.LogPrintf(LvlDbg, "Driver Error %s %d ", server.URL, driverErr.Number)
+
+		// access denied
+		if driverErr.Number == 1045 {
+			server.State = stateErrorAuth
+			server.ClusterGroup.SetState("ERR00004", state.State{ErrType: LvlErr, ErrDesc: fmt.Sprintf(clusterError["ERR00004"], server.URL, err.Error()), ErrFrom: "SRV"})
+			return
+		} else {
+			server.ClusterGroup.LogPrintf(LvlErr, "Driver Error %s %d ", server.URL, driverErr.Number)
+		}
+	}
+	if err != sql.ErrNoRows {
+		server.FailCount++
+		if server.ClusterGroup.master == nil {
+			server.ClusterGroup.LogPrintf(LvlDbg, "Master not defined")
+		}
+		if server.ClusterGroup.master != nil && server.URL == server.ClusterGroup.master.URL {
+			server.FailSuspectHeartbeat = server.ClusterGroup.sme.GetHeartbeats()
+			if server.ClusterGroup.master.FailCount <= server.ClusterGroup.Conf.MaxFail {
+				server.ClusterGroup.LogPrintf("INFO", "Master Failure detected! Retry %d/%d", server.ClusterGroup.master.FailCount, server.ClusterGroup.Conf.MaxFail)
+			}
+			if server.FailCount >= server.ClusterGroup.Conf.MaxFail {
+				if server.FailCount == server.ClusterGroup.Conf.MaxFail {
+					server.ClusterGroup.LogPrintf("INFO", "Declaring db master as failed %s", server.URL)
+				}
+				server.ClusterGroup.master.State = stateFailed
+				server.DelWaitStopCookie()
+			} else {
+				server.ClusterGroup.master.State = stateSuspect
+
+			}
+		} else {
+			// not the master
+			server.ClusterGroup.LogPrintf(LvlDbg, "Failure detection of no master FailCount %d MaxFail %d", server.FailCount, server.ClusterGroup.Conf.MaxFail)
+			if server.FailCount >= server.ClusterGroup.Conf.MaxFail {
+				if server.FailCount == server.ClusterGroup.Conf.MaxFail {
+					server.ClusterGroup.LogPrintf("INFO", "Declaring slave db %s as failed", server.URL)
+					server.State = stateFailed
+					server.DelWaitStopCookie()
+					// remove from slave list
+					server.delete(&server.ClusterGroup.slaves)
+					if server.Replications != nil {
+						server.LastSeenReplications = server.Replications
+					}
+					server.Replications = nil
+				}
+			} else {
+				server.State = stateSuspect
+			}
+		}
+	}
+	// Send alert if state has changed
+	if server.PrevState != server.State {
+		//if cluster.Conf.Verbose {
+		server.ClusterGroup.LogPrintf(LvlDbg, "Server %s state changed from %s to %s", server.URL, server.PrevState, server.State)
+		if server.State != stateSuspect {
+			server.ClusterGroup.LogPrintf("ALERT", "Server %s state changed from %s to %s", server.URL, server.PrevState, server.State)
+			server.ClusterGroup.backendStateChangeProxies()
+			server.SendAlert()
+			server.ProcessFailedSlave()
+		}
+	}
+	if server.PrevState != server.State {
+		server.PrevState = server.State
+	}
+}
+
 func (server *ServerMonitor) ProcessFailedSlave() {
 
 	if server.State == stateSlaveErr {
@@ -519,6 +846,25 @@ func (server *ServerMonitor) ProcessFailedSlave() {
 				server.ClusterGroup.LogPrintf("INFO", "Skip event and restart slave on %s", server.URL)
 			}
 		}
+		if server.ClusterGroup.Conf.ReplicationSkipErrorsAuto {
+			ss, err := server.GetSlaveStatus(server.ReplicationSourceName)
+			if err != nil {
+				return
+			}
+			errno, err := strconv.Atoi(ss.LastSQLErrno.String)
+			if err == nil && isReplicationErrorSkippable(server.ClusterGroup.Conf.ReplicationSkipErrors, errno) {
+				if server.IsReplicationErrorSkipBudgetExceeded(time.Now()) {
+					server.ClusterGroup.LogPrintf(LvlWarn, "Not skipping replication error %d on %s, %d events skipped in the last hour", errno, server.URL, len(server.replicationErrorSkips))
+					return
+				}
+				_, err = server.SkipReplicationError(server.ReplicationSourceName, errno, false)
+				if err != nil {
+					server.ClusterGroup.LogPrintf(LvlErr, "Could not skip replication error %d on %s: %s", errno, server.URL, err)
+				} else {
+					server.replicationErrorSkips = append(server.replicationErrorSkips, time.Now())
+				}
+			}
+		}
 	}
 }
 
@@ -645,11 +991,15 @@ func (server *ServerMonitor) Refresh() error {
 		}
 
 		if server.ClusterGroup.Conf.MonitorProcessList {
-			server.FullProcessList, logs, err = dbhelper.GetProcesslist(server.Conn, server.DBVersion)
-			server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Could not get process %s %s", server.URL, err)
+			server.FullProcessList, err = server.getProcessList()
 			if err != nil {
 				server.ClusterGroup.SetState("ERR00075", state.State{ErrType: LvlErr, ErrDesc: fmt.Sprintf(clusterError["ERR00075"], err), ServerUrl: server.URL, ErrFrom: "MON"})
 			}
+			ids, logs, err := dbhelper.GetPoolConnectionIDs(server.Conn, server.DBVersion)
+			server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Could not get monitoring connection ids %s %s", server.URL, err)
+			server.setMonitorConnectionIds(ids)
+			server.SetProcessListDigests()
+			server.SetProcessListStates(time.Now())
 		}
 	}
 	if server.InCaptureMode {
@@ -671,10 +1021,14 @@ func (server *ServerMonitor) Refresh() error {
 				go server.JobBackupBinlogPurge(server.BinaryLogFilePrevious)
 			}
 		}
+		server.BinlogWriteRate = getBinlogRate(server.binlogWriteSample, binlogCoordinate{File: server.BinaryLogFile, Pos: uint64(server.MasterStatus.Position), Time: time.Now()}, server.BinaryLogFiles)
+		server.binlogWriteSample = binlogCoordinate{File: server.BinaryLogFile, Pos: uint64(server.MasterStatus.Position), Time: time.Now()}
 		server.BinaryLogFilePrevious = server.BinaryLogFile
 		server.BinaryLogPos = strconv.FormatUint(uint64(server.MasterStatus.Position), 10)
 	}
 
+	// the delay of the previous poll, replication status is fetched after the heavy gathering
+	server.SetHeavyMonitoringSkipped()
 	if !server.DBVersion.IsPPostgreSQL() {
 		server.BinlogDumpThreads, logs, err = dbhelper.GetBinlogDumpThreads(server.Conn, server.DBVersion)
 		if err != nil {
@@ -693,21 +1047,31 @@ func (server *ServerMonitor) Refresh() error {
 
 		if server.ClusterGroup.Conf.MonitorInnoDBStatus {
 			// SHOW ENGINE INNODB STATUS
-			server.EngineInnoDB, logs, err = dbhelper.GetEngineInnoDBVariables(server.Conn)
+			var innodbStatus string
+			innodbStatus, logs, err = dbhelper.GetEngineInnoDBSatus(server.Conn)
 			server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Could not get engine innodb status %s %s", server.URL, err)
+			if err == nil {
+				server.EngineInnoDB = dbhelper.ParseEngineInnoDBVariables(innodbStatus)
+				server.addDeadlockHistory(dbhelper.ParseEngineInnoDBDeadlock(innodbStatus))
+				server.addHistoryListLength()
+			} else {
+				server.EngineInnoDB = nil
+			}
+			server.BufferPoolStats, logs, err = dbhelper.GetBufferPoolStats(server.Conn, server.DBVersion)
+			server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Could not get buffer pool stats %s %s", server.URL, err)
 		}
-		if server.ClusterGroup.Conf.MonitorPFS {
+		if server.ClusterGroup.Conf.MonitorPFS && !server.HeavyMonitoringSkipped {
 			// GET PFS query digest
 			server.PFSQueries, logs, err = dbhelper.GetQueries(server.Conn)
 			server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Could not get queries %s %s", server.URL, err)
 		}
-		if server.HaveDiskMonitor {
+		if server.HaveDiskMonitor && !server.HeavyMonitoringSkipped {
 			server.Disks, logs, err = dbhelper.GetDisks(server.Conn, server.DBVersion)
 		}
 		if server.ClusterGroup.Conf.MonitorScheduler {
 			server.CheckDisks()
 		}
-		if server.HasLogsInSystemTables() {
+		if server.HasLogsInSystemTables() && !server.HeavyMonitoringSkipped {
 			go server.GetSlowLogTable()
 		}
 
@@ -727,6 +1091,19 @@ func (server *ServerMonitor) Refresh() error {
 		server.Replications, logs, err = dbhelper.GetChannelSlaveStatus(server.Conn, server.DBVersion)
 	}
 	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Could not get slaves status %s %s", server.URL, err)
+	if err == nil {
+		server.ReplicationsTimestamp = time.Now().Unix()
+		server.DetectReplicationSourceName()
+		server.setReplicationApplyRate(time.Now())
+		server.addChannelDelaySamples(time.Now())
+	}
+	if server.DBVersion.HasReplicationApplierFilters() {
+		filters, logs, ferr := dbhelper.GetReplicationApplierFilters(server.Conn, server.DBVersion)
+		server.ClusterGroup.LogSQL(logs, ferr, server.URL, "Monitor", LvlDbg, "Could not get replication filters %s %s", server.URL, ferr)
+		if ferr == nil {
+			server.ReplicationFilters = filters
+		}
+	}
 
 	// select a replication status get an err if repliciations array is empty
 	server.SlaveStatus, err = server.GetSlaveStatus(server.ReplicationSourceName)
@@ -762,6 +1139,14 @@ func (server *ServerMonitor) Refresh() error {
 		}
 	}
 	server.ReplicationHealth = server.CheckReplication()
+	server.UpdateReplicationDelaySmoothing()
+	server.CheckReplicationDelayAlert()
+	server.CheckReplicationErrorSkipBudget()
+	server.CheckReadEligibility()
+	server.CheckReplicationSLO()
+	if server.ClusterGroup.MetricSink != nil {
+		server.RecordReplicationDelay()
+	}
 	// if MaxScale exit at fetch variables and status part as not supported
 
 	if server.ClusterGroup.Conf.MxsBinlogOn && server.IsMaxscale {
@@ -770,6 +1155,8 @@ func (server *ServerMonitor) Refresh() error {
 	server.PrevStatus = server.Status
 
 	server.Status, logs, _ = dbhelper.GetStatus(server.Conn, server.DBVersion)
+	server.CheckRestart(time.Now())
+	server.refreshThreadPoolStats()
 	//server.ClusterGroup.LogPrintf("ERROR: %s %s %s", su["RPL_SEMI_SYNC_MASTER_STATUS"], su["RPL_SEMI_SYNC_SLAVE_STATUS"], server.URL)
 	if server.Status["RPL_SEMI_SYNC_MASTER_STATUS"] == "" || server.Status["RPL_SEMI_SYNC_SLAVE_STATUS"] == "" {
 		server.HaveSemiSync = false
@@ -810,6 +1197,8 @@ func (server *ServerMonitor) Refresh() error {
 		}
 	}
 
+	server.addConnectionSample(time.Now())
+
 	if server.HasHighNumberSlowQueries() {
 		server.ClusterGroup.SetState("WARN0088", state.State{ErrType: LvlInfo, ErrDesc: fmt.Sprintf(clusterError["WARN0088"], server.URL), ServerUrl: server.URL, ErrFrom: "MON"})
 	}
@@ -835,12 +1224,13 @@ func (server *ServerMonitor) Refresh() error {
 			server.HaveDiskMonitor = server.HasInstallPlugin("DISK")
 			server.HaveSQLErrorLog = server.HasInstallPlugin("SQL_ERROR_LOG")
 		}
-		if server.HaveMetaDataLocksLog {
+		if server.HaveMetaDataLocksLog && !server.HeavyMonitoringSkipped {
 			server.MetaDataLocks, logs, err = dbhelper.GetMetaDataLock(server.Conn, server.DBVersion)
 			server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Could not get Metat data locks  %s %s", server.URL, err)
 		}
 	}
 	server.CheckMaxConnections()
+	server.CheckConnectionStorm()
 
 	// Initialize graphite monitoring
 	if server.ClusterGroup.Conf.GraphiteMetrics {
@@ -1044,6 +1434,110 @@ func (server *ServerMonitor) SkipReplicationEvent() {
 	server.StartSlave()
 }
 
+// SkipReplicationError skips the event the SQL thread of the channel failed on and restarts it, only when the
+// error is errno and is allowed by replication-skip-errors. MySQL GTID replication commits an empty transaction
+// with the failing GTID, other replication uses sql_slave_skip_counter. Dry run only logs the skip
+func (server *ServerMonitor) SkipReplicationError(channel string, errno int, dryRun bool) (ReplicationErrorSkip, error) {
+	skip := ReplicationErrorSkip{Channel: channel, Errno: errno, Method: "skip-counter", DryRun: dryRun}
+	if !isReplicationErrorSkippable(server.ClusterGroup.Conf.ReplicationSkipErrors, errno) {
+		return skip, fmt.Errorf("Replication error %d is not in replication-skip-errors", errno)
+	}
+	ss, err := server.GetSlaveStatus(channel)
+	if err != nil {
+		return skip, err
+	}
+	if ss.LastSQLErrno.String != strconv.Itoa(errno) {
+		return skip, fmt.Errorf("SQL thread of channel '%s' failed on error %s, not %d", channel, ss.LastSQLErrno.String, errno)
+	}
+	skip.Error = ss.LastSQLError.String
+	skip.Statement = getReplicationErrorStatement(skip.Error)
+	if server.DBVersion.IsMySQLOrPercona() && server.HaveMySQLGTID {
+		skip.Method = "empty-gtid"
+		skip.GTID = getReplicationErrorGTID(skip.Error)
+		if skip.GTID == "" {
+			gtids, logs, err := dbhelper.GetFailedTransactionGTIDs(server.Conn, channel, server.DBVersion)
+			server.ClusterGroup.LogSQL(logs, err, server.URL, "SkipReplicationError", LvlErr, "Could not get failed transaction of %s %s", server.URL, err)
+			if err != nil {
+				return skip, err
+			}
+			skip.GTID = getLastGTID(gtids)
+		}
+		if skip.GTID == "" {
+			return skip, fmt.Errorf("Could not find the failed transaction GTID of channel '%s'", channel)
+		}
+	}
+	if dryRun {
+		server.ClusterGroup.LogPrintf(LvlInfo, "Dry run skip of replication error %d on %s channel '%s' with %s %s statement: %s", errno, server.URL, channel, skip.Method, skip.GTID, skip.Statement)
+		return skip, nil
+	}
+	server.ClusterGroup.LogPrintf(LvlInfo, "Skipping replication error %d on %s channel '%s' with %s %s statement: %s error: %s", errno, server.URL, channel, skip.Method, skip.GTID, skip.Statement, skip.Error)
+	logs, err := dbhelper.StopSlaveSQLThread(server.Conn, channel, server.DBVersion)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "SkipReplicationError", LvlErr, "Could not stop SQL thread of %s %s", server.URL, err)
+	if err != nil {
+		return skip, err
+	}
+	if skip.Method == "empty-gtid" {
+		logs, err = dbhelper.SkipGTIDTransaction(server.Conn, skip.GTID)
+	} else {
+		logs, err = dbhelper.SkipBinlogEvent(server.Conn, channel, server.DBVersion)
+	}
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "SkipReplicationError", LvlErr, "Could not skip replication event on %s %s", server.URL, err)
+	if err != nil {
+		return skip, err
+	}
+	logs, err = dbhelper.StartSlave(server.Conn, channel, server.DBVersion)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "SkipReplicationError", LvlErr, "Could not start slave %s %s", server.URL, err)
+	return skip, err
+}
+
+func isReplicationErrorSkippable(allowed string, errno int) bool {
+	for _, e := range strings.Split(allowed, ",") {
+		if n, err := strconv.Atoi(strings.TrimSpace(e)); err == nil && n == errno && n != 0 {
+			return true
+		}
+	}
+	return false
+}
+
+var (
+	replicationErrorGTIDRegexp      = regexp.MustCompile(`transaction '([0-9a-fA-F-]{36}(?::[A-Za-z0-9_]+)?:[0-9]+)'`)
+	replicationErrorStatementRegexp = regexp.MustCompile(`(?s)Query: '(.*)'$`)
+)
+
+// getReplicationErrorGTID returns the GTID of the failed transaction given by the worker error message
+func getReplicationErrorGTID(lastError string) string {
+	if m := replicationErrorGTIDRegexp.FindStringSubmatch(lastError); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// getLastGTID returns the GTID with the highest sequence number, sequence numbers are compared as numbers
+// for uuid:9 to come before uuid:10
+func getLastGTID(gtids []string) string {
+	last := ""
+	var lastSeq uint64
+	for _, g := range gtids {
+		seq, err := strconv.ParseUint(g[strings.LastIndexAny(g, ":-")+1:], 10, 64)
+		if err != nil {
+			continue
+		}
+		if last == "" || seq > lastSeq {
+			last, lastSeq = g, seq
+		}
+	}
+	return last
+}
+
+// getReplicationErrorStatement returns the failed statement of a statement event error, row events errors give
+// the table and the event position only
+func getReplicationErrorStatement(lastError string) string {
+	if m := replicationErrorStatementRegexp.FindStringSubmatch(strings.TrimSpace(lastError)); m != nil {
+		return m[1]
+	}
+	return lastError
+}
+
 func (server *ServerMonitor) KillThread(id string) (string, error) {
 	return dbhelper.KillThread(server.Conn, id, server.DBVersion)
 }
@@ -1052,6 +1546,114 @@ func (server *ServerMonitor) KillQuery(id string) (string, error) {
 	return dbhelper.KillQuery(server.Conn, id, server.DBVersion)
 }
 
+// getCurrentThread re-reads a thread of the last process list just before acting on it, false when the thread
+// ended or no longer runs the same statement or idle period for the same user
+func (server *ServerMonitor) getCurrentThread(q dbhelper.Processlist) (dbhelper.Processlist, bool) {
+	cur, logs, err := dbhelper.GetProcesslistThread(server.Conn, server.DBVersion, q.Id)
+	if err == sql.ErrNoRows {
+		return cur, false
+	}
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get thread %d on %s: %s", q.Id, server.URL, err)
+	if err != nil {
+		return cur, false
+	}
+	if cur.User != q.User || cur.Command != q.Command || cur.Info.String != q.Info.String || !cur.Time.Valid || cur.Time.Float64 < q.Time.Float64 {
+		return cur, false
+	}
+	return cur, true
+}
+
+// KillQueriesByDigest kill the running queries of a digest, system, replication and monitoring threads are skipped,
+// returns the killed thread ids or the thread ids that would be killed in dry run
+func (server *ServerMonitor) KillQueriesByDigest(digest string, dryRun bool) ([]uint64, error) {
+	var ids []uint64
+	var lasterr error
+	for _, q := range server.getProcessListByDigest(digest) {
+		if dryRun {
+			server.ClusterGroup.LogPrintf(LvlInfo, "Dry run kill query %d on %s: %s", q.Id, server.URL, q.Info.String)
+			ids = append(ids, q.Id)
+			continue
+		}
+		logs, err := server.KillQuery(strconv.FormatUint(q.Id, 10))
+		server.ClusterGroup.LogSQL(logs, err, server.URL, "KillQuery", LvlErr, "Could not kill query %d on %s: %s", q.Id, server.URL, err)
+		if err != nil {
+			lasterr = err
+			continue
+		}
+		server.ClusterGroup.LogPrintf(LvlInfo, "Killed query %d on %s: %s", q.Id, server.URL, q.Info.String)
+		ids = append(ids, q.Id)
+	}
+	return ids, lasterr
+}
+
+// ReapIdleConnections kill the connections in Sleep longer than idleThreshold, monitoring, replication and
+// monitoring-idle-connection-allow-users connections are skipped, a connection is read again before the kill and
+// skipped when it is no longer idle since the process list, returns the killed thread ids or the thread ids that
+// would be killed in dry run
+func (server *ServerMonitor) ReapIdleConnections(idleThreshold time.Duration, dryRun bool) ([]uint64, error) {
+	ids := []uint64{}
+	var lasterr error
+	for _, q := range server.getIdleConnections(idleThreshold) {
+		if dryRun {
+			server.ClusterGroup.LogPrintf(LvlInfo, "Dry run kill idle connection %d of %s on %s idle %.0fs", q.Id, q.User, server.URL, q.Time.Float64)
+			ids = append(ids, q.Id)
+			continue
+		}
+		cur, ok := server.getCurrentThread(q)
+		if !ok || cur.Command != "Sleep" || cur.Time.Float64 < idleThreshold.Seconds() {
+			server.ClusterGroup.LogPrintf(LvlInfo, "Skip kill of connection %d on %s no longer idle", q.Id, server.URL)
+			continue
+		}
+		logs, err := server.KillThread(strconv.FormatUint(q.Id, 10))
+		server.ClusterGroup.LogSQL(logs, err, server.URL, "KillThread", LvlErr, "Could not kill idle connection %d on %s: %s", q.Id, server.URL, err)
+		if err != nil {
+			lasterr = err
+			continue
+		}
+		server.ClusterGroup.LogPrintf(LvlInfo, "Killed idle connection %d of %s on %s idle %.0fs", q.Id, q.User, server.URL, q.Time.Float64)
+		ids = append(ids, q.Id)
+	}
+	return ids, lasterr
+}
+
+func (server *ServerMonitor) getIdleConnections(idleThreshold time.Duration) []dbhelper.Processlist {
+	allowed := make(map[string]bool)
+	for _, u := range strings.Split(server.ClusterGroup.Conf.MonitorIdleConnectionAllowUsers, ",") {
+		allowed[strings.TrimSpace(u)] = true
+	}
+	var pl []dbhelper.Processlist
+	for _, q := range server.FullProcessList {
+		if q.User == server.User || q.User == "system user" || q.User == "event_scheduler" || q.User == server.ClusterGroup.rplUser || allowed[q.User] {
+			continue
+		}
+		if q.Command != "Sleep" || !q.Time.Valid || q.Time.Float64 < idleThreshold.Seconds() {
+			continue
+		}
+		pl = append(pl, q)
+	}
+	return pl
+}
+
+func (server *ServerMonitor) getProcessListByDigest(digest string) []dbhelper.Processlist {
+	var pl []dbhelper.Processlist
+	for _, q := range server.FullProcessList {
+		if q.User == server.User || q.User == "system user" || q.User == "event_scheduler" {
+			continue
+		}
+		if (q.Command != "Query" && q.Command != "Execute") || !q.Info.Valid || q.Info.String == "" {
+			continue
+		}
+		d := q.Digest
+		if d == "" {
+			d = dbhelper.GetQueryDigest(q.Info.String)
+		}
+		if d == digest {
+			pl = append(pl, q)
+		}
+	}
+	return pl
+}
+
 func (server *ServerMonitor) ExecQueryNoBinLog(query string) error {
 	Conn, err := server.GetNewDBConn()
 	if err != nil {
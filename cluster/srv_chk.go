@@ -11,6 +11,7 @@ package cluster
 import (
 	"fmt"
 	"strconv"
+	"time"
 
 	"github.com/signal18/replication-manager/utils/dbhelper"
 	"github.com/signal18/replication-manager/utils/misc"
@@ -26,6 +27,37 @@ func (server *ServerMonitor) CheckMaxConnections() {
 	}
 }
 
+// CheckConnectionStorm raise a warning when the connected threads jump between two polls
+func (server *ServerMonitor) CheckConnectionStorm() {
+	c := server.GetConnectionStormDetector()
+	if c.Storm {
+		server.ClusterGroup.sme.AddState("WARN0110", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0110"], server.URL, c.Jump, c.Rate), ErrFrom: "MON", ServerUrl: server.URL})
+	}
+}
+
+// IsReplicationErrorSkipBudgetExceeded forgets the automatic skips older than an hour and tells if
+// replication-skip-errors-auto-max skips are left
+func (server *ServerMonitor) IsReplicationErrorSkipBudgetExceeded(now time.Time) bool {
+	i := 0
+	for i < len(server.replicationErrorSkips) && now.Sub(server.replicationErrorSkips[i]) > time.Hour {
+		i++
+	}
+	server.replicationErrorSkips = server.replicationErrorSkips[i:]
+	max := server.ClusterGroup.Conf.ReplicationSkipErrorsAutoMax
+	return max > 0 && len(server.replicationErrorSkips) >= max
+}
+
+// CheckReplicationErrorSkipBudget raise a warning while a slave stays stopped on an error automatic skips are
+// no more allowed for
+func (server *ServerMonitor) CheckReplicationErrorSkipBudget() {
+	if !server.ClusterGroup.Conf.ReplicationSkipErrorsAuto || server.State != stateSlaveErr {
+		return
+	}
+	if server.IsReplicationErrorSkipBudgetExceeded(time.Now()) {
+		server.ClusterGroup.SetState("WARN0114", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0114"], server.URL, len(server.replicationErrorSkips)), ErrFrom: "MON", ServerUrl: server.URL})
+	}
+}
+
 func (server *ServerMonitor) CheckVersion() {
 
 	if server.DBVersion.IsMariaDB() && ((server.DBVersion.Major == 10 && server.DBVersion.Minor == 4 && server.DBVersion.Release < 12) || (server.DBVersion.Major == 10 && server.DBVersion.Minor == 5 && server.DBVersion.Release < 1)) {
@@ -121,7 +153,7 @@ func (server *ServerMonitor) CheckReplication() string {
 	}
 
 	if ss.SecondsBehindMaster.Int64 > 0 {
-		if ss.SecondsBehindMaster.Int64 > server.ClusterGroup.Conf.FailMaxDelay && server.ClusterGroup.Conf.RplChecks == true {
+		if ss.SecondsBehindMaster.Int64-ss.SQLDelay.Int64 > server.ClusterGroup.Conf.FailMaxDelay && server.ClusterGroup.Conf.RplChecks == true && !server.ClusterGroup.IsInMaintenanceWindow() {
 			if server.IsRelay == false && server.IsMaxscale == false {
 				server.State = stateSlaveLate
 			} else if server.IsRelay {
@@ -135,6 +167,10 @@ func (server *ServerMonitor) CheckReplication() string {
 				server.State = stateRelay
 			}
 		}
+		// intentionally delayed with MASTER_DELAY, still not routed for reads
+		if ss.SQLDelay.Int64 > 0 {
+			return "Delayed"
+		}
 		return "Behind master"
 	}
 	if server.IsRelay == false && server.IsMaxscale == false {
@@ -173,10 +209,10 @@ func (server *ServerMonitor) CheckSlaveSettings() {
 	} else if sl.IsIgnored() == false && sl.GetReplicationHearbeatPeriod() > 1 {
 		server.ClusterGroup.sme.AddState("WARN0050", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0050"], sl.URL), ErrFrom: "TOPO", ServerUrl: sl.URL})
 	}
-	if server.ClusterGroup.Conf.ForceSlaveGtid && sl.GetReplicationUsingGtid() == "No" {
+	if server.ClusterGroup.Conf.ForceSlaveGtid && sl.GetReplicationGtidEnabled() == false {
 		dbhelper.SetSlaveGTIDMode(sl.Conn, "slave_pos", server.ClusterGroup.Conf.MasterConn, server.DBVersion)
 		server.ClusterGroup.LogPrintf("INFO", "Enforce GTID replication on slave %s", sl.URL)
-	} else if sl.IsIgnored() == false && sl.GetReplicationUsingGtid() == "No" {
+	} else if sl.IsIgnored() == false && sl.GetReplicationGtidEnabled() == false {
 		server.ClusterGroup.sme.AddState("WARN0051", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0051"], sl.URL), ErrFrom: "TOPO", ServerUrl: sl.URL})
 	}
 	if server.ClusterGroup.Conf.ForceSlaveGtidStrict && sl.IsReplicationUsingGtidStrict() == false {
@@ -342,3 +378,90 @@ func (server *ServerMonitor) CheckPrivileges() {
 		}
 	}
 }
+
+// CheckReplicationDelayAlert update the replication delay alert hysteresis of each tier, an alert is raised
+// after alert-replication-delay-raise-polls over the tier delay and cleared after
+// alert-replication-delay-clear-polls under it, the critical tier is over alert-replication-delay-critical or
+// failover-max-slave-delay and the warning tier over alert-replication-delay-warning
+func (server *ServerMonitor) CheckReplicationDelayAlert() {
+	if server.ClusterGroup.Conf.FailMaxDelay == -1 || server.ClusterGroup.IsInMaintenanceWindow() {
+		server.ReplicationDelayAlertState = DelayAlertState{}
+		server.ReplicationDelayWarnState = DelayAlertState{}
+		return
+	}
+	level := server.GetReplicationDelayAlertLevel()
+	delay := server.GetExcessReplicationDelay()
+	raise := server.ClusterGroup.Conf.AlertReplicationDelayRaisePolls
+	clear := server.ClusterGroup.Conf.AlertReplicationDelayClearPolls
+	critical := server.ClusterGroup.Conf.AlertReplicationDelayCritical
+	if critical <= 0 {
+		critical = server.ClusterGroup.Conf.FailMaxDelay
+	}
+	warning := server.ClusterGroup.Conf.AlertReplicationDelayWarning
+	server.ReplicationDelayAlertState.Update(server.IsSlave && delay > critical, raise, clear)
+	server.ReplicationDelayWarnState.Update(server.IsSlave && warning > 0 && delay > warning, raise, clear)
+	if server.ReplicationDelayAlertState.Alerting {
+		server.ClusterGroup.sme.AddState("WARN0101", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0101"], server.ReplicationDelayAlertState.AboveCount, server.URL), ErrFrom: "MON", ServerUrl: server.URL})
+	} else if server.ReplicationDelayWarnState.Alerting {
+		server.ClusterGroup.sme.AddState("WARN0111", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0111"], server.ReplicationDelayWarnState.AboveCount, server.URL), ErrFrom: "MON", ServerUrl: server.URL})
+	}
+	if newLevel := server.GetReplicationDelayAlertLevel(); newLevel != level {
+		server.ClusterGroup.LogPrintf(LvlInfo, "Replication delay alert of %s from %s to %s with delay %d", server.URL, level, newLevel, delay)
+	}
+	server.NotifyReplicationDelayWebhook()
+}
+
+// CheckReadEligibility remove the slave from reads at the first poll over read-max-slave-delay
+// and give it back after read-max-slave-delay-clear-polls under it
+func (server *ServerMonitor) CheckReadEligibility() {
+	if server.ClusterGroup.Conf.MaxReadLag <= 0 {
+		server.ReadLagState = DelayAlertState{}
+		return
+	}
+	eligible := server.IsReadEligible()
+	server.ReadLagState.Update(server.IsSlave && server.GetReplicationDelay() > server.ClusterGroup.Conf.MaxReadLag, 1, server.ClusterGroup.Conf.MaxReadLagClearPolls)
+	if eligible && !server.IsReadEligible() {
+		server.ClusterGroup.LogPrintf(LvlInfo, "Slave %s not eligible for reads, replication delay %d over read-max-slave-delay %d", server.URL, server.GetReplicationDelay(), server.ClusterGroup.Conf.MaxReadLag)
+	} else if !eligible && server.IsReadEligible() {
+		server.ClusterGroup.LogPrintf(LvlInfo, "Slave %s eligible for reads again, replication delay %d", server.URL, server.GetReplicationDelay())
+	}
+}
+
+// CheckReplicationSLO record if the slave replication is running within failover-max-slave-delay for this poll
+func (server *ServerMonitor) CheckReplicationSLO() {
+	if !server.IsSlave || server.ClusterGroup.Conf.FailMaxDelay == -1 {
+		return
+	}
+	server.checkReplicationSLO(time.Now())
+}
+
+func (server *ServerMonitor) checkReplicationSLO(now time.Time) {
+	good := !server.IsReplicationBroken() && server.GetExcessReplicationDelay() <= server.ClusterGroup.Conf.FailMaxDelay
+	if !server.restartTime.IsZero() {
+		if good || now.Sub(server.restartTime) > time.Duration(server.ClusterGroup.Conf.ReplicationRestartGrace)*time.Second {
+			server.restartTime = time.Time{}
+		} else {
+			return
+		}
+	}
+	server.addReplicationSLOSample(now, good)
+}
+
+// Update count the poll and switch alerting when raise or clear polls are reached
+func (d *DelayAlertState) Update(above bool, raise int, clear int) {
+	if above {
+		d.AboveCount++
+		d.BelowCount = 0
+		if !d.Alerting && d.AboveCount >= raise {
+			d.Alerting = true
+		}
+	} else {
+		d.BelowCount++
+		if d.Alerting && d.BelowCount >= clear {
+			d.Alerting = false
+		}
+		if !d.Alerting {
+			d.AboveCount = 0
+		}
+	}
+}
@@ -0,0 +1,347 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"io/ioutil"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/dbhelper"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+func TestDelayAlertStateHysteresis(t *testing.T) {
+	var d DelayAlertState
+	polls := []bool{true, true, false, true, true, true, false, false, true, false, false, false}
+	expected := []bool{false, false, false, false, false, true, true, true, true, true, true, false}
+	for i, above := range polls {
+		d.Update(above, 3, 3)
+		if d.Alerting != expected[i] {
+			t.Fatalf("Poll %d alerting %t, expected %t", i, d.Alerting, expected[i])
+		}
+	}
+}
+
+func TestReadEligibility(t *testing.T) {
+	server := &ServerMonitor{IsSlave: true, ClusterGroup: &Cluster{Conf: config.Config{MaxReadLag: 10, MaxReadLagClearPolls: 2}}}
+	delays := []int64{0, 30, 5, 30, 5, 5, 5}
+	expected := []bool{true, false, false, false, false, true, true}
+	for i, delay := range delays {
+		server.ReplicationStatus = replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}}}
+		server.CheckReadEligibility()
+		if server.IsReadEligible() != expected[i] {
+			t.Fatalf("Poll %d read eligible %t, expected %t", i, server.IsReadEligible(), expected[i])
+		}
+	}
+	server.ClusterGroup.Conf.MaxReadLag = 0
+	server.CheckReadEligibility()
+	if !server.IsReadEligible() {
+		t.Fatal("Expected read eligible when read-max-slave-delay is disabled")
+	}
+}
+
+func TestReplicationDelaySmoothingReset(t *testing.T) {
+	status := func(host string, delay int64) replicationStatusFixture {
+		return replicationStatusFixture{{MasterHost: sql.NullString{String: host, Valid: true}, MasterPort: sql.NullString{String: "3306", Valid: true}, SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}}}
+	}
+	server := &ServerMonitor{URL: "db2:3306", ClusterGroup: &Cluster{}}
+	server.ReplicationStatus = status("db1", 100)
+	server.UpdateReplicationDelaySmoothing()
+	if server.SmoothedReplicationDelay != 100 {
+		t.Fatalf("Got smoothed delay %v, expected seeded 100", server.SmoothedReplicationDelay)
+	}
+	server.ReplicationStatus = status("db1", 0)
+	server.UpdateReplicationDelaySmoothing()
+	if server.SmoothedReplicationDelay != 70 {
+		t.Fatalf("Got smoothed delay %v, expected 70", server.SmoothedReplicationDelay)
+	}
+	server.ReplicationDelayAlertState = DelayAlertState{Alerting: true, AboveCount: 5}
+	server.ReadLagState = DelayAlertState{Alerting: true, AboveCount: 5}
+	server.ReplicationStatus = status("db3", 4)
+	server.UpdateReplicationDelaySmoothing()
+	if server.SmoothedReplicationDelay != 4 || server.ReplicationDelayAlertState.Alerting || server.ReadLagState.Alerting {
+		t.Fatalf("Got smoothed delay %v alert %v, expected re-seeded from 4 and alerts reset", server.SmoothedReplicationDelay, server.ReplicationDelayAlertState)
+	}
+	server.ReplicationDelayAlertState = DelayAlertState{Alerting: true, AboveCount: 5}
+	for _, transient := range []replicationStatusFixture{{}, {{SecondsBehindMaster: sql.NullInt64{Int64: 9, Valid: true}}}} {
+		server.ReplicationStatus = transient
+		server.UpdateReplicationDelaySmoothing()
+		if server.SmoothedReplicationDelay != 4 || !server.ReplicationDelayAlertState.Alerting {
+			t.Fatalf("Got smoothed delay %v alert %v, expected history kept without a slave status", server.SmoothedReplicationDelay, server.ReplicationDelayAlertState)
+		}
+	}
+	server.ReplicationStatus = status("db3", 4)
+	server.UpdateReplicationDelaySmoothing()
+	if !server.ReplicationDelayAlertState.Alerting {
+		t.Fatal("Expected alert kept when the master is unchanged after a failed poll")
+	}
+}
+
+func TestReplicationSLORestartBoundary(t *testing.T) {
+	running := func(delay int64, valid bool) replicationStatusFixture {
+		return replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: valid}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}}}
+	}
+	server := &ServerMonitor{URL: "db2:3306", IsSlave: true, ClusterGroup: &Cluster{Conf: config.Config{FailMaxDelay: 30, ReplicationRestartGrace: 300, ReplicationSLOTarget: "99"}}}
+	now := time.Unix(1700000000, 0)
+	poll := func(uptime string, status replicationStatusFixture) {
+		now = now.Add(10 * time.Second)
+		server.ReplicationStatus = status
+		server.checkReplicationSLO(now)
+		server.PrevStatus = server.Status
+		server.Status = map[string]string{"UPTIME": uptime}
+		server.CheckRestart(now)
+	}
+	poll("1000", running(0, true))
+	poll("1010", running(0, true))
+	// the slave restarts, reconnects with a huge delay then catches up
+	poll("3", replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{}}})
+	poll("13", running(4000, true))
+	poll("23", running(900, true))
+	poll("33", running(1, true))
+	slo := server.getReplicationSLO(now)
+	if slo[0].Polls != 3 || slo[0].Compliance != 1 {
+		t.Fatalf("Expected restart polls excluded from the SLO, got %+v", slo[0])
+	}
+	poll("43", running(100, true))
+	if slo = server.getReplicationSLO(now); slo[0].Polls != 4 || slo[0].Compliance != 0.75 {
+		t.Fatalf("Expected delay after catch up counted, got %+v", slo[0])
+	}
+	poll("1", running(100, true))
+	now = now.Add(time.Duration(server.ClusterGroup.Conf.ReplicationRestartGrace) * time.Second)
+	poll("400", running(100, true))
+	if slo = server.getReplicationSLO(now); slo[0].Polls != 5 || slo[0].Compliance != 0.6 {
+		t.Fatalf("Expected delay still counted after the restart grace, got %+v", slo[0])
+	}
+}
+
+func TestHeavyMonitoringSkipped(t *testing.T) {
+	server := &ServerMonitor{URL: "db2:3306", IsSlave: true, ClusterGroup: &Cluster{Conf: config.Config{MonitorSkipHeavyMaxDelay: 60}}}
+	tests := []struct {
+		delay    sql.NullInt64
+		expected bool
+	}{
+		{sql.NullInt64{Int64: 30, Valid: true}, false},
+		{sql.NullInt64{Int64: 90, Valid: true}, true},
+		{sql.NullInt64{}, false},
+		{sql.NullInt64{Int64: 60, Valid: true}, false},
+	}
+	for _, test := range tests {
+		server.ReplicationStatus = replicationStatusFixture{{SecondsBehindMaster: test.delay}}
+		server.SetHeavyMonitoringSkipped()
+		if server.HeavyMonitoringSkipped != test.expected {
+			t.Fatalf("Heavy monitoring skipped %t with delay %v, expected %t", server.HeavyMonitoringSkipped, test.delay, test.expected)
+		}
+	}
+	server.ClusterGroup.Conf.MonitorSkipHeavyMaxDelay = 0
+	server.ReplicationStatus = replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: 90, Valid: true}}}
+	server.SetHeavyMonitoringSkipped()
+	if server.HeavyMonitoringSkipped {
+		t.Fatal("Heavy monitoring skipped with monitoring-skip-heavy-max-delay disabled")
+	}
+}
+
+func TestConfiguredReplicationDelay(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	server := &ServerMonitor{URL: "db2:3306", IsSlave: true, ClusterGroup: &Cluster{sme: sme, Conf: config.Config{FailMaxDelay: 30, RplChecks: true, AlertReplicationDelayRaisePolls: 1, AlertReplicationDelayClearPolls: 1}}}
+	status := func(delay int64, sqlDelay int64) replicationStatusFixture {
+		return replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}, SQLDelay: sql.NullInt64{Int64: sqlDelay, Valid: true}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}}}
+	}
+	tests := []struct {
+		delay, sqlDelay int64
+		excess          int64
+		alerting        bool
+		health          string
+	}{
+		{3620, 3600, 20, false, "Delayed"},
+		{3700, 3600, 100, true, "Delayed"},
+		{100, 3600, 0, false, "Delayed"},
+		{100, 0, 100, true, "Behind master"},
+	}
+	for _, test := range tests {
+		server.ReplicationStatus = status(test.delay, test.sqlDelay)
+		server.CheckReplicationDelayAlert()
+		if d := server.GetConfiguredReplicationDelay(""); d != test.sqlDelay {
+			t.Fatalf("Got configured delay %d, expected %d", d, test.sqlDelay)
+		}
+		if d := server.GetExcessReplicationDelay(); d != test.excess {
+			t.Fatalf("Got excess delay %d, expected %d", d, test.excess)
+		}
+		if server.ReplicationDelayAlertState.Alerting != test.alerting {
+			t.Fatalf("Delay %d with SQL_Delay %d alerting %t, expected %t", test.delay, test.sqlDelay, server.ReplicationDelayAlertState.Alerting, test.alerting)
+		}
+		if h := server.CheckReplication(); h != test.health || (server.State == stateSlaveLate) != test.alerting {
+			t.Fatalf("Delay %d with SQL_Delay %d health %s state %s, expected %s", test.delay, test.sqlDelay, h, server.State, test.health)
+		}
+	}
+}
+
+func TestConnectionStorm(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	server := &ServerMonitor{URL: "db1:3306", ClusterGroup: &Cluster{sme: sme, Conf: config.Config{MonitorConnectionStormJump: 1000, MonitorConnectionStormRate: 50}}}
+	now := time.Unix(1700000000, 0)
+	tests := []struct {
+		connected string
+		elapsed   time.Duration
+		jump      int64
+		rate      float64
+		storm     bool
+	}{
+		{"100", 0, 0, 0, false},
+		{"300", 10 * time.Second, 200, 20, false},
+		{"900", 10 * time.Second, 600, 60, true},
+		{"800", 2 * time.Second, -100, -50, false},
+		{"2000", 60 * time.Second, 1200, 20, true},
+	}
+	for i, test := range tests {
+		now = now.Add(test.elapsed)
+		server.Status = map[string]string{"THREADS_CONNECTED": test.connected, "THREADS_RUNNING": "4"}
+		server.addConnectionSample(now)
+		c := server.GetConnectionStormDetector()
+		if c.Jump != test.jump || c.Rate != test.rate || c.Storm != test.storm || c.Running != 4 {
+			t.Fatalf("Poll %d got %+v, expected jump %d rate %v storm %t", i, c, test.jump, test.rate, test.storm)
+		}
+	}
+	server.CheckConnectionStorm()
+	if !sme.CurState.Search("WARN0110") {
+		t.Fatal("Expected WARN0110 on a connection storm")
+	}
+	for i := 0; i < connectionSampleSize; i++ {
+		server.addConnectionSample(now)
+	}
+	if n := len(server.GetConnectionStormDetector().History); n != connectionSampleSize {
+		t.Fatalf("Got %d samples, expected %d", n, connectionSampleSize)
+	}
+}
+
+func TestReplicationDelayAlertTiers(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	server := &ServerMonitor{URL: "db2:3306", IsSlave: true, ClusterGroup: &Cluster{sme: sme, Conf: config.Config{FailMaxDelay: 30, AlertReplicationDelayWarning: 10, AlertReplicationDelayCritical: 60, AlertReplicationDelayRaisePolls: 2, AlertReplicationDelayClearPolls: 2}}}
+	delays := []int64{5, 20, 20, 90, 90, 40, 40, 5, 5}
+	expected := []string{DelayAlertLevelOK, DelayAlertLevelOK, DelayAlertLevelWarning, DelayAlertLevelWarning, DelayAlertLevelCritical, DelayAlertLevelCritical, DelayAlertLevelWarning, DelayAlertLevelWarning, DelayAlertLevelOK}
+	for i, delay := range delays {
+		server.ReplicationStatus = replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}}}
+		server.CheckReplicationDelayAlert()
+		if level := server.GetReplicationDelayAlertLevel(); level != expected[i] {
+			t.Fatalf("Poll %d delay %d alert level %s, expected %s", i, delay, level, expected[i])
+		}
+	}
+	if !sme.CurState.Search("WARN0111") || !sme.CurState.Search("WARN0101") {
+		t.Fatal("Expected WARN0111 and WARN0101 states")
+	}
+}
+
+func TestClearCompletedRestartCookies(t *testing.T) {
+	cluster := &Cluster{Conf: config.Config{FailMaxDelay: 30}}
+	newServer := func(url string, uptime string) *ServerMonitor {
+		dir, err := ioutil.TempDir("", "cookie")
+		if err != nil {
+			t.Fatal(err)
+		}
+		s := &ServerMonitor{URL: url, Datadir: dir, ClusterGroup: cluster, Status: map[string]string{"UPTIME": uptime}}
+		s.SetRestartCookie()
+		hour := time.Now().Add(-time.Hour)
+		os.Chtimes(dir+"/@cookie_restart", hour, hour)
+		return s
+	}
+	db1 := newServer("db1:3306", "7200")
+	db2 := newServer("db2:3306", "60")
+	defer os.RemoveAll(db1.Datadir)
+	defer os.RemoveAll(db2.Datadir)
+	db2.IsSlave = true
+	running := func(delay int64) replicationStatusFixture {
+		return replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}}}
+	}
+	db2.ReplicationStatus = running(100)
+	cluster.Servers = serverList{db1, db2}
+
+	cluster.ClearCompletedRestartCookies()
+	if !db1.HasRestartCookie() || !db2.HasRestartCookie() {
+		t.Fatal("Expected restart cookies kept before the slave catches up")
+	}
+	db2.ReplicationStatus = running(2)
+	cluster.ClearCompletedRestartCookies()
+	if db2.HasRestartCookie() {
+		t.Fatal("Expected restart cookie removed on the restarted slave")
+	}
+	if !db1.HasRestartCookie() || cluster.IsNeedDatabasesRollingRestart || !cluster.IsNeedDatabasesRestart {
+		t.Fatal("Expected restart cookie kept on the master not restarted")
+	}
+}
+
+func TestSkipReplicationError(t *testing.T) {
+	failed := func(errno string, msg string) replicationStatusFixture {
+		return replicationStatusFixture{{LastSQLErrno: sql.NullString{String: errno, Valid: true}, LastSQLError: sql.NullString{String: msg, Valid: true}}}
+	}
+	cluster := &Cluster{Conf: config.Config{ReplicationSkipErrors: "1062, 1032"}}
+	server := &ServerMonitor{URL: "db2:3306", ClusterGroup: cluster, DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", "")}
+	server.ReplicationStatus = failed("1062", "Error 'Duplicate entry '1' for key 'PRIMARY'' on query. Default database: 'app'. Query: 'INSERT INTO t1 VALUES (1)'")
+	if _, err := server.SkipReplicationError("", 1146, true); err == nil {
+		t.Fatal("Expected error outside replication-skip-errors refused")
+	}
+	if _, err := server.SkipReplicationError("", 1032, true); err == nil {
+		t.Fatal("Expected skip refused when the SQL thread failed on another error")
+	}
+	skip, err := server.SkipReplicationError("", 1062, true)
+	if err != nil || skip.Method != "skip-counter" || skip.Statement != "INSERT INTO t1 VALUES (1)" || !skip.DryRun {
+		t.Fatalf("Unexpected skip %+v %v", skip, err)
+	}
+	server.DBVersion = dbhelper.NewMySQLVersion("8.0.32", "")
+	server.HaveMySQLGTID = true
+	server.ReplicationStatus = failed("1032", "Coordinator stopped because there were error(s) in the worker(s). The most recent failure being: Worker 1 failed executing transaction '3e11fa47-71ca-11e1-9e33-c80aa9429562:23' at master log binlog.000004, end_log_pos 1402.")
+	skip, err = server.SkipReplicationError("", 1032, true)
+	if err != nil || skip.Method != "empty-gtid" || skip.GTID != "3e11fa47-71ca-11e1-9e33-c80aa9429562:23" {
+		t.Fatalf("Unexpected GTID skip %+v %v", skip, err)
+	}
+}
+
+func TestGetLastGTID(t *testing.T) {
+	gtids := []string{"3e11fa47-71ca-11e1-9e33-c80aa9429562:9", "3e11fa47-71ca-11e1-9e33-c80aa9429562:10", "3e11fa47-71ca-11e1-9e33-c80aa9429562:tag:2"}
+	if g := getLastGTID(gtids); g != "3e11fa47-71ca-11e1-9e33-c80aa9429562:10" {
+		t.Fatalf("Expected sequence 10 after 9, got %s", g)
+	}
+	if g := getLastGTID([]string{"0-1-9", "0-1-10"}); g != "0-1-10" {
+		t.Fatalf("Expected 0-1-10 after 0-1-9, got %s", g)
+	}
+	if g := getLastGTID(nil); g != "" {
+		t.Fatalf("Expected no GTID, got %s", g)
+	}
+}
+
+func TestReplicationErrorSkipBudget(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{Conf: config.Config{ReplicationSkipErrorsAuto: true, ReplicationSkipErrorsAutoMax: 2}, sme: sme}
+	server := &ServerMonitor{URL: "db2:3306", ClusterGroup: cluster, State: stateSlaveErr}
+	now := time.Now()
+	server.replicationErrorSkips = []time.Time{now.Add(-2 * time.Hour), now.Add(-time.Minute)}
+	if server.IsReplicationErrorSkipBudgetExceeded(now) || len(server.replicationErrorSkips) != 1 {
+		t.Fatalf("Expected skips older than an hour forgotten, got %d", len(server.replicationErrorSkips))
+	}
+	server.CheckReplicationErrorSkipBudget()
+	if sme.CurState.Search("WARN0114") {
+		t.Fatal("Unexpected WARN0114 within the skip budget")
+	}
+	server.replicationErrorSkips = append(server.replicationErrorSkips, now)
+	server.CheckReplicationErrorSkipBudget()
+	if !sme.CurState.Search("WARN0114") {
+		t.Fatal("Expected WARN0114 once the skip budget is exceeded")
+	}
+	cluster.Conf.ReplicationSkipErrorsAutoMax = 0
+	if server.IsReplicationErrorSkipBudgetExceeded(now) {
+		t.Fatal("Expected no skip budget with replication-skip-errors-auto-max 0")
+	}
+}
@@ -11,9 +11,17 @@ package cluster
 
 import (
 	"bufio"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"net"
 	"os"
 	"path/filepath"
 	"regexp"
@@ -34,12 +42,149 @@ func (server *ServerMonitor) GetProcessList() []dbhelper.Processlist {
 	return server.FullProcessList
 }
 
+// GetProcessListRedacted returns the process list with the query literals replaced by the digest placeholders
+func (server *ServerMonitor) GetProcessListRedacted() []dbhelper.Processlist {
+	pl := make([]dbhelper.Processlist, len(server.FullProcessList))
+	copy(pl, server.FullProcessList)
+	for i, q := range pl {
+		if !q.Info.Valid || q.Info.String == "" {
+			continue
+		}
+		if q.Digest == "" {
+			q.Digest = dbhelper.GetQueryDigest(q.Info.String)
+		}
+		pl[i].Info.String = q.Digest
+	}
+	return pl
+}
+
+// redactQuery returns the digest of a statement, its literals replaced by placeholders
+func redactQuery(info string) string {
+	if info == "" {
+		return ""
+	}
+	return dbhelper.GetQueryDigest(info)
+}
+
+// Redacted returns the lock wait chain with the query literals of its threads replaced by placeholders
+func (c LockWaitChain) Redacted() LockWaitChain {
+	c.Threads = append([]LockWaitThread{}, c.Threads...)
+	for i := range c.Threads {
+		c.Threads[i].Info = redactQuery(c.Threads[i].Info)
+	}
+	return c
+}
+
+// Redacted returns the metadata lock report with the query literals of the waiters and blockers replaced by
+// placeholders
+func (r MDLReport) Redacted() MDLReport {
+	r.Waiters = append([]MDLWait{}, r.Waiters...)
+	for i := range r.Waiters {
+		r.Waiters[i].Waiter.Info = redactQuery(r.Waiters[i].Waiter.Info)
+		r.Waiters[i].Blockers = append([]MDLBlocker{}, r.Waiters[i].Blockers...)
+		for j := range r.Waiters[i].Blockers {
+			r.Waiters[i].Blockers[j].Info = redactQuery(r.Waiters[i].Blockers[j].Info)
+		}
+	}
+	return r
+}
+
+// Redacted returns the replication threads with the literals of the applied statements replaced by placeholders
+func (t ReplicationThreads) Redacted() ReplicationThreads {
+	for _, threads := range []*[]ReplicationThread{&t.IO, &t.SQL, &t.Workers} {
+		*threads = append([]ReplicationThread{}, *threads...)
+		for i := range *threads {
+			(*threads)[i].Info = redactQuery((*threads)[i].Info)
+		}
+	}
+	return t
+}
+
+// Redacted returns the temporary tables report with the query literals of the threads replaced by placeholders
+func (r ThreadTempReport) Redacted() ThreadTempReport {
+	r.Threads = append([]ThreadTempUsage{}, r.Threads...)
+	for i := range r.Threads {
+		r.Threads[i].Info = redactQuery(r.Threads[i].Info)
+	}
+	return r
+}
+
+// Redacted returns the apply bottleneck with the literals of the applied statement replaced by placeholders
+func (b ReplicationApplyBottleneck) Redacted() ReplicationApplyBottleneck {
+	b.Statement = redactQuery(b.Statement)
+	return b
+}
+
+// GetProcessListExcludingSelf returns the process list without the monitoring connections of replication-manager,
+// every thread of the monitoring user is left out when monitoring-processlist-dedicated-user is set
+func (server *ServerMonitor) GetProcessListExcludingSelf() []dbhelper.Processlist {
+	var pl []dbhelper.Processlist
+	ids := server.monitorConnectionIds
+	for _, q := range server.FullProcessList {
+		if ids[q.Id] || (server.ClusterGroup.Conf.MonitorProcessListDedicatedUser && q.User == server.User) {
+			continue
+		}
+		pl = append(pl, q)
+	}
+	return pl
+}
+
+// GetProcessListByClientHost count the connections per client host, ports are stripped and IP addresses normalized
+func (server *ServerMonitor) GetProcessListByClientHost() map[string]int {
+	hosts := make(map[string]int)
+	for _, q := range server.FullProcessList {
+		if host := getProcessListClientHost(q.Host); host != "" {
+			hosts[host]++
+		}
+	}
+	return hosts
+}
+
+func getProcessListClientHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	} else if i := strings.LastIndex(host, ":"); i > 0 && strings.Count(host, ":") > 1 {
+		// IPv6 client is reported unbracketed with the port appended
+		if _, err := strconv.Atoi(host[i+1:]); err == nil {
+			host = host[:i]
+		}
+	}
+	host = strings.Trim(host, "[]")
+	if ip := net.ParseIP(host); ip != nil {
+		return ip.String()
+	}
+	return strings.ToLower(host)
+}
+
+// GetProcessListGroupedByDigest count running queries per digest, most frequent first
+func (server *ServerMonitor) GetProcessListGroupedByDigest() []dbhelper.ProcesslistDigest {
+	counts := make(map[string]int)
+	for _, q := range server.FullProcessList {
+		if q.Digest != "" {
+			counts[q.Digest]++
+		}
+	}
+	var digests []dbhelper.ProcesslistDigest
+	for d, c := range counts {
+		digests = append(digests, dbhelper.ProcesslistDigest{Digest: d, Count: c})
+	}
+	sort.Slice(digests, func(i, j int) bool {
+		if digests[i].Count == digests[j].Count {
+			return digests[i].Digest < digests[j].Digest
+		}
+		return digests[i].Count > digests[j].Count
+	})
+	return digests
+}
+
 func (server *ServerMonitor) GetProcessListReplicationLongQuery() string {
 	if !server.ClusterGroup.Conf.MonitorProcessList {
 		return ""
 	}
+	commands := server.GetProcessListReplicationCommands()
+	idleStates := server.GetProcessListReplicationIdleStates()
 	for _, q := range server.FullProcessList {
-		if strings.HasPrefix(q.Command, "Slave_worker") && q.State.Valid && !strings.HasPrefix(q.State.String, "Waiting") {
+		if server.isReplicationApplierThread(q, commands) && q.State.Valid && !hasAnyPrefix(q.State.String, idleStates) {
 			if q.Time.Valid && server.ClusterGroup.Conf.FailMaxDelay != -1 && q.Time.Float64 > float64(server.ClusterGroup.Conf.FailMaxDelay) {
 				if q.Info.Valid {
 					return q.Info.String
@@ -50,24 +195,548 @@ func (server *ServerMonitor) GetProcessListReplicationLongQuery() string {
 	return ""
 }
 
+// GetReplicationApplyBottleneck returns the statement the replication applier has been running the longest when
+// the replication delay is over failover-max-slave-delay, nil when the slave is not late or no statement is applied
+func (server *ServerMonitor) GetReplicationApplyBottleneck() *ReplicationApplyBottleneck {
+	delay := server.GetReplicationDelay()
+	if server.ClusterGroup.Conf.FailMaxDelay == -1 || delay <= server.ClusterGroup.Conf.FailMaxDelay {
+		return nil
+	}
+	var b *ReplicationApplyBottleneck
+	commands := server.GetProcessListReplicationCommands()
+	idleStates := server.GetProcessListReplicationIdleStates()
+	for _, q := range server.FullProcessList {
+		if !server.isReplicationApplierThread(q, commands) || (q.State.Valid && hasAnyPrefix(q.State.String, idleStates)) {
+			continue
+		}
+		if !q.Info.Valid || q.Info.String == "" || !q.Time.Valid {
+			continue
+		}
+		if b == nil || q.Time.Float64 > b.Elapsed {
+			b = &ReplicationApplyBottleneck{Id: q.Id, Statement: q.Info.String, State: q.State.String, Elapsed: q.Time.Float64, Delay: delay}
+		}
+	}
+	return b
+}
+
+// GetProcessListReplicationCommands returns the processlist command prefixes of the replication applier threads
+func (server *ServerMonitor) GetProcessListReplicationCommands() []string {
+	if server.ClusterGroup.Conf.MonitorProcessListReplicationCommands != "" {
+		return strings.Split(server.ClusterGroup.Conf.MonitorProcessListReplicationCommands, ",")
+	}
+	if server.DBVersion != nil && server.DBVersion.IsMySQLOrPercona() {
+		return []string{"Slave_worker", "Connect", "Query"}
+	}
+	return []string{"Slave_worker"}
+}
+
+// GetProcessListReplicationIdleStates returns the processlist state prefixes of replication applier threads waiting for work
+func (server *ServerMonitor) GetProcessListReplicationIdleStates() []string {
+	if server.ClusterGroup.Conf.MonitorProcessListReplicationIdleStates != "" {
+		return strings.Split(server.ClusterGroup.Conf.MonitorProcessListReplicationIdleStates, ",")
+	}
+	if server.DBVersion != nil && server.DBVersion.IsMySQLOrPercona() {
+		return []string{"Waiting", "Slave has read all relay log", "Replica has read all relay log"}
+	}
+	return []string{"Waiting"}
+}
+
+// isReplicationApplierThread tells if processlist row is a replication applier, MySQL applier threads run as system user with Connect or Query command
+func (server *ServerMonitor) isReplicationApplierThread(q dbhelper.Processlist, commands []string) bool {
+	if !hasAnyPrefix(q.Command, commands) {
+		return false
+	}
+	if server.DBVersion != nil && server.DBVersion.IsMySQLOrPercona() && !strings.HasPrefix(q.Command, "Slave_") {
+		return q.User == "system user"
+	}
+	return true
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if prefix != "" && strings.HasPrefix(s, strings.TrimSpace(prefix)) {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	replicationThreadIO     = "io"
+	replicationThreadSQL    = "sql"
+	replicationThreadWorker = "worker"
+)
+
+// GetReplicationThreads returns the replication threads of the last processlist grouped by role, an error is
+// returned when monitoring-processlist is disabled
+func (server *ServerMonitor) GetReplicationThreads() (ReplicationThreads, error) {
+	threads := ReplicationThreads{IO: []ReplicationThread{}, SQL: []ReplicationThread{}, Workers: []ReplicationThread{}}
+	if !server.ClusterGroup.Conf.MonitorProcessList {
+		return threads, errors.New("Processlist monitoring is disabled")
+	}
+	idleStates := server.GetProcessListReplicationIdleStates()
+	for _, q := range server.FullProcessList {
+		t := ReplicationThread{Id: q.Id, Command: q.Command, Time: q.Time.Float64, State: q.State.String, Info: q.Info.String}
+		t.Idle = q.State.Valid && hasAnyPrefix(q.State.String, idleStates)
+		switch server.getReplicationThreadRole(q) {
+		case replicationThreadIO:
+			threads.IO = append(threads.IO, t)
+		case replicationThreadSQL:
+			threads.SQL = append(threads.SQL, t)
+		case replicationThreadWorker:
+			threads.Workers = append(threads.Workers, t)
+		}
+	}
+	for _, g := range [][]ReplicationThread{threads.IO, threads.SQL, threads.Workers} {
+		sort.Slice(g, func(i, j int) bool { return g[i].Id < g[j].Id })
+	}
+	return threads, nil
+}
+
+// getReplicationThreadRole returns the role of a replication thread from the MariaDB command, MySQL runs all the
+// replication threads as system user with Connect or Query command so the role is guessed from the state, the
+// applier of a non parallel replication running a statement is then reported as a worker
+func (server *ServerMonitor) getReplicationThreadRole(q dbhelper.Processlist) string {
+	switch q.Command {
+	case "Slave_IO":
+		return replicationThreadIO
+	case "Slave_SQL":
+		return replicationThreadSQL
+	case "Slave_worker":
+		return replicationThreadWorker
+	}
+	if server.DBVersion == nil || !server.DBVersion.IsMySQLOrPercona() || q.User != "system user" || (q.Command != "Connect" && q.Command != "Query") {
+		return ""
+	}
+	state := strings.ToLower(q.State.String)
+	for _, s := range []string{"coordinator", "preceding transaction", "dependent transaction"} {
+		if strings.Contains(state, s) {
+			return replicationThreadWorker
+		}
+	}
+	for _, s := range []string{"send event", "connecting to", "reconnect", "queueing", "binlog dump", "registering", "master version", "source version", "relay log space"} {
+		if strings.Contains(state, s) {
+			return replicationThreadIO
+		}
+	}
+	for _, s := range []string{"relay log", "workers", "master_delay", "source_delay"} {
+		if strings.Contains(state, s) {
+			return replicationThreadSQL
+		}
+	}
+	return replicationThreadWorker
+}
+
+const (
+	processListSnapshotsMax       = 16
+	processListSnapshotThreadsMax = 10000
+)
+
+// CaptureProcessListSnapshot keep a copy of the current process list under name, a snapshot with the same name is
+// replaced and the oldest snapshot is dropped over 16 snapshots
+func (server *ServerMonitor) CaptureProcessListSnapshot(name string) *ProcessListSnapshot {
+	snap := newProcessListSnapshot(name, time.Now(), server.FullProcessList)
+	var snapshots []*ProcessListSnapshot
+	for _, s := range server.processListSnapshots {
+		if s.Name != name {
+			snapshots = append(snapshots, s)
+		}
+	}
+	snapshots = append(snapshots, snap)
+	if len(snapshots) > processListSnapshotsMax {
+		snapshots = snapshots[len(snapshots)-processListSnapshotsMax:]
+	}
+	server.processListSnapshots = snapshots
+	return snap
+}
+
+// GetProcessListSnapshot returns the snapshot captured under name or nil
+func (server *ServerMonitor) GetProcessListSnapshot(name string) *ProcessListSnapshot {
+	for _, s := range server.processListSnapshots {
+		if s.Name == name {
+			return s
+		}
+	}
+	return nil
+}
+
+func newProcessListSnapshot(name string, now time.Time, processlist []dbhelper.Processlist) *ProcessListSnapshot {
+	snap := &ProcessListSnapshot{Name: name, Time: now, threads: make(map[uint64]dbhelper.Processlist, len(processlist))}
+	for _, q := range processlist {
+		if len(snap.threads) >= processListSnapshotThreadsMax {
+			break
+		}
+		snap.threads[q.Id] = q
+	}
+	return snap
+}
+
+// DiffProcessListSnapshots returns the threads of b not in a, the threads of a not in b and the threads which
+// command, state or query changed from a to b, each list sorted by thread id
+func DiffProcessListSnapshots(a *ProcessListSnapshot, b *ProcessListSnapshot) ProcessListDiff {
+	var diff ProcessListDiff
+	for id, after := range b.threads {
+		before, ok := a.threads[id]
+		if !ok {
+			diff.Appeared = append(diff.Appeared, after)
+		} else if before.Command != after.Command || before.State.String != after.State.String || before.Info.String != after.Info.String {
+			diff.Changed = append(diff.Changed, ProcessListChange{Before: before, After: after})
+		}
+	}
+	for id, before := range a.threads {
+		if _, ok := b.threads[id]; !ok {
+			diff.Disappeared = append(diff.Disappeared, before)
+		}
+	}
+	sort.Slice(diff.Appeared, func(i, j int) bool { return diff.Appeared[i].Id < diff.Appeared[j].Id })
+	sort.Slice(diff.Disappeared, func(i, j int) bool { return diff.Disappeared[i].Id < diff.Disappeared[j].Id })
+	sort.Slice(diff.Changed, func(i, j int) bool { return diff.Changed[i].After.Id < diff.Changed[j].After.Id })
+	return diff
+}
+
+// GetProcessListStuckInState returns the threads in state for longer than threshold, longest first,
+// the duration is tracked across polls so it is precise to the monitoring ticker
+func (server *ServerMonitor) GetProcessListStuckInState(state string, threshold time.Duration) []dbhelper.Processlist {
+	return server.getProcessListStuckInState(state, threshold, time.Now())
+}
+
+func (server *ServerMonitor) getProcessListStuckInState(state string, threshold time.Duration, now time.Time) []dbhelper.Processlist {
+	var res []dbhelper.Processlist
+	for _, q := range server.FullProcessList {
+		ts, ok := server.processListStates[q.Id]
+		if ok && q.State.String == state && ts.State == state && now.Sub(ts.Since) >= threshold {
+			res = append(res, q)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool {
+		return server.processListStates[res[i].Id].Since.Before(server.processListStates[res[j].Id].Since)
+	})
+	return res
+}
+
+// GetLongOpenTransactions returns the sessions with a transaction open for longer than threshold,
+// including idle sessions that keep a transaction open
+func (server *ServerMonitor) GetLongOpenTransactions(threshold time.Duration) ([]dbhelper.OpenTransaction, error) {
+	trx, logs, err := dbhelper.GetInnoDBTrx(server.Conn, server.DBVersion)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get innodb transactions %s %s", server.URL, err)
+	if err != nil {
+		return nil, err
+	}
+	return getLongOpenTransactions(server.FullProcessList, trx, threshold), nil
+}
+
+func getLongOpenTransactions(processlist []dbhelper.Processlist, trx []dbhelper.InnoDBTrx, threshold time.Duration) []dbhelper.OpenTransaction {
+	sessions := make(map[uint64]dbhelper.Processlist, len(processlist))
+	for _, q := range processlist {
+		sessions[q.Id] = q
+	}
+	var res []dbhelper.OpenTransaction
+	for _, t := range trx {
+		if time.Duration(t.Trx_age)*time.Second < threshold {
+			continue
+		}
+		q, ok := sessions[t.Trx_mysql_thread_id]
+		if !ok {
+			q = dbhelper.Processlist{Id: t.Trx_mysql_thread_id}
+		}
+		res = append(res, dbhelper.OpenTransaction{Processlist: q, Trx: t})
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].Trx.Trx_age > res[j].Trx.Trx_age
+	})
+	return res
+}
+
+// GetCookieTime returns the creation time of a cookie in the server datadir, false if the cookie does not exist
+func (server *ServerMonitor) GetCookieTime(cookie string) (time.Time, bool) {
+	fi, err := os.Stat(server.Datadir + "/@cookie_" + cookie)
+	if err != nil {
+		return time.Time{}, false
+	}
+	return fi.ModTime(), true
+}
+
 func (server *ServerMonitor) GetSchemas() ([]string, string, error) {
 	return dbhelper.GetSchemas(server.Conn)
 }
 
+func (server *ServerMonitor) GetSchemasDetailed() ([]dbhelper.SchemaDetail, string, error) {
+	return dbhelper.GetSchemasDetailed(server.Conn)
+}
+
 func (server *ServerMonitor) GetPrometheusMetrics() string {
 	metrics := server.GetDatabaseMetrics()
+	statusAge := server.GetReplicationStatusAge()
+	stale := server.ClusterGroup.Conf.MonitorReplicationStatusMaxAge > 0 && (statusAge < 0 || statusAge > time.Duration(server.ClusterGroup.Conf.MonitorReplicationStatusMaxAge)*time.Second)
 	var s string
 	for _, m := range metrics {
 		v := strings.Split(m.Name, ".")
+		if stale && v[2] == "mysql_slave_status_seconds_behind_master" {
+			continue
+		}
+		if v[2] == "mysql_slave_status_seconds_behind_master" && !server.SlaveStatus.SecondsBehindMaster.Valid {
+			m.Value = "NaN"
+		}
 		if v[2] == "pfs" {
 			s = s + v[2] + "_" + v[3] + "{instance=\"" + v[1] + "\"} " + m.Value + "\n"
 		} else {
 			s = s + v[2] + "{instance=\"" + v[1] + "\"} " + m.Value + "\n"
 		}
 	}
+	replacer := strings.NewReplacer("`", "", "?", "", " ", "_", ".", "-", "(", "-", ")", "-", "/", "_", "<", "-", "'", "-", "\"", "-")
+	instance := replacer.Replace(server.Variables["HOSTNAME"])
+	if server.IsSlave {
+		if statusAge >= 0 {
+			s = s + "replication_status_age_seconds{instance=\"" + instance + "\"} " + strconv.FormatInt(int64(statusAge/time.Second), 10) + "\n"
+		}
+		for _, w := range server.GetReplicationSLO() {
+			s = s + "replication_slo_compliance{instance=\"" + instance + "\",window=\"" + w.Window + "\"} " + strconv.FormatFloat(w.Compliance, 'f', -1, 64) + "\n"
+			s = s + "replication_slo_burn_rate{instance=\"" + instance + "\",window=\"" + w.Window + "\"} " + strconv.FormatFloat(w.BurnRate, 'f', -1, 64) + "\n"
+		}
+	}
+	derived := server.GetDerivedMetrics()
+	var names []string
+	for name := range derived {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		s = s + name + "{instance=\"" + instance + "\"} " + strconv.FormatFloat(derived[name], 'f', -1, 64) + "\n"
+	}
+	s = s + server.getChannelDelayMetrics(instance)
+	for _, a := range server.getStatusAnomalies() {
+		anomalous := "0"
+		if a.Anomalous {
+			anomalous = "1"
+		}
+		s = s + "mysql_status_anomaly{instance=\"" + instance + "\",status=\"" + strings.ToLower(a.Name) + "\"} " + anomalous + "\n"
+	}
+	if len(server.historyListLengths) > 0 {
+		p := server.GetPurgeLagStatus()
+		alert := "0"
+		if p.Alert {
+			alert = "1"
+		}
+		s = s + "mysql_innodb_history_list_length{instance=\"" + instance + "\"} " + strconv.FormatInt(p.HistoryListLength, 10) + "\n"
+		s = s + "mysql_innodb_history_list_length_trend{instance=\"" + instance + "\"} " + strconv.FormatInt(p.Trend, 10) + "\n"
+		s = s + "mysql_innodb_purge_lag_alert{instance=\"" + instance + "\"} " + alert + "\n"
+	}
+	if len(server.BufferPoolStats) > 0 {
+		s = s + "mysql_innodb_buffer_pool_instances{instance=\"" + instance + "\"} " + strconv.Itoa(len(server.BufferPoolStats)) + "\n"
+		s = s + "mysql_innodb_buffer_pool_instances_hit_ratio{instance=\"" + instance + "\"} " + strconv.FormatFloat(server.GetBufferPoolHitRate(), 'f', -1, 64) + "\n"
+	}
+	if len(server.connectionSamples) > 1 {
+		c := server.GetConnectionStormDetector()
+		storm := "0"
+		if c.Storm {
+			storm = "1"
+		}
+		s = s + "mysql_connection_jump{instance=\"" + instance + "\"} " + strconv.FormatInt(c.Jump, 10) + "\n"
+		s = s + "mysql_connection_rate{instance=\"" + instance + "\"} " + strconv.FormatFloat(c.Rate, 'f', -1, 64) + "\n"
+		s = s + "mysql_connection_storm{instance=\"" + instance + "\"} " + storm + "\n"
+	}
+	if tp := server.GetThreadPoolStats(); tp.Enabled {
+		s = s + "mysql_threadpool_size{instance=\"" + instance + "\"} " + strconv.FormatInt(tp.Size, 10) + "\n"
+		s = s + "mysql_threadpool_threads{instance=\"" + instance + "\"} " + strconv.FormatInt(tp.Threads, 10) + "\n"
+		s = s + "mysql_threadpool_idle_threads{instance=\"" + instance + "\"} " + strconv.FormatInt(tp.IdleThreads, 10) + "\n"
+		s = s + "mysql_threadpool_active_threads{instance=\"" + instance + "\"} " + strconv.FormatInt(tp.ActiveThreads, 10) + "\n"
+		s = s + "mysql_threadpool_queue_length{instance=\"" + instance + "\"} " + strconv.FormatInt(tp.QueueLength, 10) + "\n"
+		s = s + "mysql_threadpool_stalled_groups{instance=\"" + instance + "\"} " + strconv.Itoa(tp.StalledGroups) + "\n"
+	}
+	if server.HaveQueryResponseTimeLog {
+		s = s + getQueryResponseTimeHistogram(instance, server.GetQueryResponseTime())
+	}
+	return s
+}
+
+// getChannelDelayMetrics returns per replication channel the max delay since the last scrape, reset on each call,
+// and the total seconds spent over failover-max-slave-delay
+func (server *ServerMonitor) getChannelDelayMetrics(instance string) string {
+	server.channelDelayStats.Lock()
+	defer server.channelDelayStats.Unlock()
+	var channels []string
+	for channel := range server.channelDelayStats.channels {
+		channels = append(channels, channel)
+	}
+	sort.Strings(channels)
+	var s string
+	for _, channel := range channels {
+		stat := server.channelDelayStats.channels[channel]
+		s = s + "replication_delay_max_seconds{instance=\"" + instance + "\",channel=\"" + channel + "\"} " + strconv.FormatInt(stat.Max, 10) + "\n"
+		s = s + "replication_delay_breach_seconds_total{instance=\"" + instance + "\",channel=\"" + channel + "\"} " + strconv.FormatFloat(stat.BreachSeconds, 'f', -1, 64) + "\n"
+		stat.Max = 0
+	}
 	return s
 }
 
+// GetReplicationSLO returns the replication delay SLO compliance and burn rate over the last 1h, 6h and 24h
+func (server *ServerMonitor) GetReplicationSLO() []ReplicationSLOWindow {
+	return server.getReplicationSLO(time.Now())
+}
+
+func (server *ServerMonitor) getReplicationSLO(now time.Time) []ReplicationSLOWindow {
+	var windows []ReplicationSLOWindow
+	target, _ := strconv.ParseFloat(server.ClusterGroup.Conf.ReplicationSLOTarget, 64)
+	minute := now.Unix() / 60
+	for _, hours := range []int64{1, 6, 24} {
+		w := ReplicationSLOWindow{Window: strconv.FormatInt(hours, 10) + "h", Compliance: 1}
+		good := 0
+		for _, b := range server.replicationSLOBuckets {
+			if b.Minute > minute-hours*60 && b.Minute <= minute {
+				w.Polls += b.Total
+				good += b.Good
+			}
+		}
+		if w.Polls > 0 {
+			w.Compliance = float64(good) / float64(w.Polls)
+		}
+		if budget := 1 - target/100; budget > 0 && budget < 1 {
+			w.BurnRate = (1 - w.Compliance) / budget
+		}
+		windows = append(windows, w)
+	}
+	return windows
+}
+
+// GetReplicationStatusAge returns the time since the last successful slave status fetch, -1 when the slave
+// status was never fetched
+func (server *ServerMonitor) GetReplicationStatusAge() time.Duration {
+	if server.ReplicationsTimestamp == 0 {
+		return -1
+	}
+	return time.Since(time.Unix(server.ReplicationsTimestamp, 0))
+}
+
+// GetDerivedMetrics returns ratios and per second rates computed from global status counters,
+// NaN is returned when the divisor is zero
+func (server *ServerMonitor) GetDerivedMetrics() map[string]float64 {
+	return getDerivedMetrics(server.Status)
+}
+
+func getDerivedMetrics(status map[string]string) map[string]float64 {
+	get := func(name string) float64 {
+		v, _ := strconv.ParseFloat(status[name], 64)
+		return v
+	}
+	ratio := func(a float64, b float64) float64 {
+		if b == 0 {
+			return math.NaN()
+		}
+		return a / b
+	}
+	uptime := get("UPTIME")
+	metrics := make(map[string]float64)
+	metrics["mysql_innodb_buffer_pool_hit_ratio"] = 1 - ratio(get("INNODB_BUFFER_POOL_READS"), get("INNODB_BUFFER_POOL_READ_REQUESTS"))
+	metrics["mysql_qcache_hit_ratio"] = ratio(get("QCACHE_HITS"), get("QCACHE_HITS")+get("COM_SELECT"))
+	metrics["mysql_table_locks_waited_ratio"] = ratio(get("TABLE_LOCKS_WAITED"), get("TABLE_LOCKS_WAITED")+get("TABLE_LOCKS_IMMEDIATE"))
+	metrics["mysql_tmp_disk_tables_ratio"] = ratio(get("CREATED_TMP_DISK_TABLES"), get("CREATED_TMP_TABLES"))
+	metrics["mysql_thread_cache_miss_ratio"] = ratio(get("THREADS_CREATED"), get("CONNECTIONS"))
+	metrics["mysql_aborted_connects_ratio"] = ratio(get("ABORTED_CONNECTS"), get("CONNECTIONS"))
+	metrics["mysql_queries_per_second"] = ratio(get("QUERIES"), uptime)
+	metrics["mysql_slow_queries_per_second"] = ratio(get("SLOW_QUERIES"), uptime)
+	metrics["mysql_connections_per_second"] = ratio(get("CONNECTIONS"), uptime)
+	return metrics
+}
+
+// getQueryResponseTimeHistogram converts QUERY_RESPONSE_TIME buckets to a prometheus histogram,
+// buckets counts are cumulative and the TOO LONG bucket is reported as +Inf
+func getQueryResponseTimeHistogram(instance string, qrt []dbhelper.ResponseTime) string {
+	if len(qrt) == 0 {
+		return ""
+	}
+	var s string
+	var count uint64
+	var sum float64
+	for _, r := range qrt {
+		count = count + r.Count
+		le := strings.TrimSpace(r.Time)
+		if _, err := strconv.ParseFloat(le, 64); err != nil {
+			le = "+Inf"
+		}
+		if total, err := strconv.ParseFloat(strings.TrimSpace(r.Total), 64); err == nil {
+			sum = sum + total
+		}
+		s = s + "query_response_time_seconds_bucket{instance=\"" + instance + "\",le=\"" + le + "\"} " + strconv.FormatUint(count, 10) + "\n"
+		if le == "+Inf" {
+			break
+		}
+	}
+	if !strings.Contains(s, "le=\"+Inf\"") {
+		s = s + "query_response_time_seconds_bucket{instance=\"" + instance + "\",le=\"+Inf\"} " + strconv.FormatUint(count, 10) + "\n"
+	}
+	s = s + "query_response_time_seconds_sum{instance=\"" + instance + "\"} " + strconv.FormatFloat(sum, 'f', 6, 64) + "\n"
+	s = s + "query_response_time_seconds_count{instance=\"" + instance + "\"} " + strconv.FormatUint(count, 10) + "\n"
+	return s
+}
+
+// GetOpenMetricsQueryExec returns the running queries execution time as OpenMetrics histogram samples, each
+// bucket carries the slowest query of the bucket as exemplar labelled with its digest id, the family TYPE
+// header and the EOF marker are left to the caller
+func (server *ServerMonitor) GetOpenMetricsQueryExec() string {
+	replacer := strings.NewReplacer("`", "", "?", "", " ", "_", ".", "-", "(", "-", ")", "-", "/", "_", "<", "-", "'", "-", "\"", "-")
+	return getQueryExecHistogram(replacer.Replace(server.Variables["HOSTNAME"]), server.GetProcessListExcludingSelf())
+}
+
+// GetQueryDigestID returns a short stable id of a normalized query, OpenMetrics limits exemplar labels to 128 characters
+func GetQueryDigestID(digest string) string {
+	h := sha256.Sum256([]byte(digest))
+	return hex.EncodeToString(h[:8])
+}
+
+func getQueryExecHistogram(instance string, pl []dbhelper.Processlist) string {
+	bounds := []float64{0.1, 1, 10, 60, 300, math.Inf(1)}
+	counts := make([]uint64, len(bounds))
+	exemplars := make([]*dbhelper.Processlist, len(bounds))
+	var sum float64
+	var count uint64
+	for i, q := range pl {
+		if !q.Info.Valid || q.Info.String == "" || !q.Time.Valid {
+			continue
+		}
+		count++
+		sum = sum + q.Time.Float64
+		for b := range bounds {
+			if q.Time.Float64 <= bounds[b] {
+				counts[b]++
+				if exemplars[b] == nil || q.Time.Float64 > exemplars[b].Time.Float64 {
+					exemplars[b] = &pl[i]
+				}
+			}
+		}
+	}
+	var s string
+	var prev float64 = -1
+	for b, le := range bounds {
+		s = s + "query_exec_seconds_bucket{instance=\"" + instance + "\",le=\"" + strconv.FormatFloat(le, 'f', -1, 64) + "\"} " + strconv.FormatUint(counts[b], 10)
+		// the exemplar must fall in the bucket itself, not in the lower cumulated ones
+		if e := exemplars[b]; e != nil && e.Time.Float64 > prev {
+			digest := e.Digest
+			if digest == "" {
+				digest = dbhelper.GetQueryDigest(e.Info.String)
+			}
+			s = s + " # {digest=\"" + GetQueryDigestID(digest) + "\"} " + strconv.FormatFloat(e.Time.Float64, 'f', -1, 64)
+		}
+		s = s + "\n"
+		prev = le
+	}
+	s = s + "query_exec_seconds_sum{instance=\"" + instance + "\"} " + strconv.FormatFloat(sum, 'f', -1, 64) + "\n"
+	s = s + "query_exec_seconds_count{instance=\"" + instance + "\"} " + strconv.FormatUint(count, 10) + "\n"
+	return s
+}
+
+// GetEndpoint returns a normalized ip:port or host:port used to compare servers declared in different forms
+func (server *ServerMonitor) GetEndpoint() string {
+	host := server.IP
+	if host == "" {
+		host = strings.ToLower(strings.Trim(server.Host, "[]"))
+	}
+	port := server.Port
+	if port == "" {
+		port = "3306"
+	}
+	return host + ":" + port
+}
+
 func (server *ServerMonitor) GetReplicationServerID() uint64 {
 	ss, sserr := server.GetSlaveStatus(server.ReplicationSourceName)
 	if sserr != nil {
@@ -76,6 +745,26 @@ func (server *ServerMonitor) GetReplicationServerID() uint64 {
 	return ss.MasterServerID
 }
 
+// GetConfiguredReplicationDelay returns the SQL_Delay set with MASTER_DELAY on the channel, 0 when the slave is
+// not intentionally delayed
+func (server *ServerMonitor) GetConfiguredReplicationDelay(channel string) int64 {
+	ss, sserr := server.GetSlaveStatus(channel)
+	if sserr != nil || !ss.SQLDelay.Valid {
+		return 0
+	}
+	return ss.SQLDelay.Int64
+}
+
+// GetExcessReplicationDelay returns the replication delay over the configured delay of the channel, the lag
+// alerting is based on it so a delayed slave is late only when behind its MASTER_DELAY
+func (server *ServerMonitor) GetExcessReplicationDelay() int64 {
+	delay := server.GetReplicationDelay() - server.GetConfiguredReplicationDelay(server.ReplicationSourceName)
+	if delay < 0 {
+		return 0
+	}
+	return delay
+}
+
 func (server *ServerMonitor) GetReplicationDelay() int64 {
 	ss, sserr := server.GetSlaveStatus(server.ReplicationSourceName)
 	if sserr != nil {
@@ -87,6 +776,65 @@ func (server *ServerMonitor) GetReplicationDelay() int64 {
 	return ss.SecondsBehindMaster.Int64
 }
 
+// GetEndToEndReplicationDelay sum the delays of each hop from the server up to the top master of a relay chain
+func (server *ServerMonitor) GetEndToEndReplicationDelay() (int64, error) {
+	var delay int64
+	visited := make(map[string]bool)
+	current := server
+	for {
+		if visited[current.URL] {
+			return delay, fmt.Errorf("Replication cycle detected at %s", current.URL)
+		}
+		visited[current.URL] = true
+		if current != server && current == server.ClusterGroup.master {
+			return delay, nil
+		}
+		ss, err := current.GetSlaveStatus(current.ReplicationSourceName)
+		if err != nil {
+			// reached a server that is not a slave
+			return delay, nil
+		}
+		if ss.SecondsBehindMaster.Valid == false {
+			return delay, fmt.Errorf("Unknown replication delay on %s", current.URL)
+		}
+		delay = delay + ss.SecondsBehindMaster.Int64
+		parent, err := server.ClusterGroup.GetMasterFromReplication(current)
+		if err != nil || parent == nil {
+			return delay, nil
+		}
+		current = parent
+	}
+}
+
+func (server *ServerMonitor) GetReplicationDelayDuration() time.Duration {
+	return time.Duration(server.GetReplicationDelay()) * time.Second
+}
+
+// GetReplicationDelayHuman format the replication delay and tell apart a caught up slave from
+// stopped replication threads when delay is unknown
+func (server *ServerMonitor) GetReplicationDelayHuman() string {
+	ss, sserr := server.GetSlaveStatus(server.ReplicationSourceName)
+	if sserr != nil {
+		return "not a slave"
+	}
+	if ss.SecondsBehindMaster.Valid == false {
+		if ss.SlaveIORunning.String != "Yes" && ss.SlaveSQLRunning.String != "Yes" {
+			return "replication stopped"
+		}
+		if ss.SlaveIORunning.String != "Yes" {
+			return "IO stopped"
+		}
+		if ss.SlaveSQLRunning.String != "Yes" {
+			return "SQL stopped"
+		}
+		return "unknown"
+	}
+	if ss.SecondsBehindMaster.Int64 == 0 {
+		return "caught up"
+	}
+	return (time.Duration(ss.SecondsBehindMaster.Int64) * time.Second).String()
+}
+
 func (server *ServerMonitor) GetReplicationHearbeatPeriod() float64 {
 	ss, sserr := server.GetSlaveStatus(server.ReplicationSourceName)
 	if sserr != nil {
@@ -110,6 +858,50 @@ func (server *ServerMonitor) GetReplicationUsingGtid() string {
 	}
 }
 
+// GetReplicationGtidEnabled returns true when replication use any GTID mode whatever the flavor
+func (server *ServerMonitor) GetReplicationGtidEnabled() bool {
+	if server.IsMariaDB() {
+		switch server.GetReplicationUsingGtid() {
+		case "Slave_Pos", "Current_Pos":
+			return true
+		}
+		return false
+	}
+	return server.HaveMySQLGTID
+}
+
+// GetReplicationFilters returns the replication filter variables that are set on the server and the channel
+// filters read from performance_schema on MySQL 8, a channel filter is keyed by name:channel
+func (server *ServerMonitor) GetReplicationFilters() map[string]string {
+	filters := make(map[string]string)
+	for _, name := range []string{"REPLICATE_DO_DB", "REPLICATE_IGNORE_DB", "REPLICATE_DO_TABLE", "REPLICATE_IGNORE_TABLE", "REPLICATE_WILD_DO_TABLE", "REPLICATE_WILD_IGNORE_TABLE"} {
+		if value := server.Variables[name]; value != "" {
+			filters[name] = value
+		}
+	}
+	for _, f := range server.ReplicationFilters {
+		name := strings.ToUpper(f.Name)
+		if f.Channel != "" {
+			name += ":" + f.Channel
+		}
+		filters[name] = f.Rule
+	}
+	return filters
+}
+
+func (server *ServerMonitor) GetReplicationFiltersString() string {
+	filters := server.GetReplicationFilters()
+	names := make([]string, 0, len(filters))
+	for name := range filters {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for i, name := range names {
+		names[i] = strings.ToLower(name) + "=" + filters[name]
+	}
+	return strings.Join(names, " ")
+}
+
 func (server *ServerMonitor) GetBindAddress() string {
 	if server.ClusterGroup.Conf.ProvOrchestrator == config.ConstOrchestratorSlapOS {
 		return server.Host
@@ -129,6 +921,41 @@ func (server *ServerMonitor) IsReplicationUsingGtidStrict() bool {
 	}
 }
 
+// GetGtidStrictnessRemediation returns the statements to run and the config lines to persist to switch GTID strictness,
+// MySQL gtid_mode can only be changed one step at a time
+func (server *ServerMonitor) GetGtidStrictnessRemediation(strict bool) ([]string, []string) {
+	if server.IsMariaDB() {
+		if strict {
+			return []string{"SET GLOBAL gtid_strict_mode=ON"}, []string{"gtid_strict_mode = 1"}
+		}
+		return []string{"SET GLOBAL gtid_strict_mode=OFF"}, []string{"gtid_strict_mode = 0"}
+	}
+	modes := []string{"OFF", "OFF_PERMISSIVE", "ON_PERMISSIVE", "ON"}
+	current := 0
+	for i, mode := range modes {
+		if server.Variables["GTID_MODE"] == mode {
+			current = i
+		}
+	}
+	var statements []string
+	if strict {
+		if server.Variables["ENFORCE_GTID_CONSISTENCY"] != "ON" {
+			statements = append(statements, "SET GLOBAL enforce_gtid_consistency=ON")
+		}
+		for i := current + 1; i < len(modes); i++ {
+			statements = append(statements, "SET GLOBAL gtid_mode="+modes[i])
+		}
+		return statements, []string{"gtid_mode = ON", "enforce_gtid_consistency = ON"}
+	}
+	for i := current - 1; i >= 0; i-- {
+		statements = append(statements, "SET GLOBAL gtid_mode="+modes[i])
+	}
+	if server.Variables["ENFORCE_GTID_CONSISTENCY"] != "OFF" {
+		statements = append(statements, "SET GLOBAL enforce_gtid_consistency=OFF")
+	}
+	return statements, []string{"gtid_mode = OFF", "enforce_gtid_consistency = OFF"}
+}
+
 func (server *ServerMonitor) GetReplicationMasterHost() string {
 	ss, sserr := server.GetSlaveStatus(server.ReplicationSourceName)
 	if sserr != nil {
@@ -155,16 +982,50 @@ func (server *ServerMonitor) GetSibling() *ServerMonitor {
 		if err != nil {
 			continue
 		}
-		if sssib.MasterServerID == ssserver.MasterServerID && sl.ServerID != server.ServerID {
-			return sl
+		if sssib.MasterServerID == ssserver.MasterServerID && sl.ServerID != server.ServerID {
+			return sl
+		}
+	}
+	return nil
+}
+
+// GetSiblings returns all the servers replicating from the same master server id
+func (server *ServerMonitor) GetSiblings() serverList {
+	var siblings serverList
+	ssserver, err := server.GetSlaveStatus(server.ReplicationSourceName)
+	if err != nil {
+		return siblings
+	}
+	for _, sl := range server.ClusterGroup.Servers {
+		sssib, err := sl.GetSlaveStatus(sl.ReplicationSourceName)
+		if err != nil {
+			continue
+		}
+		if sssib.MasterServerID == ssserver.MasterServerID && sl.ServerID != server.ServerID {
+			siblings = append(siblings, sl)
+		}
+	}
+	return siblings
+}
+
+// GetBestSibling returns the least delayed sibling without replication error, nil when none is usable
+func (server *ServerMonitor) GetBestSibling() *ServerMonitor {
+	var best *ServerMonitor
+	for _, sl := range server.GetSiblings() {
+		if sl.IsFailed() || sl.IsReplicationBroken() || sl.HasReplicationError() {
+			continue
+		}
+		if best == nil || sl.GetReplicationDelay() < best.GetReplicationDelay() {
+			best = sl
 		}
 	}
-	return nil
+	return best
 }
 
 func (server *ServerMonitor) GetSlaveStatus(name string) (*dbhelper.SlaveStatus, error) {
-	if server.Replications != nil {
-		for _, ss := range server.Replications {
+	replications := server.GetAllSlavesStatus()
+	if replications != nil {
+		for _, ss := range replications {
 			if ss.ConnectionName.String == name {
 				return &ss, nil
 			}
@@ -187,6 +1048,9 @@ func (server *ServerMonitor) GetSlaveStatusLastSeen(name string) (*dbhelper.Slav
 }
 
 func (server *ServerMonitor) GetAllSlavesStatus() []dbhelper.SlaveStatus {
+	if server.ReplicationStatus != nil {
+		return server.ReplicationStatus.GetReplications()
+	}
 	return server.Replications
 }
 
@@ -249,11 +1113,53 @@ func (server *ServerMonitor) GetVariables() []dbhelper.Variable {
 	return variables
 }
 
+// ExportVariables write the server variables to path, a .json extension produce a JSON document,
+// any other extension a my.cnf style file
+func (server *ServerMonitor) ExportVariables(path string) error {
+	variables := server.GetVariables()
+	flavor := ""
+	if server.DBVersion != nil {
+		flavor = server.DBVersion.Flavor
+	}
+	date := time.Now().Format(time.RFC3339)
+	var content []byte
+	if filepath.Ext(path) == ".json" {
+		type Export struct {
+			Server    string              `json:"server"`
+			Flavor    string              `json:"flavor"`
+			Version   string              `json:"version"`
+			Date      string              `json:"date"`
+			Variables []dbhelper.Variable `json:"variables"`
+		}
+		var err error
+		content, err = json.MarshalIndent(Export{Server: server.URL, Flavor: flavor, Version: server.Variables["VERSION"], Date: date, Variables: variables}, "", "\t")
+		if err != nil {
+			return err
+		}
+	} else {
+		var s strings.Builder
+		fmt.Fprintf(&s, "# Variables export of %s\n# Version: %s %s\n# Date: %s\n[mysqld]\n", server.URL, flavor, server.Variables["VERSION"], date)
+		for _, v := range variables {
+			value := v.Value
+			if value == "" || strings.ContainsAny(value, " \t#") {
+				value = strconv.Quote(value)
+			}
+			fmt.Fprintf(&s, "%s = %s\n", strings.ToLower(v.Variable_name), value)
+		}
+		content = []byte(s.String())
+	}
+	return ioutil.WriteFile(path, content, 0644)
+}
+
 func (server *ServerMonitor) GetQueryFromPFSDigest(digest string) (string, string, error) {
 	for _, v := range server.PFSQueries {
 		//server.ClusterGroup.LogPrintf(LvlInfo, "Status %s %s", digest, v.Digest)
 		if v.Digest == digest {
-			return v.Schema_name, v.Query, nil
+			// history sample may be missing or a prepared statement text
+			if v.Query == "" {
+				return v.Schema_name, dbhelper.GetQueryWithLiterals(v.Digest_text), nil
+			}
+			return v.Schema_name, dbhelper.GetQueryWithLiterals(v.Query), nil
 		}
 	}
 	return "", "", errors.New("Query digest not found in PFS")
@@ -343,6 +1249,156 @@ func (server *ServerMonitor) GetStatusDelta() []dbhelper.Variable {
 	return delta
 }
 
+// statusGauges are the status variables compared by value instead of per second rate
+var statusGauges = map[string]bool{
+	"THREADS_RUNNING":               true,
+	"THREADS_CONNECTED":             true,
+	"THREADS_CACHED":                true,
+	"OPEN_FILES":                    true,
+	"OPEN_TABLES":                   true,
+	"INNODB_ROW_LOCK_CURRENT_WAITS": true,
+	"SLAVE_OPEN_TEMP_TABLES":        true,
+}
+
+// GetPurgeLagStatus returns the innodb history list length, its growth over the last polls and the oldest
+// active transaction, alerting when the length is over monitoring-innodb-purge-lag-threshold
+func (server *ServerMonitor) GetPurgeLagStatus() PurgeLagStatus {
+	p := PurgeLagStatus{
+		History:           server.historyListLengths,
+		OldestActiveTrxID: server.EngineInnoDB["oldest_active_trx_id"],
+		Threshold:         server.ClusterGroup.Conf.MonitorInnoDBPurgeLagThreshold,
+	}
+	p.OldestActiveTrxSeconds, _ = strconv.ParseInt(server.EngineInnoDB["oldest_active_trx_seconds"], 10, 64)
+	if n := len(server.historyListLengths); n > 0 {
+		p.HistoryListLength = server.historyListLengths[n-1]
+		p.Trend = p.HistoryListLength - server.historyListLengths[0]
+	}
+	p.Alert = p.Threshold > 0 && p.HistoryListLength > p.Threshold
+	return p
+}
+
+// GetConnectionStormDetector returns the connected threads jump and rate of change between the last two polls
+func (server *ServerMonitor) GetConnectionStormDetector() ConnectionStorm {
+	c := ConnectionStorm{
+		JumpThreshold: server.ClusterGroup.Conf.MonitorConnectionStormJump,
+		RateThreshold: server.ClusterGroup.Conf.MonitorConnectionStormRate,
+		History:       server.connectionSamples,
+	}
+	n := len(server.connectionSamples)
+	if n == 0 {
+		c.History = []ConnectionSample{}
+		return c
+	}
+	last := server.connectionSamples[n-1]
+	c.Connected = last.Connected
+	c.Running = last.Running
+	if n < 2 {
+		return c
+	}
+	prev := server.connectionSamples[n-2]
+	c.Jump = last.Connected - prev.Connected
+	if elapsed := float64(last.Time-prev.Time) / 1000; elapsed > 0 {
+		c.Rate = float64(c.Jump) / elapsed
+	}
+	c.Storm = (c.JumpThreshold > 0 && c.Jump > c.JumpThreshold) || (c.RateThreshold > 0 && c.Rate > float64(c.RateThreshold))
+	return c
+}
+
+// GetThreadPoolStats returns the thread pool size, threads and queue gathered at the last monitoring loop, a
+// server not running thread_handling=pool-of-threads returns a disabled pool
+func (server *ServerMonitor) GetThreadPoolStats() ThreadPoolStats {
+	tp := server.ThreadPool
+	if tp.Groups == nil {
+		tp.Groups = []dbhelper.ThreadPoolGroup{}
+	}
+	return tp
+}
+
+// refreshThreadPoolStats gathers the thread pool from the status and the thread groups in the monitoring loop so
+// that the API and the metrics scrapes do not query the server
+func (server *ServerMonitor) refreshThreadPoolStats() {
+	if server.Variables["THREAD_HANDLING"] != "pool-of-threads" || server.DBVersion == nil || !server.DBVersion.IsMariaDB() {
+		server.ThreadPool = ThreadPoolStats{Groups: []dbhelper.ThreadPoolGroup{}}
+		return
+	}
+	groups, logs, err := dbhelper.GetThreadPoolGroups(server.Conn, server.DBVersion)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Can't fetch thread pool groups %s", err)
+	server.ThreadPool = getThreadPoolStats(server.Variables, server.Status, groups)
+}
+
+func getThreadPoolStats(variables map[string]string, status map[string]string, groups []dbhelper.ThreadPoolGroup) ThreadPoolStats {
+	tp := ThreadPoolStats{Enabled: true, Groups: []dbhelper.ThreadPoolGroup{}}
+	tp.Size, _ = strconv.ParseInt(variables["THREAD_POOL_SIZE"], 10, 64)
+	tp.Threads, _ = strconv.ParseInt(status["THREADPOOL_THREADS"], 10, 64)
+	tp.IdleThreads, _ = strconv.ParseInt(status["THREADPOOL_IDLE_THREADS"], 10, 64)
+	tp.ActiveThreads = tp.Threads - tp.IdleThreads
+	if len(groups) == 0 {
+		return tp
+	}
+	tp.Groups = groups
+	tp.ActiveThreads = 0
+	for _, g := range groups {
+		tp.ActiveThreads += g.Active_threads
+		tp.QueueLength += g.Queue_length
+		if g.Is_stalled {
+			tp.StalledGroups++
+		}
+	}
+	return tp
+}
+
+// GetStatusAnomalies returns the status from monitoring-status-anomaly-thresholds whose per second rate since the
+// previous monitoring loop, or value for gauges, is over the threshold
+func (server *ServerMonitor) GetStatusAnomalies() []StatusAnomaly {
+	var anomalies []StatusAnomaly
+	for _, a := range server.getStatusAnomalies() {
+		if a.Anomalous {
+			anomalies = append(anomalies, a)
+		}
+	}
+	return anomalies
+}
+
+func (server *ServerMonitor) getStatusAnomalies() []StatusAnomaly {
+	var res []StatusAnomaly
+	elapsed := server.MonitorTime - server.PrevMonitorTime
+	for _, pair := range strings.Split(server.ClusterGroup.Conf.MonitorStatusAnomalyThresholds, ",") {
+		name, value := misc.SplitPair(strings.TrimSpace(pair))
+		threshold, err := strconv.ParseFloat(value, 64)
+		if name == "" || err != nil {
+			continue
+		}
+		a := StatusAnomaly{Name: strings.ToUpper(name), Threshold: threshold}
+		if statusGauges[a.Name] {
+			if _, ok := server.Status[a.Name]; !ok {
+				continue
+			}
+			a.Rate, _ = strconv.ParseFloat(server.Status[a.Name], 64)
+		} else {
+			if elapsed <= 0 || server.PrevStatus[a.Name] == "" {
+				continue
+			}
+			a.Rate = float64(server.GetStatusDeltaValue(a.Name)) / float64(elapsed)
+		}
+		a.Anomalous = a.Rate > a.Threshold
+		res = append(res, a)
+	}
+	return res
+}
+
+// GetStatusDeltaValue returns the increase of a status counter since the previous monitoring loop
+func (server *ServerMonitor) GetStatusDeltaValue(key string) int64 {
+	cur, err := strconv.ParseInt(server.Status[key], 10, 64)
+	if err != nil {
+		return 0
+	}
+	prev, err := strconv.ParseInt(server.PrevStatus[key], 10, 64)
+	if err != nil || cur < prev {
+		return 0
+	}
+	return cur - prev
+}
+
 func (server *ServerMonitor) GetErrorLog() s18log.HttpLog {
 	return server.ErrorLog
 }
@@ -445,35 +1501,56 @@ func (server *ServerMonitor) GetNewDBConn() (*sqlx.DB, error) {
 		return sqlx.Connect("postgres", server.DSN)
 
 	}
-	conn, err := sqlx.Connect("mysql", server.DSN)
-	if err != nil && server.ClusterGroup.HaveDBTLSCert {
-		// Possible can't connect because of SSL key rotation try old key until server rebooted or key reloaded
-		server.TLSConfigUsed = ConstTLSOldConfig
-		server.SetDSN()
-		conn, err := sqlx.Connect("mysql", server.DSN)
-		if err == nil {
-			server.ClusterGroup.SetState("ERR00080", state.State{ErrType: LvlErr, ErrDesc: fmt.Sprintf(clusterError["ERR00080"], server.URL), ServerUrl: server.URL, ErrFrom: "MON"})
-		} else {
-			server.TLSConfigUsed = ConstTLSNoConfig
+	// Possible can't connect because of SSL key rotation try old key until server rebooted or key reloaded
+	// if not –require_secure_transport can still connect with no certificate MDEV-13362
+	ladder := []string{ConstTLSCurrentConfig}
+	if server.ClusterGroup.HaveDBTLSCert {
+		ladder = append(ladder, ConstTLSOldConfig, ConstTLSNoConfig)
+	}
+	if server.ClusterGroup.Conf.MonitorConnectRetry >= 0 && len(ladder) > server.ClusterGroup.Conf.MonitorConnectRetry+1 {
+		ladder = ladder[:server.ClusterGroup.Conf.MonitorConnectRetry+1]
+	}
+	var conn *sqlx.DB
+	var err error
+	for i, tlsconfig := range ladder {
+		if i > 0 {
+			// wait before retrying to not storm a server that is restarting
+			time.Sleep(getConnectBackoff(i, time.Duration(server.ClusterGroup.Conf.MonitorConnectBackoff)*time.Millisecond, rand.Float64))
+			server.TLSConfigUsed = tlsconfig
 			server.SetDSN()
-			conn, err := sqlx.Connect("mysql", server.DSN)
-			if err == nil {
-				// if not –require_secure_transport can still connect with no certificate MDEV-13362
-				//server.ClusterGroup.SetState("ERR00081", state.State{ErrType: LvlErr, ErrDesc: fmt.Sprintf(clusterError["ERR00081"], server.URL), ServerUrl: server.URL, ErrFrom: "MON"})
+		}
+		conn, err = sqlx.Connect("mysql", server.DSN)
+		if err == nil {
+			if tlsconfig == ConstTLSOldConfig {
+				server.ClusterGroup.SetState("ERR00080", state.State{ErrType: LvlErr, ErrDesc: fmt.Sprintf(clusterError["ERR00080"], server.URL), ServerUrl: server.URL, ErrFrom: "MON"})
+				conn.SetConnMaxLifetime(3595 * time.Second)
 			}
-			server.TLSConfigUsed = ConstTLSCurrentConfig
-			server.SetDSN()
-			return conn, err
+			break
 		}
-		//reset DNS in case the server is restarted
+	}
+	if err != nil && len(ladder) > 1 {
+		server.ClusterGroup.SetState("WARN0113", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0113"], server.URL, len(ladder), err), ServerUrl: server.URL, ErrFrom: "MON"})
+	}
+	//reset DNS in case the server is restarted
+	if server.TLSConfigUsed != ConstTLSCurrentConfig {
 		server.TLSConfigUsed = ConstTLSCurrentConfig
-		conn.SetConnMaxLifetime(3595 * time.Second)
 		server.SetDSN()
-		return conn, err
 	}
-
 	return conn, err
+}
 
+// getConnectBackoff returns the delay before the given connection retry, the
+// base delay doubled on each retry with equal jitter so that concurrent
+// monitors do not retry in step: the delay is within [d/2, d] for d = base*2^(retry-1)
+func getConnectBackoff(retry int, base time.Duration, rnd func() float64) time.Duration {
+	if retry < 1 || base <= 0 {
+		return 0
+	}
+	if retry > 16 {
+		retry = 16
+	}
+	d := base << uint(retry-1)
+	return d/2 + time.Duration(rnd()*float64(d/2))
 }
 
 func (server *ServerMonitor) GetSlowLogTable() {
@@ -498,33 +1575,170 @@ func (server *ServerMonitor) GetSlowLogTable() {
 	}
 	defer f.Close()
 
-	slowqueries := []dbhelper.LogSlow{}
+	slowqueries, err := server.getSlowLogTableQueries()
+	if err != nil {
+		server.ClusterGroup.LogPrintf(LvlErr, "Could not get slow queries from table %s", err)
+	}
+	for _, s := range slowqueries {
+		writeSlowLogEntry(f, slowLogEntry{
+			Timestamp:    s.Start_time,
+			UserHost:     s.User_host.String,
+			ThreadID:     s.Thread_id,
+			Schema:       s.Db.String,
+			QueryTime:    s.Query_time,
+			LockTime:     s.Lock_time,
+			RowsSent:     uint64(s.Rows_sent),
+			RowsExamined: uint64(s.Rows_examined),
+			RowsAffected: uint64(s.Rows_affected),
+			Query:        s.Sql_text.String,
+		})
+	}
+	server.ExecQueryNoBinLog("TRUNCATE mysql.slow_log")
+}
+
+type slowLogEntry struct {
+	Time         string
+	Timestamp    int64
+	UserHost     string
+	ThreadID     int64
+	Schema       string
+	QueryTime    string
+	LockTime     string
+	RowsSent     uint64
+	RowsExamined uint64
+	RowsAffected uint64
+	Query        string
+}
+
+// writeSlowLogEntry writes an entry in the MariaDB slow query log format understood by pt-query-digest
+func writeSlowLogEntry(w io.Writer, e slowLogEntry) {
+	if e.Time != "" {
+		fmt.Fprintf(w, "# Time: %s\n", e.Time)
+	}
+	fmt.Fprintf(w, "# User@Host: %s\n# Thread_id: %d  Schema: %s  QC_hit: No\n# Query_time: %s  Lock_time: %s  Rows_sent: %d  Rows_examined: %d\n# Rows_affected: %d\n",
+		e.UserHost,
+		e.ThreadID,
+		e.Schema,
+		e.QueryTime,
+		e.LockTime,
+		e.RowsSent,
+		e.RowsExamined,
+		e.RowsAffected,
+	)
+	if e.Timestamp > 0 {
+		fmt.Fprintf(w, "SET timestamp=%d;\n", e.Timestamp)
+	}
+	fmt.Fprintf(w, "%s;\n", strings.TrimRight(strings.Replace(strings.Replace(e.Query, "\r\n", " ", -1), "\n", " ", -1), ";"))
+}
+
+// ExportSlowLogPTFormat writes the slow log buffer, oldest query first, as a slow query log file for pt-query-digest
+func (server *ServerMonitor) ExportSlowLogPTFormat(path string) error {
+	f, err := os.Create(path)
+	if err != nil {
+		server.ClusterGroup.LogPrintf(LvlErr, "Error exporting slow queries %s", err)
+		return err
+	}
+	defer f.Close()
+	server.SlowLog.L.Lock()
+	buffer := make([]s18log.SlowMessage, len(server.SlowLog.Buffer))
+	copy(buffer, server.SlowLog.Buffer)
+	server.SlowLog.L.Unlock()
+	writeSlowLogPTFormat(f, server.URL, server.Port, buffer)
+	return nil
+}
+
+func writeSlowLogPTFormat(w io.Writer, url string, port string, buffer []s18log.SlowMessage) {
+	fmt.Fprintf(w, "replication-manager export of %s, Version: slow log buffer. started with:\nTcp port: %s  Unix socket: \nTime                 Id Command    Argument\n", url, port)
+	for i := len(buffer) - 1; i >= 0; i-- {
+		m := buffer[i]
+		if m.Query == "" {
+			continue
+		}
+		e := slowLogEntry{
+			Time:         m.Timestamp,
+			UserHost:     m.User + "[" + m.User + "] @  [" + m.Host + "]",
+			ThreadID:     int64(m.NumberMetrics[misc.Camelcase("Thread_id")]),
+			Schema:       m.Db,
+			QueryTime:    strconv.FormatFloat(m.TimeMetrics[misc.Camelcase("Query_time")], 'f', 6, 64),
+			LockTime:     strconv.FormatFloat(m.TimeMetrics[misc.Camelcase("Lock_time")], 'f', 6, 64),
+			RowsSent:     m.NumberMetrics[misc.Camelcase("Rows_sent")],
+			RowsExamined: m.NumberMetrics[misc.Camelcase("Rows_examined")],
+			RowsAffected: m.NumberMetrics[misc.Camelcase("Rows_affected")],
+			Query:        m.Query,
+		}
+		if m.Admin {
+			e.Query = "# administrator command: " + m.Query
+		}
+		writeSlowLogEntry(w, e)
+	}
+}
+
+// GetSlowLogTableCSV is the CSV flavor of GetSlowLogTable for external tools ingestion
+func (server *ServerMonitor) GetSlowLogTableCSV() {
+	if server.ClusterGroup.IsInFailover() {
+		return
+	}
+	if !server.HasLogsInSystemTables() {
+		return
+	}
+	if server.IsDown() {
+		return
+	}
+	f, err := os.OpenFile(server.Datadir+"/log/log_slow_query.csv", os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		server.ClusterGroup.LogPrintf(LvlErr, "Error writing slow queries %s", err)
+		return
+	}
+	fi, _ := f.Stat()
+	if fi.Size() > 100000000 {
+		f.Truncate(0)
+		f.Seek(0, 0)
+		fi, _ = f.Stat()
+	}
+	defer f.Close()
+
+	slowqueries, err := server.getSlowLogTableQueries()
+	if err != nil {
+		server.ClusterGroup.LogPrintf(LvlErr, "Could not get slow queries from table %s", err)
+	}
+	err = writeSlowLogCSV(f, slowqueries, fi.Size() == 0)
+	if err != nil {
+		server.ClusterGroup.LogPrintf(LvlErr, "Error writing slow queries %s", err)
+		return
+	}
+	server.ExecQueryNoBinLog("TRUNCATE mysql.slow_log")
+}
 
+func (server *ServerMonitor) getSlowLogTableQueries() ([]dbhelper.LogSlow, error) {
+	slowqueries := []dbhelper.LogSlow{}
+	var err error
 	if server.DBVersion.IsMySQLOrPercona() {
 		err = server.Conn.Select(&slowqueries, "SELECT FLOOR(UNIX_TIMESTAMP(start_time)) as start_time, user_host,TIME_TO_SEC(query_time) AS query_time,TIME_TO_SEC(lock_time) AS lock_time,rows_sent,rows_examined,db,last_insert_id,insert_id,server_id,sql_text,thread_id, 0 as rows_affected FROM  mysql.slow_log")
 	} else {
 		err = server.Conn.Select(&slowqueries, "SELECT FLOOR(UNIX_TIMESTAMP(start_time)) as start_time, user_host,TIME_TO_SEC(query_time) AS query_time,TIME_TO_SEC(lock_time) AS lock_time,rows_sent,rows_examined,db,last_insert_id,insert_id,server_id,sql_text,thread_id,rows_affected FROM  mysql.slow_log")
 	}
+	return slowqueries, err
+}
 
-	if err != nil {
-		server.ClusterGroup.LogPrintf(LvlErr, "Could not get slow queries from table %s", err)
+func writeSlowLogCSV(w io.Writer, slowqueries []dbhelper.LogSlow, header bool) error {
+	cw := csv.NewWriter(w)
+	if header {
+		cw.Write([]string{"start_time", "user_host", "query_time", "lock_time", "rows_sent", "rows_examined", "db", "digest"})
 	}
 	for _, s := range slowqueries {
-
-		fmt.Fprintf(f, "# User@Host: %s\n# Thread_id: %d  Schema: %s  QC_hit: No\n# Query_time: %s  Lock_time: %s  Rows_sent: %d  Rows_examined: %d\n# Rows_affected: %d\nSET timestamp=%d;\n%s;\n",
+		cw.Write([]string{
+			strconv.FormatInt(s.Start_time, 10),
 			s.User_host.String,
-			s.Thread_id,
-			s.Db.String,
 			s.Query_time,
 			s.Lock_time,
-			s.Rows_sent,
-			s.Rows_examined,
-			s.Rows_affected,
-			s.Start_time,
-			strings.Replace(strings.Replace(s.Sql_text.String, "\r\n", " ", -1), "\n", " ", -1),
-		)
+			strconv.Itoa(s.Rows_sent),
+			strconv.Itoa(s.Rows_examined),
+			s.Db.String,
+			dbhelper.GetQueryDigest(s.Sql_text.String),
+		})
 	}
-	server.ExecQueryNoBinLog("TRUNCATE mysql.slow_log")
+	cw.Flush()
+	return cw.Error()
 }
 
 func (server *ServerMonitor) GetTables() []dbhelper.Table {
@@ -544,33 +1758,355 @@ func (server *ServerMonitor) GetDictTables() []dbhelper.Table {
 		tables = append(tables, t)
 
 	}
-	sort.Sort(dbhelper.TableSizeSorter(tables))
-	return tables
+	sort.Sort(dbhelper.TableSizeSorter(tables))
+	return tables
+}
+
+// GetTablesByEngine returns dictionary tables using the storage engine, sorted by size
+func (server *ServerMonitor) GetTablesByEngine(engine string) []dbhelper.Table {
+	var tables []dbhelper.Table
+	for _, t := range server.GetDictTables() {
+		if strings.EqualFold(t.Engine, engine) {
+			tables = append(tables, t)
+		}
+	}
+	return tables
+}
+
+// GetTableAccessStats returns per table read and write counts since server start from performance_schema
+func (server *ServerMonitor) GetTableAccessStats() []dbhelper.TableAccess {
+	if !server.HasLogPFS() {
+		return nil
+	}
+	ta, logs, err := dbhelper.GetTableAccessStats(server.Conn, server.DBVersion)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Can't fetch table access statistics %s", err)
+	dict := server.DictTables
+	if len(dict) == 0 && server.ClusterGroup.master != nil {
+		dict = server.ClusterGroup.master.DictTables
+	}
+	for i, t := range ta {
+		if d, ok := dict[t.Table_schema+"."+t.Table_name]; ok {
+			ta[i].Data_length = d.Data_length
+			ta[i].Index_length = d.Index_length
+		}
+	}
+	return ta
+}
+
+// GetUnusedTables returns tables never read or written, largest first, counters are only trusted once
+// the server uptime reach sinceUptime
+func (server *ServerMonitor) GetUnusedTables(sinceUptime time.Duration) []dbhelper.TableAccess {
+	var unused []dbhelper.TableAccess
+	uptime, _ := strconv.ParseInt(server.Status["UPTIME"], 10, 64)
+	if time.Duration(uptime)*time.Second < sinceUptime {
+		return unused
+	}
+	for _, t := range server.GetTableAccessStats() {
+		if t.Count_read == 0 && t.Count_write == 0 {
+			unused = append(unused, t)
+		}
+	}
+	sort.Slice(unused, func(i, j int) bool {
+		return unused[i].Data_length+unused[i].Index_length > unused[j].Data_length+unused[j].Index_length
+	})
+	return unused
+}
+
+func (server *ServerMonitor) GetDeadlockHistory() []dbhelper.Deadlock {
+	return server.DeadlockHistory
+}
+
+// GetBufferPoolStats returns the per instance buffer pool stats collected with the engine innodb status
+func (server *ServerMonitor) GetBufferPoolStats() []dbhelper.BufferPoolStats {
+	return server.BufferPoolStats
+}
+
+// GetBufferPoolHitRate returns the hit rate aggregated over all buffer pool instances
+func (server *ServerMonitor) GetBufferPoolHitRate() float64 {
+	return getBufferPoolHitRate(server.BufferPoolStats)
+}
+
+func getBufferPoolHitRate(stats []dbhelper.BufferPoolStats) float64 {
+	var get, read int64
+	for _, bp := range stats {
+		get += bp.Number_pages_get
+		read += bp.Number_pages_read
+	}
+	if get == 0 {
+		return 1
+	}
+	return 1 - float64(read)/float64(get)
+}
+
+func (server *ServerMonitor) GetInnoDBStatus() []dbhelper.Variable {
+	var status []dbhelper.Variable
+	for k, v := range server.EngineInnoDB {
+		var r dbhelper.Variable
+		r.Variable_name = k
+		r.Value = v
+		status = append(status, r)
+	}
+	sort.Sort(dbhelper.VariableSorter(status))
+	return status
+
+}
+
+func (server *ServerMonitor) GetTableDefinition(schema string, table string) (string, error) {
+	query := "SHOW CREATE TABLE `" + schema + "`.`" + table + "`"
+	var tbl, ddl string
+
+	err := server.Conn.QueryRowx(query).Scan(&tbl, &ddl)
+	if err != nil {
+		server.ClusterGroup.LogPrintf(LvlErr, "Failed query %s %s", query, err)
+		return "", err
+	}
+	return ddl, nil
+}
+
+// GetTableDefinitionSummary returns the table definition with the partition list replaced by the number of partitions
+func (server *ServerMonitor) GetTableDefinitionSummary(schema string, table string) (string, error) {
+	ddl, err := server.GetTableDefinition(schema, table)
+	if err != nil {
+		return "", err
+	}
+	return dbhelper.SummarizeDDLPartitions(ddl), nil
+}
+
+func (server *ServerMonitor) GetTablePartitions(schema string, table string) ([]dbhelper.TablePartition, error) {
+	partitions, logs, err := dbhelper.GetTablePartitions(server.Conn, schema, table)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get partitions of %s.%s %s %s", schema, table, server.URL, err)
+	return partitions, err
+}
+
+func (server *ServerMonitor) GetTableColumns(schema string, table string) ([]dbhelper.TableColumn, error) {
+	cols, logs, err := dbhelper.GetTableColumns(server.Conn, server.DBVersion, schema, table)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get columns of %s.%s %s %s", schema, table, server.URL, err)
+	return cols, err
+}
+
+// GetTableColumnFlags returns whether the table uses virtual or stored generated columns and whether it uses
+// invisible columns, both change what a row image or a SELECT * returns
+func (server *ServerMonitor) GetTableColumnFlags(schema string, table string) (bool, bool, error) {
+	cols, err := server.GetTableColumns(schema, table)
+	if err != nil {
+		return false, false, err
+	}
+	generated, invisible := getColumnFlags(cols)
+	return generated, invisible, nil
+}
+
+func getColumnFlags(cols []dbhelper.TableColumn) (bool, bool) {
+	generated, invisible := false, false
+	for _, col := range cols {
+		if col.Generated != "" {
+			generated = true
+		}
+		if col.Invisible {
+			invisible = true
+		}
+	}
+	return generated, invisible
+}
+
+// GetTablesWithoutPrimaryKey returns the monitored tables of a schema having no primary key, row based
+// replication does a full table scan per row event applied on such tables
+func (server *ServerMonitor) GetTablesWithoutPrimaryKey(schema string) ([]string, error) {
+	pks, err := server.GetTablePKs(schema)
+	if err != nil {
+		return nil, err
+	}
+	var tables []string
+	for _, t := range server.Tables {
+		if t.Table_schema == schema && len(pks[t.Table_name]) == 0 {
+			tables = append(tables, t.Table_name)
+		}
+	}
+	sort.Strings(tables)
+	return tables, nil
+}
+
+// SuggestPrimaryKey proposes the ALTER statement giving a primary key to a table, promoting the smallest unique
+// index on not null columns or else adding an auto increment column, nothing is executed
+func (server *ServerMonitor) SuggestPrimaryKey(schema string, table string) (PrimaryKeySuggestion, error) {
+	cols, err := server.GetTableColumns(schema, table)
+	if err != nil {
+		return PrimaryKeySuggestion{}, err
+	}
+	idx, logs, err := dbhelper.GetTableUniqueIndexColumns(server.Conn, server.DBVersion, schema, table)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get unique indexes of %s.%s %s %s", schema, table, server.URL, err)
+	if err != nil {
+		return PrimaryKeySuggestion{}, err
+	}
+	return suggestPrimaryKey(schema, table, cols, idx)
+}
+
+func suggestPrimaryKey(schema string, table string, cols []dbhelper.TableColumn, idx []dbhelper.TableIndexColumn) (PrimaryKeySuggestion, error) {
+	p := PrimaryKeySuggestion{Schema: schema, Table: table}
+	if len(cols) == 0 {
+		return p, fmt.Errorf("Table %s.%s not found", schema, table)
+	}
+	columns := make(map[string]dbhelper.TableColumn)
+	for _, col := range cols {
+		columns[strings.ToLower(col.Name)] = col
+	}
+	indexes := make(map[string][]string)
+	var names []string
+	for _, i := range idx {
+		if _, ok := indexes[i.Index]; !ok {
+			names = append(names, i.Index)
+		}
+		indexes[i.Index] = append(indexes[i.Index], i.Column)
+	}
+	if _, ok := indexes["PRIMARY"]; ok {
+		return p, fmt.Errorf("Table %s.%s already has a primary key", schema, table)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		eligible := true
+		for _, c := range indexes[name] {
+			// virtual generated columns can not be part of a primary key
+			if col, ok := columns[strings.ToLower(c)]; !ok || col.Nullable || col.Generated == "VIRTUAL" {
+				eligible = false
+			}
+		}
+		if eligible && (p.Index == "" || len(indexes[name]) < len(p.Columns)) {
+			p.Index = name
+			p.Columns = indexes[name]
+		}
+	}
+	if p.Index != "" {
+		p.Statement = "ALTER TABLE `" + schema + "`.`" + table + "` DROP INDEX `" + p.Index + "`, ADD PRIMARY KEY (`" + strings.Join(p.Columns, "`,`") + "`)"
+		return p, nil
+	}
+	for _, col := range cols {
+		if strings.Contains(strings.ToLower(col.Extra), "auto_increment") {
+			// a table can only have one auto increment column
+			return p, fmt.Errorf("Table %s.%s has a non unique auto increment column %s", schema, table, col.Name)
+		}
+	}
+	for _, name := range []string{"id", "row_id", "my_row_id"} {
+		if _, ok := columns[name]; !ok {
+			p.Columns = []string{name}
+			p.Statement = "ALTER TABLE `" + schema + "`.`" + table + "` ADD COLUMN `" + name + "` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY FIRST"
+			return p, nil
+		}
+	}
+	return p, fmt.Errorf("Table %s.%s has no free name for a synthetic primary key column", schema, table)
+}
+
+// GetTablesWithMixedCollation returns the schema tables having character columns with a collation different from
+// the table default, comparing such columns needs an implicit conversion that prevents index usage
+func (server *ServerMonitor) GetTablesWithMixedCollation(schema string) ([]TableCollationMismatch, error) {
+	collations, logs, err := dbhelper.GetSchemaTableCollations(server.Conn, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get table collations of %s %s %s", schema, server.URL, err)
+	if err != nil {
+		return nil, err
+	}
+	schemaCols, logs, err := dbhelper.GetSchemaColumns(server.Conn, server.DBVersion, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get columns of %s %s %s", schema, server.URL, err)
+	if err != nil {
+		return nil, err
+	}
+	cols := make(map[string][]dbhelper.TableColumn)
+	for _, col := range schemaCols {
+		cols[col.Table] = append(cols[col.Table], col)
+	}
+	var tables []string
+	for table := range collations {
+		tables = append(tables, table)
+	}
+	sort.Strings(tables)
+	res := []TableCollationMismatch{}
+	for _, table := range tables {
+		if m, ok := getMixedCollation(table, collations[table], cols[table]); ok {
+			res = append(res, m)
+		}
+	}
+	return res, nil
 }
 
-func (server *ServerMonitor) GetInnoDBStatus() []dbhelper.Variable {
-	var status []dbhelper.Variable
-	for k, v := range server.EngineInnoDB {
-		var r dbhelper.Variable
-		r.Variable_name = k
-		r.Value = v
-		status = append(status, r)
+func getMixedCollation(table string, collation string, cols []dbhelper.TableColumn) (TableCollationMismatch, bool) {
+	m := TableCollationMismatch{Table: table, Collation: collation}
+	for _, col := range cols {
+		// numeric and temporal columns have no collation
+		if col.Collation == "" || col.Collation == collation {
+			continue
+		}
+		m.Columns = append(m.Columns, ColumnCollation{Name: col.Name, Collation: col.Collation})
 	}
-	sort.Sort(dbhelper.VariableSorter(status))
-	return status
-
+	return m, len(m.Columns) > 0
 }
 
-func (server *ServerMonitor) GetTableDefinition(schema string, table string) (string, error) {
-	query := "SHOW CREATE TABLE `" + schema + "`.`" + table + "`"
-	var tbl, ddl string
+func (server *ServerMonitor) GetTableForeignKeys(schema string) ([]dbhelper.ForeignKey, error) {
+	fks, logs, err := dbhelper.GetTableForeignKeys(server.Conn, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get foreign keys of %s %s %s", schema, server.URL, err)
+	return fks, err
+}
 
-	err := server.Conn.QueryRowx(query).Scan(&tbl, &ddl)
+// GetTableDependencyOrder returns the schema tables ordered so that referenced tables come before the tables
+// referencing them, the create and restore order, drop in reverse order. Foreign key cycles return an error
+func (server *ServerMonitor) GetTableDependencyOrder(schema string) ([]string, error) {
+	tables, logs, err := dbhelper.GetSchemaTableNames(server.Conn, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get tables of %s %s %s", schema, server.URL, err)
 	if err != nil {
-		server.ClusterGroup.LogPrintf(LvlErr, "Failed query %s %s", query, err)
-		return "", err
+		return nil, err
 	}
-	return ddl, nil
+	fks, err := server.GetTableForeignKeys(schema)
+	if err != nil {
+		return nil, err
+	}
+	return getTableDependencyOrder(schema, tables, fks)
+}
+
+func getTableDependencyOrder(schema string, tables []string, fks []dbhelper.ForeignKey) ([]string, error) {
+	refs := make(map[string]map[string]bool)
+	referencedBy := make(map[string][]string)
+	for _, t := range tables {
+		refs[t] = make(map[string]bool)
+	}
+	for _, fk := range fks {
+		if fk.RefSchema != schema || fk.RefTable == fk.Table {
+			continue
+		}
+		if _, ok := refs[fk.Table]; !ok {
+			continue
+		}
+		if _, ok := refs[fk.RefTable]; !ok || refs[fk.Table][fk.RefTable] {
+			continue
+		}
+		refs[fk.Table][fk.RefTable] = true
+		referencedBy[fk.RefTable] = append(referencedBy[fk.RefTable], fk.Table)
+	}
+	var ready []string
+	for _, t := range tables {
+		if len(refs[t]) == 0 {
+			ready = append(ready, t)
+		}
+	}
+	var order []string
+	for len(ready) > 0 {
+		sort.Strings(ready)
+		t := ready[0]
+		ready = ready[1:]
+		order = append(order, t)
+		for _, child := range referencedBy[t] {
+			delete(refs[child], t)
+			if len(refs[child]) == 0 {
+				ready = append(ready, child)
+			}
+		}
+	}
+	if len(order) < len(tables) {
+		var cycle []string
+		for _, t := range tables {
+			if len(refs[t]) > 0 {
+				cycle = append(cycle, t)
+			}
+		}
+		sort.Strings(cycle)
+		return order, fmt.Errorf("Foreign key cycle between tables %s", strings.Join(cycle, ", "))
+	}
+	return order, nil
 }
 
 func (server *ServerMonitor) GetTablePK(schema string, table string) (string, error) {
@@ -584,6 +2120,28 @@ func (server *ServerMonitor) GetTablePK(schema string, table string) (string, er
 	return pk, nil
 }
 
+// GetTablePKs returns primary key columns of all schema tables in a single round trip,
+// tables without primary key are not in the map
+func (server *ServerMonitor) GetTablePKs(schema string) (map[string][]string, error) {
+	pks := make(map[string][]string)
+	query := "SELECT TABLE_NAME, COLUMN_NAME from information_schema.KEY_COLUMN_USAGE WHERE CONSTRAINT_NAME='PRIMARY' AND CONSTRAINT_SCHEMA='" + schema + "' ORDER BY TABLE_NAME, ORDINAL_POSITION"
+	rows, err := server.Conn.Queryx(query)
+	if err != nil {
+		server.ClusterGroup.LogPrintf(LvlErr, "Failed query %s %s", query, err)
+		return pks, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, column string
+		err = rows.Scan(&table, &column)
+		if err != nil {
+			return pks, err
+		}
+		pks[table] = append(pks[table], column)
+	}
+	return pks, rows.Err()
+}
+
 func (server *ServerMonitor) IsFilterInTags(filter string) bool {
 	tags := server.ClusterGroup.GetDatabaseTags()
 	for _, tag := range tags {
@@ -641,10 +2199,31 @@ func (server *ServerMonitor) GetDatabaseClientBasedir() string {
 	return "/usr/bin/mysql"
 }
 
-func (server *ServerMonitor) GetDatabaseConfig() string {
-	type File struct {
-		Path    string `json:"path"`
-		Content string `json:"fmt"`
+type databaseConfigFile struct {
+	Path    string
+	Content string
+	Write   bool
+}
+
+type databaseConfigLink struct {
+	Symlink string `json:"symlink"`
+	Target  string `json:"target"`
+}
+
+// GenerateDatabaseConfig write the config tree and tarball of the server and returns the manifest of the tarball,
+// generation is skipped and false returned when the resolved config did not change since the last generation
+// unless force is set
+func (server *ServerMonitor) GenerateDatabaseConfig(force bool) (ConfigManifest, bool, error) {
+	files, links := server.getDatabaseConfigFiles()
+	hash := server.getDatabaseConfigHash(files, links)
+	if !force && hash == server.DatabaseConfigHash {
+		_, errtar := os.Stat(server.Datadir + "/config.tar.gz")
+		_, errinit := os.Stat(server.Datadir + "/init")
+		if errtar == nil && errinit == nil {
+			server.ClusterGroup.LogPrintf(LvlDbg, "Database Config not changed %s", server.Datadir+"/config.tar.gz")
+			m, err := server.readDatabaseConfigManifest()
+			return m, false, err
+		}
 	}
 	server.ClusterGroup.LogPrintf(LvlInfo, "Database Config generation "+server.Datadir+"/config.tar.gz")
 	// Extract files
@@ -665,27 +2244,115 @@ func (server *ServerMonitor) GetDatabaseConfig() string {
 	} else {
 		os.RemoveAll(server.Datadir + "/init")
 	}
+	for _, f := range files {
+		dir := filepath.Dir(f.Path)
+		if server.ClusterGroup.Conf.LogLevel > 2 {
+			server.ClusterGroup.LogPrintf(LvlInfo, "Config create %s", f.Path)
+		}
+		// create directory
+		if _, err := os.Stat(dir); os.IsNotExist(err) {
+			err := os.MkdirAll(dir, os.FileMode(0775))
+			if err != nil {
+				server.ClusterGroup.LogPrintf(LvlErr, "Compliance create directory %q: %s", dir, err)
+			}
+		}
+		if !f.Write {
+			continue
+		}
+		outFile, err := os.Create(f.Path)
+		if err != nil {
+			server.ClusterGroup.LogPrintf(LvlErr, "Compliance create file failed %q: %s", f.Path, err)
+		} else {
+			_, err = outFile.WriteString(f.Content)
+
+			if err != nil {
+				server.ClusterGroup.LogPrintf(LvlErr, "Compliance writing file failed %q: %s", f.Path, err)
+			}
+			outFile.Close()
+		}
+	}
+	// processing symlink
+	for _, f := range links {
+		action, err := misc.Symlink(f.Target, f.Symlink)
+		if err != nil {
+			server.ClusterGroup.LogPrintf(LvlErr, "Config symlink %s skipped: %s", f.Symlink, err)
+		} else if server.ClusterGroup.Conf.LogLevel > 2 {
+			server.ClusterGroup.LogPrintf(LvlInfo, "Config symlink %s %s to %s", f.Symlink, action, f.Target)
+		}
+	}
+
+	if server.ClusterGroup.HaveDBTag("docker") {
+		err := misc.ChownR(server.Datadir+"/init/data", 999, 999)
+		if err != nil {
+			server.ClusterGroup.LogPrintf(LvlErr, "Chown failed %q: %s", server.Datadir+"/init/data", err)
+		}
+		err = misc.ChmodR(server.Datadir+"/init/init", 0755)
+		if err != nil {
+			server.ClusterGroup.LogPrintf(LvlErr, "Chown failed %q: %s", server.Datadir+"/init/init", err)
+		}
+	}
+
+	for _, cert := range server.getDatabaseConfigCerts() {
+		misc.CopyFile(server.ClusterGroup.Conf.WorkingDir+"/"+server.ClusterGroup.Name+"/"+cert, server.Datadir+"/init/etc/mysql/ssl/"+cert)
+	}
+
+	server.ClusterGroup.TarGz(server.Datadir+"/config.tar.gz", server.Datadir+"/init")
+	m, err := server.ClusterGroup.WriteTarGzManifest(server.Datadir + "/config.tar.gz")
+	if err != nil {
+		// the manifest of the previous tarball must not be served for the new one
+		os.Remove(server.Datadir + "/manifest.json")
+		server.ClusterGroup.LogPrintf(LvlErr, "Compliance writing config manifest failed : %s", err)
+		return m, true, err
+	}
+	server.DatabaseConfigHash = hash
+	return m, true, nil
+}
+
+// GetDatabaseConfigManifest returns the files of the config tarball with their size and sha256, the config is
+// generated first when it changed
+func (server *ServerMonitor) GetDatabaseConfigManifest() (ConfigManifest, error) {
+	m, _, err := server.GenerateDatabaseConfig(false)
+	return m, err
+}
+
+// readDatabaseConfigManifest returns the manifest of the unchanged config tarball
+func (server *ServerMonitor) readDatabaseConfigManifest() (ConfigManifest, error) {
+	var m ConfigManifest
+	content, err := ioutil.ReadFile(server.Datadir + "/manifest.json")
+	if err == nil && json.Unmarshal(content, &m) == nil {
+		return m, nil
+	}
+	// tarball generated before manifests existed or manifest write failed
+	return server.ClusterGroup.WriteTarGzManifest(server.Datadir + "/config.tar.gz")
+}
+
+// GetEnvMissingKeys returns the %%ENV:...%% tokens of a config template that GetEnv does not supply
+func (server *ServerMonitor) GetEnvMissingKeys(content string) []string {
+	return misc.ExtractMissingKeys(content, server.GetEnv())
+}
+
+// getDatabaseConfigFiles resolve the config files and symlinks of the server from the db module rulesets
+func (server *ServerMonitor) getDatabaseConfigFiles() ([]databaseConfigFile, []databaseConfigLink) {
+	type File struct {
+		Path    string `json:"path"`
+		Content string `json:"fmt"`
+	}
+	var files []databaseConfigFile
+	var links []databaseConfigLink
+	prefix := server.getDatabaseConfigRulesetPrefix()
 	for _, rule := range server.ClusterGroup.DBModule.Rulesets {
-		if strings.Contains(rule.Name, "mariadb.svc.mrm.db.cnf") {
+		if strings.Contains(rule.Name, prefix) {
 
 			for _, variable := range rule.Variables {
 				if variable.Class == "file" || variable.Class == "fileprop" {
 					var f File
 					json.Unmarshal([]byte(variable.Value), &f)
 					fpath := strings.Replace(f.Path, "%%ENV:SVC_CONF_ENV_BASE_DIR%%/%%ENV:POD%%", server.Datadir+"/init", -1)
-					dir := filepath.Dir(fpath)
-					if server.ClusterGroup.Conf.LogLevel > 2 {
-						server.ClusterGroup.LogPrintf(LvlInfo, "Config create %s", fpath)
-					}
-					// create directory
-					if _, err := os.Stat(dir); os.IsNotExist(err) {
-						err := os.MkdirAll(dir, os.FileMode(0775))
-						if err != nil {
-							server.ClusterGroup.LogPrintf(LvlErr, "Compliance create directory %q: %s", dir, err)
+					cf := databaseConfigFile{Path: fpath}
+					if fpath[len(fpath)-1:] != "/" && (server.IsFilterInTags(rule.Filter) || rule.Name == prefix+".generic") {
+						if missing := server.GetEnvMissingKeys(f.Content); len(missing) > 0 {
+							server.ClusterGroup.LogPrintf(LvlInfo, "Database config %s for %s has keys not provided by environment: %s", fpath, server.URL, strings.Join(missing, ","))
 						}
-					}
-
-					if fpath[len(fpath)-1:] != "/" && (server.IsFilterInTags(rule.Filter) || rule.Name == "mariadb.svc.mrm.db.cnf.generic") {
 						content := misc.ExtractKey(f.Content, server.GetEnv())
 
 						if server.IsFilterInTags("docker") && server.ClusterGroup.Conf.ProvOrchestrator != config.ConstOrchestratorLocalhost {
@@ -707,68 +2374,97 @@ func (server *ServerMonitor) GetDatabaseConfig() string {
 							content = strings.Replace(content, "../etc/mysql", server.SlapOSDatadir+"/etc/mysql", -1)
 							content = strings.Replace(content, "./.system", server.SlapOSDatadir+"/var/lib/mysql/.system", -1)
 						}
-						outFile, err := os.Create(fpath)
-						if err != nil {
-							server.ClusterGroup.LogPrintf(LvlErr, "Compliance create file failed %q: %s", fpath, err)
-						} else {
-							_, err = outFile.WriteString(content)
-
-							if err != nil {
-								server.ClusterGroup.LogPrintf(LvlErr, "Compliance writing file failed %q: %s", fpath, err)
-							}
-							outFile.Close()
-							//server.ClusterGroup.LogPrintf(LvlInfo, "Variable name %s", variable.Name)
-						}
-
+						cf.Content = content
+						cf.Write = true
 					}
+					files = append(files, cf)
 				}
 			}
 		}
 	}
-	// processing symlink
-	type Link struct {
-		Symlink string `json:"symlink"`
-		Target  string `json:"target"`
-	}
 	for _, rule := range server.ClusterGroup.DBModule.Rulesets {
-		if strings.Contains(rule.Name, "mariadb.svc.mrm.db.cnf.generic") {
+		if strings.Contains(rule.Name, prefix+".generic") {
 			for _, variable := range rule.Variables {
 				if variable.Class == "symlink" {
-					if server.IsFilterInTags(rule.Filter) || rule.Name == "mariadb.svc.mrm.db.cnf.generic" {
-						var f Link
+					if server.IsFilterInTags(rule.Filter) || rule.Name == prefix+".generic" {
+						var f databaseConfigLink
 						json.Unmarshal([]byte(variable.Value), &f)
-						fpath := strings.Replace(f.Symlink, "%%ENV:SVC_CONF_ENV_BASE_DIR%%/%%ENV:POD%%", server.Datadir+"/init", -1)
-						if server.ClusterGroup.Conf.LogLevel > 2 {
-							server.ClusterGroup.LogPrintf(LvlInfo, "Config symlink %s", fpath)
-						}
-						os.Symlink(f.Target, fpath)
-						//	keys := strings.Split(variable.Value, " ")
+						f.Symlink = strings.Replace(f.Symlink, "%%ENV:SVC_CONF_ENV_BASE_DIR%%/%%ENV:POD%%", server.Datadir+"/init", -1)
+						links = append(links, f)
 					}
 				}
 			}
 		}
 	}
+	if override := server.getDatabaseConfigOverride(); override != "" {
+		files = append(files, databaseConfigFile{Path: server.Datadir + "/init/etc/mysql/conf.d/zz_override.cnf", Content: override, Write: true})
+	}
+	return files, links
+}
 
-	if server.ClusterGroup.HaveDBTag("docker") {
-		err := misc.ChownR(server.Datadir+"/init/data", 999, 999)
-		if err != nil {
-			server.ClusterGroup.LogPrintf(LvlErr, "Chown failed %q: %s", server.Datadir+"/init/data", err)
+// getDatabaseConfigRulesetPrefix returns the db module ruleset name prefix matching the server flavor, the mariadb
+// rulesets are used when the version is not yet known or the module has no ruleset for the flavor
+func (server *ServerMonitor) getDatabaseConfigRulesetPrefix() string {
+	prefix := "mariadb.svc.mrm.db.cnf"
+	if server.DBVersion == nil || server.DBVersion.Major == 0 {
+		return prefix
+	}
+	flavor := prefix
+	if server.DBVersion.IsPercona() {
+		flavor = "percona.svc.mrm.db.cnf"
+	} else if server.DBVersion.IsMySQL() {
+		flavor = "mysql.svc.mrm.db.cnf"
+	}
+	for _, rule := range server.ClusterGroup.DBModule.Rulesets {
+		if strings.Contains(rule.Name, flavor) {
+			return flavor
 		}
-		err = misc.ChmodR(server.Datadir+"/init/init", 0755)
+	}
+	return prefix
+}
+
+// getDatabaseConfigOverride returns the override snippet from prov-db-config-override-file and prov-db-config-override,
+// an empty string is returned when the snippet is not a valid config file
+func (server *ServerMonitor) getDatabaseConfigOverride() string {
+	var override string
+	if server.ClusterGroup.Conf.ProvDBConfigOverrideFile != "" {
+		content, err := ioutil.ReadFile(server.ClusterGroup.Conf.ProvDBConfigOverrideFile)
 		if err != nil {
-			server.ClusterGroup.LogPrintf(LvlErr, "Chown failed %q: %s", server.Datadir+"/init/init", err)
+			server.ClusterGroup.LogPrintf(LvlErr, "Can't read database config override %s: %s", server.ClusterGroup.Conf.ProvDBConfigOverrideFile, err)
+			return ""
 		}
+		override = string(content) + "\n"
 	}
+	override += server.ClusterGroup.Conf.ProvDBConfigOverride
+	if strings.TrimSpace(override) == "" {
+		return ""
+	}
+	if err := misc.ValidateIniConfig(override); err != nil {
+		server.ClusterGroup.LogPrintf(LvlErr, "Skip invalid database config override: %s", err)
+		return ""
+	}
+	return override + "\n"
+}
 
-	misc.CopyFile(server.ClusterGroup.Conf.WorkingDir+"/"+server.ClusterGroup.Name+"/ca-cert.pem", server.Datadir+"/init/etc/mysql/ssl/ca-cert.pem")
-	misc.CopyFile(server.ClusterGroup.Conf.WorkingDir+"/"+server.ClusterGroup.Name+"/server-cert.pem", server.Datadir+"/init/etc/mysql/ssl/server-cert.pem")
-	misc.CopyFile(server.ClusterGroup.Conf.WorkingDir+"/"+server.ClusterGroup.Name+"/server-key.pem", server.Datadir+"/init/etc/mysql/ssl/server-key.pem")
-	misc.CopyFile(server.ClusterGroup.Conf.WorkingDir+"/"+server.ClusterGroup.Name+"/client-cert.pem", server.Datadir+"/init/etc/mysql/ssl/client-cert.pem")
-	misc.CopyFile(server.ClusterGroup.Conf.WorkingDir+"/"+server.ClusterGroup.Name+"/client-key.pem", server.Datadir+"/init/etc/mysql/ssl/client-key.pem")
-
-	server.ClusterGroup.TarGz(server.Datadir+"/config.tar.gz", server.Datadir+"/init")
+func (server *ServerMonitor) getDatabaseConfigCerts() []string {
+	return []string{"ca-cert.pem", "server-cert.pem", "server-key.pem", "client-cert.pem", "client-key.pem"}
+}
 
-	return ""
+// getDatabaseConfigHash fingerprint everything that end up in the config tarball
+func (server *ServerMonitor) getDatabaseConfigHash(files []databaseConfigFile, links []databaseConfigLink) string {
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%t\x00%s\x00", server.ClusterGroup.Conf.ProvOrchestrator, server.ClusterGroup.Conf.ProvBinaryInTarball, server.ClusterGroup.Conf.ProvBinaryTarballName)
+	for _, f := range files {
+		fmt.Fprintf(h, "%s\x00%t\x00%s\x00", f.Path, f.Write, f.Content)
+	}
+	for _, l := range links {
+		fmt.Fprintf(h, "%s\x00%s\x00", l.Symlink, l.Target)
+	}
+	for _, cert := range server.getDatabaseConfigCerts() {
+		content, _ := ioutil.ReadFile(server.ClusterGroup.Conf.WorkingDir + "/" + server.ClusterGroup.Name + "/" + cert)
+		h.Write(content)
+	}
+	return hex.EncodeToString(h.Sum(nil))
 }
 
 func (server *ServerMonitor) GetDatabaseDynamicConfig(filter string, cmd string) string {
@@ -778,11 +2474,12 @@ func (server *ServerMonitor) GetDatabaseDynamicConfig(filter string, cmd string)
 		Symlink string `json:"symlink"`
 		Target  string `json:"target"`
 	}
+	prefix := server.getDatabaseConfigRulesetPrefix()
 	for _, rule := range server.ClusterGroup.DBModule.Rulesets {
-		if strings.Contains(rule.Name, "mariadb.svc.mrm.db.cnf.generic") {
+		if strings.Contains(rule.Name, prefix+".generic") {
 			for _, variable := range rule.Variables {
 				if variable.Class == "symlink" {
-					if server.IsFilterInTags(rule.Filter) || rule.Name == "mariadb.svc.mrm.db.cnf.generic" {
+					if server.IsFilterInTags(rule.Filter) || rule.Name == prefix+".generic" {
 						//	server.ClusterGroup.LogPrintf(LvlInfo, "content %s %s", filter, rule.Filter)
 						if filter == "" || strings.Contains(rule.Filter, filter) {
 							var f Link
@@ -812,3 +2509,371 @@ func (server *ServerMonitor) GetDatabaseDynamicConfig(filter string, cmd string)
 	}
 	return mydynamicconf
 }
+
+// GetTableStorageInfo returns the row format, key block size and page compression of the tables of a schema
+func (server *ServerMonitor) GetTableStorageInfo(schema string) ([]TableStorageInfo, error) {
+	ts, logs, err := dbhelper.GetTableStorage(server.Conn, schema)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get table storage of %s %s %s", schema, server.URL, err)
+	if err != nil {
+		return nil, err
+	}
+	return getTableStorageInfo(schema, ts, server.Variables["INNODB_COMPRESSION_ALGORITHM"]), nil
+}
+
+// getTableStorageInfo parses the create options, MariaDB page compression uses the server
+// innodb_compression_algorithm while MySQL sets the algorithm per table
+func getTableStorageInfo(schema string, ts []dbhelper.TableStorage, algorithm string) []TableStorageInfo {
+	res := []TableStorageInfo{}
+	for _, t := range ts {
+		info := TableStorageInfo{
+			Schema:     schema,
+			Table:      t.Table,
+			Engine:     t.Engine.String,
+			RowFormat:  strings.ToUpper(t.Row_format.String),
+			DataLength: t.Data_length,
+		}
+		for _, o := range strings.Fields(t.Create_options.String) {
+			kv := strings.SplitN(o, "=", 2)
+			if len(kv) != 2 {
+				continue
+			}
+			value := strings.Trim(kv[1], "'\"")
+			switch strings.ToLower(strings.Trim(kv[0], "`")) {
+			case "key_block_size":
+				info.KeyBlockSize, _ = strconv.ParseInt(value, 10, 64)
+			case "page_compressed":
+				if strings.EqualFold(value, "ON") || value == "1" {
+					info.PageCompressed = true
+					info.Compression = strings.ToLower(algorithm)
+				}
+			case "compression":
+				if value != "" && !strings.EqualFold(value, "none") {
+					info.PageCompressed = true
+					info.Compression = strings.ToLower(value)
+				}
+			}
+		}
+		info.SuggestPageCompression = info.RowFormat == "COMPRESSED" && !info.PageCompressed && strings.EqualFold(info.Engine, "InnoDB")
+		res = append(res, info)
+	}
+	return res
+}
+
+// getProcessList reads the processlist from performance_schema with monitoring-processlist-pfs when it is
+// enabled on the server, falling back to SHOW PROCESSLIST
+func (server *ServerMonitor) getProcessList() ([]dbhelper.Processlist, error) {
+	if server.ClusterGroup.Conf.MonitorProcessListPFS && server.HavePFS && !server.DBVersion.IsPPostgreSQL() {
+		pl, logs, err := dbhelper.GetProcesslistPFS(server.Conn, server.DBVersion)
+		server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Could not get process from performance_schema %s %s", server.URL, err)
+		if err == nil {
+			return pl, nil
+		}
+	}
+	pl, logs, err := dbhelper.GetProcesslist(server.Conn, server.DBVersion)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlDbg, "Could not get process %s %s", server.URL, err)
+	return pl, err
+}
+
+// GetMDLWaiters returns the threads waiting for a table metadata lock with the threads holding a lock on the
+// same table, the longest running blockers first, from the last processlist and metadata_lock_info when the
+// plugin is installed or from performance_schema.metadata_locks
+func (server *ServerMonitor) GetMDLWaiters() (MDLReport, error) {
+	report := MDLReport{Waiters: []MDLWait{}}
+	if server.HaveMetaDataLocksLog {
+		if server.HeavyMonitoringSkipped {
+			report.Reason = "metadata_lock_info gathering skipped on a late slave"
+			return report, nil
+		}
+		report.Available = true
+		report.Waiters = buildMDLWaiters(server.FullProcessList, server.MetaDataLocks)
+		return report, nil
+	}
+	if !server.HasLogPFS() {
+		report.Reason = "metadata_lock_info plugin not installed and performance_schema is disabled"
+		return report, nil
+	}
+	disabled, logs, err := dbhelper.GetPFSDisabledInstruments(server.Conn, "wait/lock/metadata/sql/mdl")
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get performance_schema instruments %s %s", server.URL, err)
+	if err != nil {
+		return report, err
+	}
+	if len(disabled) > 0 {
+		report.Reason = "performance_schema instrument disabled: " + strings.Join(disabled, ", ")
+		return report, nil
+	}
+	locks, logs, err := dbhelper.GetPFSMetaDataLocks(server.Conn)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get metadata locks %s %s", server.URL, err)
+	if err != nil {
+		return report, err
+	}
+	pl, err := server.getProcessList()
+	if err != nil {
+		return report, err
+	}
+	report.Available = true
+	report.Waiters = buildMDLWaiters(pl, locks)
+	return report, nil
+}
+
+// isMDLUpgradable returns true for the locks a DDL holds on a table before upgrading to the exclusive lock
+func isMDLUpgradable(mode string) bool {
+	mode = strings.TrimPrefix(mode, "MDL_")
+	return mode == "SHARED_UPGRADABLE" || mode == "SHARED_NO_WRITE" || mode == "SHARED_NO_READ_WRITE"
+}
+
+func appendMDLTable(tables []string, table string) []string {
+	for _, t := range tables {
+		if t == table {
+			return tables
+		}
+	}
+	return append(tables, table)
+}
+
+func buildMDLWaiters(pl []dbhelper.Processlist, locks []dbhelper.MetaDataLock) []MDLWait {
+	waiting := make(map[uint64]bool)
+	for _, p := range pl {
+		if strings.Contains(strings.ToLower(p.State.String), "waiting for table metadata lock") {
+			waiting[p.Id] = true
+		}
+	}
+	// the pending locks of performance_schema are the tables waited for, metadata_lock_info only lists the granted
+	// locks of the waiter
+	pending := make(map[uint64][]string)
+	granted := make(map[uint64][]string)
+	holders := make(map[string][]dbhelper.MetaDataLock)
+	var upgrading []dbhelper.MetaDataLock
+	for _, l := range locks {
+		if l.Lock_name.String == "" {
+			continue
+		}
+		table := l.Lock_schema.String + "." + l.Lock_name.String
+		if l.Lock_status.String == "PENDING" {
+			if waiting[l.Thread_id] {
+				pending[l.Thread_id] = appendMDLTable(pending[l.Thread_id], table)
+			}
+			// a pending exclusive lock blocks the queries queued after it on the table
+			if strings.TrimPrefix(l.Lock_mode.String, "MDL_") == "EXCLUSIVE" {
+				holders[table] = append(holders[table], l)
+			}
+			continue
+		}
+		holders[table] = append(holders[table], l)
+		if waiting[l.Thread_id] {
+			granted[l.Thread_id] = appendMDLTable(granted[l.Thread_id], table)
+			if isMDLUpgradable(l.Lock_mode.String) {
+				upgrading = append(upgrading, l)
+			}
+		}
+	}
+	threads := make(map[uint64]dbhelper.Processlist)
+	for _, p := range pl {
+		threads[p.Id] = p
+	}
+	waits := []MDLWait{}
+	for _, p := range pl {
+		if !waiting[p.Id] {
+			continue
+		}
+		w := MDLWait{Waiter: getLockWaitThread(p), Blockers: []MDLBlocker{}}
+		tables := pending[p.Id]
+		if len(tables) == 0 {
+			tables = granted[p.Id]
+		}
+		var blocking []dbhelper.MetaDataLock
+		for _, table := range tables {
+			blocking = append(blocking, holders[table]...)
+		}
+		if len(tables) == 0 {
+			// a query without any table lock is queued behind the DDL waiting to upgrade its lock to exclusive
+			for _, l := range upgrading {
+				if l.Thread_id != p.Id {
+					blocking = append(blocking, l)
+					tables = appendMDLTable(tables, l.Lock_schema.String+"."+l.Lock_name.String)
+				}
+			}
+		}
+		w.Waiter.LockedTable = strings.Join(tables, ",")
+		seen := map[uint64]bool{p.Id: true}
+		for _, l := range blocking {
+			if seen[l.Thread_id] {
+				continue
+			}
+			seen[l.Thread_id] = true
+			b := MDLBlocker{LockWaitThread: LockWaitThread{Id: l.Thread_id}, LockMode: l.Lock_mode.String, LockDuration: l.Lock_duration.String}
+			if t, ok := threads[l.Thread_id]; ok {
+				b.LockWaitThread = getLockWaitThread(t)
+				b.IdleInTransaction = t.Command == "Sleep"
+			}
+			w.Blockers = append(w.Blockers, b)
+		}
+		sort.SliceStable(w.Blockers, func(i, j int) bool {
+			return w.Blockers[i].Time > w.Blockers[j].Time
+		})
+		waits = append(waits, w)
+	}
+	return waits
+}
+
+// threadTempUsageWorst is the number of threads spilling to disk flagged as worst offenders
+const threadTempUsageWorst = 3
+
+// GetThreadTempUsage returns the running threads creating temporary tables, the ones spilling the most to disk
+// first, from the statement counters of performance_schema and the state of the processlist
+func (server *ServerMonitor) GetThreadTempUsage() (ThreadTempReport, error) {
+	report := ThreadTempReport{Threads: []ThreadTempUsage{}}
+	if !server.HasLogPFS() {
+		report.Reason = "performance_schema is disabled"
+		return report, nil
+	}
+	disabled, logs, err := dbhelper.GetPFSDisabledConsumers(server.Conn, "global_instrumentation", "thread_instrumentation", "events_statements_current")
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get performance_schema consumers %s %s", server.URL, err)
+	if err != nil {
+		return report, err
+	}
+	if len(disabled) > 0 {
+		report.Reason = "performance_schema consumers disabled: " + strings.Join(disabled, ", ")
+		return report, nil
+	}
+	tmp, logs, err := dbhelper.GetThreadTempTables(server.Conn)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get thread temporary tables %s %s", server.URL, err)
+	if err != nil {
+		return report, err
+	}
+	pl, err := server.getProcessList()
+	if err != nil {
+		return report, err
+	}
+	report.Available = true
+	report.Threads = buildThreadTempUsage(pl, tmp)
+	return report, nil
+}
+
+func buildThreadTempUsage(pl []dbhelper.Processlist, tmp []dbhelper.ThreadTempTables) []ThreadTempUsage {
+	// nested statements of stored programs give a row per nesting level
+	tables := make(map[uint64]dbhelper.ThreadTempTables)
+	for _, t := range tmp {
+		c := tables[t.Thread_id]
+		c.Tmp_tables += t.Tmp_tables
+		c.Tmp_disk_tables += t.Tmp_disk_tables
+		tables[t.Thread_id] = c
+	}
+	threads := []ThreadTempUsage{}
+	for _, p := range pl {
+		if p.Command == "Sleep" || p.Command == "Daemon" || strings.HasPrefix(p.Command, "Binlog Dump") {
+			continue
+		}
+		state := strings.ToLower(p.State.String)
+		u := ThreadTempUsage{Id: p.Id, User: p.User, Host: p.Host, Db: p.Db.String, Time: p.Time.Float64, State: p.State.String, Info: p.Info.String, TmpTables: tables[p.Id].Tmp_tables, TmpDiskTables: tables[p.Id].Tmp_disk_tables}
+		u.OnDisk = strings.Contains(state, "on disk") || strings.Contains(state, "ondisk")
+		if u.TmpTables == 0 && u.TmpDiskTables == 0 && !u.OnDisk && !strings.Contains(state, "tmp table") {
+			continue
+		}
+		threads = append(threads, u)
+	}
+	sort.SliceStable(threads, func(i, j int) bool {
+		if threads[i].TmpDiskTables != threads[j].TmpDiskTables {
+			return threads[i].TmpDiskTables > threads[j].TmpDiskTables
+		}
+		if threads[i].OnDisk != threads[j].OnDisk {
+			return threads[i].OnDisk
+		}
+		return threads[i].Time > threads[j].Time
+	})
+	for i := 0; i < len(threads) && i < threadTempUsageWorst; i++ {
+		threads[i].Worst = threads[i].TmpDiskTables > 0 || threads[i].OnDisk
+	}
+	return threads
+}
+
+func getLockWaitThread(p dbhelper.Processlist) LockWaitThread {
+	return LockWaitThread{Id: p.Id, User: p.User, Host: p.Host, Db: p.Db.String, Command: p.Command, Time: p.Time.Float64, State: p.State.String, Info: p.Info.String}
+}
+
+// GetLockWaitChains returns the InnoDB lock wait chains from the blockers to the last waiters with the current
+// query of each thread, the chains with the most waiting threads first
+func (server *ServerMonitor) GetLockWaitChains() ([]LockWaitChain, error) {
+	waits, logs, err := dbhelper.GetLockWaits(server.Conn, server.DBVersion)
+	server.ClusterGroup.LogSQL(logs, err, server.URL, "Monitor", LvlErr, "Could not get lock waits %s %s", server.URL, err)
+	if err != nil {
+		return nil, err
+	}
+	if len(waits) == 0 {
+		return []LockWaitChain{}, nil
+	}
+	pl, err := server.getProcessList()
+	if err != nil {
+		return nil, err
+	}
+	return buildLockWaitChains(waits, pl), nil
+}
+
+func buildLockWaitChains(waits []dbhelper.LockWait, pl []dbhelper.Processlist) []LockWaitChain {
+	threads := make(map[uint64]dbhelper.Processlist)
+	for _, p := range pl {
+		threads[p.Id] = p
+	}
+	waiters := make(map[uint64][]uint64)
+	tables := make(map[uint64]string)
+	for _, w := range waits {
+		found := false
+		for _, id := range waiters[w.BlockingThread] {
+			found = found || id == w.WaitingThread
+		}
+		if !found {
+			waiters[w.BlockingThread] = append(waiters[w.BlockingThread], w.WaitingThread)
+		}
+		if _, ok := tables[w.WaitingThread]; !ok || w.LockedTable.Valid {
+			tables[w.WaitingThread] = w.LockedTable.String
+		}
+	}
+	// a blocker waiting itself is not a head, threads waiting on each other are a deadlock resolved by InnoDB
+	var heads []uint64
+	for b, ws := range waiters {
+		if _, ok := tables[b]; !ok {
+			heads = append(heads, b)
+		}
+		sort.Slice(ws, func(i, j int) bool { return ws[i] < ws[j] })
+	}
+	sort.Slice(heads, func(i, j int) bool { return heads[i] < heads[j] })
+	newThread := func(id uint64) LockWaitThread {
+		t := LockWaitThread{Id: id}
+		if p, ok := threads[id]; ok {
+			t = getLockWaitThread(p)
+		}
+		t.LockedTable = tables[id]
+		return t
+	}
+	chains := []LockWaitChain{}
+	for _, head := range heads {
+		seen := map[uint64]bool{head: true}
+		var paths [][]uint64
+		var walk func(path []uint64)
+		walk = func(path []uint64) {
+			leaf := true
+			for _, w := range waiters[path[len(path)-1]] {
+				if seen[w] {
+					continue
+				}
+				seen[w] = true
+				leaf = false
+				walk(append(path[:len(path):len(path)], w))
+			}
+			if leaf {
+				paths = append(paths, path)
+			}
+		}
+		walk([]uint64{head})
+		for _, path := range paths {
+			c := LockWaitChain{Blocker: head, Waiters: len(seen) - 1}
+			for _, id := range path {
+				c.Threads = append(c.Threads, newThread(id))
+			}
+			chains = append(chains, c)
+		}
+	}
+	sort.SliceStable(chains, func(i, j int) bool {
+		return chains[i].Waiters > chains[j].Waiters
+	})
+	return chains
+}
@@ -0,0 +1,1525 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"math"
+	"math/rand"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/dbhelper"
+	"github.com/signal18/replication-manager/utils/s18log"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+func TestQueryResponseTimeHistogram(t *testing.T) {
+	qrt := []dbhelper.ResponseTime{
+		{Time: "      0.000001", Count: 2, Total: "      0.000002"},
+		{Time: "      0.000010", Count: 3, Total: "      0.000020"},
+		{Time: "TOO LONG", Count: 1, Total: "TOO LONG"},
+	}
+	s := getQueryResponseTimeHistogram("db1", qrt)
+	expected := []string{
+		"query_response_time_seconds_bucket{instance=\"db1\",le=\"0.000001\"} 2",
+		"query_response_time_seconds_bucket{instance=\"db1\",le=\"0.000010\"} 5",
+		"query_response_time_seconds_bucket{instance=\"db1\",le=\"+Inf\"} 6",
+		"query_response_time_seconds_sum{instance=\"db1\"} 0.000022",
+		"query_response_time_seconds_count{instance=\"db1\"} 6",
+	}
+	for _, e := range expected {
+		if !strings.Contains(s, e+"\n") {
+			t.Fatalf("Missing line %s in histogram:\n%s", e, s)
+		}
+	}
+	if getQueryResponseTimeHistogram("db1", nil) != "" {
+		t.Fatal("Expected empty histogram without buckets")
+	}
+}
+
+type replicationStatusFixture []dbhelper.SlaveStatus
+
+func (r replicationStatusFixture) GetReplications() []dbhelper.SlaveStatus {
+	return r
+}
+
+func TestReplicationStatusProvider(t *testing.T) {
+	server := &ServerMonitor{ReplicationSourceName: "db1"}
+	server.ReplicationStatus = replicationStatusFixture{
+		{
+			ConnectionName:      sql.NullString{String: "db1", Valid: true},
+			MasterHost:          sql.NullString{String: "10.0.0.1", Valid: true},
+			MasterPort:          sql.NullString{String: "3306", Valid: true},
+			SecondsBehindMaster: sql.NullInt64{Int64: 12, Valid: true},
+			UsingGtid:           sql.NullString{String: "Slave_Pos", Valid: true},
+			MasterServerID:      1001,
+		},
+	}
+	if d := server.GetReplicationDelay(); d != 12 {
+		t.Fatalf("Replication delay %d, expected 12", d)
+	}
+	if h := server.GetReplicationMasterHost(); h != "10.0.0.1" {
+		t.Fatalf("Replication master host %s, expected 10.0.0.1", h)
+	}
+	if id := server.GetReplicationServerID(); id != 1001 {
+		t.Fatalf("Replication server id %d, expected 1001", id)
+	}
+	if !server.GetReplicationGtidEnabled() {
+		t.Fatal("Expected GTID replication enabled")
+	}
+	server.ReplicationSourceName = "db2"
+	if _, err := server.GetSlaveStatus(server.ReplicationSourceName); err == nil {
+		t.Fatal("Expected error on unknown replication channel")
+	}
+	if d := server.GetReplicationDelay(); d != 0 {
+		t.Fatalf("Replication delay %d, expected 0 on unknown channel", d)
+	}
+}
+
+func TestReplicationDelayHuman(t *testing.T) {
+	server := &ServerMonitor{}
+	if h := server.GetReplicationDelayHuman(); h != "not a slave" {
+		t.Fatalf("Got %s, expected not a slave", h)
+	}
+	tests := []struct {
+		ss       dbhelper.SlaveStatus
+		expected string
+	}{
+		{dbhelper.SlaveStatus{SecondsBehindMaster: sql.NullInt64{Int64: 133, Valid: true}}, "2m13s"},
+		{dbhelper.SlaveStatus{SecondsBehindMaster: sql.NullInt64{Int64: 0, Valid: true}}, "caught up"},
+		{dbhelper.SlaveStatus{SlaveIORunning: sql.NullString{String: "No", Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}}, "IO stopped"},
+		{dbhelper.SlaveStatus{SlaveIORunning: sql.NullString{String: "Yes", Valid: true}, SlaveSQLRunning: sql.NullString{String: "No", Valid: true}}, "SQL stopped"},
+	}
+	for _, test := range tests {
+		server.ReplicationStatus = replicationStatusFixture{test.ss}
+		if h := server.GetReplicationDelayHuman(); h != test.expected {
+			t.Fatalf("Got %s, expected %s", h, test.expected)
+		}
+	}
+}
+
+func TestReplicationFilters(t *testing.T) {
+	server := &ServerMonitor{Variables: map[string]string{"REPLICATE_DO_DB": "", "REPLICATE_IGNORE_DB": "mysql", "REPLICATE_WILD_IGNORE_TABLE": "test.%"}}
+	if !server.HasReplicationFilters() {
+		t.Fatal("Expected replication filters")
+	}
+	if s := server.GetReplicationFiltersString(); s != "replicate_ignore_db=mysql replicate_wild_ignore_table=test.%" {
+		t.Fatalf("Got %s", s)
+	}
+	server.Variables = map[string]string{"REPLICATE_DO_DB": ""}
+	if server.HasReplicationFilters() {
+		t.Fatal("Expected no replication filters")
+	}
+	server.ReplicationFilters = []dbhelper.ReplicationFilter{{Channel: "", Name: "REPLICATE_IGNORE_DB", Rule: "mysql"}, {Channel: "ch1", Name: "REPLICATE_DO_DB", Rule: "app"}}
+	if s := server.GetReplicationFiltersString(); s != "replicate_do_db:ch1=app replicate_ignore_db=mysql" {
+		t.Fatalf("Got %s", s)
+	}
+}
+
+func TestProcessListReplicationLongQuery(t *testing.T) {
+	conf := config.Config{MonitorProcessList: true, FailMaxDelay: 30}
+	worker := func(user string, command string, state string) dbhelper.Processlist {
+		return dbhelper.Processlist{User: user, Command: command, State: sql.NullString{String: state, Valid: true}, Time: sql.NullFloat64{Float64: 60, Valid: true}, Info: sql.NullString{String: "UPDATE t SET a=1", Valid: true}}
+	}
+	tests := []struct {
+		flavor   string
+		row      dbhelper.Processlist
+		expected string
+	}{
+		{"MariaDB", worker("system user", "Slave_worker", "Update_rows_log_event::ha_update_row(-1)"), "UPDATE t SET a=1"},
+		{"MariaDB", worker("system user", "Slave_worker", "Waiting for work from SQL thread"), ""},
+		{"MariaDB", worker("system user", "Slave_worker", "Waiting for prior transaction to commit"), ""},
+		{"MySQL", worker("system user", "Connect", "Applying batch of row changes (update)"), "UPDATE t SET a=1"},
+		{"MySQL", worker("system user", "Query", "Waiting for dependent transaction to commit"), ""},
+		{"MySQL", worker("system user", "Query", "Replica has read all relay log; waiting for more updates"), ""},
+		{"MySQL", worker("app", "Query", "Sending data"), ""},
+	}
+	for _, test := range tests {
+		server := &ServerMonitor{ClusterGroup: &Cluster{Conf: conf}, DBVersion: &dbhelper.MySQLVersion{Flavor: test.flavor}, FullProcessList: []dbhelper.Processlist{test.row}}
+		if q := server.GetProcessListReplicationLongQuery(); q != test.expected {
+			t.Fatalf("Got %q for %s %s %s, expected %q", q, test.flavor, test.row.Command, test.row.State.String, test.expected)
+		}
+	}
+	conf.MonitorProcessListReplicationIdleStates = "Waiting for work"
+	server := &ServerMonitor{ClusterGroup: &Cluster{Conf: conf}, DBVersion: &dbhelper.MySQLVersion{Flavor: "MariaDB"}, FullProcessList: []dbhelper.Processlist{worker("system user", "Slave_worker", "Waiting for prior transaction to commit")}}
+	if q := server.GetProcessListReplicationLongQuery(); q != "UPDATE t SET a=1" {
+		t.Fatalf("Got %q with configured idle states", q)
+	}
+}
+
+func TestReplicationThreads(t *testing.T) {
+	thread := func(id uint64, user string, command string, state string, info string) dbhelper.Processlist {
+		return dbhelper.Processlist{Id: id, User: user, Command: command, State: sql.NullString{String: state, Valid: true}, Info: sql.NullString{String: info, Valid: info != ""}}
+	}
+	conf := config.Config{MonitorProcessList: true}
+	mariadb := &ServerMonitor{ClusterGroup: &Cluster{Conf: conf}, DBVersion: &dbhelper.MySQLVersion{Flavor: "MariaDB"}, FullProcessList: []dbhelper.Processlist{
+		thread(40, "app", "Query", "Sending data", "SELECT 1"),
+		thread(12, "system user", "Slave_worker", "Update_rows_log_event::ha_update_row(-1)", "UPDATE t SET a=1"),
+		thread(11, "system user", "Slave_worker", "Waiting for work from SQL thread", ""),
+		thread(10, "system user", "Slave_SQL", "Slave has read all relay log; waiting for more updates", ""),
+		thread(9, "system user", "Slave_IO", "Waiting for master to send event", ""),
+	}}
+	threads, err := mariadb.GetReplicationThreads()
+	if err != nil || len(threads.IO) != 1 || len(threads.SQL) != 1 || len(threads.Workers) != 2 {
+		t.Fatalf("Unexpected MariaDB replication threads %+v %v", threads, err)
+	}
+	if w := threads.Workers; w[0].Id != 11 || !w[0].Idle || w[1].Info != "UPDATE t SET a=1" || w[1].Idle {
+		t.Fatalf("Unexpected MariaDB workers %+v", w)
+	}
+	mysql := &ServerMonitor{ClusterGroup: &Cluster{Conf: conf}, DBVersion: &dbhelper.MySQLVersion{Flavor: "MySQL"}, FullProcessList: []dbhelper.Processlist{
+		thread(5, "system user", "Connect", "Waiting for source to send event", ""),
+		thread(6, "system user", "Query", "Replica has read all relay log; waiting for more updates", ""),
+		thread(7, "system user", "Query", "Waiting for an event from Coordinator", ""),
+		thread(8, "system user", "Query", "Applying batch of row changes (update)", "UPDATE t SET a=2"),
+		thread(20, "event_scheduler", "Daemon", "Waiting on empty queue", ""),
+	}}
+	threads, _ = mysql.GetReplicationThreads()
+	if len(threads.IO) != 1 || threads.IO[0].Id != 5 || len(threads.SQL) != 1 || threads.SQL[0].Id != 6 || len(threads.Workers) != 2 || threads.Workers[1].Info != "UPDATE t SET a=2" {
+		t.Fatalf("Unexpected MySQL replication threads %+v", threads)
+	}
+	mysql.ClusterGroup.Conf.MonitorProcessList = false
+	if threads, err := mysql.GetReplicationThreads(); err == nil || len(threads.Workers) != 0 {
+		t.Fatalf("Expected an error with processlist monitoring disabled, got %+v", threads)
+	}
+}
+
+func TestReplicationApplyBottleneck(t *testing.T) {
+	applier := func(id uint64, state string, seconds float64, info string) dbhelper.Processlist {
+		return dbhelper.Processlist{Id: id, User: "system user", Command: "Slave_worker", State: sql.NullString{String: state, Valid: true}, Time: sql.NullFloat64{Float64: seconds, Valid: true}, Info: sql.NullString{String: info, Valid: info != ""}}
+	}
+	server := &ServerMonitor{ClusterGroup: &Cluster{Conf: config.Config{FailMaxDelay: 30}}, DBVersion: &dbhelper.MySQLVersion{Flavor: "MariaDB"}, FullProcessList: []dbhelper.Processlist{
+		applier(10, "Update_rows_log_event::ha_update_row(-1)", 20, "UPDATE t SET a=1"),
+		applier(11, "Delete_rows_log_event::find_row(-1)", 95, "DELETE FROM big WHERE d < NOW()"),
+		applier(12, "Waiting for work from SQL thread", 300, ""),
+		{Id: 13, User: "app", Command: "Query", Time: sql.NullFloat64{Float64: 500, Valid: true}, Info: sql.NullString{String: "SELECT SLEEP(500)", Valid: true}},
+	}}
+	server.ReplicationStatus = replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: 10, Valid: true}}}
+	if b := server.GetReplicationApplyBottleneck(); b != nil {
+		t.Fatalf("Expected no bottleneck under failover-max-slave-delay, got %+v", b)
+	}
+	server.ReplicationStatus = replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: 120, Valid: true}}}
+	b := server.GetReplicationApplyBottleneck()
+	if b == nil || b.Id != 11 || b.Statement != "DELETE FROM big WHERE d < NOW()" || b.Elapsed != 95 || b.Delay != 120 {
+		t.Fatalf("Got %+v, expected the longest applied delete", b)
+	}
+}
+
+func TestSummarizeDDLPartitions(t *testing.T) {
+	tests := []struct {
+		ddl      string
+		expected string
+	}{
+		{"CREATE TABLE `t` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB", "CREATE TABLE `t` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB"},
+		{"CREATE TABLE `t` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB\n/*!50100 PARTITION BY HASH (`id`)\nPARTITIONS 128 */", "CREATE TABLE `t` (\n  `id` int(11) NOT NULL\n) ENGINE=InnoDB\n/*!50100 PARTITION BY HASH (`id`)\nPARTITIONS 128 */"},
+		{"CREATE TABLE `t` (\n  `d` date NOT NULL\n) ENGINE=InnoDB\n PARTITION BY RANGE (year(`d`))\n(PARTITION `p0` VALUES LESS THAN (1990) ENGINE = InnoDB,\n PARTITION `p1` VALUES LESS THAN MAXVALUE ENGINE = InnoDB)", "CREATE TABLE `t` (\n  `d` date NOT NULL\n) ENGINE=InnoDB\n PARTITION BY RANGE (year(`d`))\nPARTITIONS 2"},
+		{"CREATE TABLE `t` (\n  `c` char(2) NOT NULL\n) ENGINE=InnoDB\n/*!50500 PARTITION BY LIST  COLUMNS(`c`)\n(PARTITION `p0` VALUES IN ('a,b',')') ENGINE = InnoDB,\n PARTITION `p1` VALUES IN ('c') ENGINE = InnoDB,\n PARTITION `p2` VALUES IN ('d') ENGINE = InnoDB) */", "CREATE TABLE `t` (\n  `c` char(2) NOT NULL\n) ENGINE=InnoDB\n/*!50500 PARTITION BY LIST  COLUMNS(`c`)\nPARTITIONS 3 */"},
+	}
+	for _, test := range tests {
+		if ddl := dbhelper.SummarizeDDLPartitions(test.ddl); ddl != test.expected {
+			t.Fatalf("Got %q, expected %q", ddl, test.expected)
+		}
+	}
+}
+
+func TestLongOpenTransactions(t *testing.T) {
+	processlist := []dbhelper.Processlist{
+		{Id: 10, User: "app", Command: "Sleep", Time: sql.NullFloat64{Float64: 2, Valid: true}},
+		{Id: 11, User: "app", Command: "Query", Time: sql.NullFloat64{Float64: 1, Valid: true}, Info: sql.NullString{String: "SELECT 1", Valid: true}},
+		{Id: 12, User: "batch", Command: "Sleep"},
+	}
+	trx := []dbhelper.InnoDBTrx{
+		{Trx_id: "1", Trx_mysql_thread_id: 10, Trx_state: "RUNNING", Trx_age: 600},
+		{Trx_id: "2", Trx_mysql_thread_id: 11, Trx_state: "RUNNING", Trx_age: 1},
+		{Trx_id: "3", Trx_mysql_thread_id: 12, Trx_state: "RUNNING", Trx_age: 3600},
+	}
+	res := getLongOpenTransactions(processlist, trx, time.Minute)
+	if len(res) != 2 {
+		t.Fatalf("Got %d transactions, expected 2", len(res))
+	}
+	if res[0].Id != 12 || res[0].User != "batch" || res[1].Id != 10 || res[1].Command != "Sleep" {
+		t.Fatalf("Unexpected transactions %v", res)
+	}
+}
+
+func TestProcessListByDigest(t *testing.T) {
+	query := func(id uint64, user string, command string, info string) dbhelper.Processlist {
+		return dbhelper.Processlist{Id: id, User: user, Command: command, Info: sql.NullString{String: info, Valid: info != ""}}
+	}
+	server := &ServerMonitor{User: "repman", ClusterGroup: &Cluster{}, FullProcessList: []dbhelper.Processlist{
+		query(1, "app", "Query", "SELECT * FROM t WHERE id = 1"),
+		query(2, "app", "Query", "SELECT * FROM t WHERE id = 2"),
+		query(3, "app", "Sleep", ""),
+		query(4, "repman", "Query", "SELECT * FROM t WHERE id = 3"),
+		query(5, "system user", "Slave_SQL", "SELECT * FROM t WHERE id = 4"),
+		query(6, "app", "Query", "SELECT * FROM u WHERE id = 1"),
+	}}
+	digest := dbhelper.GetQueryDigest("SELECT * FROM t WHERE id = 5")
+	ids, err := server.KillQueriesByDigest(digest, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 2 {
+		t.Fatalf("Got %v, expected [1 2]", ids)
+	}
+}
+
+func TestReapIdleConnections(t *testing.T) {
+	idle := func(id uint64, user string, command string, seconds float64) dbhelper.Processlist {
+		return dbhelper.Processlist{Id: id, User: user, Command: command, Time: sql.NullFloat64{Float64: seconds, Valid: true}}
+	}
+	server := &ServerMonitor{User: "repman", ClusterGroup: &Cluster{rplUser: "repl", Conf: config.Config{MonitorIdleConnectionAllowUsers: "pool, batch"}}, FullProcessList: []dbhelper.Processlist{
+		idle(1, "app", "Sleep", 7200),
+		idle(2, "app", "Sleep", 10),
+		idle(3, "app", "Query", 7200),
+		idle(4, "repman", "Sleep", 7200),
+		idle(5, "repl", "Sleep", 7200),
+		idle(6, "batch", "Sleep", 7200),
+		idle(7, "report", "Sleep", 3600),
+	}}
+	ids, err := server.ReapIdleConnections(time.Hour, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ids) != 2 || ids[0] != 1 || ids[1] != 7 {
+		t.Fatalf("Got %v, expected [1 7]", ids)
+	}
+	// read again before the kill, connection 7 ran a query since the process list
+	current := map[int64][]driver.Value{
+		1: {int64(1), "app", "", nil, "Sleep", int64(7210), nil, nil, int64(0)},
+		7: {int64(7), "report", "", nil, "Sleep", int64(2), nil, nil, int64(0)},
+	}
+	log := &execLog{}
+	name := fmt.Sprintf("reapidle%d", time.Now().UnixNano())
+	sql.Register(name, execDriver{log: log, rows: func(query string, args []driver.Value) *pkRows {
+		r := &pkRows{cols: []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info", "Progress"}}
+		if row, ok := current[args[0].(int64)]; ok {
+			r.values = [][]driver.Value{row}
+		}
+		return r
+	}})
+	if server.Conn, err = sqlx.Open(name, ""); err != nil {
+		t.Fatal(err)
+	}
+	server.DBVersion = dbhelper.NewMySQLVersion("10.6.4-MariaDB", "")
+	ids, err = server.ReapIdleConnections(time.Hour, false)
+	if err != nil || len(ids) != 1 || ids[0] != 1 {
+		t.Fatalf("Expected only connection 1 killed, got %v %v", ids, err)
+	}
+	if stmts := strings.Join(log.stmts, ";"); stmts != "KILL ? [1]" {
+		t.Fatalf("Unexpected kill statements %s", stmts)
+	}
+	cluster := &Cluster{Name: "c1", APIUsers: map[string]APIUser{"dba": {User: "dba", Grants: map[string]bool{config.GrantDBKill: true}}}}
+	if !cluster.IsURLPassDatabasesACL("dba", "/api/clusters/c1/servers/db1/actions/kill-idle-connections/3600") {
+		t.Fatal("Expected idle connections kill allowed with the db kill grant")
+	}
+}
+
+func TestTableDependencyOrder(t *testing.T) {
+	fk := func(table string, refSchema string, refTable string) dbhelper.ForeignKey {
+		return dbhelper.ForeignKey{Table: table, RefSchema: refSchema, RefTable: refTable}
+	}
+	tables := []string{"order_lines", "orders", "customers", "products", "employees"}
+	fks := []dbhelper.ForeignKey{
+		fk("order_lines", "shop", "orders"),
+		fk("order_lines", "shop", "products"),
+		fk("orders", "shop", "customers"),
+		fk("employees", "shop", "employees"),
+		fk("customers", "crm", "accounts"),
+	}
+	order, err := getTableDependencyOrder("shop", tables, fks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []string{"customers", "employees", "orders", "products", "order_lines"}
+	if strings.Join(order, ",") != strings.Join(expected, ",") {
+		t.Fatalf("Got %v, expected %v", order, expected)
+	}
+	fks = append(fks, fk("customers", "shop", "order_lines"))
+	if _, err = getTableDependencyOrder("shop", tables, fks); err == nil || !strings.Contains(err.Error(), "customers, order_lines, orders") {
+		t.Fatalf("Expected cycle error, got %v", err)
+	}
+}
+
+func TestProcessListByClientHost(t *testing.T) {
+	server := &ServerMonitor{}
+	for _, host := range []string{"10.0.0.1:54321", "10.0.0.1:54322", "[::ffff:10.0.0.1]:3306", "App1.example.com:1234", "app1.example.com", "[2001:db8:0:0:0:0:0:1]:4444", "2001:db8::1:5555", "::1:37262", "localhost", ""} {
+		server.FullProcessList = append(server.FullProcessList, dbhelper.Processlist{Host: host})
+	}
+	hosts := server.GetProcessListByClientHost()
+	expected := map[string]int{"10.0.0.1": 3, "app1.example.com": 2, "2001:db8::1": 2, "::1": 1, "localhost": 1}
+	if fmt.Sprint(hosts) != fmt.Sprint(expected) {
+		t.Fatalf("Got %v, expected %v", hosts, expected)
+	}
+}
+
+func TestProcessListExcludingSelf(t *testing.T) {
+	var next int64
+	name := fmt.Sprintf("connid%d", time.Now().UnixNano())
+	sql.Register(name, execDriver{log: &execLog{}, rows: func(query string, args []driver.Value) *pkRows {
+		next++
+		return &pkRows{cols: []string{"CONNECTION_ID()"}, values: [][]driver.Value{{next}}}
+	}})
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// two idle connections in the pool
+	c1, _ := db.DB.Conn(context.Background())
+	c2, _ := db.DB.Conn(context.Background())
+	c1.Close()
+	c2.Close()
+	ids, _, err := dbhelper.GetPoolConnectionIDs(db, dbhelper.NewMySQLVersion("10.6.4-MariaDB", ""))
+	if err != nil || len(ids) != 2 || ids[0] == ids[1] {
+		t.Fatalf("Expected the ids of the two pool connections, got %v %v", ids, err)
+	}
+
+	pl := []dbhelper.Processlist{{Id: 1, User: "repman"}, {Id: 2, User: "repman"}, {Id: 3, User: "app"}, {Id: 4, User: "repman"}}
+	server := &ServerMonitor{User: "repman", ClusterGroup: &Cluster{}, FullProcessList: pl}
+	server.setMonitorConnectionIds([]uint64{1, 4})
+	server.FullProcessList = pl[:3]
+	server.setMonitorConnectionIds([]uint64{2})
+	if len(server.monitorConnectionIds) != 2 || !server.monitorConnectionIds[1] || !server.monitorConnectionIds[2] {
+		t.Fatalf("Expected the closed connection 4 dropped, got %v", server.monitorConnectionIds)
+	}
+	server.monitorConnectionIds = map[uint64]bool{1: true}
+	// an application sharing the monitoring user is kept
+	if got := server.GetProcessListExcludingSelf(); len(got) != 2 || got[0].Id != 2 || got[1].Id != 3 {
+		t.Fatalf("Expected threads 2 and 3, got %v", got)
+	}
+	server.ClusterGroup.Conf.MonitorProcessListDedicatedUser = true
+	if got := server.GetProcessListExcludingSelf(); len(got) != 1 || got[0].Id != 3 {
+		t.Fatalf("Expected thread 3 with a dedicated monitoring user, got %v", got)
+	}
+}
+
+func TestQueryFromPFSDigestParameterized(t *testing.T) {
+	server := &ServerMonitor{PFSQueries: map[string]dbhelper.PFSQuery{
+		"d1": {Digest: "d1", Schema_name: "test", Digest_text: "SELECT `a` FROM `t` WHERE `b` = ? AND `c` IN (...) LIMIT ?"},
+		"d2": {Digest: "d2", Schema_name: "test", Query: "SELECT a FROM t WHERE b = ? AND c = '?'", Digest_text: "SELECT `a` FROM `t` WHERE `b` = ? AND `c` = ?"},
+		"d3": {Digest: "d3", Schema_name: "test", Query: "SELECT a FROM t WHERE b = 12", Digest_text: "SELECT `a` FROM `t` WHERE `b` = ?"},
+	}}
+	expected := map[string]string{
+		"d1": "SELECT `a` FROM `t` WHERE `b` = 1 AND `c` IN (1) LIMIT 1",
+		"d2": "SELECT a FROM t WHERE b = 1 AND c = '?'",
+		"d3": "SELECT a FROM t WHERE b = 12",
+	}
+	for digest, e := range expected {
+		schema, query, err := server.GetQueryFromPFSDigest(digest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if schema != "test" || query != e {
+			t.Fatalf("Got %s, expected %s", query, e)
+		}
+	}
+}
+
+func TestDerivedMetrics(t *testing.T) {
+	m := getDerivedMetrics(map[string]string{
+		"UPTIME":                           "100",
+		"QUERIES":                          "5000",
+		"INNODB_BUFFER_POOL_READS":         "10",
+		"INNODB_BUFFER_POOL_READ_REQUESTS": "1000",
+		"CREATED_TMP_TABLES":               "0",
+	})
+	if m["mysql_queries_per_second"] != 50 {
+		t.Fatalf("Got %f queries per second, expected 50", m["mysql_queries_per_second"])
+	}
+	if m["mysql_innodb_buffer_pool_hit_ratio"] != 0.99 {
+		t.Fatalf("Got %f buffer pool hit ratio, expected 0.99", m["mysql_innodb_buffer_pool_hit_ratio"])
+	}
+	if !math.IsNaN(m["mysql_tmp_disk_tables_ratio"]) {
+		t.Fatalf("Got %f tmp disk tables ratio, expected NaN", m["mysql_tmp_disk_tables_ratio"])
+	}
+}
+
+func TestPrometheusReplicationStatusAge(t *testing.T) {
+	server := &ServerMonitor{
+		IsSlave:               true,
+		ClusterGroup:          &Cluster{Conf: config.Config{MonitorReplicationStatusMaxAge: 30}},
+		Variables:             map[string]string{"HOSTNAME": "db1"},
+		SlaveStatus:           &dbhelper.SlaveStatus{SecondsBehindMaster: sql.NullInt64{Int64: 12, Valid: true}},
+		ReplicationsTimestamp: time.Now().Unix(),
+	}
+	s := server.GetPrometheusMetrics()
+	if !strings.Contains(s, "mysql_slave_status_seconds_behind_master{instance=\"db1\"} 12\n") || !strings.Contains(s, "replication_status_age_seconds{instance=\"db1\"} 0\n") {
+		t.Fatalf("Missing replication metrics in %s", s)
+	}
+	server.ReplicationsTimestamp = time.Now().Add(-time.Minute).Unix()
+	s = server.GetPrometheusMetrics()
+	if strings.Contains(s, "mysql_slave_status_seconds_behind_master") || !strings.Contains(s, "replication_status_age_seconds{instance=\"db1\"} 60\n") {
+		t.Fatalf("Stale replication delay exported in %s", s)
+	}
+	server.ReplicationsTimestamp = time.Now().Unix()
+	server.SlaveStatus.SecondsBehindMaster = sql.NullInt64{}
+	s = server.GetPrometheusMetrics()
+	if !strings.Contains(s, "mysql_slave_status_seconds_behind_master{instance=\"db1\"} NaN\n") {
+		t.Fatalf("Unknown replication delay not exported as NaN in %s", s)
+	}
+	server.ReplicationsTimestamp = 0
+	s = server.GetPrometheusMetrics()
+	if strings.Contains(s, "replication_status_age_seconds") || strings.Contains(s, "mysql_slave_status_seconds_behind_master") {
+		t.Fatalf("Replication metrics exported without slave status in %s", s)
+	}
+}
+
+func TestEndToEndReplicationDelay(t *testing.T) {
+	replicating := func(url string, id uint64, from uint64, delay sql.NullInt64) *ServerMonitor {
+		server := &ServerMonitor{URL: url, ServerID: id}
+		if from != 0 {
+			server.Replications = []dbhelper.SlaveStatus{{MasterServerID: from, SecondsBehindMaster: delay,
+				SlaveIORunning: sql.NullString{String: "Yes", Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}}}
+		}
+		return server
+	}
+	delay := func(d int64) sql.NullInt64 { return sql.NullInt64{Int64: d, Valid: true} }
+	cluster := &Cluster{}
+	master := replicating("db1:3306", 1, 0, sql.NullInt64{})
+	relay := replicating("db2:3306", 2, 1, delay(5))
+	slave := replicating("db3:3306", 3, 2, delay(7))
+	cluster.master = master
+	cluster.Servers = serverList{master, relay, slave}
+	for _, s := range cluster.Servers {
+		s.ClusterGroup = cluster
+	}
+	if d, err := slave.GetEndToEndReplicationDelay(); err != nil || d != 12 {
+		t.Fatalf("Got end to end delay %d %v, expected 12", d, err)
+	}
+
+	relay.Replications[0].SecondsBehindMaster = sql.NullInt64{}
+	if _, err := slave.GetEndToEndReplicationDelay(); err == nil {
+		t.Fatal("Expected error when the relay replication delay is unknown")
+	}
+	relay.Replications[0].SecondsBehindMaster = delay(5)
+
+	// db2 and db3 replicate from each other, the master is out of the chain
+	relay.Replications[0].MasterServerID = 3
+	if _, err := slave.GetEndToEndReplicationDelay(); err == nil || !strings.Contains(err.Error(), "cycle") {
+		t.Fatalf("Expected replication cycle detected, got %v", err)
+	}
+}
+
+func TestReplicationSLO(t *testing.T) {
+	server := &ServerMonitor{ClusterGroup: &Cluster{Conf: config.Config{ReplicationSLOTarget: "99"}}}
+	now := time.Unix(1700000000, 0)
+	for i := 0; i < 24*60; i++ {
+		server.addReplicationSLOSample(now.Add(-time.Duration(i)*time.Minute), i >= 60 || i%10 != 0)
+	}
+	slo := server.getReplicationSLO(now)
+	if len(slo) != 3 || slo[0].Window != "1h" || slo[0].Polls != 60 || slo[2].Polls != 24*60 {
+		t.Fatalf("Unexpected windows %v", slo)
+	}
+	if slo[0].Compliance != 0.9 || math.Abs(slo[0].BurnRate-10) > 1e-9 {
+		t.Fatalf("Got 1h compliance %f burn rate %f, expected 0.9 and 10", slo[0].Compliance, slo[0].BurnRate)
+	}
+	if math.Abs(slo[2].Compliance-(1-6.0/1440)) > 1e-9 {
+		t.Fatalf("Got 24h compliance %f", slo[2].Compliance)
+	}
+	server.addReplicationSLOSample(now.Add(24*time.Hour), true)
+	if slo = server.getReplicationSLO(now.Add(24 * time.Hour)); slo[2].Polls != 1 || slo[2].Compliance != 1 {
+		t.Fatalf("Expected expired buckets to be reset, got %v", slo)
+	}
+}
+
+func TestSlowLogCSV(t *testing.T) {
+	var b bytes.Buffer
+	slowqueries := []dbhelper.LogSlow{
+		{Start_time: 1500000000, User_host: sql.NullString{String: "app[app] @ localhost []", Valid: true}, Query_time: "2", Lock_time: "0", Rows_sent: 1, Rows_examined: 100, Db: sql.NullString{String: "test", Valid: true}, Sql_text: sql.NullString{String: "SELECT * FROM t WHERE id=12", Valid: true}},
+	}
+	err := writeSlowLogCSV(&b, slowqueries, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(strings.TrimSpace(b.String()), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected header and one row, got %d lines", len(lines))
+	}
+	if lines[0] != "start_time,user_host,query_time,lock_time,rows_sent,rows_examined,db,digest" {
+		t.Fatalf("Unexpected header %s", lines[0])
+	}
+	if lines[1] != "1500000000,app[app] @ localhost [],2,0,1,100,test,select * from t where id=?" {
+		t.Fatalf("Unexpected row %s", lines[1])
+	}
+}
+
+// pkDriver simulate a network round trip on each query against KEY_COLUMN_USAGE
+type pkDriver struct {
+	tables  int
+	latency time.Duration
+}
+
+func (d pkDriver) Open(name string) (driver.Conn, error) { return pkConn(d), nil }
+
+type pkConn pkDriver
+
+func (c pkConn) Prepare(query string) (driver.Stmt, error) { return pkStmt{pkDriver(c), query}, nil }
+func (c pkConn) Close() error                              { return nil }
+func (c pkConn) Begin() (driver.Tx, error)                 { return nil, fmt.Errorf("not supported") }
+
+type pkStmt struct {
+	d     pkDriver
+	query string
+}
+
+func (s pkStmt) Close() error  { return nil }
+func (s pkStmt) NumInput() int { return -1 }
+func (s pkStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (s pkStmt) Query(args []driver.Value) (driver.Rows, error) {
+	time.Sleep(s.d.latency)
+	r := &pkRows{}
+	if strings.Contains(s.query, "group_concat") {
+		r.cols = []string{"pk"}
+		r.values = [][]driver.Value{{"id"}}
+		return r, nil
+	}
+	r.cols = []string{"TABLE_NAME", "COLUMN_NAME"}
+	for i := 0; i < s.d.tables; i++ {
+		r.values = append(r.values, []driver.Value{fmt.Sprintf("t%d", i), "id"})
+	}
+	return r, nil
+}
+
+type pkRows struct {
+	cols   []string
+	values [][]driver.Value
+	pos    int
+}
+
+func (r *pkRows) Columns() []string { return r.cols }
+func (r *pkRows) Close() error      { return nil }
+func (r *pkRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+	copy(dest, r.values[r.pos])
+	r.pos++
+	return nil
+}
+
+const pkBenchTables = 100
+
+func newPKServer(b *testing.B) *ServerMonitor {
+	name := fmt.Sprintf("pkbench%d", time.Now().UnixNano())
+	sql.Register(name, pkDriver{tables: pkBenchTables, latency: 200 * time.Microsecond})
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		b.Fatal(err)
+	}
+	return &ServerMonitor{Conn: db}
+}
+
+func BenchmarkGetTablePKPerTable(b *testing.B) {
+	server := newPKServer(b)
+	for n := 0; n < b.N; n++ {
+		for i := 0; i < pkBenchTables; i++ {
+			server.GetTablePK("test", fmt.Sprintf("t%d", i))
+		}
+	}
+}
+
+func BenchmarkGetTablePKsPerSchema(b *testing.B) {
+	server := newPKServer(b)
+	for n := 0; n < b.N; n++ {
+		pks, err := server.GetTablePKs("test")
+		if err != nil || len(pks) != pkBenchTables {
+			b.Fatalf("Expected %d tables, got %d %s", pkBenchTables, len(pks), err)
+		}
+	}
+}
+
+func TestBufferPoolHitRate(t *testing.T) {
+	if r := getBufferPoolHitRate(nil); r != 1 {
+		t.Fatalf("Got hit rate %v without pool, expected 1", r)
+	}
+	single := []dbhelper.BufferPoolStats{{Number_pages_get: 1000, Number_pages_read: 100}}
+	if r := getBufferPoolHitRate(single); r != 0.9 {
+		t.Fatalf("Got hit rate %v for single pool, expected 0.9", r)
+	}
+	multi := append(single, dbhelper.BufferPoolStats{Pool_id: 1, Number_pages_get: 3000, Number_pages_read: 100})
+	if r := getBufferPoolHitRate(multi); r != 0.95 {
+		t.Fatalf("Got hit rate %v for two pools, expected 0.95", r)
+	}
+}
+
+func TestWriteSlowLogPTFormat(t *testing.T) {
+	slowlog := s18log.NewSlowLog(2)
+	for _, q := range []string{"SELECT 1;", "UPDATE t SET a=1;"} {
+		m := s18log.NewSlowMessage()
+		for _, line := range []string{
+			"# User@Host: app[app] @  [10.0.0.1]",
+			"# Thread_id: 12  Schema: test  QC_hit: No",
+			"# Query_time: 2.500000  Lock_time: 0.000100  Rows_sent: 1  Rows_examined: 100",
+			"# Rows_affected: 3",
+			q,
+		} {
+			slowlog.ParseLine(line, m)
+		}
+		slowlog.Add(m)
+	}
+	var b bytes.Buffer
+	writeSlowLogPTFormat(&b, "db1:3306", "3306", slowlog.Buffer)
+	out := b.String()
+	if !strings.HasPrefix(out, "replication-manager export of db1:3306") || !strings.Contains(out, "Tcp port: 3306") {
+		t.Fatalf("Missing slow log header in %s", out)
+	}
+	entry := "# User@Host: app[app] @  [10.0.0.1]\n# Thread_id: 12  Schema: test  QC_hit: No\n# Query_time: 2.500000  Lock_time: 0.000100  Rows_sent: 1  Rows_examined: 100\n# Rows_affected: 3\n"
+	first := strings.Index(out, entry+"SELECT 1;\n")
+	second := strings.Index(out, entry+"UPDATE t SET a=1;\n")
+	if first == -1 || second == -1 || first > second {
+		t.Fatalf("Expected both queries oldest first, got %s", out)
+	}
+}
+
+func TestBinlogRate(t *testing.T) {
+	now := time.Unix(1600000000, 0)
+	prev := binlogCoordinate{File: "mariadb-bin.000008", Pos: 1000, Time: now}
+	if r := getBinlogRate(binlogCoordinate{}, prev, nil); r != 0 {
+		t.Fatalf("Got rate %v without previous poll, expected 0", r)
+	}
+	if r := getBinlogRate(prev, binlogCoordinate{File: "mariadb-bin.000008", Pos: 21000, Time: now.Add(2 * time.Second)}, nil); r != 10000 {
+		t.Fatalf("Got rate %v in same file, expected 10000", r)
+	}
+	sizes := map[string]uint{"mariadb-bin.000008": 5000, "mariadb-bin.000009": 10000, "mariadb-bin.000010": 500}
+	if r := getBinlogRate(prev, binlogCoordinate{File: "mariadb-bin.000010", Pos: 6000, Time: now.Add(4 * time.Second)}, sizes); r != 5000 {
+		t.Fatalf("Got rate %v across rotations, expected 5000", r)
+	}
+}
+
+func TestBestSibling(t *testing.T) {
+	slave := func(id uint64, delay int64, sqlErrno string) *ServerMonitor {
+		return &ServerMonitor{ServerID: id, State: stateSlave, ReplicationStatus: replicationStatusFixture{{MasterServerID: 1, SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}, SlaveSQLRunning: sql.NullString{String: "Yes", Valid: true}, SlaveIORunning: sql.NullString{String: "Yes", Valid: true}, LastSQLErrno: sql.NullString{String: sqlErrno, Valid: true}}}}
+	}
+	cluster := &Cluster{}
+	cluster.Servers = serverList{slave(2, 0, "0"), slave(3, 30, "0"), slave(4, 0, "1062"), slave(5, 10, "0")}
+	for _, s := range cluster.Servers {
+		s.ClusterGroup = cluster
+	}
+	server := cluster.Servers[0]
+	if siblings := server.GetSiblings(); len(siblings) != 3 {
+		t.Fatalf("Got %d siblings, expected 3", len(siblings))
+	}
+	if first := server.GetSibling(); first != cluster.Servers[1] {
+		t.Fatal("Expected GetSibling to keep returning the first sibling")
+	}
+	if best := server.GetBestSibling(); best != cluster.Servers[3] {
+		t.Fatalf("Got best sibling %v, expected server id 5", best)
+	}
+}
+
+func TestProcessListStuckInState(t *testing.T) {
+	thread := func(id uint64, state string) dbhelper.Processlist {
+		return dbhelper.Processlist{Id: id, State: sql.NullString{String: state, Valid: true}}
+	}
+	mdl := "Waiting for table metadata lock"
+	now := time.Unix(1600000000, 0)
+	server := &ServerMonitor{}
+	server.FullProcessList = []dbhelper.Processlist{thread(1, mdl), thread(2, "Sending data"), thread(3, "")}
+	server.SetProcessListStates(now)
+	server.FullProcessList = []dbhelper.Processlist{thread(1, mdl), thread(2, mdl), thread(4, mdl)}
+	server.SetProcessListStates(now.Add(30 * time.Second))
+	if len(server.processListStates) != 3 {
+		t.Fatalf("Got %d tracked threads, expected gone thread 3 expired", len(server.processListStates))
+	}
+	stuck := server.getProcessListStuckInState(mdl, 20*time.Second, now.Add(40*time.Second))
+	if len(stuck) != 1 || stuck[0].Id != 1 {
+		t.Fatalf("Got %v, expected thread 1 only", stuck)
+	}
+	stuck = server.getProcessListStuckInState(mdl, 5*time.Second, now.Add(40*time.Second))
+	if len(stuck) != 3 || stuck[0].Id != 1 {
+		t.Fatalf("Got %v, expected 3 threads with thread 1 first", stuck)
+	}
+}
+
+func TestDiffProcessListSnapshots(t *testing.T) {
+	thread := func(id uint64, state string, info string) dbhelper.Processlist {
+		return dbhelper.Processlist{Id: id, Command: "Query", State: sql.NullString{String: state, Valid: true}, Info: sql.NullString{String: info, Valid: info != ""}}
+	}
+	server := &ServerMonitor{}
+	server.FullProcessList = []dbhelper.Processlist{thread(1, "Sending data", "SELECT 1"), thread(2, "", ""), thread(3, "Updating", "UPDATE t SET a=1")}
+	server.CaptureProcessListSnapshot("before")
+	server.FullProcessList = []dbhelper.Processlist{thread(1, "Sending data", "SELECT 1"), thread(3, "Waiting for table metadata lock", "UPDATE t SET a=1"), thread(4, "Sending data", "SELECT 2")}
+	server.CaptureProcessListSnapshot("after")
+	diff := DiffProcessListSnapshots(server.GetProcessListSnapshot("before"), server.GetProcessListSnapshot("after"))
+	if len(diff.Appeared) != 1 || diff.Appeared[0].Id != 4 {
+		t.Fatalf("Got appeared %v, expected thread 4", diff.Appeared)
+	}
+	if len(diff.Disappeared) != 1 || diff.Disappeared[0].Id != 2 {
+		t.Fatalf("Got disappeared %v, expected thread 2", diff.Disappeared)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].After.State.String != "Waiting for table metadata lock" {
+		t.Fatalf("Got changed %v, expected thread 3", diff.Changed)
+	}
+	for i := 0; i < processListSnapshotsMax+2; i++ {
+		server.CaptureProcessListSnapshot(fmt.Sprintf("s%d", i))
+	}
+	if len(server.processListSnapshots) != processListSnapshotsMax || server.GetProcessListSnapshot("before") != nil {
+		t.Fatalf("Got %d snapshots, expected oldest dropped over %d", len(server.processListSnapshots), processListSnapshotsMax)
+	}
+}
+
+func TestChannelDelayMetrics(t *testing.T) {
+	status := func(delay int64) replicationStatusFixture {
+		return replicationStatusFixture{
+			{ConnectionName: sql.NullString{String: "a", Valid: true}, SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}},
+			{ConnectionName: sql.NullString{String: "b", Valid: true}, SecondsBehindMaster: sql.NullInt64{Int64: 1, Valid: true}},
+		}
+	}
+	server := &ServerMonitor{ClusterGroup: &Cluster{Conf: config.Config{FailMaxDelay: 30}}}
+	now := time.Unix(1600000000, 0)
+	for i, delay := range []int64{10, 60, 45, 5} {
+		server.ReplicationStatus = status(delay)
+		server.addChannelDelaySamples(now.Add(time.Duration(i) * 2 * time.Second))
+	}
+	s := server.getChannelDelayMetrics("db1")
+	for _, expected := range []string{
+		"replication_delay_max_seconds{instance=\"db1\",channel=\"a\"} 60\n",
+		"replication_delay_breach_seconds_total{instance=\"db1\",channel=\"a\"} 4\n",
+		"replication_delay_max_seconds{instance=\"db1\",channel=\"b\"} 1\n",
+		"replication_delay_breach_seconds_total{instance=\"db1\",channel=\"b\"} 0\n",
+	} {
+		if !strings.Contains(s, expected) {
+			t.Fatalf("Missing %s in %s", expected, s)
+		}
+	}
+	if s = server.getChannelDelayMetrics("db1"); !strings.Contains(s, "replication_delay_max_seconds{instance=\"db1\",channel=\"a\"} 0\n") {
+		t.Fatalf("Expected max reset on scrape, got %s", s)
+	}
+	server.ReplicationStatus = status(5)[:1]
+	server.addChannelDelaySamples(now.Add(10 * time.Second))
+	if s = server.getChannelDelayMetrics("db1"); strings.Contains(s, "channel=\"b\"") {
+		t.Fatalf("Expected the removed channel b dropped, got %s", s)
+	}
+}
+
+func TestProcessListRedacted(t *testing.T) {
+	server := &ServerMonitor{FullProcessList: []dbhelper.Processlist{
+		{Id: 1, Info: sql.NullString{String: "SELECT * FROM customers WHERE email='jane@example.com' AND id=42", Valid: true}},
+		{Id: 2, Info: sql.NullString{String: "SET PASSWORD FOR 'app'@'%' = PASSWORD('secret')", Valid: true}},
+		{Id: 3},
+	}}
+	pl := server.GetProcessListRedacted()
+	for _, q := range pl {
+		if strings.Contains(q.Info.String, "jane@example.com") || strings.Contains(q.Info.String, "42") || strings.Contains(q.Info.String, "secret") {
+			t.Fatalf("Literal not redacted in %s", q.Info.String)
+		}
+	}
+	if !strings.Contains(pl[0].Info.String, "from customers where email=?") || pl[2].Info.Valid {
+		t.Fatalf("Unexpected redacted process list %v", pl)
+	}
+	if server.FullProcessList[0].Info.String != "SELECT * FROM customers WHERE email='jane@example.com' AND id=42" {
+		t.Fatal("Redaction modified the monitored process list")
+	}
+	cluster := &Cluster{Conf: config.Config{MonitorProcessListRedact: true}, APIUsers: map[string]APIUser{"dba": {User: "dba", Grants: map[string]bool{config.GrantDBShowProcessLiterals: true}}}}
+	if !cluster.IsProcessListRedacted("viewer") || cluster.IsProcessListRedacted("dba") {
+		t.Fatal("Expected redaction for viewer only")
+	}
+	secret := "UPDATE customers SET email='jane@example.com' WHERE id=42"
+	thread := LockWaitThread{Id: 1, Info: secret}
+	chain := LockWaitChain{Blocker: 1, Threads: []LockWaitThread{thread}}
+	mdl := MDLReport{Waiters: []MDLWait{{Waiter: thread, Blockers: []MDLBlocker{{LockWaitThread: thread}}}}}
+	rpl := ReplicationThreads{Workers: []ReplicationThread{{Id: 1, Info: secret}}}
+	tmp := ThreadTempReport{Threads: []ThreadTempUsage{{Id: 1, Info: secret}}}
+	bottleneck := ReplicationApplyBottleneck{Id: 1, Statement: secret}
+	for _, info := range []string{chain.Redacted().Threads[0].Info, mdl.Redacted().Waiters[0].Waiter.Info, mdl.Redacted().Waiters[0].Blockers[0].Info,
+		rpl.Redacted().Workers[0].Info, tmp.Redacted().Threads[0].Info, bottleneck.Redacted().Statement} {
+		if info != "update customers set email=? where id=?" {
+			t.Fatalf("Literal not redacted in %s", info)
+		}
+	}
+	if chain.Threads[0].Info != secret || mdl.Waiters[0].Blockers[0].Info != secret || rpl.Workers[0].Info != secret || tmp.Threads[0].Info != secret || bottleneck.Statement != secret {
+		t.Fatal("Redaction modified the monitored threads")
+	}
+	if q := cluster.getLoggedQuery(dbhelper.Processlist{Info: sql.NullString{String: secret, Valid: true}}); q != "update customers set email=? where id=?" {
+		t.Fatalf("Literal not redacted in kill log %s", q)
+	}
+}
+
+func TestTableColumnFlags(t *testing.T) {
+	cols := []dbhelper.TableColumn{
+		{Name: "id", Extra: "auto_increment"},
+		{Name: "total", Extra: "VIRTUAL GENERATED"},
+		{Name: "search", Extra: "PERSISTENT GENERATED"},
+		{Name: "audit", Extra: "INVISIBLE"},
+	}
+	for i := range cols {
+		cols[i].SetExtraFlags()
+	}
+	if cols[0].Generated != "" || cols[1].Generated != "VIRTUAL" || cols[2].Generated != "STORED" || !cols[3].Invisible || cols[1].Invisible {
+		t.Fatalf("Unexpected column flags %v", cols)
+	}
+	if generated, invisible := getColumnFlags(cols); !generated || !invisible {
+		t.Fatalf("Expected generated and invisible columns, got %t %t", generated, invisible)
+	}
+	if generated, invisible := getColumnFlags(cols[:1]); generated || invisible {
+		t.Fatalf("Expected plain columns, got %t %t", generated, invisible)
+	}
+}
+
+func TestDatabaseConfigRulesetFlavor(t *testing.T) {
+	cluster := &Cluster{}
+	module := `{"rulesets":[
+		{"ruleset_name":"mariadb.svc.mrm.db.cnf.generic","variables":[{"var_class":"file","var_value":"{\"path\":\"%%ENV:SVC_CONF_ENV_BASE_DIR%%/%%ENV:POD%%/etc/mysql/my.cnf\",\"fmt\":\"[mariadb]\\n\"}"}]},
+		{"ruleset_name":"mysql.svc.mrm.db.cnf.generic","variables":[{"var_class":"file","var_value":"{\"path\":\"%%ENV:SVC_CONF_ENV_BASE_DIR%%/%%ENV:POD%%/etc/mysql/my.cnf\",\"fmt\":\"[mysqld]\\n\"}"}]}]}`
+	if err := json.Unmarshal([]byte(module), &cluster.DBModule); err != nil {
+		t.Fatal(err)
+	}
+	server := &ServerMonitor{Id: "db1234567890", ClusterGroup: cluster, Datadir: "/data", DBVersion: dbhelper.NewMySQLVersion("8.0.26", "")}
+	if prefix := server.getDatabaseConfigRulesetPrefix(); prefix != "mysql.svc.mrm.db.cnf" {
+		t.Fatalf("Got ruleset prefix %s for mysql, expected mysql.svc.mrm.db.cnf", prefix)
+	}
+	files, _ := server.getDatabaseConfigFiles()
+	if len(files) != 1 || files[0].Content != "[mysqld]\n" || files[0].Path != "/data/init/etc/mysql/my.cnf" {
+		t.Fatalf("Expected the mysql ruleset only, got %v", files)
+	}
+	server.DBVersion = dbhelper.NewMySQLVersion("8.0.26-16", "Percona Server")
+	if prefix := server.getDatabaseConfigRulesetPrefix(); prefix != "mariadb.svc.mrm.db.cnf" {
+		t.Fatalf("Got ruleset prefix %s for percona without ruleset, expected the mariadb default", prefix)
+	}
+	server.DBVersion = dbhelper.NewMySQLVersion("Unknowed-0.0.0", "")
+	if prefix := server.getDatabaseConfigRulesetPrefix(); prefix != "mariadb.svc.mrm.db.cnf" {
+		t.Fatalf("Got ruleset prefix %s for unknown version, expected the mariadb default", prefix)
+	}
+}
+
+func TestGenerateDatabaseConfigUnchanged(t *testing.T) {
+	cluster := &Cluster{Conf: config.Config{WorkingDir: t.TempDir()}}
+	module := `{"rulesets":[
+		{"ruleset_name":"mariadb.svc.mrm.db.cnf.generic","variables":[{"var_class":"file","var_value":"{\"path\":\"%%ENV:SVC_CONF_ENV_BASE_DIR%%/%%ENV:POD%%/etc/mysql/my.cnf\",\"fmt\":\"[mariadb]\\n\"}"}]}]}`
+	if err := json.Unmarshal([]byte(module), &cluster.DBModule); err != nil {
+		t.Fatal(err)
+	}
+	server := &ServerMonitor{Id: "db1234567890", ClusterGroup: cluster, Datadir: t.TempDir(), DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", "")}
+	cnf := server.Datadir + "/init/etc/mysql/my.cnf"
+	tarball := server.Datadir + "/config.tar.gz"
+	m, generated, err := server.GenerateDatabaseConfig(false)
+	if err != nil || !generated || m.Sha256 == "" || len(m.Files) == 0 {
+		t.Fatalf("Expected the first generation to run and return the manifest, got %+v %v", m, err)
+	}
+	if content, err := ioutil.ReadFile(cnf); err != nil || string(content) != "[mariadb]\n" {
+		t.Fatalf("Got config %q %v", content, err)
+	}
+	before, err := os.Stat(tarball)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// a rewrite of the config would overwrite the marker
+	if err := ioutil.WriteFile(cnf, []byte("marker"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if unchanged, generated, err := server.GenerateDatabaseConfig(false); generated || err != nil || unchanged.Sha256 != m.Sha256 {
+		t.Fatalf("Expected generation skipped with the same manifest when the config did not change, got %+v %v", unchanged, err)
+	}
+	if content, _ := ioutil.ReadFile(cnf); string(content) != "marker" {
+		t.Fatalf("Config rewritten while unchanged, got %q", content)
+	}
+	if after, err := os.Stat(tarball); err != nil || !after.ModTime().Equal(before.ModTime()) || after.Size() != before.Size() {
+		t.Fatal("Tarball rewritten while the config did not change")
+	}
+	if _, generated, _ := server.GenerateDatabaseConfig(true); !generated {
+		t.Fatal("Expected forced generation to run")
+	}
+	if content, _ := ioutil.ReadFile(cnf); string(content) != "[mariadb]\n" {
+		t.Fatalf("Expected forced generation to rewrite the config, got %q", content)
+	}
+	os.Remove(tarball)
+	if _, generated, _ := server.GenerateDatabaseConfig(false); !generated {
+		t.Fatal("Expected generation to run when the tarball is missing")
+	}
+}
+
+func TestQueryExecHistogram(t *testing.T) {
+	query := func(info string, seconds float64) dbhelper.Processlist {
+		return dbhelper.Processlist{Command: "Query", Info: sql.NullString{String: info, Valid: true}, Time: sql.NullFloat64{Float64: seconds, Valid: true}}
+	}
+	pl := []dbhelper.Processlist{
+		query("SELECT * FROM orders WHERE id=1", 0.05),
+		query("SELECT * FROM orders WHERE id=2", 0.08),
+		query("UPDATE stock SET qty=qty-1 WHERE sku='A'", 42),
+		{Command: "Sleep", Time: sql.NullFloat64{Float64: 500, Valid: true}},
+	}
+	s := getQueryExecHistogram("db1", pl)
+	expected := "query_exec_seconds_bucket{instance=\"db1\",le=\"0.1\"} 2 # {digest=\"" + GetQueryDigestID(dbhelper.GetQueryDigest("SELECT * FROM orders WHERE id=2")) + "\"} 0.08\n" +
+		"query_exec_seconds_bucket{instance=\"db1\",le=\"1\"} 2\n" +
+		"query_exec_seconds_bucket{instance=\"db1\",le=\"10\"} 2\n" +
+		"query_exec_seconds_bucket{instance=\"db1\",le=\"60\"} 3 # {digest=\"" + GetQueryDigestID(dbhelper.GetQueryDigest("UPDATE stock SET qty=qty-1 WHERE sku='A'")) + "\"} 42\n" +
+		"query_exec_seconds_bucket{instance=\"db1\",le=\"300\"} 3\n" +
+		"query_exec_seconds_bucket{instance=\"db1\",le=\"+Inf\"} 3\n" +
+		"query_exec_seconds_sum{instance=\"db1\"} 42.13\n" +
+		"query_exec_seconds_count{instance=\"db1\"} 3\n"
+	if s != expected {
+		t.Fatalf("Got histogram\n%s\nexpected\n%s", s, expected)
+	}
+	if GetQueryDigestID(dbhelper.GetQueryDigest("SELECT * FROM orders WHERE id=1")) != GetQueryDigestID(dbhelper.GetQueryDigest("SELECT * FROM orders WHERE id=2")) {
+		t.Fatal("Expected the same digest id for the same query shape")
+	}
+}
+
+func TestStatusAnomalies(t *testing.T) {
+	server := &ServerMonitor{
+		ClusterGroup:    &Cluster{Conf: config.Config{MonitorStatusAnomalyThresholds: "ABORTED_CONNECTS:1, created_tmp_disk_tables:10,THREADS_RUNNING:50,QUERIES:bad"}},
+		Variables:       map[string]string{"HOSTNAME": "db1"},
+		PrevMonitorTime: 1000,
+		MonitorTime:     1010,
+		PrevStatus:      map[string]string{"ABORTED_CONNECTS": "100", "CREATED_TMP_DISK_TABLES": "50", "THREADS_RUNNING": "70"},
+		Status:          map[string]string{"ABORTED_CONNECTS": "160", "CREATED_TMP_DISK_TABLES": "90", "THREADS_RUNNING": "12"},
+	}
+	anomalies := server.GetStatusAnomalies()
+	if len(anomalies) != 1 || anomalies[0].Name != "ABORTED_CONNECTS" || anomalies[0].Rate != 6 {
+		t.Fatalf("Expected aborted connects at 6/s anomalous, got %v", anomalies)
+	}
+	server.Status["THREADS_RUNNING"] = "80"
+	if anomalies = server.GetStatusAnomalies(); len(anomalies) != 2 || anomalies[1].Name != "THREADS_RUNNING" || anomalies[1].Rate != 80 {
+		t.Fatalf("Expected threads running compared by value, got %v", anomalies)
+	}
+	s := server.GetPrometheusMetrics()
+	if !strings.Contains(s, "mysql_status_anomaly{instance=\"db1\",status=\"aborted_connects\"} 1\n") || !strings.Contains(s, "mysql_status_anomaly{instance=\"db1\",status=\"created_tmp_disk_tables\"} 0\n") {
+		t.Fatalf("Missing anomaly gauges in %s", s)
+	}
+}
+
+func TestMixedCollation(t *testing.T) {
+	cols := []dbhelper.TableColumn{
+		{Name: "id", Type: "int(11)"},
+		{Name: "email", Type: "varchar(255)", Collation: "utf8mb4_general_ci"},
+		{Name: "sku", Type: "varchar(32)", Collation: "latin1_swedish_ci"},
+		{Name: "created", Type: "datetime"},
+	}
+	m, ok := getMixedCollation("orders", "utf8mb4_general_ci", cols)
+	if !ok || len(m.Columns) != 1 || m.Columns[0] != (ColumnCollation{Name: "sku", Collation: "latin1_swedish_ci"}) {
+		t.Fatalf("Expected sku flagged, got %v", m)
+	}
+	if _, ok = getMixedCollation("orders", "utf8mb4_general_ci", cols[:2]); ok {
+		t.Fatal("Expected table with consistent collation not flagged")
+	}
+
+	columnQueries := 0
+	name := fmt.Sprintf("collation%d", time.Now().UnixNano())
+	sql.Register(name, execDriver{log: &execLog{}, rows: func(query string, args []driver.Value) *pkRows {
+		switch {
+		case strings.Contains(query, "information_schema.TABLES"):
+			return &pkRows{cols: []string{"TABLE_NAME", "TABLE_COLLATION"}, values: [][]driver.Value{{"customers", "utf8mb4_general_ci"}, {"orders", "utf8mb4_general_ci"}}}
+		case strings.Contains(query, "information_schema.COLUMNS"):
+			columnQueries++
+			return &pkRows{cols: []string{"Table_name", "Column_name", "Ordinal_position", "Column_type", "Is_nullable", "Has_default", "Column_default", "Collation_name", "Extra", "Generation_expression"},
+				values: [][]driver.Value{{"customers", "email", int64(1), "varchar(255)", int64(0), int64(0), "", "utf8mb4_general_ci", "", ""}, {"orders", "sku", int64(1), "varchar(32)", int64(0), int64(0), "", "latin1_swedish_ci", "", ""}}}
+		}
+		return nil
+	}})
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	server := &ServerMonitor{URL: "db1:3306", Conn: db, ClusterGroup: &Cluster{}, DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", "")}
+	tables, err := server.GetTablesWithMixedCollation("app")
+	if err != nil || columnQueries != 1 || len(tables) != 1 || tables[0].Table != "orders" {
+		t.Fatalf("Expected orders flagged from one columns query, got %v %d queries %v", tables, columnQueries, err)
+	}
+}
+
+func TestPurgeLagStatus(t *testing.T) {
+	innodbStatus := `------------
+TRANSACTIONS
+------------
+Trx id counter 421900
+Purge done for trx's n:o < 421234 undo n:o < 0 state: running but idle
+History list length %d
+LIST OF TRANSACTIONS FOR EACH SESSION:
+---TRANSACTION 421850, ACTIVE 3 sec
+---TRANSACTION 421234, ACTIVE 7200 sec
+---TRANSACTION 421880, not started
+`
+	server := &ServerMonitor{
+		ClusterGroup: &Cluster{Conf: config.Config{MonitorInnoDBPurgeLagThreshold: 1000}},
+		Variables:    map[string]string{"HOSTNAME": "db1"},
+	}
+	for _, hll := range []int{200, 700, 1500} {
+		server.EngineInnoDB = dbhelper.ParseEngineInnoDBVariables(fmt.Sprintf(innodbStatus, hll))
+		server.addHistoryListLength()
+	}
+	p := server.GetPurgeLagStatus()
+	if p.HistoryListLength != 1500 || p.Trend != 1300 || !p.Alert || p.OldestActiveTrxID != "421234" || p.OldestActiveTrxSeconds != 7200 {
+		t.Fatalf("Unexpected purge lag %+v", p)
+	}
+	s := server.GetPrometheusMetrics()
+	if !strings.Contains(s, "mysql_innodb_history_list_length{instance=\"db1\"} 1500\n") || !strings.Contains(s, "mysql_innodb_purge_lag_alert{instance=\"db1\"} 1\n") {
+		t.Fatalf("Missing purge lag gauges in %s", s)
+	}
+	server.ClusterGroup.Conf.MonitorInnoDBPurgeLagThreshold = 0
+	if server.GetPurgeLagStatus().Alert {
+		t.Fatal("Expected no alert with threshold disabled")
+	}
+}
+
+func TestDeadlockHistory(t *testing.T) {
+	innodbStatus := `
+=====================================
+2024-03-12 10:16:01 0x7f1c2c0b7700 INNODB MONITOR OUTPUT
+=====================================
+Per second averages calculated from the last 5 seconds
+-----------------
+BACKGROUND THREAD
+-----------------
+srv_master_thread loops: 41 srv_active, 0 srv_shutdown, 3120 srv_idle
+------------------------
+LATEST DETECTED DEADLOCK
+------------------------
+%s 0x7f1c2c0b7700
+*** (1) TRANSACTION:
+TRANSACTION %d, ACTIVE 12 sec starting index read
+mysql tables in use 1, locked 1
+LOCK WAIT 3 lock struct(s), heap size 1128, 2 row lock(s)
+MySQL thread id 12, OS thread handle 139758, query id 125 localhost root updating
+UPDATE t SET v=1 WHERE id=2
+*** (1) WAITING FOR THIS LOCK TO BE GRANTED:
+RECORD LOCKS space id 5 page no 3 n bits 72 index PRIMARY of table ` + "`test`.`t`" + ` trx id 4363 lock_mode X locks rec but not gap waiting
+*** (2) TRANSACTION:
+TRANSACTION %d, ACTIVE 8 sec starting index read
+mysql tables in use 1, locked 1
+3 lock struct(s), heap size 1128, 2 row lock(s)
+MySQL thread id 13, OS thread handle 139759, query id 126 localhost root updating
+UPDATE t SET v=2 WHERE id=1
+*** (2) HOLDS THE LOCK(S):
+RECORD LOCKS space id 5 page no 3 n bits 72 index PRIMARY of table ` + "`test`.`t`" + ` trx id 4364 lock_mode X locks rec but not gap
+*** WE ROLL BACK TRANSACTION (1)
+------------
+TRANSACTIONS
+------------
+Trx id counter 4370
+History list length 12
+`
+	noDeadlock := `------------
+TRANSACTIONS
+------------
+Trx id counter 4370
+History list length 12
+`
+	tests := []struct {
+		status       string
+		time         string
+		transactions []string
+	}{
+		{noDeadlock, "", nil},
+		{fmt.Sprintf(innodbStatus, "2024-03-12 10:15:42", 4363, 4364), "2024-03-12 10:15:42 0x7f1c2c0b7700",
+			[]string{"TRANSACTION 4363, ACTIVE 12 sec starting index read", "TRANSACTION 4364, ACTIVE 8 sec starting index read"}},
+	}
+	for _, test := range tests {
+		d := dbhelper.ParseEngineInnoDBDeadlock(test.status)
+		if test.transactions == nil {
+			if d != nil {
+				t.Fatalf("Expected no deadlock, got %+v", d)
+			}
+			continue
+		}
+		if d == nil || d.Time != test.time || !reflect.DeepEqual(d.Transactions, test.transactions) {
+			t.Fatalf("Unexpected deadlock %+v", d)
+		}
+		if !strings.HasSuffix(d.Text, "*** WE ROLL BACK TRANSACTION (1)") || strings.Contains(d.Text, "Trx id counter") {
+			t.Fatalf("Unexpected deadlock text %q", d.Text)
+		}
+	}
+
+	server := &ServerMonitor{}
+	server.addDeadlockHistory(dbhelper.ParseEngineInnoDBDeadlock(noDeadlock))
+	first := fmt.Sprintf(innodbStatus, "2024-03-12 10:15:42", 4363, 4364)
+	server.addDeadlockHistory(dbhelper.ParseEngineInnoDBDeadlock(first))
+	server.addDeadlockHistory(dbhelper.ParseEngineInnoDBDeadlock(first))
+	if h := server.GetDeadlockHistory(); len(h) != 1 {
+		t.Fatalf("Expected the deadlock reported by two polls once, got %d", len(h))
+	}
+	for i := 1; i <= deadlockHistorySize; i++ {
+		status := fmt.Sprintf(innodbStatus, fmt.Sprintf("2024-03-12 11:%02d:00", i), 5000+2*i, 5001+2*i)
+		server.addDeadlockHistory(dbhelper.ParseEngineInnoDBDeadlock(status))
+	}
+	h := server.GetDeadlockHistory()
+	if len(h) != deadlockHistorySize || h[0].Time != "2024-03-12 11:01:00 0x7f1c2c0b7700" || h[len(h)-1].Time != fmt.Sprintf("2024-03-12 11:%02d:00 0x7f1c2c0b7700", deadlockHistorySize) {
+		t.Fatalf("Unexpected rotated history of %d deadlocks from %s", len(h), h[0].Time)
+	}
+}
+
+func TestSuggestPrimaryKey(t *testing.T) {
+	cols := []dbhelper.TableColumn{{Name: "email"}, {Name: "tenant"}, {Name: "code"}, {Name: "note", Nullable: true}}
+	idx := []dbhelper.TableIndexColumn{{Index: "uk_code", Column: "tenant", Position: 1}, {Index: "uk_code", Column: "code", Position: 2}, {Index: "uk_email", Column: "email", Position: 1}, {Index: "uk_note", Column: "note", Position: 1}}
+	p, err := suggestPrimaryKey("app", "users", cols, idx)
+	if err != nil || p.Index != "uk_email" || p.Statement != "ALTER TABLE `app`.`users` DROP INDEX `uk_email`, ADD PRIMARY KEY (`email`)" {
+		t.Fatalf("Expected smallest not null unique index promoted, got %+v %s", p, err)
+	}
+	p, err = suggestPrimaryKey("app", "logs", []dbhelper.TableColumn{{Name: "id"}, {Name: "note", Nullable: true}}, idx[3:])
+	if err != nil || p.Index != "" || p.Statement != "ALTER TABLE `app`.`logs` ADD COLUMN `row_id` BIGINT UNSIGNED NOT NULL AUTO_INCREMENT PRIMARY KEY FIRST" {
+		t.Fatalf("Expected synthetic column, got %+v %s", p, err)
+	}
+	// a functional index part is not a column
+	functional := []dbhelper.TableIndexColumn{{Index: "uk_lower", Column: "(lower(`email`))", Position: 1}}
+	if p, err = suggestPrimaryKey("app", "users", cols[:1], functional); err != nil || p.Index != "" {
+		t.Fatalf("Expected functional unique index not promoted, got %+v %s", p, err)
+	}
+	if _, err = suggestPrimaryKey("app", "seq", []dbhelper.TableColumn{{Name: "n", Extra: "auto_increment"}}, nil); err == nil {
+		t.Fatal("Expected error for existing auto increment column")
+	}
+	if _, err = suggestPrimaryKey("app", "users", cols, []dbhelper.TableIndexColumn{{Index: "PRIMARY", Column: "email"}}); err == nil {
+		t.Fatal("Expected error for table with primary key")
+	}
+}
+
+func TestBuildLockWaitChains(t *testing.T) {
+	table := sql.NullString{String: "`app`.`t1`", Valid: true}
+	waits := []dbhelper.LockWait{
+		{WaitingThread: 11, BlockingThread: 10, LockedTable: table},
+		{WaitingThread: 12, BlockingThread: 11, LockedTable: table},
+		{WaitingThread: 13, BlockingThread: 10, LockedTable: table},
+		{WaitingThread: 21, BlockingThread: 20, LockedTable: table},
+	}
+	pl := []dbhelper.Processlist{
+		{Id: 10, User: "app", Command: "Sleep", Time: sql.NullFloat64{Float64: 120, Valid: true}},
+		{Id: 12, User: "app", Command: "Query", Info: sql.NullString{String: "UPDATE t1 SET c=1 WHERE id=1", Valid: true}},
+	}
+	chains := buildLockWaitChains(waits, pl)
+	if len(chains) != 3 {
+		t.Fatalf("Got %d chains, expected 3: %+v", len(chains), chains)
+	}
+	expected := [][]uint64{{10, 11, 12}, {10, 13}, {20, 21}}
+	for i, c := range chains {
+		if c.Blocker != expected[i][0] || len(c.Threads) != len(expected[i]) {
+			t.Fatalf("Chain %d is %+v, expected %v", i, c, expected[i])
+		}
+		for j, th := range c.Threads {
+			if th.Id != expected[i][j] {
+				t.Fatalf("Chain %d is %+v, expected %v", i, c, expected[i])
+			}
+		}
+	}
+	if chains[0].Waiters != 3 || chains[2].Waiters != 1 {
+		t.Fatalf("Got %d and %d waiters, expected 3 and 1", chains[0].Waiters, chains[2].Waiters)
+	}
+	if head := chains[0].Threads[0]; head.User != "app" || head.Time != 120 || head.LockedTable != "" {
+		t.Fatalf("Unexpected blocker %+v", head)
+	}
+	if last := chains[0].Threads[2]; last.Info != "UPDATE t1 SET c=1 WHERE id=1" || last.LockedTable != "`app`.`t1`" {
+		t.Fatalf("Unexpected waiter %+v", last)
+	}
+	// threads waiting on each other have no head-of-line blocker
+	if chains := buildLockWaitChains([]dbhelper.LockWait{{WaitingThread: 1, BlockingThread: 2}, {WaitingThread: 2, BlockingThread: 1}}, nil); len(chains) != 0 {
+		t.Fatalf("Got chains %+v for a deadlock", chains)
+	}
+}
+
+func TestBuildMDLWaiters(t *testing.T) {
+	str := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	mdlWait := str("Waiting for table metadata lock")
+	pl := []dbhelper.Processlist{
+		{Id: 10, User: "app", Command: "Sleep", Time: sql.NullFloat64{Float64: 300, Valid: true}},
+		{Id: 11, User: "app", Command: "Query", Time: sql.NullFloat64{Float64: 5, Valid: true}, Info: str("SELECT * FROM t1")},
+		{Id: 12, User: "app", Command: "Query", Info: str("SELECT * FROM t2")},
+		{Id: 30, User: "dba", Command: "Query", State: mdlWait, Info: str("ALTER TABLE t1 ADD c INT")},
+		{Id: 31, User: "app", Command: "Query", State: mdlWait, Info: str("SELECT * FROM t1")},
+	}
+	locks := []dbhelper.MetaDataLock{
+		{Thread_id: 30, Lock_mode: str("MDL_INTENTION_EXCLUSIVE"), Lock_schema: str("app"), Lock_name: str("")},
+		{Thread_id: 30, Lock_mode: str("MDL_SHARED_UPGRADABLE"), Lock_schema: str("app"), Lock_name: str("t1")},
+		{Thread_id: 11, Lock_mode: str("MDL_SHARED_READ"), Lock_duration: str("MDL_TRANSACTION"), Lock_schema: str("app"), Lock_name: str("t1")},
+		{Thread_id: 10, Lock_mode: str("MDL_SHARED_READ"), Lock_duration: str("MDL_TRANSACTION"), Lock_schema: str("app"), Lock_name: str("t1")},
+		{Thread_id: 12, Lock_mode: str("MDL_SHARED_READ"), Lock_schema: str("app"), Lock_name: str("t2")},
+	}
+	waits := buildMDLWaiters(pl, locks)
+	if len(waits) != 2 {
+		t.Fatalf("Got %d waiters, expected 2: %+v", len(waits), waits)
+	}
+	alter := waits[0]
+	if alter.Waiter.Id != 30 || alter.Waiter.LockedTable != "app.t1" || len(alter.Blockers) != 2 {
+		t.Fatalf("Unexpected ALTER wait %+v", alter)
+	}
+	if b := alter.Blockers[0]; b.Id != 10 || !b.IdleInTransaction || b.Info != "" || b.LockMode != "MDL_SHARED_READ" {
+		t.Fatalf("Expected the idle in transaction blocker first, got %+v", b)
+	}
+	if b := alter.Blockers[1]; b.Id != 11 || b.IdleInTransaction || b.Info != "SELECT * FROM t1" {
+		t.Fatalf("Unexpected running blocker %+v", b)
+	}
+	// a query queued behind the ALTER holds no lock on the table, the ALTER waiting for its exclusive lock blocks it
+	if w := waits[1]; w.Waiter.Id != 31 || w.Waiter.LockedTable != "app.t1" || len(w.Blockers) != 1 || w.Blockers[0].Id != 30 {
+		t.Fatalf("Unexpected queued wait %+v", w)
+	}
+	// a waiter holding locks on several tables waits on all of them
+	multi := append(pl, dbhelper.Processlist{Id: 32, User: "dba", Command: "Query", State: mdlWait, Info: str("RENAME TABLE t2 TO t3, t4 TO t2")})
+	waits = buildMDLWaiters(multi, append(locks,
+		dbhelper.MetaDataLock{Thread_id: 32, Lock_mode: str("MDL_SHARED_NO_WRITE"), Lock_schema: str("app"), Lock_name: str("t2")},
+		dbhelper.MetaDataLock{Thread_id: 32, Lock_mode: str("MDL_SHARED_NO_WRITE"), Lock_schema: str("app"), Lock_name: str("t4")}))
+	if w := waits[2]; w.Waiter.Id != 32 || w.Waiter.LockedTable != "app.t2,app.t4" || len(w.Blockers) != 1 || w.Blockers[0].Id != 12 {
+		t.Fatalf("Unexpected multi table wait %+v", w)
+	}
+
+	// performance_schema also gives the pending locks, the one of the waiter names the table
+	granted, pending := str("GRANTED"), str("PENDING")
+	locks = []dbhelper.MetaDataLock{
+		{Thread_id: 30, Lock_mode: str("SHARED_UPGRADABLE"), Lock_schema: str("app"), Lock_name: str("t1"), Lock_status: granted},
+		{Thread_id: 30, Lock_mode: str("EXCLUSIVE"), Lock_schema: str("app"), Lock_name: str("t1"), Lock_status: pending},
+		{Thread_id: 31, Lock_mode: str("SHARED_READ"), Lock_schema: str("app"), Lock_name: str("t1"), Lock_status: pending},
+		{Thread_id: 11, Lock_mode: str("SHARED_READ"), Lock_schema: str("app"), Lock_name: str("t1"), Lock_status: granted},
+	}
+	waits = buildMDLWaiters(pl, locks)
+	if len(waits) != 2 || len(waits[0].Blockers) != 1 || waits[0].Blockers[0].Id != 11 {
+		t.Fatalf("Unexpected performance_schema waits %+v", waits)
+	}
+	if w := waits[1]; w.Waiter.LockedTable != "app.t1" || len(w.Blockers) != 2 || w.Blockers[0].Id != 11 || w.Blockers[1].Id != 30 {
+		t.Fatalf("Expected the queued query blocked on app.t1 by the reader and the pending ALTER, got %+v", w)
+	}
+	// a DDL with only a pending exclusive lock still blocks the queries queued after it
+	locks = []dbhelper.MetaDataLock{
+		{Thread_id: 30, Lock_mode: str("EXCLUSIVE"), Lock_schema: str("app"), Lock_name: str("t1"), Lock_status: pending},
+		{Thread_id: 31, Lock_mode: str("SHARED_READ"), Lock_schema: str("app"), Lock_name: str("t1"), Lock_status: pending},
+		{Thread_id: 11, Lock_mode: str("SHARED_READ"), Lock_schema: str("app"), Lock_name: str("t1"), Lock_status: granted},
+	}
+	if w := buildMDLWaiters(pl, locks)[1]; len(w.Blockers) != 2 || w.Blockers[1].Id != 30 || w.Blockers[1].LockMode != "EXCLUSIVE" {
+		t.Fatalf("Expected the pending exclusive lock as blocker, got %+v", w)
+	}
+
+	server := &ServerMonitor{Variables: map[string]string{}, ClusterGroup: &Cluster{}}
+	if report, err := server.GetMDLWaiters(); err != nil || report.Available || report.Reason == "" {
+		t.Fatalf("Expected lock info unavailable, got %+v %s", report, err)
+	}
+}
+
+func TestBuildThreadTempUsage(t *testing.T) {
+	str := func(s string) sql.NullString { return sql.NullString{String: s, Valid: true} }
+	secs := func(f float64) sql.NullFloat64 { return sql.NullFloat64{Float64: f, Valid: true} }
+	pl := []dbhelper.Processlist{
+		{Id: 10, Command: "Sleep", Time: secs(600)},
+		{Id: 11, Command: "Query", Time: secs(2), State: str("Sending data"), Info: str("SELECT 1")},
+		{Id: 12, Command: "Query", Time: secs(40), State: str("Copying to tmp table on disk"), Info: str("SELECT a FROM t GROUP BY a")},
+		{Id: 13, Command: "Query", Time: secs(8), State: str("Creating sort index"), Info: str("CALL report()")},
+		{Id: 14, Command: "Query", Time: secs(1), State: str("Creating tmp table")},
+		{Id: 15, Command: "Query", Time: secs(90), State: str("Sending data")},
+	}
+	tmp := []dbhelper.ThreadTempTables{
+		{Thread_id: 10, Tmp_tables: 4, Tmp_disk_tables: 4},
+		{Thread_id: 13, Tmp_tables: 1, Tmp_disk_tables: 1},
+		{Thread_id: 13, Tmp_tables: 2, Tmp_disk_tables: 2},
+		{Thread_id: 15, Tmp_tables: 1},
+	}
+	threads := buildThreadTempUsage(pl, tmp)
+	var ids []uint64
+	for _, th := range threads {
+		ids = append(ids, th.Id)
+	}
+	if !reflect.DeepEqual(ids, []uint64{13, 12, 15, 14}) {
+		t.Fatalf("Got threads %v, expected 13 12 15 14", ids)
+	}
+	if th := threads[0]; th.TmpDiskTables != 3 || th.TmpTables != 3 || !th.Worst || th.OnDisk {
+		t.Fatalf("Unexpected nested statement usage %+v", th)
+	}
+	if th := threads[1]; !th.OnDisk || !th.Worst || th.Info != "SELECT a FROM t GROUP BY a" {
+		t.Fatalf("Expected the disk spilling state flagged, got %+v", th)
+	}
+	if threads[2].Worst || threads[3].Worst {
+		t.Fatalf("Expected in memory temporary tables not flagged, got %+v", threads[2:])
+	}
+	server := &ServerMonitor{Variables: map[string]string{"PERFORMANCE_SCHEMA": "OFF"}, ClusterGroup: &Cluster{}}
+	if r, err := server.GetThreadTempUsage(); err != nil || r.Available || r.Reason == "" || r.Threads == nil {
+		t.Fatalf("Expected unavailable report without performance_schema, got %+v %v", r, err)
+	}
+}
+
+func TestThreadPoolStats(t *testing.T) {
+	server := &ServerMonitor{Variables: map[string]string{"THREAD_HANDLING": "one-thread-per-connection"}, ClusterGroup: &Cluster{}}
+	if tp := server.GetThreadPoolStats(); tp.Enabled || len(tp.Groups) != 0 {
+		t.Fatalf("Got %+v, expected a disabled thread pool", tp)
+	}
+	variables := map[string]string{"THREAD_HANDLING": "pool-of-threads", "THREAD_POOL_SIZE": "4"}
+	status := map[string]string{"THREADPOOL_THREADS": "10", "THREADPOOL_IDLE_THREADS": "3"}
+	tp := getThreadPoolStats(variables, status, nil)
+	if !tp.Enabled || tp.Size != 4 || tp.Threads != 10 || tp.IdleThreads != 3 || tp.ActiveThreads != 7 || tp.QueueLength != 0 {
+		t.Fatalf("Unexpected thread pool stats without groups %+v", tp)
+	}
+	groups := []dbhelper.ThreadPoolGroup{
+		{Group_id: 0, Threads: 6, Active_threads: 4, Queue_length: 12, Is_stalled: true},
+		{Group_id: 1, Threads: 4, Active_threads: 1, Queue_length: 0},
+	}
+	tp = getThreadPoolStats(variables, status, groups)
+	if tp.ActiveThreads != 5 || tp.QueueLength != 12 || tp.StalledGroups != 1 || len(tp.Groups) != 2 {
+		t.Fatalf("Unexpected thread pool stats with groups %+v", tp)
+	}
+	server.ThreadPool = tp
+	if cached := server.GetThreadPoolStats(); cached.QueueLength != 12 || len(cached.Groups) != 2 {
+		t.Fatalf("Expected the thread pool stats of the last monitoring loop, got %+v", cached)
+	}
+	server.refreshThreadPoolStats()
+	if tp := server.GetThreadPoolStats(); tp.Enabled {
+		t.Fatalf("Expected thread pool disabled by refresh, got %+v", tp)
+	}
+}
+
+func TestTableStorageInfo(t *testing.T) {
+	ns := func(s string) sql.NullString {
+		return sql.NullString{String: s, Valid: true}
+	}
+	ts := []dbhelper.TableStorage{
+		{Table: "t1", Engine: ns("InnoDB"), Row_format: ns("Compressed"), Create_options: ns("row_format=COMPRESSED key_block_size=8")},
+		{Table: "t2", Engine: ns("InnoDB"), Row_format: ns("Dynamic"), Create_options: ns("`PAGE_COMPRESSED`='ON' `PAGE_COMPRESSION_LEVEL`='6'")},
+		{Table: "t3", Engine: ns("InnoDB"), Row_format: ns("Dynamic"), Create_options: ns("COMPRESSION=\"zlib\"")},
+		{Table: "t4", Engine: ns("InnoDB"), Row_format: ns("Dynamic"), Create_options: ns("COMPRESSION=\"None\"")},
+		{Table: "t5", Engine: ns("MyISAM"), Row_format: ns("Compressed")},
+	}
+	expected := []TableStorageInfo{
+		{Table: "t1", RowFormat: "COMPRESSED", KeyBlockSize: 8, SuggestPageCompression: true},
+		{Table: "t2", RowFormat: "DYNAMIC", PageCompressed: true, Compression: "lz4"},
+		{Table: "t3", RowFormat: "DYNAMIC", PageCompressed: true, Compression: "zlib"},
+		{Table: "t4", RowFormat: "DYNAMIC"},
+		{Table: "t5", RowFormat: "COMPRESSED"},
+	}
+	res := getTableStorageInfo("app", ts, "LZ4")
+	if len(res) != len(expected) {
+		t.Fatalf("Got %d tables, expected %d", len(res), len(expected))
+	}
+	for i, e := range expected {
+		r := res[i]
+		if r.Schema != "app" || r.Table != e.Table || r.RowFormat != e.RowFormat || r.KeyBlockSize != e.KeyBlockSize || r.PageCompressed != e.PageCompressed || r.Compression != e.Compression || r.SuggestPageCompression != e.SuggestPageCompression {
+			t.Fatalf("Got %+v, expected %+v", r, e)
+		}
+	}
+}
+
+func TestGetConnectBackoff(t *testing.T) {
+	base := 100 * time.Millisecond
+	if d := getConnectBackoff(0, base, rand.Float64); d != 0 {
+		t.Fatalf("Expected no backoff before the first attempt, got %s", d)
+	}
+	if d := getConnectBackoff(1, 0, rand.Float64); d != 0 {
+		t.Fatalf("Expected no backoff without base delay, got %s", d)
+	}
+	for retry := 1; retry <= 4; retry++ {
+		max := base << uint(retry-1)
+		if d := getConnectBackoff(retry, base, func() float64 { return 0 }); d != max/2 {
+			t.Fatalf("Expected retry %d lower bound %s, got %s", retry, max/2, d)
+		}
+		if d := getConnectBackoff(retry, base, func() float64 { return 1 }); d != max {
+			t.Fatalf("Expected retry %d upper bound %s, got %s", retry, max, d)
+		}
+		for i := 0; i < 100; i++ {
+			if d := getConnectBackoff(retry, base, rand.Float64); d < max/2 || d > max {
+				t.Fatalf("Retry %d backoff %s out of [%s, %s]", retry, d, max/2, max)
+			}
+		}
+	}
+	if d := getConnectBackoff(64, base, func() float64 { return 1 }); d != base<<15 {
+		t.Fatalf("Expected backoff capped at %s, got %s", base<<15, d)
+	}
+}
+
+func TestGetNewDBConnBackoff(t *testing.T) {
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{sme: sme, HaveDBTLSCert: true, Conf: config.Config{Timeout: 1, ReadTimeout: 1, MonitorConnectRetry: 2, MonitorConnectBackoff: 20}}
+	server := &ServerMonitor{URL: "127.0.0.1:1", Host: "127.0.0.1", Port: "1", User: "root", ClusterGroup: cluster, TLSConfigUsed: ConstTLSCurrentConfig}
+	server.SetDSN()
+	dsn := server.DSN
+	start := time.Now()
+	if _, err := server.GetNewDBConn(); err == nil {
+		t.Fatal("Expected connection failure")
+	}
+	// two retries sleeping within [10ms, 20ms] then [20ms, 40ms]
+	if elapsed := time.Since(start); elapsed < 30*time.Millisecond || elapsed > 5*time.Second {
+		t.Fatalf("Unexpected connection ladder duration %s", elapsed)
+	}
+	if !sme.CurState.Search("WARN0113") {
+		t.Fatal("Expected final connection failure in state")
+	}
+	if server.TLSConfigUsed != ConstTLSCurrentConfig || server.DSN != dsn {
+		t.Fatalf("Expected DSN reset to current TLS config, got %s", server.DSN)
+	}
+
+	sme.Init()
+	cluster.Conf.MonitorConnectRetry = 0
+	if _, err := server.GetNewDBConn(); err == nil || sme.CurState.Search("WARN0113") {
+		t.Fatal("Expected a single failed attempt without fallback state")
+	}
+}
+
+// pfsThreadsDriver returns the rows of performance_schema.threads for a MySQL slave, the replication threads have
+// a NULL PROCESSLIST_USER returned as nullUser unless the query maps it to system user
+type pfsThreadsDriver struct {
+	nullUser string
+}
+
+func (d pfsThreadsDriver) Open(name string) (driver.Conn, error) { return pfsThreadsConn(d), nil }
+
+type pfsThreadsConn pfsThreadsDriver
+
+func (c pfsThreadsConn) Prepare(query string) (driver.Stmt, error) {
+	return pfsThreadsStmt{pfsThreadsDriver(c), query}, nil
+}
+func (c pfsThreadsConn) Close() error              { return nil }
+func (c pfsThreadsConn) Begin() (driver.Tx, error) { return nil, fmt.Errorf("not supported") }
+
+type pfsThreadsStmt struct {
+	d     pfsThreadsDriver
+	query string
+}
+
+func (s pfsThreadsStmt) Close() error  { return nil }
+func (s pfsThreadsStmt) NumInput() int { return -1 }
+func (s pfsThreadsStmt) Exec(args []driver.Value) (driver.Result, error) {
+	return nil, fmt.Errorf("not supported")
+}
+func (s pfsThreadsStmt) Query(args []driver.Value) (driver.Rows, error) {
+	user := s.d.nullUser
+	if strings.Contains(s.query, "COALESCE(PROCESSLIST_USER,'system user')") {
+		user = "system user"
+	}
+	return &pkRows{
+		cols: []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info", "Progress"},
+		values: [][]driver.Value{
+			{int64(5), user, "", nil, "Connect", float64(100), "Waiting for source to send event", nil, float64(0)},
+			{int64(6), user, "", nil, "Query", float64(3), "Replica has read all relay log; waiting for more updates", nil, float64(0)},
+			{int64(7), user, "", nil, "Query", float64(40), "Applying batch of row changes (update)", "UPDATE t SET a=1", float64(0)},
+			{int64(20), "app", "app1:5000", "test", "Query", float64(40), "executing", "SELECT SLEEP(60)", float64(0)},
+		},
+	}, nil
+}
+
+func TestGetProcessListPFSSystemUser(t *testing.T) {
+	name := fmt.Sprintf("pfsthreads%d", time.Now().UnixNano())
+	sql.Register(name, pfsThreadsDriver{})
+	db, err := sqlx.Open(name, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	conf := config.Config{MonitorProcessList: true, MonitorProcessListPFS: true}
+	server := &ServerMonitor{URL: "db2:3306", Conn: db, HavePFS: true, ClusterGroup: &Cluster{Conf: conf}, DBVersion: dbhelper.NewMySQLVersion("8.0.20", "")}
+	server.FullProcessList, err = server.getProcessList()
+	if err != nil || len(server.FullProcessList) != 4 {
+		t.Fatalf("Got processlist %+v %s", server.FullProcessList, err)
+	}
+	for _, q := range server.FullProcessList[:3] {
+		if q.User != "system user" {
+			t.Fatalf("Expected replication thread %d as system user, got %q", q.Id, q.User)
+		}
+	}
+	candidates := server.getKillPolicyCandidates()
+	if len(candidates) != 1 || candidates[0].Id != 20 {
+		t.Fatalf("Expected only the client query as kill candidate, got %+v", candidates)
+	}
+	threads, err := server.GetReplicationThreads()
+	if err != nil || len(threads.IO) != 1 || len(threads.SQL) != 1 || len(threads.Workers) != 1 {
+		t.Fatalf("Unexpected replication threads %+v %s", threads, err)
+	}
+}
@@ -13,6 +13,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/signal18/replication-manager/utils/dbhelper"
 )
@@ -106,6 +107,29 @@ func (server *ServerMonitor) HasRestartCookie() bool {
 	return true
 }
 
+// HasCompletedRestart returns true when the server holding a restart cookie started after the cookie was set
+// and is back in the topology, a slave must replicate within failover-max-slave-delay
+func (server *ServerMonitor) HasCompletedRestart(now time.Time) bool {
+	if server == nil || server.IsDown() {
+		return false
+	}
+	cookie, err := os.Stat(server.Datadir + "/@cookie_restart")
+	if err != nil {
+		return false
+	}
+	uptime, err := strconv.ParseInt(server.Status["UPTIME"], 10, 64)
+	if err != nil || !now.Add(-time.Duration(uptime)*time.Second).After(cookie.ModTime()) {
+		return false
+	}
+	if !server.IsSlave {
+		return true
+	}
+	if !server.IsIOThreadRunning() || !server.IsSQLThreadRunning() {
+		return false
+	}
+	return server.ClusterGroup.Conf.FailMaxDelay <= 0 || server.GetExcessReplicationDelay() <= server.ClusterGroup.Conf.FailMaxDelay
+}
+
 func (server *ServerMonitor) HasReprovCookie() bool {
 	if server == nil {
 		return false
@@ -124,6 +148,14 @@ func (server *ServerMonitor) HasGtidStrictMode() bool {
 	return server.Variables["GTID_STRICT_MODE"] == "ON"
 }
 
+// HasGtidStrictness tells if gtid_strict_mode is on for MariaDB or gtid_mode and enforce_gtid_consistency are on for MySQL
+func (server *ServerMonitor) HasGtidStrictness() bool {
+	if server.IsMariaDB() {
+		return server.HasGtidStrictMode()
+	}
+	return server.Variables["GTID_MODE"] == "ON" && server.Variables["ENFORCE_GTID_CONSISTENCY"] == "ON"
+}
+
 func (server *ServerMonitor) HasBinlog() bool {
 	return server.Variables["LOG_BIN"] == "ON"
 }
@@ -372,6 +404,34 @@ func (server *ServerMonitor) IsFailed() bool {
 	return false
 }
 
+// IsReadEligible tells if the server replication delay allow to serve reads
+func (server *ServerMonitor) IsReadEligible() bool {
+	return !server.ReadLagState.Alerting
+}
+
+func (server *ServerMonitor) HasReplicationFilters() bool {
+	return len(server.GetReplicationFilters()) > 0
+}
+
+// HasReplicationDelay returns false when the replication delay can not be measured, Seconds_Behind_Master is NULL
+// when a replication thread is stopped
+func (server *ServerMonitor) HasReplicationDelay() bool {
+	ss, err := server.GetSlaveStatus(server.ReplicationSourceName)
+	if err != nil {
+		return false
+	}
+	return ss.SecondsBehindMaster.Valid
+}
+
+// HasReplicationError returns true when the replication channel reports an IO or SQL error
+func (server *ServerMonitor) HasReplicationError() bool {
+	ss, err := server.GetSlaveStatus(server.ReplicationSourceName)
+	if err != nil {
+		return false
+	}
+	return (ss.LastIOErrno.String != "" && ss.LastIOErrno.String != "0") || (ss.LastSQLErrno.String != "" && ss.LastSQLErrno.String != "0")
+}
+
 func (server *ServerMonitor) IsReplicationBroken() bool {
 	if server.IsSQLThreadRunning() == false || server.IsIOThreadRunning() == false {
 		return true
@@ -459,3 +519,18 @@ func (server *ServerMonitor) IsMariaDB() bool {
 func (server *ServerMonitor) HasSuperReadOnlyCapability() bool {
 	return server.DBVersion.IsMySQLOrPerconaGreater57()
 }
+
+func (server *ServerMonitor) IsReplicationDelayAlerting() bool {
+	return server.ReplicationDelayAlertState.Alerting
+}
+
+// GetReplicationDelayAlertLevel returns the highest replication delay alert tier raised
+func (server *ServerMonitor) GetReplicationDelayAlertLevel() string {
+	if server.ReplicationDelayAlertState.Alerting {
+		return DelayAlertLevelCritical
+	}
+	if server.ReplicationDelayWarnState.Alerting {
+		return DelayAlertLevelWarning
+	}
+	return DelayAlertLevelOK
+}
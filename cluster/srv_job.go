@@ -110,8 +110,16 @@ func (server *ServerMonitor) JobBackupPhysical() (int64, error) {
 			return jobid, err
 		} else {
 	*/
-	port, err := server.ClusterGroup.SSTRunReceiverToFile(server.GetMyBackupDirectory()+server.ClusterGroup.Conf.BackupPhysicalType+".xbtream", ConstJobCreateFile)
+	backupfile := server.GetMyBackupDirectory() + server.ClusterGroup.Conf.BackupPhysicalType + ".xbtream"
+	server.ClusterGroup.setBackupSource(server, server.ClusterGroup.Conf.BackupPhysicalType, backupfile, true)
+	port, err := server.ClusterGroup.SSTRunReceiverToFile(backupfile, ConstJobCreateFile, func(err error) {
+		if err != nil {
+			server.ClusterGroup.LogPrintf(LvlErr, "Physical backup %s of %s failed: %s", server.ClusterGroup.Conf.BackupPhysicalType, server.URL, err)
+		}
+		server.ClusterGroup.setBackupSourceDone(backupfile, err)
+	})
 	if err != nil {
+		server.ClusterGroup.setBackupSourceDone(backupfile, err)
 		return 0, nil
 	}
 	jobid, err := server.JobInsertTaks(server.ClusterGroup.Conf.BackupPhysicalType, port, server.ClusterGroup.Conf.MonitorAddress)
@@ -272,7 +280,7 @@ func (server *ServerMonitor) JobBackupErrorLog() (int64, error) {
 	if server.IsDown() {
 		return 0, nil
 	}
-	port, err := server.ClusterGroup.SSTRunReceiverToFile(server.Datadir+"/log/log_error.log", ConstJobAppendFile)
+	port, err := server.ClusterGroup.SSTRunReceiverToFile(server.Datadir+"/log/log_error.log", ConstJobAppendFile, nil)
 	if err != nil {
 		return 0, nil
 	}
@@ -337,7 +345,7 @@ func (server *ServerMonitor) JobBackupSlowQueryLog() (int64, error) {
 	if server.IsDown() {
 		return 0, nil
 	}
-	port, err := server.ClusterGroup.SSTRunReceiverToFile(server.Datadir+"/log/log_slow_query.log", ConstJobAppendFile)
+	port, err := server.ClusterGroup.SSTRunReceiverToFile(server.Datadir+"/log/log_slow_query.log", ConstJobAppendFile, nil)
 	if err != nil {
 		return 0, nil
 	}
@@ -543,7 +551,7 @@ func (server *ServerMonitor) JobHandler(JobId int64) error {
 
 func (server *ServerMonitor) GetMyBackupDirectory() string {
 
-	s3dir := server.ClusterGroup.Conf.WorkingDir + "/" + config.ConstStreamingSubDir + "/" + server.ClusterGroup.Name + "/" + server.Host + "_" + server.Port
+	s3dir := server.getBackupDirectory()
 
 	if _, err := os.Stat(s3dir); os.IsNotExist(err) {
 		err := os.MkdirAll(s3dir, os.ModePerm)
@@ -556,6 +564,10 @@ func (server *ServerMonitor) GetMyBackupDirectory() string {
 
 }
 
+func (server *ServerMonitor) getBackupDirectory() string {
+	return server.ClusterGroup.Conf.WorkingDir + "/" + config.ConstStreamingSubDir + "/" + server.ClusterGroup.Name + "/" + server.Host + "_" + server.Port
+}
+
 func (server *ServerMonitor) GetMasterBackupDirectory() string {
 
 	s3dir := server.ClusterGroup.Conf.WorkingDir + "/" + config.ConstStreamingSubDir + "/" + server.ClusterGroup.Name + "/" + server.ClusterGroup.master.Host + "_" + server.ClusterGroup.master.Port
@@ -627,11 +639,13 @@ func (server *ServerMonitor) JobBackupLogical() error {
 		dumpCmd := exec.Command(server.ClusterGroup.GetMysqlDumpPath(), dumpargs...)
 
 		server.ClusterGroup.LogPrintf(LvlInfo, "Command: %s ", strings.Replace(dumpCmd.String(), server.ClusterGroup.dbPass, "XXXX", -1))
-		f, err := os.Create(server.GetMyBackupDirectory() + "mysqldump.sql.gz")
+		backupfile := server.GetMyBackupDirectory() + "mysqldump.sql.gz"
+		f, err := os.Create(backupfile)
 		if err != nil {
 			server.ClusterGroup.LogPrintf(LvlErr, "Error backup request: %s", err)
 			return err
 		}
+		server.ClusterGroup.setBackupSource(server, config.ConstBackupLogicalTypeMysqldump, backupfile, true)
 		wf := bufio.NewWriter(f)
 		gw := gzip.NewWriter(wf)
 		//fw := bufio.NewWriter(gw)
@@ -640,6 +654,7 @@ func (server *ServerMonitor) JobBackupLogical() error {
 		err = dumpCmd.Start()
 		if err != nil {
 			server.ClusterGroup.LogPrintf(LvlErr, "Error backup request: %s", err)
+			server.ClusterGroup.setBackupSourceDone(backupfile, err)
 			return err
 		}
 		var wg sync.WaitGroup
@@ -659,6 +674,7 @@ func (server *ServerMonitor) JobBackupLogical() error {
 			gw.Close()
 			wf.Flush()
 			f.Close()
+			server.ClusterGroup.setBackupSourceDone(backupfile, err)
 		}()
 		wg.Wait()
 
@@ -716,6 +732,7 @@ func (server *ServerMonitor) JobBackupLogical() error {
 		*/
 		stdoutIn, _ := dumpCmd.StdoutPipe()
 		stderrIn, _ := dumpCmd.StderrPipe()
+		server.ClusterGroup.setBackupSource(server, config.ConstBackupLogicalTypeMydumper, server.GetMyBackupDirectory()+"metadata", true)
 		dumpCmd.Start()
 		var wg sync.WaitGroup
 		wg.Add(2)
@@ -728,9 +745,11 @@ func (server *ServerMonitor) JobBackupLogical() error {
 			server.copyLogs(stderrIn)
 		}()
 		wg.Wait()
-		if err := dumpCmd.Wait(); err != nil {
+		err := dumpCmd.Wait()
+		if err != nil {
 			server.ClusterGroup.LogPrintf(LvlErr, "MyDumper: %s", err)
 		}
+		server.ClusterGroup.setBackupSourceDone(server.GetMyBackupDirectory()+"metadata", err)
 	}
 
 	server.ClusterGroup.LogPrintf(LvlInfo, "Finish logical backup %s for: %s", server.ClusterGroup.Conf.BackupLogicalType, server.URL)
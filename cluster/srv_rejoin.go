@@ -325,8 +325,8 @@ func (server *ServerMonitor) RejoinDirectDump() error {
 		return err3
 	}
 	// dump here
-	backupserver := server.ClusterGroup.GetBackupServer()
-	if backupserver == nil {
+	backupserver, _ := server.ClusterGroup.GetBackupServer()
+	if backupserver == nil || backupserver == server {
 		go server.ClusterGroup.JobRejoinMysqldumpFromSource(server.ClusterGroup.master, server)
 	} else {
 		go server.ClusterGroup.JobRejoinMysqldumpFromSource(backupserver, server)
@@ -15,6 +15,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"time"
 
 	"github.com/go-sql-driver/mysql"
 
@@ -328,3 +329,288 @@ func (server *ServerMonitor) SetReprovCookie() {
 	}
 	newFile.Close()
 }
+
+// DetectReplicationSourceName adopts the connection name of the only replication
+// channel when no source name is configured. Multi source replicas still need
+// an explicit replication-source-name.
+func (server *ServerMonitor) DetectReplicationSourceName() bool {
+	if server.ReplicationSourceName != "" || len(server.Replications) != 1 {
+		return false
+	}
+	name := server.Replications[0].ConnectionName.String
+	if name == "" {
+		return false
+	}
+	server.ReplicationSourceName = name
+	server.ClusterGroup.LogPrintf(LvlInfo, "Detected replication source name %s on server %s", name, server.URL)
+	return true
+}
+
+const deadlockHistorySize = 20
+
+// addDeadlockHistory keep the last deadlocks, a deadlock still reported by the next poll is not added twice
+func (server *ServerMonitor) addDeadlockHistory(d *dbhelper.Deadlock) {
+	if d == nil {
+		return
+	}
+	for _, h := range server.DeadlockHistory {
+		if h.Signature == d.Signature {
+			return
+		}
+	}
+	server.DeadlockHistory = append(server.DeadlockHistory, *d)
+	if len(server.DeadlockHistory) > deadlockHistorySize {
+		server.DeadlockHistory = server.DeadlockHistory[len(server.DeadlockHistory)-deadlockHistorySize:]
+	}
+}
+
+const historyListLengthSize = 30
+
+// addHistoryListLength keep the innodb history list length of the last polls to compute the purge lag trend
+func (server *ServerMonitor) addHistoryListLength() {
+	hll, err := strconv.ParseInt(server.EngineInnoDB["history_list_lenght_inside_innodb"], 10, 64)
+	if err != nil {
+		return
+	}
+	server.historyListLengths = append(server.historyListLengths, hll)
+	if len(server.historyListLengths) > historyListLengthSize {
+		server.historyListLengths = server.historyListLengths[len(server.historyListLengths)-historyListLengthSize:]
+	}
+}
+
+const connectionSampleSize = 60
+
+// addConnectionSample keep the connected and running threads of the last polls to detect connection storms
+func (server *ServerMonitor) addConnectionSample(now time.Time) {
+	connected, err := strconv.ParseInt(server.Status["THREADS_CONNECTED"], 10, 64)
+	if err != nil {
+		return
+	}
+	running, _ := strconv.ParseInt(server.Status["THREADS_RUNNING"], 10, 64)
+	server.connectionSamples = append(server.connectionSamples, ConnectionSample{Time: now.UnixNano() / int64(time.Millisecond), Connected: connected, Running: running})
+	if len(server.connectionSamples) > connectionSampleSize {
+		server.connectionSamples = server.connectionSamples[len(server.connectionSamples)-connectionSampleSize:]
+	}
+}
+
+// setReplicationApplyRate derive the apply rate from the master binary log coordinates executed by the slave
+func (server *ServerMonitor) setReplicationApplyRate(now time.Time) {
+	ss, err := server.GetSlaveStatus(server.ReplicationSourceName)
+	if err != nil {
+		return
+	}
+	pos, _ := strconv.ParseUint(ss.ExecMasterLogPos.String, 10, 64)
+	cur := binlogCoordinate{File: ss.RelayMasterLogFile.String, Pos: pos, Time: now}
+	var sizes map[string]uint
+	if server.ClusterGroup.master != nil {
+		sizes = server.ClusterGroup.master.BinaryLogFiles
+	}
+	server.ReplicationApplyRate = getBinlogRate(server.replicationApplySample, cur, sizes)
+	server.replicationApplySample = cur
+}
+
+// getBinlogRate returns the bytes per second between two binary log coordinates, files between them are
+// counted with their sizes when known
+func getBinlogRate(prev binlogCoordinate, cur binlogCoordinate, sizes map[string]uint) float64 {
+	elapsed := cur.Time.Sub(prev.Time).Seconds()
+	if prev.File == "" || cur.File == "" || elapsed <= 0 || cur.File < prev.File {
+		return 0
+	}
+	if cur.File == prev.File {
+		if cur.Pos < prev.Pos {
+			return 0
+		}
+		return float64(cur.Pos-prev.Pos) / elapsed
+	}
+	var bytes uint64
+	if size := uint64(sizes[prev.File]); size > prev.Pos {
+		bytes = size - prev.Pos
+	}
+	for file, size := range sizes {
+		if file > prev.File && file < cur.File {
+			bytes += uint64(size)
+		}
+	}
+	return float64(bytes+cur.Pos) / elapsed
+}
+
+const replicationDelaySmoothingFactor = 0.3
+
+// UpdateReplicationDelaySmoothing add the current delay to the moving average, when the slave replicates from
+// another master since the last poll the average is seeded again and the delay alert hysteresis is reset, a poll
+// without a slave status or a master host and port keeps the history
+func (server *ServerMonitor) UpdateReplicationDelaySmoothing() {
+	ss, err := server.GetSlaveStatus(server.ReplicationSourceName)
+	if err != nil || ss.MasterHost.String == "" || ss.MasterPort.String == "" {
+		return
+	}
+	delay := float64(server.GetReplicationDelay())
+	master := ss.MasterHost.String + ":" + ss.MasterPort.String
+	if master != server.smoothingMasterHost {
+		if server.smoothingMasterHost != "" {
+			server.ClusterGroup.LogPrintf(LvlInfo, "Slave %s master changed from %s to %s, reset replication delay history", server.URL, server.smoothingMasterHost, master)
+		}
+		server.smoothingMasterHost = master
+		server.SmoothedReplicationDelay = delay
+		server.ReplicationDelayAlertState = DelayAlertState{}
+		server.ReplicationDelayWarnState = DelayAlertState{}
+		server.ReadLagState = DelayAlertState{}
+		return
+	}
+	server.SmoothedReplicationDelay = replicationDelaySmoothingFactor*delay + (1-replicationDelaySmoothingFactor)*server.SmoothedReplicationDelay
+}
+
+// setMonitorConnectionIds adds the thread ids of the monitoring connections, ids of the connections closed by the
+// pool are dropped when they leave the process list
+func (server *ServerMonitor) setMonitorConnectionIds(ids []uint64) {
+	threads := make(map[uint64]bool, len(server.FullProcessList))
+	for _, q := range server.FullProcessList {
+		threads[q.Id] = true
+	}
+	monitor := make(map[uint64]bool)
+	for id := range server.monitorConnectionIds {
+		if threads[id] {
+			monitor[id] = true
+		}
+	}
+	for _, id := range ids {
+		monitor[id] = true
+	}
+	server.monitorConnectionIds = monitor
+}
+
+// addChannelDelaySamples track per replication channel the max delay and the time spent over failover-max-slave-delay,
+// channels removed or renamed since the last poll are dropped
+func (server *ServerMonitor) addChannelDelaySamples(now time.Time) {
+	server.channelDelayStats.Lock()
+	defer server.channelDelayStats.Unlock()
+	if server.channelDelayStats.channels == nil {
+		server.channelDelayStats.channels = make(map[string]*channelDelayStat)
+	}
+	replications := server.GetAllSlavesStatus()
+	current := make(map[string]bool, len(replications))
+	for _, ss := range replications {
+		current[ss.ConnectionName.String] = true
+	}
+	for channel := range server.channelDelayStats.channels {
+		if !current[channel] {
+			delete(server.channelDelayStats.channels, channel)
+		}
+	}
+	for _, ss := range replications {
+		stat, ok := server.channelDelayStats.channels[ss.ConnectionName.String]
+		if !ok {
+			stat = &channelDelayStat{}
+			server.channelDelayStats.channels[ss.ConnectionName.String] = stat
+		}
+		delay := ss.SecondsBehindMaster.Int64
+		if delay > stat.Max {
+			stat.Max = delay
+		}
+		if !stat.LastSample.IsZero() && ss.SecondsBehindMaster.Valid && delay > server.ClusterGroup.Conf.FailMaxDelay && server.ClusterGroup.Conf.FailMaxDelay > 0 {
+			stat.BreachSeconds += now.Sub(stat.LastSample).Seconds()
+		}
+		stat.LastSample = now
+	}
+}
+
+const processListStatesMax = 10000
+
+// SetProcessListStates track when each thread entered its current state, threads gone from the process list are expired
+func (server *ServerMonitor) SetProcessListStates(now time.Time) {
+	states := make(map[uint64]threadState, len(server.FullProcessList))
+	for _, q := range server.FullProcessList {
+		if len(states) >= processListStatesMax {
+			break
+		}
+		ts, ok := server.processListStates[q.Id]
+		if !ok || ts.State != q.State.String {
+			ts = threadState{State: q.State.String, Since: now}
+		}
+		states[q.Id] = ts
+	}
+	server.processListStates = states
+}
+
+const replicationSLOMinutes = 24 * 60
+
+// addReplicationSLOSample count a poll in the minute bucket of the one day ring
+func (server *ServerMonitor) addReplicationSLOSample(now time.Time, good bool) {
+	if server.replicationSLOBuckets == nil {
+		server.replicationSLOBuckets = make([]sloBucket, replicationSLOMinutes)
+	}
+	minute := now.Unix() / 60
+	b := &server.replicationSLOBuckets[minute%replicationSLOMinutes]
+	if b.Minute != minute {
+		*b = sloBucket{Minute: minute}
+	}
+	b.Total++
+	if good {
+		b.Good++
+	}
+	server.lastSLOSample = sloSample{Minute: minute, Good: good, Set: true}
+}
+
+// SetHeavyMonitoringSkipped skips the expensive gathering on a slave delayed over monitoring-skip-heavy-max-delay
+// not to worsen its lag, status and variables are still collected
+func (server *ServerMonitor) SetHeavyMonitoringSkipped() {
+	max := server.ClusterGroup.Conf.MonitorSkipHeavyMaxDelay
+	skip := max > 0 && server.IsSlave && server.HasReplicationDelay() && server.GetReplicationDelay() > max
+	if skip && !server.HeavyMonitoringSkipped {
+		server.ClusterGroup.LogPrintf(LvlInfo, "Skipping heavy monitoring of %s with replication delay %d over %d", server.URL, server.GetReplicationDelay(), max)
+	} else if !skip && server.HeavyMonitoringSkipped {
+		server.ClusterGroup.LogPrintf(LvlInfo, "Resuming heavy monitoring of %s", server.URL)
+	}
+	server.HeavyMonitoringSkipped = skip
+}
+
+// CheckRestart detects a restart from the Uptime status lower than at the previous poll, the replication delay
+// read while a restarted slave reconnects is a restart boundary, the last bad poll is removed from the SLO and
+// bad polls are not counted until the slave catches up or replication-restart-grace is over, the delay history
+// is seeded again
+func (server *ServerMonitor) CheckRestart(now time.Time) bool {
+	uptime, err := strconv.ParseInt(server.Status["UPTIME"], 10, 64)
+	if err != nil {
+		return false
+	}
+	prev, err := strconv.ParseInt(server.PrevStatus["UPTIME"], 10, 64)
+	if err != nil || uptime >= prev {
+		return false
+	}
+	server.ClusterGroup.LogPrintf(LvlInfo, "Server %s restarted, uptime %d lower than %d at previous poll", server.URL, uptime, prev)
+	server.restartTime = now
+	if s := server.lastSLOSample; s.Set && !s.Good {
+		// the previous poll saw the slave reconnecting
+		if b := &server.replicationSLOBuckets[s.Minute%replicationSLOMinutes]; b.Minute == s.Minute && b.Total > 0 {
+			b.Total--
+		}
+	}
+	server.lastSLOSample = sloSample{}
+	server.smoothingMasterHost = ""
+	server.channelDelayStats.Lock()
+	for _, stat := range server.channelDelayStats.channels {
+		stat.LastSample = time.Time{}
+	}
+	server.channelDelayStats.Unlock()
+	return true
+}
+
+// SetProcessListDigests fingerprint running queries, digests of the previous poll are reused for unchanged query text
+func (server *ServerMonitor) SetProcessListDigests() {
+	digests := make(map[string]string)
+	for i, q := range server.FullProcessList {
+		if !q.Info.Valid || q.Info.String == "" {
+			continue
+		}
+		digest, ok := digests[q.Info.String]
+		if !ok {
+			digest, ok = server.processListDigests[q.Info.String]
+			if !ok {
+				digest = dbhelper.GetQueryDigest(q.Info.String)
+			}
+			digests[q.Info.String] = digest
+		}
+		server.FullProcessList[i].Digest = digest
+	}
+	server.processListDigests = digests
+}
@@ -94,6 +94,29 @@ func (server *ServerMonitor) SendDatabaseStats() error {
 	return nil
 }
 
+// RecordReplicationDelay sends the delay of every replication channel to the cluster metric sink, a channel with
+// a NULL Seconds_Behind_Master is skipped so a stopped replication is not recorded as caught up
+func (server *ServerMonitor) RecordReplicationDelay() error {
+	now := time.Now().Unix()
+	for _, ss := range server.GetAllSlavesStatus() {
+		if !ss.SecondsBehindMaster.Valid {
+			continue
+		}
+		sample := DelaySample{
+			Cluster:    server.ClusterGroup.Name,
+			Server:     server.URL,
+			Channel:    ss.ConnectionName.String,
+			LagSeconds: ss.SecondsBehindMaster.Int64,
+			Timestamp:  now,
+		}
+		if err := server.ClusterGroup.MetricSink.RecordReplicationDelay(sample); err != nil {
+			server.ClusterGroup.LogPrintf(LvlErr, "Could not record replication delay of %s: %s", server.URL, err)
+			return err
+		}
+	}
+	return nil
+}
+
 func (server *ServerMonitor) SendAlert() error {
 	if server.ClusterGroup.Status != ConstMonitorActif && server.ClusterGroup.IsDiscovered() {
 		return nil
@@ -9,7 +9,15 @@
 
 package cluster
 
-import "github.com/signal18/replication-manager/utils/dbhelper"
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
 
 func (server *ServerMonitor) WaitSyncToMaster(master *ServerMonitor) {
 	server.ClusterGroup.LogPrintf(LvlInfo, "Waiting for slave %s to sync", server.URL)
@@ -26,3 +34,45 @@ func (server *ServerMonitor) WaitSyncToMaster(master *ServerMonitor) {
 		server.LogReplPostion()
 	}
 }
+
+// WaitForReplicaToCatchUp blocks until the slave applied a master GTID set or binlog_file:position, read your
+// writes clients call it after a write before reading from the slave, returns the time waited
+func (cluster *Cluster) WaitForReplicaToCatchUp(server *ServerMonitor, gtidOrPos string, timeout time.Duration) (time.Duration, error) {
+	if server == nil || server.IsDown() {
+		return 0, errors.New("Server not available")
+	}
+	if !server.IsSlave {
+		return 0, fmt.Errorf("Server %s is not a slave", server.URL)
+	}
+	start := time.Now()
+	var ok bool
+	var logs string
+	var err error
+	if file, pos, isPos := parseReplicaWaitTarget(gtidOrPos); isPos {
+		ok, logs, err = dbhelper.WaitPosition(server.Conn, file, pos, timeout.Seconds())
+	} else {
+		ok, logs, err = dbhelper.WaitGTID(server.Conn, server.DBVersion, gtidOrPos, timeout.Seconds())
+	}
+	waited := time.Since(start)
+	cluster.LogSQL(logs, err, server.URL, "Wait", LvlErr, "Could not wait for %s to apply %s: %s", server.URL, gtidOrPos, err)
+	if err != nil {
+		return waited, err
+	}
+	if !ok {
+		return waited, fmt.Errorf("Timeout after %s waiting for %s to apply %s", timeout, server.URL, gtidOrPos)
+	}
+	return waited, nil
+}
+
+// parseReplicaWaitTarget splits a binlog_file:position, MySQL GTID sets also contain colons but no dot before
+// the last one and a binlog file always has a numbered extension
+func parseReplicaWaitTarget(target string) (string, string, bool) {
+	i := strings.LastIndex(target, ":")
+	if i <= 0 || !strings.Contains(target[:i], ".") {
+		return "", "", false
+	}
+	if _, err := strconv.ParseUint(target[i+1:], 10, 64); err != nil {
+		return "", "", false
+	}
+	return target[:i], target[i+1:], true
+}
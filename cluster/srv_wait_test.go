@@ -0,0 +1,48 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"testing"
+	"time"
+)
+
+func TestReplicaWaitTarget(t *testing.T) {
+	tests := []struct {
+		target string
+		file   string
+		pos    string
+		isPos  bool
+	}{
+		{"mysql-bin.000012:1234", "mysql-bin.000012", "1234", true},
+		{"/var/lib/mysql/binlog.000003:4", "/var/lib/mysql/binlog.000003", "4", true},
+		{"0-1-100,1-2-50", "", "", false},
+		{"3e11fa47-71ca-11e1-9e33-c80aa9429562:1-5", "", "", false},
+		{"3e11fa47-71ca-11e1-9e33-c80aa9429562:23", "", "", false},
+		{"mysql-bin.000012:pos", "", "", false},
+	}
+	for _, test := range tests {
+		file, pos, isPos := parseReplicaWaitTarget(test.target)
+		if file != test.file || pos != test.pos || isPos != test.isPos {
+			t.Fatalf("Got %q %q %t for %s", file, pos, isPos, test.target)
+		}
+	}
+}
+
+func TestWaitForReplicaToCatchUpNotSlave(t *testing.T) {
+	cluster := &Cluster{}
+	server := &ServerMonitor{URL: "db1:3306", State: stateMaster, ClusterGroup: cluster}
+	if _, err := cluster.WaitForReplicaToCatchUp(server, "0-1-100", time.Second); err == nil {
+		t.Fatal("Expected error waiting on a master")
+	}
+	if _, err := cluster.WaitForReplicaToCatchUp(nil, "0-1-100", time.Second); err == nil {
+		t.Fatal("Expected error without server")
+	}
+}
@@ -0,0 +1,105 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ClusterStatusServer is the state of a server in the cluster status file
+type ClusterStatusServer struct {
+	URL   string `json:"url"`
+	Role  string `json:"role"`
+	Up    bool   `json:"up"`
+	Delay *int64 `json:"delay"`
+}
+
+// ClusterStatusDocument is the cluster status file content for monitoring stacks that can only read files
+type ClusterStatusDocument struct {
+	Cluster     string                `json:"cluster"`
+	Timestamp   int64                 `json:"timestamp"`
+	Master      string                `json:"master"`
+	HealthScore int                   `json:"healthScore"`
+	Servers     []ClusterStatusServer `json:"servers"`
+}
+
+// GetClusterStatusDocument returns the role, delay and state of each server with the cluster health score,
+// the delay is null when it can not be measured
+func (cluster *Cluster) GetClusterStatusDocument() ClusterStatusDocument {
+	doc := ClusterStatusDocument{Cluster: cluster.Name, Timestamp: time.Now().Unix(), Servers: []ClusterStatusServer{}}
+	if master := cluster.GetMaster(); master != nil && !master.IsFailed() {
+		doc.Master = master.URL
+	}
+	doc.HealthScore = cluster.GetHealthScore().Score
+	for _, s := range cluster.Servers {
+		if s == nil {
+			continue
+		}
+		st := ClusterStatusServer{URL: s.URL, Role: s.State, Up: !s.IsDown()}
+		if s.IsSlave && s.HasReplicationDelay() {
+			delay := s.GetReplicationDelay()
+			st.Delay = &delay
+		}
+		doc.Servers = append(doc.Servers, st)
+	}
+	return doc
+}
+
+// WriteClusterStatusFile writes the cluster status document to a temporary file renamed over path, readers
+// never see a partial document
+func (cluster *Cluster) WriteClusterStatusFile(path string) error {
+	content, err := json.MarshalIndent(cluster.GetClusterStatusDocument(), "", "\t")
+	if err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(content)
+	if err == nil {
+		err = tmp.Chmod(0644)
+	}
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+// GetClusterStatusFilePath returns monitoring-status-file with the cluster name appended to the file name
+// before its extension, each cluster writes its own file
+func (cluster *Cluster) GetClusterStatusFilePath() string {
+	ext := filepath.Ext(cluster.Conf.MonitorStatusFile)
+	return strings.TrimSuffix(cluster.Conf.MonitorStatusFile, ext) + "-" + cluster.Name + ext
+}
+
+// CheckClusterStatusFile refresh monitoring-status-file every monitoring-status-file-interval seconds
+func (cluster *Cluster) CheckClusterStatusFile() {
+	if cluster.Conf.MonitorStatusFile == "" {
+		return
+	}
+	if time.Since(cluster.statusFileTime) < time.Duration(cluster.Conf.MonitorStatusFileInterval)*time.Second {
+		return
+	}
+	cluster.statusFileTime = time.Now()
+	path := cluster.GetClusterStatusFilePath()
+	if err := cluster.WriteClusterStatusFile(path); err != nil {
+		cluster.LogPrintf(LvlErr, "Can't write cluster status file %s: %s", path, err)
+	}
+}
@@ -0,0 +1,65 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"encoding/json"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+func TestClusterStatusFile(t *testing.T) {
+	dir := t.TempDir()
+	name := filepath.Join(dir, "status-c1.json")
+	sme := new(state.StateMachine)
+	sme.Init()
+	master := &ServerMonitor{URL: "db1:3306", State: stateMaster}
+	slave := &ServerMonitor{URL: "db2:3306", State: stateSlave, IsSlave: true, ReplicationStatus: replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: 7, Valid: true}}}}
+	failed := &ServerMonitor{URL: "db3:3306", State: stateFailed, IsSlave: true}
+	cluster := &Cluster{Name: "c1", sme: sme, master: master, Conf: config.Config{MonitorStatusFile: filepath.Join(dir, "status.json"), MonitorStatusFileInterval: 10}}
+	cluster.Servers = serverList{master, slave, failed}
+	cluster.CheckClusterStatusFile()
+	content, err := ioutil.ReadFile(name)
+	if err != nil {
+		t.Fatal("Error reading status file: ", err)
+	}
+	var doc ClusterStatusDocument
+	if err = json.Unmarshal(content, &doc); err != nil {
+		t.Fatal("Error decoding status file: ", err)
+	}
+	if doc.Cluster != "c1" || doc.Master != "db1:3306" || len(doc.Servers) != 3 {
+		t.Fatalf("Unexpected status document %s", content)
+	}
+	if !doc.Servers[1].Up || doc.Servers[1].Delay == nil || *doc.Servers[1].Delay != 7 || doc.Servers[2].Up || doc.Servers[2].Delay != nil {
+		t.Fatalf("Unexpected server status %s", content)
+	}
+	files, _ := ioutil.ReadDir(dir)
+	if len(files) != 1 {
+		t.Fatalf("Expected temporary file renamed, got %d files", len(files))
+	}
+	cluster.Servers = serverList{master}
+	cluster.CheckClusterStatusFile()
+	if content, _ = ioutil.ReadFile(name); json.Unmarshal(content, &doc) != nil || len(doc.Servers) != 3 {
+		t.Fatalf("Expected no refresh before the interval, got %s", content)
+	}
+	other := &Cluster{Name: "c2", sme: sme, master: master, Servers: serverList{master}, Conf: cluster.Conf}
+	other.CheckClusterStatusFile()
+	if content, _ = ioutil.ReadFile(name); json.Unmarshal(content, &doc) != nil || doc.Cluster != "c1" {
+		t.Fatalf("Expected the status file of c1 kept by another cluster, got %s", content)
+	}
+	if content, err = ioutil.ReadFile(filepath.Join(dir, "status-c2.json")); err != nil || json.Unmarshal(content, &doc) != nil || doc.Cluster != "c2" {
+		t.Fatalf("Expected the status file of c2 written aside, got %s %v", content, err)
+	}
+}
@@ -0,0 +1,154 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// replicationStreamBuffer is the number of pending deltas of a subscriber, a slow subscriber gets its pending
+// deltas replaced by a snapshot
+const replicationStreamBuffer = 16
+
+// ReplicationStreamEvent is the replication status of a slave channel pushed to the stream subscribers, Lag is
+// null when Seconds_Behind_Master is not known
+type ReplicationStreamEvent struct {
+	Server     string `json:"server"`
+	Channel    string `json:"channel"`
+	Lag        *int64 `json:"lag"`
+	IORunning  bool   `json:"ioRunning"`
+	SQLRunning bool   `json:"sqlRunning"`
+	Timestamp  int64  `json:"timestamp"`
+}
+
+// ReplicationStreamUpdate is a message pushed to a stream subscriber, Snapshot is set when Events is the status of
+// all channels replacing the previous ones instead of the channels changed since the previous message
+type ReplicationStreamUpdate struct {
+	Snapshot bool
+	Events   []ReplicationStreamEvent
+}
+
+type replicationStream struct {
+	sync.Mutex
+	subscribers map[chan ReplicationStreamUpdate]bool
+	last        map[string]ReplicationStreamEvent
+}
+
+// SubscribeReplicationStream returns the channel receiving the replication status deltas of each monitor cycle
+// and the current status of all channels to send first
+func (cluster *Cluster) SubscribeReplicationStream() (chan ReplicationStreamUpdate, []ReplicationStreamEvent) {
+	cluster.replicationStream.Lock()
+	defer cluster.replicationStream.Unlock()
+	if cluster.replicationStream.subscribers == nil {
+		cluster.replicationStream.subscribers = make(map[chan ReplicationStreamUpdate]bool)
+	}
+	ch := make(chan ReplicationStreamUpdate, replicationStreamBuffer)
+	cluster.replicationStream.subscribers[ch] = true
+	return ch, sortReplicationStreamEvents(cluster.replicationStream.last)
+}
+
+// UnsubscribeReplicationStream stops sending deltas to the channel
+func (cluster *Cluster) UnsubscribeReplicationStream(ch chan ReplicationStreamUpdate) {
+	cluster.replicationStream.Lock()
+	defer cluster.replicationStream.Unlock()
+	delete(cluster.replicationStream.subscribers, ch)
+}
+
+// PublishReplicationStream sends to the subscribers the channels whose status changed since the previous cycle, a
+// subscriber too slow to keep up gets its pending deltas dropped and replaced by a snapshot of all channels
+func (cluster *Cluster) PublishReplicationStream() []ReplicationStreamEvent {
+	events := cluster.getReplicationStreamEvents(time.Now())
+	cluster.replicationStream.Lock()
+	defer cluster.replicationStream.Unlock()
+	changed := make(map[string]ReplicationStreamEvent)
+	for key, e := range events {
+		if l, ok := cluster.replicationStream.last[key]; !ok || !l.equal(e) {
+			changed[key] = e
+		}
+	}
+	for key, l := range cluster.replicationStream.last {
+		// a channel no more replicating is sent once with stopped threads
+		if _, ok := events[key]; !ok && (l.IORunning || l.SQLRunning) {
+			l.IORunning = false
+			l.SQLRunning = false
+			l.Lag = nil
+			l.Timestamp = time.Now().Unix()
+			events[key] = l
+			changed[key] = l
+		}
+	}
+	cluster.replicationStream.last = events
+	delta := sortReplicationStreamEvents(changed)
+	if len(delta) == 0 {
+		return delta
+	}
+	for ch := range cluster.replicationStream.subscribers {
+		select {
+		case ch <- ReplicationStreamUpdate{Events: delta}:
+		default:
+			// only the publisher sends so the drained channel has room for the snapshot
+			for len(ch) > 0 {
+				select {
+				case <-ch:
+				default:
+				}
+			}
+			ch <- ReplicationStreamUpdate{Snapshot: true, Events: sortReplicationStreamEvents(events)}
+		}
+	}
+	return delta
+}
+
+func (cluster *Cluster) getReplicationStreamEvents(now time.Time) map[string]ReplicationStreamEvent {
+	events := make(map[string]ReplicationStreamEvent)
+	for _, s := range cluster.Servers {
+		if s == nil {
+			continue
+		}
+		for _, ss := range s.GetAllSlavesStatus() {
+			e := ReplicationStreamEvent{
+				Server:     s.URL,
+				Channel:    ss.ConnectionName.String,
+				IORunning:  ss.SlaveIORunning.String == "Yes",
+				SQLRunning: ss.SlaveSQLRunning.String == "Yes",
+				Timestamp:  now.Unix(),
+			}
+			if ss.SecondsBehindMaster.Valid {
+				lag := ss.SecondsBehindMaster.Int64
+				e.Lag = &lag
+			}
+			events[e.Server+"/"+e.Channel] = e
+		}
+	}
+	return events
+}
+
+func (e ReplicationStreamEvent) equal(o ReplicationStreamEvent) bool {
+	if e.IORunning != o.IORunning || e.SQLRunning != o.SQLRunning || (e.Lag == nil) != (o.Lag == nil) {
+		return false
+	}
+	return e.Lag == nil || *e.Lag == *o.Lag
+}
+
+func sortReplicationStreamEvents(events map[string]ReplicationStreamEvent) []ReplicationStreamEvent {
+	res := []ReplicationStreamEvent{}
+	for _, e := range events {
+		res = append(res, e)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		if res[i].Server == res[j].Server {
+			return res[i].Channel < res[j].Channel
+		}
+		return res[i].Server < res[j].Server
+	})
+	return res
+}
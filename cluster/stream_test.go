@@ -0,0 +1,86 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"testing"
+
+	"github.com/signal18/replication-manager/utils/dbhelper"
+)
+
+func TestReplicationStream(t *testing.T) {
+	channel := func(name string, delay int64, io string) dbhelper.SlaveStatus {
+		return dbhelper.SlaveStatus{
+			ConnectionName:      sql.NullString{String: name, Valid: true},
+			SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: io == "Yes"},
+			SlaveIORunning:      sql.NullString{String: io, Valid: true},
+			SlaveSQLRunning:     sql.NullString{String: "Yes", Valid: true},
+		}
+	}
+	db1 := &ServerMonitor{URL: "db1:3306"}
+	db2 := &ServerMonitor{URL: "db2:3306"}
+	cluster := &Cluster{Servers: serverList{db1, db2}}
+	ch, snapshot := cluster.SubscribeReplicationStream()
+	defer cluster.UnsubscribeReplicationStream(ch)
+	if len(snapshot) != 0 {
+		t.Fatalf("Got snapshot %+v before the first cycle", snapshot)
+	}
+
+	db2.ReplicationStatus = replicationStatusFixture{channel("", 5, "Yes"), channel("dc2", 0, "Yes")}
+	if delta := cluster.PublishReplicationStream(); len(delta) != 2 || delta[0].Channel != "" || *delta[0].Lag != 5 || delta[1].Channel != "dc2" {
+		t.Fatalf("Unexpected first delta %+v", delta)
+	}
+	if update := <-ch; update.Snapshot || len(update.Events) != 2 {
+		t.Fatalf("Subscriber got %+v, expected 2 channels", update)
+	}
+	if delta := cluster.PublishReplicationStream(); len(delta) != 0 {
+		t.Fatalf("Got delta %+v without change", delta)
+	}
+
+	db2.ReplicationStatus = replicationStatusFixture{channel("", 5, "Yes"), channel("dc2", 0, "Connecting")}
+	delta := cluster.PublishReplicationStream()
+	if len(delta) != 1 || delta[0].Channel != "dc2" || delta[0].IORunning || delta[0].Lag != nil {
+		t.Fatalf("Unexpected delta %+v on IO thread stop", delta)
+	}
+
+	db2.ReplicationStatus = replicationStatusFixture{channel("", 5, "Yes")}
+	if delta := cluster.PublishReplicationStream(); len(delta) != 1 || delta[0].Channel != "dc2" || delta[0].IORunning || delta[0].SQLRunning {
+		t.Fatalf("Unexpected delta %+v on removed channel", delta)
+	}
+	if delta := cluster.PublishReplicationStream(); len(delta) != 0 {
+		t.Fatalf("Got delta %+v for a channel already removed", delta)
+	}
+	if _, snapshot := cluster.SubscribeReplicationStream(); len(snapshot) != 1 || snapshot[0].Channel != "" {
+		t.Fatalf("Got snapshot %+v, expected the running channel", snapshot)
+	}
+}
+
+func TestReplicationStreamSlowSubscriber(t *testing.T) {
+	db1 := &ServerMonitor{URL: "db1:3306"}
+	cluster := &Cluster{Servers: serverList{db1}}
+	ch, _ := cluster.SubscribeReplicationStream()
+	defer cluster.UnsubscribeReplicationStream(ch)
+	for i := 0; i <= replicationStreamBuffer; i++ {
+		db1.ReplicationStatus = replicationStatusFixture{{
+			ConnectionName:      sql.NullString{String: "", Valid: true},
+			SecondsBehindMaster: sql.NullInt64{Int64: int64(i), Valid: true},
+			SlaveIORunning:      sql.NullString{String: "Yes", Valid: true},
+			SlaveSQLRunning:     sql.NullString{String: "Yes", Valid: true},
+		}}
+		cluster.PublishReplicationStream()
+	}
+	if len(ch) != 1 {
+		t.Fatalf("Expected the pending deltas replaced by a snapshot, got %d messages", len(ch))
+	}
+	if update := <-ch; !update.Snapshot || len(update.Events) != 1 || *update.Events[0].Lag != int64(replicationStreamBuffer) {
+		t.Fatalf("Unexpected resync %+v", update)
+	}
+}
@@ -0,0 +1,184 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/signal18/replication-manager/utils/dbhelper"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+// UserQuota is a soft limit of a database user, a zero limit is not checked, MaxActiveTime is the sum in seconds
+// of the running time of the user queries
+type UserQuota struct {
+	User             string  `json:"user"`
+	MaxConnections   int     `json:"maxConnections"`
+	MaxActiveQueries int     `json:"maxActiveQueries"`
+	MaxActiveTime    float64 `json:"maxActiveTime"`
+}
+
+// UserResourceUsage is the process list usage of a database user, Exceeded lists the quotas over the limit
+type UserResourceUsage struct {
+	User            string   `json:"user"`
+	Connections     int      `json:"connections"`
+	ActiveQueries   int      `json:"activeQueries"`
+	TotalActiveTime float64  `json:"totalActiveTime"`
+	OverQuota       bool     `json:"overQuota"`
+	Exceeded        []string `json:"exceeded"`
+}
+
+// LoadUserQuotas reads the JSON array of monitoring-user-quotas
+func (cluster *Cluster) LoadUserQuotas() {
+	quotas := make(map[string]UserQuota)
+	if cluster.Conf.MonitorUserQuotas != "" {
+		var list []UserQuota
+		if err := json.Unmarshal([]byte(cluster.Conf.MonitorUserQuotas), &list); err != nil {
+			cluster.LogPrintf(LvlErr, "Can't parse user quotas: %s", err)
+		}
+		for _, q := range list {
+			quotas[q.User] = q
+		}
+	}
+	cluster.Lock()
+	cluster.userQuotas = quotas
+	cluster.Unlock()
+}
+
+// GetUserQuotas returns the quotas per user loaded from monitoring-user-quotas
+func (cluster *Cluster) GetUserQuotas() map[string]UserQuota {
+	cluster.Lock()
+	defer cluster.Unlock()
+	return cluster.userQuotas
+}
+
+// GetUserResourceUsage returns the connections, running queries and their total running time per user, sorted
+// by user, system threads are not counted
+func (server *ServerMonitor) GetUserResourceUsage() []UserResourceUsage {
+	quotas := server.ClusterGroup.GetUserQuotas()
+	usage := make(map[string]*UserResourceUsage)
+	for _, q := range server.FullProcessList {
+		if q.User == "system user" || q.User == "event_scheduler" || q.User == "" {
+			continue
+		}
+		u, ok := usage[q.User]
+		if !ok {
+			u = &UserResourceUsage{User: q.User, Exceeded: []string{}}
+			usage[q.User] = u
+		}
+		u.Connections++
+		if isActiveQuery(q) {
+			u.ActiveQueries++
+			u.TotalActiveTime += q.Time.Float64
+		}
+	}
+	var res []UserResourceUsage
+	for _, u := range usage {
+		if quota, ok := quotas[u.User]; ok {
+			u.Exceeded = quota.exceeded(*u)
+			u.OverQuota = len(u.Exceeded) > 0
+		}
+		res = append(res, *u)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].User < res[j].User
+	})
+	return res
+}
+
+func isActiveQuery(q dbhelper.Processlist) bool {
+	return (q.Command == "Query" || q.Command == "Execute") && q.Info.Valid && q.Info.String != "" && q.Time.Valid
+}
+
+func (quota UserQuota) exceeded(u UserResourceUsage) []string {
+	exceeded := []string{}
+	if quota.MaxConnections > 0 && u.Connections > quota.MaxConnections {
+		exceeded = append(exceeded, "connections")
+	}
+	if quota.MaxActiveQueries > 0 && u.ActiveQueries > quota.MaxActiveQueries {
+		exceeded = append(exceeded, "activeQueries")
+	}
+	if quota.MaxActiveTime > 0 && u.TotalActiveTime > quota.MaxActiveTime {
+		exceeded = append(exceeded, "totalActiveTime")
+	}
+	return exceeded
+}
+
+// getUserQuotaKillCandidates returns the longest running queries of a user to kill to get back under its active
+// queries and active time quotas, connections over quota are only reported
+func (server *ServerMonitor) getUserQuotaKillCandidates(quota UserQuota) []dbhelper.Processlist {
+	var pl []dbhelper.Processlist
+	for _, q := range server.getKillPolicyCandidates() {
+		if q.User == quota.User && isActiveQuery(q) {
+			pl = append(pl, q)
+		}
+	}
+	sort.Slice(pl, func(i, j int) bool {
+		return pl[i].Time.Float64 > pl[j].Time.Float64
+	})
+	active := len(pl)
+	var total float64
+	for _, q := range pl {
+		total += q.Time.Float64
+	}
+	var kill []dbhelper.Processlist
+	for _, q := range pl {
+		if (quota.MaxActiveQueries <= 0 || active <= quota.MaxActiveQueries) && (quota.MaxActiveTime <= 0 || total <= quota.MaxActiveTime) {
+			break
+		}
+		kill = append(kill, q)
+		active--
+		total -= q.Time.Float64
+	}
+	return kill
+}
+
+// CheckUserQuotas raise a warning for every user over its monitoring-user-quotas on a running server, with
+// monitoring-user-quotas-kill the longest queries of the user are killed until it is back in quota
+func (cluster *Cluster) CheckUserQuotas() []KillPolicyAction {
+	quotas := cluster.GetUserQuotas()
+	if len(quotas) == 0 {
+		return nil
+	}
+	var actions []KillPolicyAction
+	for _, s := range cluster.Servers {
+		if s == nil || s.IsDown() {
+			continue
+		}
+		for _, u := range s.GetUserResourceUsage() {
+			if !u.OverQuota {
+				continue
+			}
+			cluster.sme.AddState("WARN0109", state.State{ErrType: LvlWarn, ErrDesc: fmt.Sprintf(clusterError["WARN0109"], u.User, s.URL, strings.Join(u.Exceeded, ",")), ErrFrom: "MON", ServerUrl: s.URL})
+			if !cluster.Conf.MonitorUserQuotasKill {
+				continue
+			}
+			for _, q := range s.getUserQuotaKillCandidates(quotas[u.User]) {
+				// the thread may have ended or run another query since the process list was read
+				if _, ok := s.getCurrentThread(q); !ok {
+					cluster.LogPrintf(LvlInfo, "User quota skip query %d on %s, no longer running", q.Id, s.URL)
+					continue
+				}
+				logs, err := s.KillQuery(strconv.FormatUint(q.Id, 10))
+				cluster.LogSQL(logs, err, s.URL, "UserQuota", LvlErr, "User quota could not kill query %d on %s: %s", q.Id, s.URL, err)
+				if err != nil {
+					continue
+				}
+				cluster.LogPrintf(LvlInfo, "User quota killed query %d of %s on %s running %.0fs: %s", q.Id, q.User, s.URL, q.Time.Float64, cluster.getLoggedQuery(q))
+				actions = append(actions, KillPolicyAction{Policy: "user-quota", URL: s.URL, Id: q.Id, User: q.User, Time: int64(q.Time.Float64)})
+			}
+		}
+	}
+	return actions
+}
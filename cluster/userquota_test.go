@@ -0,0 +1,82 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/dbhelper"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+func TestUserResourceUsage(t *testing.T) {
+	query := func(id uint64, user string, command string, info string, seconds float64) dbhelper.Processlist {
+		return dbhelper.Processlist{Id: id, User: user, Command: command, Info: sql.NullString{String: info, Valid: info != ""}, Time: sql.NullFloat64{Float64: seconds, Valid: true}}
+	}
+	sme := new(state.StateMachine)
+	sme.Init()
+	cluster := &Cluster{sme: sme, Conf: config.Config{MonitorUserQuotas: `[{"user":"tenant1","maxConnections":3,"maxActiveQueries":2,"maxActiveTime":100},{"user":"tenant2","maxConnections":10}]`}}
+	server := &ServerMonitor{URL: "db1:3306", User: "repman", State: stateSlave, ClusterGroup: cluster, DBVersion: dbhelper.NewMySQLVersion("10.6.4-MariaDB", "")}
+	server.FullProcessList = []dbhelper.Processlist{
+		query(1, "tenant1", "Query", "SELECT a FROM t", 80),
+		query(2, "tenant1", "Query", "SELECT b FROM t", 30),
+		query(3, "tenant1", "Query", "SELECT c FROM t", 5),
+		query(4, "tenant1", "Sleep", "", 900),
+		query(5, "tenant2", "Query", "SELECT 1", 1000),
+		query(6, "system user", "Slave_SQL", "", 1000),
+	}
+	cluster.Servers = serverList{server}
+	cluster.LoadUserQuotas()
+	usage := server.GetUserResourceUsage()
+	expected := []UserResourceUsage{
+		{User: "tenant1", Connections: 4, ActiveQueries: 3, TotalActiveTime: 115, OverQuota: true, Exceeded: []string{"connections", "activeQueries", "totalActiveTime"}},
+		{User: "tenant2", Connections: 1, ActiveQueries: 1, TotalActiveTime: 1000, Exceeded: []string{}},
+	}
+	if !reflect.DeepEqual(usage, expected) {
+		t.Fatalf("Got %+v, expected %+v", usage, expected)
+	}
+	kill := server.getUserQuotaKillCandidates(UserQuota{User: "tenant1", MaxActiveQueries: 2, MaxActiveTime: 100})
+	if len(kill) != 1 || kill[0].Id != 1 {
+		t.Fatalf("Expected the longest query killed to get back in quota, got %v", kill)
+	}
+	cluster.CheckUserQuotas()
+	if !sme.CurState.Search("WARN0109") {
+		t.Fatal("Expected over quota warning")
+	}
+
+	// query 1 is read again before the kill, it is not killed once ended
+	running := false
+	log := &execLog{}
+	name := fmt.Sprintf("userquota%d", time.Now().UnixNano())
+	sql.Register(name, execDriver{log: log, rows: func(query string, args []driver.Value) *pkRows {
+		r := &pkRows{cols: []string{"Id", "User", "Host", "db", "Command", "Time", "State", "Info", "Progress"}}
+		if running {
+			r.values = [][]driver.Value{{int64(1), "tenant1", "", nil, "Query", int64(85), nil, "SELECT a FROM t", int64(0)}}
+		}
+		return r
+	}})
+	server.Conn, _ = sqlx.Open(name, "")
+	cluster.Conf.MonitorUserQuotasKill = true
+	if actions := cluster.CheckUserQuotas(); len(actions) != 0 || len(log.stmts) != 0 {
+		t.Fatalf("Expected no kill of an ended query, got %v %v", actions, log.stmts)
+	}
+	running = true
+	if actions := cluster.CheckUserQuotas(); len(actions) != 1 || strings.Join(log.stmts, ";") != "KILL QUERY ? [1]" {
+		t.Fatalf("Expected query 1 killed, got %v %v", actions, log.stmts)
+	}
+}
@@ -0,0 +1,128 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"time"
+)
+
+const (
+	delayWebhookRaised  string = "raised"
+	delayWebhookCleared string = "cleared"
+)
+
+// DelayWebhookAlert is posted to alert-replication-delay-webhook-url when the replication delay alert of a
+// slave is raised or cleared, and to alert-replication-delay-warning-webhook-url for the warning tier
+type DelayWebhookAlert struct {
+	Cluster   string `json:"cluster"`
+	Server    string `json:"server"`
+	Channel   string `json:"channel"`
+	Lag       int64  `json:"lag"`
+	Level     string `json:"level"`
+	State     string `json:"state"`
+	Timestamp int64  `json:"timestamp"`
+}
+
+// delayWebhookBackoff is the wait before the first retry, doubled at each retry
+var delayWebhookBackoff = time.Second
+
+// delayWebhookQueueSize is the number of alerts of a server waiting to be posted to a webhook
+const delayWebhookQueueSize = 16
+
+// webhookQueues are the alerts of a server waiting to be posted per webhook url
+type webhookQueues map[string]chan DelayWebhookAlert
+
+// NotifyReplicationDelayWebhook posts the replication delay alert state of each tier when it differs from the
+// last one posted for this server, so a raised or cleared alert is sent once
+func (server *ServerMonitor) NotifyReplicationDelayWebhook() {
+	server.notifyDelayWebhook(server.ClusterGroup.Conf.AlertReplicationDelayWebhookURL, DelayAlertLevelCritical, server.ReplicationDelayAlertState.Alerting, &server.delayWebhookState)
+	server.notifyDelayWebhook(server.ClusterGroup.Conf.AlertReplicationDelayWarningWebhookURL, DelayAlertLevelWarning, server.ReplicationDelayWarnState.Alerting, &server.delayWarningWebhookState)
+}
+
+func (server *ServerMonitor) notifyDelayWebhook(url string, level string, alerting bool, last *string) {
+	if url == "" {
+		return
+	}
+	st := delayWebhookCleared
+	if alerting {
+		st = delayWebhookRaised
+	}
+	// nothing was raised, no need to send a recovery
+	if st == *last || (*last == "" && st == delayWebhookCleared) {
+		return
+	}
+	*last = st
+	a := DelayWebhookAlert{
+		Cluster:   server.ClusterGroup.Name,
+		Server:    server.URL,
+		Channel:   server.ReplicationSourceName,
+		Lag:       server.GetReplicationDelay(),
+		Level:     level,
+		State:     st,
+		Timestamp: time.Now().Unix(),
+	}
+	select {
+	case server.getDelayWebhookQueue(url) <- a:
+	default:
+		server.ClusterGroup.LogPrintf(LvlErr, "Could not post replication delay %s alert of %s to webhook: too many alerts waiting", level, server.URL)
+	}
+}
+
+// getDelayWebhookQueue returns the queue of the alerts of the server posted to url one at a time by a single
+// worker, so that an alert is never received after the next one while it is retried
+func (server *ServerMonitor) getDelayWebhookQueue(url string) chan DelayWebhookAlert {
+	if server.delayWebhookQueues == nil {
+		server.delayWebhookQueues = make(webhookQueues)
+	}
+	queue, ok := server.delayWebhookQueues[url]
+	if ok {
+		return queue
+	}
+	queue = make(chan DelayWebhookAlert, delayWebhookQueueSize)
+	server.delayWebhookQueues[url] = queue
+	go func() {
+		for a := range queue {
+			err := postDelayWebhook(url, a, server.ClusterGroup.Conf.AlertReplicationDelayWebhookRetries, delayWebhookBackoff)
+			if err != nil {
+				server.ClusterGroup.LogPrintf(LvlErr, "Could not post replication delay %s alert of %s to webhook: %s", a.Level, server.URL, err)
+			}
+		}
+	}()
+	return queue
+}
+
+// postDelayWebhook posts the alert as JSON, retrying with an exponential backoff until a 2xx response
+func postDelayWebhook(url string, a DelayWebhookAlert, retries int, backoff time.Duration) error {
+	body, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	for attempt := 0; ; attempt++ {
+		var resp *http.Response
+		resp, err = client.Post(url, "application/json", bytes.NewReader(body))
+		if err == nil {
+			resp.Body.Close()
+			if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+				return nil
+			}
+			err = fmt.Errorf("Webhook returned status %s", resp.Status)
+		}
+		if attempt >= retries {
+			return err
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
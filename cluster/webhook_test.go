@@ -0,0 +1,77 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/signal18/replication-manager/config"
+	"github.com/signal18/replication-manager/utils/state"
+)
+
+func TestPostDelayWebhookRetry(t *testing.T) {
+	calls := 0
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		if calls < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer ts.Close()
+	if err := postDelayWebhook(ts.URL, DelayWebhookAlert{}, 1, time.Millisecond); err == nil || calls != 2 {
+		t.Fatalf("Expected failure after 2 calls, got %d calls %v", calls, err)
+	}
+	calls = 0
+	if err := postDelayWebhook(ts.URL, DelayWebhookAlert{}, 3, time.Millisecond); err != nil || calls != 3 {
+		t.Fatalf("Expected success at the third call, got %d calls %v", calls, err)
+	}
+}
+
+func TestReplicationDelayWebhook(t *testing.T) {
+	alerts := make(chan DelayWebhookAlert, 10)
+	ts := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var a DelayWebhookAlert
+		json.NewDecoder(r.Body).Decode(&a)
+		// a slow raised alert must still be received before the cleared one
+		if a.State == delayWebhookRaised {
+			time.Sleep(200 * time.Millisecond)
+		}
+		alerts <- a
+	}))
+	defer ts.Close()
+	sme := new(state.StateMachine)
+	sme.Init()
+	server := &ServerMonitor{URL: "db2:3306", IsSlave: true, ClusterGroup: &Cluster{Name: "c1", sme: sme, Conf: config.Config{FailMaxDelay: 30, AlertReplicationDelayRaisePolls: 2, AlertReplicationDelayClearPolls: 1, AlertReplicationDelayWebhookURL: ts.URL}}}
+	delays := []int64{0, 60, 60, 90, 0, 0}
+	for _, delay := range delays {
+		server.ReplicationStatus = replicationStatusFixture{{SecondsBehindMaster: sql.NullInt64{Int64: delay, Valid: true}}}
+		server.CheckReplicationDelayAlert()
+	}
+	for _, expected := range []DelayWebhookAlert{{Lag: 60, State: delayWebhookRaised}, {Lag: 0, State: delayWebhookCleared}} {
+		select {
+		case a := <-alerts:
+			if a.Cluster != "c1" || a.Server != "db2:3306" || a.State != expected.State || a.Lag != expected.Lag {
+				t.Fatalf("Got alert %+v, expected %+v", a, expected)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Missing alert %+v", expected)
+		}
+	}
+	select {
+	case a := <-alerts:
+		t.Fatalf("Unexpected duplicate alert %+v", a)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,59 @@
+// replication-manager - Replication Manager Monitoring and CLI for MariaDB and MySQL
+// Copyright 2017 Signal 18 SARL
+// Authors: Guillaume Lefranc <guillaume@signal18.io>
+//          Stephane Varoqui  <svaroqui@gmail.com>
+// This source code is licensed under the GNU General Public License, version 3.
+// Redistribution/Reuse of this code is permitted under the GNU v3 license, as
+// an additional term, ALL code must carry the original Author(s) credit in comment form.
+// See LICENSE in this directory for the integral text.
+
+package cluster
+
+// WriteCircuitBreakerAction protects the master writes when no slave is close enough to bound data loss on failover
+type WriteCircuitBreakerAction interface {
+	Open(cluster *Cluster, margin int64) error
+	Close(cluster *Cluster) error
+}
+
+func newWriteCircuitBreakerAction(name string) WriteCircuitBreakerAction {
+	switch name {
+	case "readonly":
+		return readOnlyWriteCircuitBreaker{}
+	}
+	return logWriteCircuitBreaker{}
+}
+
+type logWriteCircuitBreaker struct{}
+
+func (logWriteCircuitBreaker) Open(cluster *Cluster, margin int64) error {
+	cluster.LogPrintf(LvlWarn, "Write circuit breaker open, replication safety margin %d over write-circuit-breaker-max-slave-delay %d", margin, cluster.Conf.WriteCircuitBreakerMaxDelay)
+	return nil
+}
+
+func (logWriteCircuitBreaker) Close(cluster *Cluster) error {
+	cluster.LogPrintf(LvlInfo, "Write circuit breaker closed")
+	return nil
+}
+
+// readOnlyWriteCircuitBreaker set the master read only while the circuit is open
+type readOnlyWriteCircuitBreaker struct{}
+
+func (readOnlyWriteCircuitBreaker) Open(cluster *Cluster, margin int64) error {
+	logWriteCircuitBreaker{}.Open(cluster, margin)
+	if cluster.master == nil {
+		return nil
+	}
+	cluster.LogPrintf(LvlWarn, "Write circuit breaker set master %s read only", cluster.master.URL)
+	logs, err := cluster.master.SetReadOnly()
+	cluster.LogSQL(logs, err, cluster.master.URL, "WriteCircuitBreaker", LvlErr, "Could not set master %s read only: %s", cluster.master.URL, err)
+	return err
+}
+
+func (readOnlyWriteCircuitBreaker) Close(cluster *Cluster) error {
+	logWriteCircuitBreaker{}.Close(cluster)
+	if cluster.master == nil {
+		return nil
+	}
+	cluster.LogPrintf(LvlInfo, "Write circuit breaker set master %s read write", cluster.master.URL)
+	return cluster.master.SetReadWrite()
+}
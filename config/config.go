@@ -51,9 +51,28 @@ type Config struct {
 	MonitorQueryRules                         bool   `mapstructure:"monitoring-query-rules" toml:"monitoring-query-rules" json:"monitoringQueryRules"`
 	MonitorSchemaChangeScript                 string `mapstructure:"monitoring-schema-change-script" toml:"monitoring-schema-change-script" json:"monitoringSchemaChangeScript"`
 	MonitorProcessList                        bool   `mapstructure:"monitoring-processlist" toml:"monitoring-processlist" json:"monitoringProcesslist"`
+	MonitorProcessListPFS                     bool   `mapstructure:"monitoring-processlist-pfs" toml:"monitoring-processlist-pfs" json:"monitoringProcesslistPfs"`
+	MonitorProcessListReplicationCommands     string `mapstructure:"monitoring-processlist-replication-commands" toml:"monitoring-processlist-replication-commands" json:"monitoringProcesslistReplicationCommands"`
+	MonitorProcessListReplicationIdleStates   string `mapstructure:"monitoring-processlist-replication-idle-states" toml:"monitoring-processlist-replication-idle-states" json:"monitoringProcesslistReplicationIdleStates"`
+	MonitorProcessListRedact                  bool   `mapstructure:"monitoring-processlist-redact" toml:"monitoring-processlist-redact" json:"monitoringProcesslistRedact"`
+	MonitorProcessListDedicatedUser           bool   `mapstructure:"monitoring-processlist-dedicated-user" toml:"monitoring-processlist-dedicated-user" json:"monitoringProcesslistDedicatedUser"`
+	MonitorStatusAnomalyThresholds            string `mapstructure:"monitoring-status-anomaly-thresholds" toml:"monitoring-status-anomaly-thresholds" json:"monitoringStatusAnomalyThresholds"`
+	KillPolicies                              string `mapstructure:"monitoring-kill-policies" toml:"monitoring-kill-policies" json:"monitoringKillPolicies"`
+	KillPoliciesDryRun                        bool   `mapstructure:"monitoring-kill-policies-dry-run" toml:"monitoring-kill-policies-dry-run" json:"monitoringKillPoliciesDryRun"`
+	MonitorIdleConnectionAllowUsers           string `mapstructure:"monitoring-idle-connection-allow-users" toml:"monitoring-idle-connection-allow-users" json:"monitoringIdleConnectionAllowUsers"`
+	MonitorUserQuotas                         string `mapstructure:"monitoring-user-quotas" toml:"monitoring-user-quotas" json:"monitoringUserQuotas"`
+	MonitorUserQuotasKill                     bool   `mapstructure:"monitoring-user-quotas-kill" toml:"monitoring-user-quotas-kill" json:"monitoringUserQuotasKill"`
 	MonitorQueries                            bool   `mapstructure:"monitoring-queries" toml:"monitoring-queries" json:"monitoringQueries"`
 	MonitorPFS                                bool   `mapstructure:"monitoring-performance-schema" toml:"monitoring-performance-schema" json:"monitoringPerformanceSchema"`
 	MonitorInnoDBStatus                       bool   `mapstructure:"monitoring-innodb-status" toml:"monitoring-innodb-status" json:"monitoringInnoDBStatus"`
+	MonitorInnoDBPurgeLagThreshold            int64  `mapstructure:"monitoring-innodb-purge-lag-threshold" toml:"monitoring-innodb-purge-lag-threshold" json:"monitoringInnoDBPurgeLagThreshold"`
+	MonitorSkipHeavyMaxDelay                  int64  `mapstructure:"monitoring-skip-heavy-max-delay" toml:"monitoring-skip-heavy-max-delay" json:"monitoringSkipHeavyMaxDelay"`
+	MonitorRefreshWorkers                     int    `mapstructure:"monitoring-refresh-workers" toml:"monitoring-refresh-workers" json:"monitoringRefreshWorkers"`
+	MonitorRefreshTimeout                     int64  `mapstructure:"monitoring-refresh-timeout" toml:"monitoring-refresh-timeout" json:"monitoringRefreshTimeout"`
+	MonitorConnectRetry                       int    `mapstructure:"monitoring-connect-retry" toml:"monitoring-connect-retry" json:"monitoringConnectRetry"`
+	MonitorConnectBackoff                     int64  `mapstructure:"monitoring-connect-backoff" toml:"monitoring-connect-backoff" json:"monitoringConnectBackoff"`
+	MonitorConnectionStormRate                int64  `mapstructure:"monitoring-connection-storm-rate" toml:"monitoring-connection-storm-rate" json:"monitoringConnectionStormRate"`
+	MonitorConnectionStormJump                int64  `mapstructure:"monitoring-connection-storm-jump" toml:"monitoring-connection-storm-jump" json:"monitoringConnectionStormJump"`
 	MonitorLongQueryWithProcess               bool   `mapstructure:"monitoring-long-query-with-process" toml:"monitoring-long-query-with-process" json:"monitoringLongQueryWithProcess"`
 	MonitorLongQueryTime                      int    `mapstructure:"monitoring-long-query-time" toml:"monitoring-long-query-time" json:"monitoringLongQueryTime"`
 	MonitorLongQueryScript                    string `mapstructure:"monitoring-long-query-script" toml:"monitoring-long-query-script" json:"monitoringLongQueryScript"`
@@ -99,6 +118,7 @@ type Config struct {
 	PRXServersBackendMaxReplicationLag        int    `mapstructure:"proxy-servers-backend-max-replication-lag" toml:"proxy-servers-backend--max-replication-lag" json:"proxyServersBackendMaxReplicationLag"`
 	PRXServersBackendMaxConnections           int    `mapstructure:"proxy-servers-backend-max-connections" toml:"proxy-servers-backend--max-connections" json:"proxyServersBackendMaxConnections"`
 	ClusterHead                               string `mapstructure:"cluster-head" toml:"cluster-head" json:"clusterHead"`
+	ClusterGroup                              string `mapstructure:"cluster-group" toml:"cluster-group" json:"clusterGroup"`
 	MasterConnectRetry                        int    `mapstructure:"replication-master-connect-retry" toml:"replication-master-connect-retry" json:"replicationMasterConnectRetry"`
 	RplUser                                   string `mapstructure:"replication-credential" toml:"replication-credential" json:"replicationCredential"`
 	ReplicationErrorScript                    string `mapstructure:"replication-error-script" toml:"replication-error-script" json:"replicationErrorScript"`
@@ -113,6 +133,9 @@ type Config struct {
 	MasterSlavePgLogical                      bool   `mapstructure:"replication-master-slave-pg-logical" toml:"replication-master-slave-pg-logical" json:"replicationMasterSlavePgLogical"`
 	ReplicationNoRelay                        bool   `mapstructure:"replication-master-slave-never-relay" toml:"replication-master-slave-never-relay" json:"replicationMasterSlaveNeverRelay"`
 	ReplicationRestartOnSQLErrorMatch         string `mapstructure:"replication-restart-on-sqlerror-match" toml:"replication-restart-on-sqlerror-match" json:"eeplicationRestartOnSqlLErrorMatch"`
+	ReplicationSkipErrors                     string `mapstructure:"replication-skip-errors" toml:"replication-skip-errors" json:"replicationSkipErrors"`
+	ReplicationSkipErrorsAuto                 bool   `mapstructure:"replication-skip-errors-auto" toml:"replication-skip-errors-auto" json:"replicationSkipErrorsAuto"`
+	ReplicationSkipErrorsAutoMax              int    `mapstructure:"replication-skip-errors-auto-max" toml:"replication-skip-errors-auto-max" json:"replicationSkipErrorsAutoMax"`
 	SwitchWaitKill                            int64  `mapstructure:"switchover-wait-kill" toml:"switchover-wait-kill" json:"switchoverWaitKill"`
 	SwitchWaitTrx                             int64  `mapstructure:"switchover-wait-trx" toml:"switchover-wait-trx" json:"switchoverWaitTrx"`
 	SwitchWaitWrite                           int    `mapstructure:"switchover-wait-write-query" toml:"switchover-wait-write-query" json:"switchoverWaitWriteQuery"`
@@ -136,6 +159,7 @@ type Config struct {
 	FailResetTime                             int64  `mapstructure:"failcount-reset-time" toml:"failover-reset-time" json:"failoverResetTime"`
 	FailMode                                  string `mapstructure:"failover-mode" toml:"failover-mode" json:"failoverMode"`
 	FailMaxDelay                              int64  `mapstructure:"failover-max-slave-delay" toml:"failover-max-slave-delay" json:"failoverMaxSlaveDelay"`
+	FailAllowReplicationFilters               bool   `mapstructure:"failover-allow-replication-filters" toml:"failover-allow-replication-filters" json:"failoverAllowReplicationFilters"`
 	MaxFail                                   int    `mapstructure:"failover-falsepositive-ping-counter" toml:"failover-falsepositive-ping-counter" json:"failoverFalsePositivePingCounter"`
 	CheckFalsePositiveHeartbeat               bool   `mapstructure:"failover-falsepositive-heartbeat" toml:"failover-falsepositive-heartbeat" json:"failoverFalsePositiveHeartbeat"`
 	CheckFalsePositiveMaxscale                bool   `mapstructure:"failover-falsepositive-maxscale" toml:"failover-falsepositive-maxscale" json:"failoverFalsePositiveMaxscale"`
@@ -143,6 +167,7 @@ type Config struct {
 	CheckFalsePositiveMaxscaleTimeout         int    `mapstructure:"failover-falsepositive-maxscale-timeout" toml:"failover-falsepositive-maxscale-timeout" json:"failoverFalsePositiveMaxscaleTimeout"`
 	CheckFalsePositiveExternal                bool   `mapstructure:"failover-falsepositive-external" toml:"failover-falsepositive-external" json:"failoverFalsePositiveExternal"`
 	CheckFalsePositiveExternalPort            int    `mapstructure:"failover-falsepositive-external-port" toml:"failover-falsepositive-external-port" json:"failoverFalsePositiveExternalPort"`
+	CheckFalsePositiveFencing                 bool   `mapstructure:"failover-falsepositive-fencing" toml:"failover-falsepositive-fencing" json:"failoverFalsePositiveFencing"`
 	FailoverLogFileKeep                       int    `mapstructure:"failover-log-file-keep" toml:"failover-log-file-keep" json:"failoverLogFileKeep"`
 	FailoverSwitchToPrefered                  bool   `mapstructure:"failover-switch-to-prefered" toml:"failover-switch-to-prefered" json:"failoverSwithToPrefered"`
 	Autorejoin                                bool   `mapstructure:"autorejoin" toml:"autorejoin" json:"autorejoin"`
@@ -157,6 +182,7 @@ type Config struct {
 	AutorejoinSemisync                        bool   `mapstructure:"autorejoin-flashback-on-sync" toml:"autorejoin-flashback-on-sync" json:"autorejoinFlashbackOnSync"`
 	AutorejoinNoSemisync                      bool   `mapstructure:"autorejoin-flashback-on-unsync" toml:"autorejoin-flashback-on-unsync" json:"autorejoinFlashbackOnUnsync"`
 	AutorejoinSlavePositionalHeartbeat        bool   `mapstructure:"autorejoin-slave-positional-heartbeat" toml:"autorejoin-slave-positional-heartbeat" json:"autorejoinSlavePositionalHeartbeat"`
+	AutorejoinMisdirectedSlaves               bool   `mapstructure:"autorejoin-misdirected-slaves" toml:"autorejoin-misdirected-slaves" json:"autorejoinMisdirectedSlaves"`
 	CheckType                                 string `mapstructure:"check-type" toml:"check-type" json:"checkType"`
 	CheckReplFilter                           bool   `mapstructure:"check-replication-filters" toml:"check-replication-filters" json:"checkReplicationFilters"`
 	CheckBinFilter                            bool   `mapstructure:"check-binlog-filters" toml:"check-binlog-filters" json:"checkBinlogFilters"`
@@ -283,6 +309,10 @@ type Config struct {
 	KeyPath                                   string `mapstructure:"keypath" toml:"-" json:"-"`
 	Topology                                  string `mapstructure:"topology" toml:"-" json:"-"` // use by bootstrap
 	GraphiteMetrics                           bool   `mapstructure:"graphite-metrics" toml:"graphite-metrics" json:"graphiteMetrics"`
+	MonitorReplicationDelaySinkFile           string `mapstructure:"monitoring-replication-delay-sink-file" toml:"monitoring-replication-delay-sink-file" json:"monitoringReplicationDelaySinkFile"`
+	MonitorStatusFile                         string `mapstructure:"monitoring-status-file" toml:"monitoring-status-file" json:"monitoringStatusFile"`
+	MonitorStatusFileInterval                 int    `mapstructure:"monitoring-status-file-interval" toml:"monitoring-status-file-interval" json:"monitoringStatusFileInterval"`
+	MonitorReplicationStatusMaxAge            int64  `mapstructure:"monitoring-replication-status-max-age" toml:"monitoring-replication-status-max-age" json:"monitoringReplicationStatusMaxAge"`
 	GraphiteEmbedded                          bool   `mapstructure:"graphite-embedded" toml:"graphite-embedded" json:"graphiteEmbedded"`
 	GraphiteCarbonHost                        string `mapstructure:"graphite-carbon-host" toml:"graphite-carbon-host" json:"graphiteCarbonHost"`
 	GraphiteCarbonPort                        int    `mapstructure:"graphite-carbon-port" toml:"graphite-carbon-port" json:"graphiteCarbonPort"`
@@ -343,6 +373,8 @@ type Config struct {
 	ProvTags                                  string `mapstructure:"prov-db-tags" toml:"prov-db-tags" json:"provDbTags"`
 	ProvBinaryInTarball                       bool   `mapstructure:"prov-db-binary-in-tarball" toml:"prov-db-binary-in-tarball" json:"provDbBinaryInTarball"`
 	ProvBinaryTarballName                     string `mapstructure:"prov-db-binary-tarball-name" toml:"prov-db-binary-tarball-name" json:"provDbBinaryTarballName"`
+	ProvDBConfigOverride                      string `mapstructure:"prov-db-config-override" toml:"prov-db-config-override" json:"provDbConfigOverride"`
+	ProvDBConfigOverrideFile                  string `mapstructure:"prov-db-config-override-file" toml:"prov-db-config-override-file" json:"provDbConfigOverrideFile"`
 	ProvDomain                                string `mapstructure:"prov-db-domain" toml:"prov-db-domain" json:"provDbDomain"`
 	ProvDisk                                  string `mapstructure:"prov-db-disk-size" toml:"prov-db-disk-size" json:"provDbDiskSize"`
 	ProvDiskSystemSize                        string `mapstructure:"prov-db-disk-system-size" toml:"prov-db-disk-system-size" json:"provDbDiskSystemSize"`
@@ -420,7 +452,27 @@ type Config struct {
 	APIPort                                   string `mapstructure:"api-port" toml:"api-port" json:"apiPort"`
 	APIBind                                   string `mapstructure:"api-bind" toml:"api-bind" json:"apiBind"`
 	APIHttpsBind                              bool   `mapstructure:"api-https-bind" toml:"api-secure" json:"apiHttpsBind"`
+	APIWaitCatchUpMaxTimeout                  int    `mapstructure:"api-wait-catch-up-max-timeout" toml:"api-wait-catch-up-max-timeout" json:"apiWaitCatchUpMaxTimeout"`
 	AlertScript                               string `mapstructure:"alert-script" toml:"alert-script" json:"alertScript"`
+	AlertReplicationDelayRaisePolls           int    `mapstructure:"alert-replication-delay-raise-polls" toml:"alert-replication-delay-raise-polls" json:"alertReplicationDelayRaisePolls"`
+	AlertReplicationDelayClearPolls           int    `mapstructure:"alert-replication-delay-clear-polls" toml:"alert-replication-delay-clear-polls" json:"alertReplicationDelayClearPolls"`
+	AlertReplicationDelayWebhookURL           string `mapstructure:"alert-replication-delay-webhook-url" toml:"alert-replication-delay-webhook-url" json:"alertReplicationDelayWebhookUrl"`
+	AlertReplicationDelayWebhookRetries       int    `mapstructure:"alert-replication-delay-webhook-retries" toml:"alert-replication-delay-webhook-retries" json:"alertReplicationDelayWebhookRetries"`
+	AlertReplicationDelayWarning              int64  `mapstructure:"alert-replication-delay-warning" toml:"alert-replication-delay-warning" json:"alertReplicationDelayWarning"`
+	AlertReplicationDelayWarningWebhookURL    string `mapstructure:"alert-replication-delay-warning-webhook-url" toml:"alert-replication-delay-warning-webhook-url" json:"alertReplicationDelayWarningWebhookUrl"`
+	AlertReplicationDelayCritical             int64  `mapstructure:"alert-replication-delay-critical" toml:"alert-replication-delay-critical" json:"alertReplicationDelayCritical"`
+	MaxReadLag                                int64  `mapstructure:"read-max-slave-delay" toml:"read-max-slave-delay" json:"readMaxSlaveDelay"`
+	MaxReadLagClearPolls                      int    `mapstructure:"read-max-slave-delay-clear-polls" toml:"read-max-slave-delay-clear-polls" json:"readMaxSlaveDelayClearPolls"`
+	ReadWeightDecay                           string `mapstructure:"read-weight-decay" toml:"read-weight-decay" json:"readWeightDecay"`
+	ReadWeightHalfLife                        int64  `mapstructure:"read-weight-half-life" toml:"read-weight-half-life" json:"readWeightHalfLife"`
+	ReplicationSLOTarget                      string `mapstructure:"replication-slo-target" toml:"replication-slo-target" json:"replicationSloTarget"`
+	ReplicationRestartGrace                   int    `mapstructure:"replication-restart-grace" toml:"replication-restart-grace" json:"replicationRestartGrace"`
+	WriteCircuitBreakerMaxDelay               int64  `mapstructure:"write-circuit-breaker-max-slave-delay" toml:"write-circuit-breaker-max-slave-delay" json:"writeCircuitBreakerMaxSlaveDelay"`
+	WriteCircuitBreakerAction                 string `mapstructure:"write-circuit-breaker-action" toml:"write-circuit-breaker-action" json:"writeCircuitBreakerAction"`
+	HealthScoreWeightDelay                    int    `mapstructure:"health-score-weight-delay" toml:"health-score-weight-delay" json:"healthScoreWeightDelay"`
+	HealthScoreWeightDown                     int    `mapstructure:"health-score-weight-down" toml:"health-score-weight-down" json:"healthScoreWeightDown"`
+	HealthScoreWeightGtid                     int    `mapstructure:"health-score-weight-gtid" toml:"health-score-weight-gtid" json:"healthScoreWeightGtid"`
+	HealthScoreWeightDrift                    int    `mapstructure:"health-score-weight-drift" toml:"health-score-weight-drift" json:"healthScoreWeightDrift"`
 	ConfigFile                                string `mapstructure:"config" toml:"-" json:"-"`
 	MonitorScheduler                          bool   `mapstructure:"monitoring-scheduler" toml:"monitoring-scheduler" json:"monitoringScheduler"`
 	SchedulerReceiverPorts                    string `mapstructure:"scheduler-db-servers-receiver-ports" toml:"scheduler--db-servers-receiver-ports" json:"schedulerDbServersReceiverPorts"`
@@ -440,6 +492,13 @@ type Config struct {
 	SchedulerRollingRestartCron               string `mapstructure:"scheduler-rolling-restart-cron" toml:"scheduler-rolling-restart-cron" json:"schedulerRollingRestartCron"`
 	SchedulerRollingReprov                    bool   `mapstructure:"scheduler-rolling-reprov" toml:"scheduler-rolling-reprov" json:"schedulerRollingReprov"`
 	SchedulerRollingReprovCron                string `mapstructure:"scheduler-rolling-reprov-cron" toml:"scheduler-rolling-reprov-cron" json:"schedulerRollingReprovCron"`
+	RollingRestartCatchUpTimeout              int64  `mapstructure:"rolling-restart-catchup-timeout" toml:"rolling-restart-catchup-timeout" json:"rollingRestartCatchupTimeout"`
+	SchedulerConsistencyCheckSchemas          string `mapstructure:"scheduler-consistency-check-schemas" toml:"scheduler-consistency-check-schemas" json:"schedulerConsistencyCheckSchemas"`
+	SchedulerConsistencyCheckCron             string `mapstructure:"scheduler-consistency-check-cron" toml:"scheduler-consistency-check-cron" json:"schedulerConsistencyCheckCron"`
+	ChecksumChunkSize                         int    `mapstructure:"checksum-chunk-size" toml:"checksum-chunk-size" json:"checksumChunkSize"`
+	ChecksumChunkSleep                        int    `mapstructure:"checksum-chunk-sleep" toml:"checksum-chunk-sleep" json:"checksumChunkSleep"`
+	ChecksumMaxSlaveDelay                     int64  `mapstructure:"checksum-max-slave-delay" toml:"checksum-max-slave-delay" json:"checksumMaxSlaveDelay"`
+	ChecksumMaxPause                          int64  `mapstructure:"checksum-max-pause" toml:"checksum-max-pause" json:"checksumMaxPause"`
 	SchedulerJobsSSH                          bool   `mapstructure:"scheduler-jobs-ssh" toml:"scheduler-jobs-ssh" json:"schedulerJobsSsh"`
 	SchedulerJobsSSHCron                      string `mapstructure:"scheduler-jobs-ssh-cron" toml:"scheduler-jobs-ssh-cron" json:"schedulerJobsSshCron"`
 	Backup                                    bool   `mapstructure:"backup" toml:"backup" json:"backup"`
@@ -453,6 +512,7 @@ type Config struct {
 	BackupKeepWeekly                          int    `mapstructure:"backup-keep-weekly" toml:"backup-keep-weekly" json:"backupKeepWeekly"`
 	BackupKeepMonthly                         int    `mapstructure:"backup-keep-monthly" toml:"backup-keep-monthly" json:"backupKeepMonthly"`
 	BackupKeepYearly                          int    `mapstructure:"backup-keep-yearly" toml:"backup-keep-yearly" json:"backupKeepYearly"`
+	BackupFreshnessMaxAge                     int64  `mapstructure:"backup-freshness-max-age" toml:"backup-freshness-max-age" json:"backupFreshnessMaxAge"`
 	BackupRestic                              bool   `mapstructure:"backup-restic" toml:"backup-restic" json:"backupRestic"`
 	BackupResticBinaryPath                    string `mapstructure:"backup-restic-binary-path" toml:"backup-restic-binary-path" json:"backupResticBinaryPath"`
 	BackupResticAwsAccessKeyId                string `mapstructure:"backup-restic-aws-access-key-id" toml:"backup-restic-aws-access-key-id" json:"-"`
@@ -592,6 +652,7 @@ const (
 	GrantDBShowStatus            string = "db-show-status"
 	GrantDBShowSchema            string = "db-show-schema"
 	GrantDBShowProcess           string = "db-show-process"
+	GrantDBShowProcessLiterals   string = "db-show-process-literals"
 	GrantDBShowLogs              string = "db-show-logs"
 	GrantDBCapture               string = "db-capture"
 	GrantDBMaintenance           string = "db-maintenance"
@@ -839,6 +900,7 @@ func (conf *Config) GetGrantType() map[string]string {
 		GrantDBShowStatus:            GrantDBShowStatus,
 		GrantDBShowSchema:            GrantDBShowSchema,
 		GrantDBShowProcess:           GrantDBShowProcess,
+		GrantDBShowProcessLiterals:   GrantDBShowProcessLiterals,
 		GrantDBShowLogs:              GrantDBShowLogs,
 		GrantDBDebug:                 GrantDBDebug,
 		GrantClusterCreate:           GrantClusterCreate,
@@ -156,6 +156,14 @@ func init() {
 	monitorCmd.Flags().BoolVar(&conf.MonitorVariableDiff, "monitoring-variable-diff", true, "Monitor variable difference beetween nodes")
 	monitorCmd.Flags().BoolVar(&conf.MonitorPFS, "monitoring-performance-schema", true, "Monitor performance schema")
 	monitorCmd.Flags().BoolVar(&conf.MonitorInnoDBStatus, "monitoring-innodb-status", true, "Monitor innodb status")
+	monitorCmd.Flags().Int64Var(&conf.MonitorInnoDBPurgeLagThreshold, "monitoring-innodb-purge-lag-threshold", 1000000, "InnoDB history list length over which the purge lag is alerted, 0 to disable")
+	monitorCmd.Flags().Int64Var(&conf.MonitorConnectionStormRate, "monitoring-connection-storm-rate", 0, "New connections per second between two polls over which a connection storm is alerted, 0 to disable")
+	monitorCmd.Flags().Int64Var(&conf.MonitorConnectionStormJump, "monitoring-connection-storm-jump", 1000, "New connections between two polls over which a connection storm is alerted, 0 to disable")
+	monitorCmd.Flags().IntVar(&conf.MonitorRefreshWorkers, "monitoring-refresh-workers", 32, "Maximum number of database servers refreshed concurrently in a monitoring loop, 0 for all")
+	monitorCmd.Flags().Int64Var(&conf.MonitorRefreshTimeout, "monitoring-refresh-timeout", 0, "Seconds after which a server connection check counts as a failed ping and the monitoring loop stops waiting for a server refresh, the server is not refreshed again until it returns, 0 to wait")
+	monitorCmd.Flags().IntVar(&conf.MonitorConnectRetry, "monitoring-connect-retry", 2, "Maximum number of connection retries down the TLS fallback ladder, old certificates then no TLS, 0 to only try the current certificates")
+	monitorCmd.Flags().Int64Var(&conf.MonitorConnectBackoff, "monitoring-connect-backoff", 100, "Base delay in milliseconds between connection retries, doubled on each retry with jitter, 0 to retry immediately")
+	monitorCmd.Flags().Int64Var(&conf.MonitorSkipHeavyMaxDelay, "monitoring-skip-heavy-max-delay", 0, "Replication delay in seconds over which performance schema, slow log, metadata locks and disks gathering is skipped on a slave, 0 to disable")
 	monitorCmd.Flags().StringVar(&conf.MonitorIgnoreError, "monitoring-ignore-errors", "", "Comma separated list of error or warning to ignore")
 	monitorCmd.Flags().BoolVar(&conf.MonitorSchemaChange, "monitoring-schema-change", true, "Monitor schema change")
 	monitorCmd.Flags().StringVar(&conf.MonitorSchemaChangeScript, "monitoring-schema-change-script", "", "Monitor schema change external script")
@@ -173,6 +181,21 @@ func init() {
 	monitorCmd.Flags().BoolVar(&conf.MonitorScheduler, "monitoring-scheduler", false, "Enable internal scheduler")
 	monitorCmd.Flags().BoolVar(&conf.MonitorPause, "monitoring-pause", false, "Disable monitoring")
 	monitorCmd.Flags().BoolVar(&conf.MonitorProcessList, "monitoring-processlist", true, "Enable capture 50 longuest process via processlist")
+	monitorCmd.Flags().BoolVar(&conf.MonitorProcessListPFS, "monitoring-processlist-pfs", false, "Capture processlist from performance_schema when enabled, falling back to SHOW PROCESSLIST")
+	monitorCmd.Flags().StringVar(&conf.MonitorProcessListReplicationCommands, "monitoring-processlist-replication-commands", "", "List of processlist command prefixes of replication applier threads, empty for server version defaults")
+	monitorCmd.Flags().StringVar(&conf.MonitorStatusAnomalyThresholds, "monitoring-status-anomaly-thresholds", "ABORTED_CONNECTS:1,CREATED_TMP_DISK_TABLES:10,THREADS_RUNNING:50", "List of status:threshold flagging a status per second rate, or value for gauges like THREADS_RUNNING, as anomalous")
+	monitorCmd.Flags().StringVar(&conf.KillPolicies, "monitoring-kill-policies", "", "JSON array of query kill policies with name, user, host, digest, statement, minDuration, schema, schedule and dryRun, one of user, schema, host or digest is required, applied to the process list each monitoring loop")
+	monitorCmd.Flags().BoolVar(&conf.KillPoliciesDryRun, "monitoring-kill-policies-dry-run", false, "Only log the queries the kill policies would kill")
+	monitorCmd.Flags().StringVar(&conf.MonitorIdleConnectionAllowUsers, "monitoring-idle-connection-allow-users", "", "Comma separated list of users whose idle connections are never reaped")
+	monitorCmd.Flags().StringVar(&conf.MonitorUserQuotas, "monitoring-user-quotas", "", "JSON array of per user soft quotas with user, maxConnections, maxActiveQueries and maxActiveTime in seconds")
+	monitorCmd.Flags().BoolVar(&conf.MonitorUserQuotasKill, "monitoring-user-quotas-kill", false, "Kill the longest queries of a user over its active queries or active time quota")
+	monitorCmd.Flags().BoolVar(&conf.MonitorProcessListDedicatedUser, "monitoring-processlist-dedicated-user", false, "The database user of replication-manager is used by no application, all its threads are left out of the process list counts")
+	monitorCmd.Flags().BoolVar(&conf.MonitorProcessListRedact, "monitoring-processlist-redact", false, "Replace query literals with placeholders in the process list API unless the user has the db-show-process-literals grant")
+	monitorCmd.Flags().StringVar(&conf.MonitorProcessListReplicationIdleStates, "monitoring-processlist-replication-idle-states", "", "List of processlist state prefixes of idle replication applier threads, empty for server version defaults")
+	monitorCmd.Flags().StringVar(&conf.MonitorReplicationDelaySinkFile, "monitoring-replication-delay-sink-file", "", "Append replication delay of each poll as JSON lines to this file")
+	monitorCmd.Flags().StringVar(&conf.MonitorStatusFile, "monitoring-status-file", "", "Write the cluster and servers status as a JSON document to this file, the cluster name is appended to the file name, ex: status.json is written to status-<cluster>.json")
+	monitorCmd.Flags().IntVar(&conf.MonitorStatusFileInterval, "monitoring-status-file-interval", 10, "Seconds between monitoring-status-file refresh")
+	monitorCmd.Flags().Int64Var(&conf.MonitorReplicationStatusMaxAge, "monitoring-replication-status-max-age", 0, "Do not export replication delay metric when slave status is older than this time in sec (0: always export)")
 	monitorCmd.Flags().StringVar(&conf.MonitorAddress, "monitoring-address", "localhost", "How to contact this monitoring")
 	monitorCmd.Flags().StringVar(&conf.MonitorTenant, "monitoring-tenant", "default", "Can be use to store multi tenant identifier")
 	monitorCmd.Flags().Int64Var(&conf.MonitorWaitRetry, "monitoring-wait-retry", 30, "Retry this number of time before giving up state transition <999999")
@@ -205,6 +228,7 @@ func init() {
 	monitorCmd.Flags().Int64Var(&conf.SwitchDecreaseMaxConnValue, "switchover-decrease-max-conn-value", 10, "Switchover decrease max connection to this value different according to flavor")
 	monitorCmd.Flags().IntVar(&conf.SwitchSlaveWaitRouteChange, "switchover-wait-route-change", 2, "Switchover wait for unmanged proxy monitor to dicoverd new state")
 	monitorCmd.Flags().StringVar(&conf.MasterConn, "replication-source-name", "", "Replication channel name to use for multisource")
+	monitorCmd.Flags().StringVar(&conf.ClusterGroup, "cluster-group", "", "Group of the cluster for fleet roll up, a slash separated path like emea/prod makes it part of emea/prod and emea")
 
 	monitorCmd.Flags().StringVar(&conf.HostsDelayed, "replication-delayed-hosts", "", "Database hosts list that need delayed replication separated by commas")
 	monitorCmd.Flags().IntVar(&conf.HostsDelayedTime, "replication-delayed-time", 3600, "Delayed replication time")
@@ -222,12 +246,16 @@ func init() {
 	monitorCmd.Flags().BoolVar(&conf.ReplicationNoRelay, "replication-master-slave-never-relay", true, "Do not allow relay server MSS MXS XXM RSM")
 	monitorCmd.Flags().StringVar(&conf.ReplicationErrorScript, "replication-error-script", "", "Replication error script")
 	monitorCmd.Flags().StringVar(&conf.ReplicationRestartOnSQLErrorMatch, "replication-restart-on-sqlerror-match", "", "Auto restart replication on SQL Error regexep")
+	monitorCmd.Flags().StringVar(&conf.ReplicationSkipErrors, "replication-skip-errors", "", "Comma separated list of SQL thread error codes that can be skipped, ex: 1062,1032")
+	monitorCmd.Flags().BoolVar(&conf.ReplicationSkipErrorsAuto, "replication-skip-errors-auto", false, "Automatically skip the failing event of a slave stopped on an error of replication-skip-errors")
+	monitorCmd.Flags().IntVar(&conf.ReplicationSkipErrorsAutoMax, "replication-skip-errors-auto-max", 10, "Maximum events skipped automatically on a slave in an hour, over it the slave stays stopped and a warning is raised, 0 for no limit")
 	monitorCmd.Flags().StringVar(&conf.PreScript, "failover-pre-script", "", "Path of pre-failover script")
 	monitorCmd.Flags().StringVar(&conf.PostScript, "failover-post-script", "", "Path of post-failover script")
 	monitorCmd.Flags().BoolVar(&conf.ReadOnly, "failover-readonly-state", true, "Failover Switchover set slaves as read-only")
 	monitorCmd.Flags().BoolVar(&conf.SuperReadOnly, "failover-superreadonly-state", false, "Failover Switchover set slaves as super-read-only")
 	monitorCmd.Flags().StringVar(&conf.FailMode, "failover-mode", "manual", "Failover is manual or automatic")
 	monitorCmd.Flags().Int64Var(&conf.FailMaxDelay, "failover-max-slave-delay", 30, "Election ignore slave with replication delay over this time in sec")
+	monitorCmd.Flags().BoolVar(&conf.FailAllowReplicationFilters, "failover-allow-replication-filters", true, "Election allow slave with replication filters, writes on filtered databases are lost after failover")
 	monitorCmd.Flags().BoolVar(&conf.FailRestartUnsafe, "failover-restart-unsafe", false, "Failover when cluster down if a slave is start first ")
 	monitorCmd.Flags().IntVar(&conf.FailLimit, "failover-limit", 5, "Failover is canceld if already failover this number of time (0: unlimited)")
 	monitorCmd.Flags().Int64Var(&conf.FailTime, "failover-time-limit", 0, "Failover is canceled if timer in sec is not passed with previous failover (0: do not wait)")
@@ -239,6 +267,7 @@ func init() {
 	monitorCmd.Flags().IntVar(&conf.CheckFalsePositiveHeartbeatTimeout, "failover-falsepositive-heartbeat-timeout", 3, "Failover checks that slaves do not receive heartbeat detection timeout ")
 	monitorCmd.Flags().BoolVar(&conf.CheckFalsePositiveExternal, "failover-falsepositive-external", false, "Failover checks that http//master:80 does not reponse 200 OK header")
 	monitorCmd.Flags().IntVar(&conf.CheckFalsePositiveExternalPort, "failover-falsepositive-external-port", 80, "Failover checks external port")
+	monitorCmd.Flags().BoolVar(&conf.CheckFalsePositiveFencing, "failover-falsepositive-fencing", false, "Failover checks that all slaves replicating from the master lost it after the ping counter is reached")
 	monitorCmd.Flags().IntVar(&conf.MaxFail, "failover-falsepositive-ping-counter", 5, "Failover after this number of ping failures (interval 1s)")
 	monitorCmd.Flags().IntVar(&conf.FailoverLogFileKeep, "failover-log-file-keep", 5, "Purge log files taken during failover")
 	monitorCmd.Flags().BoolVar(&conf.Autoseed, "autoseed", false, "Automatic join a standalone node")
@@ -253,11 +282,31 @@ func init() {
 	monitorCmd.Flags().BoolVar(&conf.AutorejoinPhysicalBackup, "autorejoin-physical-backup", false, "Automatic rejoin ahead failed master via reseed previous phyiscal backup")
 	monitorCmd.Flags().BoolVar(&conf.AutorejoinLogicalBackup, "autorejoin-logical-backup", false, "Automatic rejoin ahead failed master via reseed previous logical backup")
 	monitorCmd.Flags().BoolVar(&conf.AutorejoinSlavePositionalHeartbeat, "autorejoin-slave-positional-heartbeat", false, "Automatically rejoin extra slaves via pseudo gtid heartbeat for positional replication")
+	monitorCmd.Flags().BoolVar(&conf.AutorejoinMisdirectedSlaves, "autorejoin-misdirected-slaves", false, "Automatically change master of GTID slaves replicating from another server than the elected master")
 
 	monitorCmd.Flags().StringVar(&conf.AlertScript, "alert-script", "", "Path for alerting script server status change")
 	monitorCmd.Flags().StringVar(&conf.SlackURL, "alert-slack-url", "", "Slack webhook URL to alert")
 	monitorCmd.Flags().StringVar(&conf.SlackChannel, "alert-slack-channel", "#support", "Slack channel to alert")
 	monitorCmd.Flags().StringVar(&conf.SlackUser, "alert-slack-user", "", "Slack user for alert")
+	monitorCmd.Flags().IntVar(&conf.AlertReplicationDelayRaisePolls, "alert-replication-delay-raise-polls", 3, "Alert replication delay after this number of monitoring polls over failover-max-slave-delay")
+	monitorCmd.Flags().IntVar(&conf.AlertReplicationDelayClearPolls, "alert-replication-delay-clear-polls", 3, "Clear replication delay alert after this number of monitoring polls under failover-max-slave-delay")
+	monitorCmd.Flags().StringVar(&conf.AlertReplicationDelayWebhookURL, "alert-replication-delay-webhook-url", "", "URL receiving a JSON POST when a replication delay alert is raised or cleared")
+	monitorCmd.Flags().IntVar(&conf.AlertReplicationDelayWebhookRetries, "alert-replication-delay-webhook-retries", 3, "Number of retries with exponential backoff of a failed replication delay webhook post")
+	monitorCmd.Flags().Int64Var(&conf.AlertReplicationDelayWarning, "alert-replication-delay-warning", 0, "Replication delay in seconds of the warning alert tier, 0 to disable")
+	monitorCmd.Flags().StringVar(&conf.AlertReplicationDelayWarningWebhookURL, "alert-replication-delay-warning-webhook-url", "", "URL receiving a JSON POST when a replication delay warning is raised or cleared")
+	monitorCmd.Flags().Int64Var(&conf.AlertReplicationDelayCritical, "alert-replication-delay-critical", 0, "Replication delay in seconds of the critical alert tier, 0 to use failover-max-slave-delay")
+	monitorCmd.Flags().Int64Var(&conf.MaxReadLag, "read-max-slave-delay", 0, "Slave with replication delay over this time in sec is not eligible for reads (0: disabled)")
+	monitorCmd.Flags().StringVar(&conf.ReadWeightDecay, "read-weight-decay", "linear", "Decay of the slave read weight with replication delay, linear down to zero at read-max-slave-delay or exponential")
+	monitorCmd.Flags().Int64Var(&conf.ReadWeightHalfLife, "read-weight-half-life", 10, "Replication delay in sec halving the slave read weight with exponential read-weight-decay")
+	monitorCmd.Flags().IntVar(&conf.MaxReadLagClearPolls, "read-max-slave-delay-clear-polls", 3, "Slave is eligible for reads again after this number of monitoring polls under read-max-slave-delay")
+	monitorCmd.Flags().StringVar(&conf.ReplicationSLOTarget, "replication-slo-target", "99", "Target percentage of monitoring polls with slave replication delay under failover-max-slave-delay, used for burn rate")
+	monitorCmd.Flags().IntVar(&conf.ReplicationRestartGrace, "replication-restart-grace", 300, "Seconds after a slave restart during which polls over failover-max-slave-delay are not counted in the replication SLO")
+	monitorCmd.Flags().Int64Var(&conf.WriteCircuitBreakerMaxDelay, "write-circuit-breaker-max-slave-delay", 0, "Trip the write circuit breaker when the least delayed healthy slave is over this time in sec (0: disabled)")
+	monitorCmd.Flags().StringVar(&conf.WriteCircuitBreakerAction, "write-circuit-breaker-action", "log", "Write circuit breaker action log|readonly")
+	monitorCmd.Flags().IntVar(&conf.HealthScoreWeightDelay, "health-score-weight-delay", 40, "Weight of the 90th percentile of slaves replication delay in the cluster health score")
+	monitorCmd.Flags().IntVar(&conf.HealthScoreWeightDown, "health-score-weight-down", 30, "Weight of the failed or suspect servers in the cluster health score")
+	monitorCmd.Flags().IntVar(&conf.HealthScoreWeightGtid, "health-score-weight-gtid", 15, "Weight of the GTID errant transactions in the cluster health score")
+	monitorCmd.Flags().IntVar(&conf.HealthScoreWeightDrift, "health-score-weight-drift", 15, "Weight of the schema charset drift in the cluster health score")
 
 	monitorCmd.Flags().BoolVar(&conf.RegistryConsul, "registry-consul", false, "Register write and read SRV DNS to consul")
 	monitorCmd.Flags().StringVar(&conf.RegistryHosts, "registry-servers", "127.0.0.1", "Comma-separated list of registry addresses")
@@ -276,6 +325,7 @@ func init() {
 	monitorCmd.Flags().StringVar(&conf.APIBind, "api-bind", "0.0.0.0", "Rest API bind ip")
 	monitorCmd.Flags().BoolVar(&conf.APIHttpsBind, "api-https-bind", false, "Bind API call to https Web UI will error with http")
 	monitorCmd.Flags().BoolVar(&conf.APISecureConfig, "api-credentials-secure-config", false, "Need JWT token to download config tar.gz")
+	monitorCmd.Flags().IntVar(&conf.APIWaitCatchUpMaxTimeout, "api-wait-catch-up-max-timeout", 30, "Max timeout in seconds of a wait for slave catch up call, each call holds a monitoring connection")
 
 	//monitorCmd.Flags().BoolVar(&conf.Daemon, "daemon", true, "Daemon mode. Do not start the Termbox console")
 	conf.Daemon = true
@@ -472,6 +522,13 @@ func init() {
 	monitorCmd.Flags().StringVar(&conf.SchedulerRollingRestartCron, "scheduler-rolling-restart-cron", "0 30 11 * * *", "Rolling restart cron expression represents a set of times, using 6 space-separated fields.")
 	monitorCmd.Flags().BoolVar(&conf.SchedulerRollingReprov, "scheduler-rolling-reprov", false, "Schedule rolling reprov")
 	monitorCmd.Flags().StringVar(&conf.SchedulerRollingReprovCron, "scheduler-rolling-reprov-cron", "0 30 10 * * 5", "Rolling reprov cron expression represents a set of times, using 6 space-separated fields.")
+	monitorCmd.Flags().Int64Var(&conf.RollingRestartCatchUpTimeout, "rolling-restart-catchup-timeout", 300, "Rolling restart wait this time in sec for a restarted slave to catch up replication before restarting the next server")
+	monitorCmd.Flags().StringVar(&conf.SchedulerConsistencyCheckSchemas, "scheduler-consistency-check-schemas", "", "Schedule chunked table checksum between master and slaves for this comma separated list of schemas")
+	monitorCmd.Flags().StringVar(&conf.SchedulerConsistencyCheckCron, "scheduler-consistency-check-cron", "0 0 2 * * 0", "Consistency check cron expression represents a set of times, using 6 space-separated fields.")
+	monitorCmd.Flags().IntVar(&conf.ChecksumChunkSize, "checksum-chunk-size", 2000, "Number of rows per chunk of a table checksum")
+	monitorCmd.Flags().IntVar(&conf.ChecksumChunkSleep, "checksum-chunk-sleep", 0, "Time in ms to sleep between two chunks of a table checksum")
+	monitorCmd.Flags().Int64Var(&conf.ChecksumMaxSlaveDelay, "checksum-max-slave-delay", 0, "Pause table checksum while a slave replication delay is over this time in sec (0: disabled)")
+	monitorCmd.Flags().Int64Var(&conf.ChecksumMaxPause, "checksum-max-pause", 3600, "Abort a table checksum when a slave stays late for more than this time in sec, behind checksum-max-slave-delay or the master position")
 	monitorCmd.Flags().BoolVar(&conf.SchedulerJobsSSH, "scheduler-jobs-ssh", false, "Schedule remote execution of dbjobs via ssh ")
 	monitorCmd.Flags().StringVar(&conf.SchedulerJobsSSHCron, "scheduler-jobs-ssh-cron", "0 * * * * *", "Remote execution of dbjobs via ssh ")
 
@@ -502,6 +559,7 @@ func init() {
 	monitorCmd.Flags().IntVar(&conf.BackupKeepWeekly, "backup-keep-weekly", 4, "Keep this number of weekly backup")
 	monitorCmd.Flags().IntVar(&conf.BackupKeepMonthly, "backup-keep-monthly", 12, "Keep this number of monthly backup")
 	monitorCmd.Flags().IntVar(&conf.BackupKeepYearly, "backup-keep-yearly", 2, "Keep this number of yearly backup")
+	monitorCmd.Flags().Int64Var(&conf.BackupFreshnessMaxAge, "backup-freshness-max-age", 0, "Warn when the newest backup is older than this number of sec, 0 to disable")
 
 	monitorCmd.Flags().StringVar(&conf.BackupMyDumperPath, "backup-mydumper-path", "/usr/bin/mydumper", "Path to mydumper binary")
 	monitorCmd.Flags().StringVar(&conf.BackupMyLoaderPath, "backup-myloader-path", "/usr/bin/myloader", "Path to myloader binary")
@@ -513,6 +571,8 @@ func init() {
 	monitorCmd.Flags().IntVar(&conf.BackupBinlogsKeep, "backup-binlogs-keep", 10, "Number of master binlog to keep")
 	monitorCmd.Flags().BoolVar(&conf.ProvBinaryInTarball, "prov-db-binary-in-tarball", false, "Add prov-db-binary-tarball-name binaries to init tarball")
 	monitorCmd.Flags().StringVar(&conf.ProvBinaryTarballName, "prov-db-binary-tarball-name", "mysql-8.0.17-macos10.14-x86_64.tar.gz", "Name of binary tarball to put in tarball")
+	monitorCmd.Flags().StringVar(&conf.ProvDBConfigOverride, "prov-db-config-override", "", "Database config snippet added last in conf.d of the config tarball, overriding generated variables")
+	monitorCmd.Flags().StringVar(&conf.ProvDBConfigOverrideFile, "prov-db-config-override-file", "", "Path of a database config file added last in conf.d of the config tarball, overriding generated variables")
 
 	monitorCmd.Flags().StringVar(&conf.ProvIops, "prov-db-disk-iops", "300", "Rnd IO/s in for micro service VM")
 	monitorCmd.Flags().StringVar(&conf.ProvIopsLatency, "prov-db-disk-iops-latency", "0.002", "IO latency in s")
@@ -115,6 +115,9 @@ func (repman *ReplicationManager) apiserver() {
 	router.Handle("/api/prometheus", negroni.New(
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxPrometheus)),
 	))
+	router.Handle("/api/openmetrics", negroni.New(
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxOpenMetrics)),
+	))
 	router.Handle("/api/status", negroni.New(
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxStatus)),
 	))
@@ -180,6 +183,38 @@ func (repman *ReplicationManager) IsValidClusterACL(r *http.Request, cluster *cl
 	return false
 }
 
+// isValidClusterUser returns true when the user of the request token can read the cluster itself
+func (repman *ReplicationManager) isValidClusterUser(r *http.Request, cluster *cluster.Cluster) bool {
+	token, err := request.ParseFromRequest(r, request.AuthorizationHeaderExtractor, func(token *jwt.Token) (interface{}, error) {
+		vk, _ := jwt.ParseRSAPublicKeyFromPEM(verificationKey)
+		return vk, nil
+	})
+	if err == nil {
+		claims := token.Claims.(jwt.MapClaims)
+		userinfo := claims["CustomUserInfo"]
+		mycutinfo := userinfo.(map[string]interface{})
+		meuser := mycutinfo["Name"].(string)
+		mepwd := mycutinfo["Password"].(string)
+		return cluster.IsValidACL(meuser, mepwd, "/api/clusters/"+cluster.Name)
+	}
+	return false
+}
+
+// getUserFromRequest returns the API user name of the request token or an empty string
+func (repman *ReplicationManager) getUserFromRequest(r *http.Request) string {
+	token, err := request.ParseFromRequest(r, request.AuthorizationHeaderExtractor, func(token *jwt.Token) (interface{}, error) {
+		vk, _ := jwt.ParseRSAPublicKeyFromPEM(verificationKey)
+		return vk, nil
+	})
+	if err != nil {
+		return ""
+	}
+	claims := token.Claims.(jwt.MapClaims)
+	userinfo := claims["CustomUserInfo"]
+	mycutinfo := userinfo.(map[string]interface{})
+	return mycutinfo["Name"].(string)
+}
+
 func (repman *ReplicationManager) loginHandler(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	var user userCredentials
@@ -359,6 +394,20 @@ func (repman *ReplicationManager) handlerMuxPrometheus(w http.ResponseWriter, r
 			w.Write([]byte(res))
 		}
 	}
+	w.Write([]byte(cluster.GetGroupPrometheusMetrics(cluster.GetGroupReplicationSummaries(repman.Clusters))))
+}
+
+func (repman *ReplicationManager) handlerMuxOpenMetrics(w http.ResponseWriter, r *http.Request) {
+
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	w.Header().Set("Content-Type", "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	w.Write([]byte("# TYPE query_exec_seconds histogram\n# UNIT query_exec_seconds seconds\n# HELP query_exec_seconds Execution time of the running queries.\n"))
+	for _, cluster := range repman.Clusters {
+		for _, server := range cluster.Servers {
+			w.Write([]byte(server.GetOpenMetricsQueryExec()))
+		}
+	}
+	w.Write([]byte("# EOF\n"))
 }
 
 func (repman *ReplicationManager) handlerMuxClustersOld(w http.ResponseWriter, r *http.Request) {
@@ -8,6 +8,7 @@ package server
 
 import (
 	"encoding/json"
+	"fmt"
 	"io"
 	"net/http"
 	"strconv"
@@ -54,6 +55,10 @@ func (repman *ReplicationManager) apiClusterProtectedHandler(router *mux.Router)
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxClusterBackups)),
 	))
+	router.Handle("/api/clusters/{clusterName}/backups/freshness", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxClusterBackupFreshness)),
+	))
 
 	router.Handle("/api/clusters/{clusterName}/certificates", negroni.New(
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
@@ -64,6 +69,18 @@ func (repman *ReplicationManager) apiClusterProtectedHandler(router *mux.Router)
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxClusterQueryRules)),
 	))
+	router.Handle("/api/clusters/{clusterName}/kill-policies", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxClusterKillPolicies)),
+	))
+	router.Handle("/api/clusters/{clusterName}/actions/kill-policies/add", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxClusterKillPolicyAdd)),
+	))
+	router.Handle("/api/clusters/{clusterName}/actions/kill-policies/{policyName}/drop", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxClusterKillPolicyDrop)),
+	))
 	router.Handle("/api/clusters/{clusterName}/shardclusters", negroni.New(
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxClusterShardClusters)),
@@ -236,6 +253,26 @@ func (repman *ReplicationManager) apiClusterProtectedHandler(router *mux.Router)
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServers)),
 	))
+	router.Handle("/api/clusters/{clusterName}/topology/servers-export", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServersExport)),
+	))
+	router.Handle("/api/clusters/{clusterName}/topology/replication-stream", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxReplicationStream)),
+	))
+	router.Handle("/api/clusters/{clusterName}/topology/master-fencing", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxMasterFencing)),
+	))
+	router.Handle("/api/clusters/{clusterName}/topology/read-weights", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxReplicaReadWeights)),
+	))
+	router.Handle("/api/clusters/{clusterName}/topology/group-summary", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxGroupReplicationSummary)),
+	))
 	router.Handle("/api/clusters/{clusterName}/topology/master", negroni.New(
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxMaster)),
@@ -315,6 +352,138 @@ func (repman *ReplicationManager) handlerMuxServers(w http.ResponseWriter, r *ht
 	}
 }
 
+func (repman *ReplicationManager) handlerMuxServersExport(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		data, err := mycluster.ExportServersJSON()
+		if err != nil {
+			mycluster.LogPrintf(cluster.LvlErr, "API Error encoding JSON: ", err)
+			http.Error(w, "Encoding error", 500)
+			return
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(data)
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+// handlerMuxReplicationStream sends the replication status of the slave channels as server-sent events, snapshot
+// events are the status of all channels, sent first and when the client could not keep up, replication events
+// the channels changed at each monitor cycle
+func (repman *ReplicationManager) handlerMuxReplicationStream(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster == nil {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+	if !repman.IsValidClusterACL(r, mycluster) {
+		http.Error(w, "No valid ACL", 403)
+		return
+	}
+	flusher, ok := w.(http.Flusher)
+	if !ok {
+		http.Error(w, "Streaming not supported", 500)
+		return
+	}
+	ch, snapshot := mycluster.SubscribeReplicationStream()
+	defer mycluster.UnsubscribeReplicationStream(ch)
+	update := cluster.ReplicationStreamUpdate{Snapshot: true, Events: snapshot}
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("Connection", "keep-alive")
+	for {
+		data, err := json.Marshal(update.Events)
+		if err != nil {
+			mycluster.LogPrintf(cluster.LvlErr, "API Error encoding JSON: ", err)
+			return
+		}
+		event := "replication"
+		if update.Snapshot {
+			event = "snapshot"
+		}
+		if _, err := fmt.Fprintf(w, "event: %s\ndata: %s\n\n", event, data); err != nil {
+			return
+		}
+		flusher.Flush()
+		select {
+		case update = <-ch:
+		case <-r.Context().Done():
+			return
+		}
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxMasterFencing(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "\t")
+		err := e.Encode(mycluster.GetMasterFencingState())
+		if err != nil {
+			http.Error(w, "Encoding error", 500)
+			return
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxReplicaReadWeights(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		e := json.NewEncoder(w)
+		e.SetIndent("", "\t")
+		err := e.Encode(mycluster.GetReplicaReadWeights())
+		if err != nil {
+			http.Error(w, "Encoding error", 500)
+			return
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxGroupReplicationSummary(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.isValidClusterUser(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		// only roll up the clusters the user can access
+		clusters := make(map[string]*cluster.Cluster)
+		for name, cl := range repman.Clusters {
+			if repman.isValidClusterUser(r, cl) {
+				clusters[name] = cl
+			}
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "\t")
+		err := e.Encode(mycluster.GetGroupReplicationSummaryOf(clusters))
+		if err != nil {
+			http.Error(w, "Encoding error", 500)
+			return
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
 func (repman *ReplicationManager) handlerMuxSlaves(w http.ResponseWriter, r *http.Request) {
 	//marshal unmarchal for ofuscation deep copy of struc
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -877,6 +1046,28 @@ func (repman *ReplicationManager) handlerMuxClusterBackups(w http.ResponseWriter
 	}
 }
 
+func (repman *ReplicationManager) handlerMuxClusterBackupFreshness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "\t")
+		err := e.Encode(mycluster.GetBackupFreshness())
+		if err != nil {
+			http.Error(w, "Encoding error", 500)
+			return
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
 func (repman *ReplicationManager) handlerMuxClusterShardClusters(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
@@ -921,6 +1112,75 @@ func (repman *ReplicationManager) handlerMuxClusterQueryRules(w http.ResponseWri
 	}
 }
 
+func (repman *ReplicationManager) handlerMuxClusterKillPolicies(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		e := json.NewEncoder(w)
+		e.SetIndent("", "\t")
+		err := e.Encode(mycluster.GetKillPolicies())
+		if err != nil {
+			http.Error(w, "Encoding error", 500)
+			return
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxClusterKillPolicyAdd(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		var policy cluster.KillPolicy
+		err := json.NewDecoder(r.Body).Decode(&policy)
+		if err != nil {
+			http.Error(w, "Decoding error", 400)
+			return
+		}
+		err = mycluster.AddKillPolicy(policy)
+		if err != nil {
+			http.Error(w, err.Error(), 400)
+			return
+		}
+		mycluster.LogPrintf(cluster.LvlInfo, "Kill policy %s added by API", policy.Name)
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxClusterKillPolicyDrop(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		if !mycluster.DropKillPolicy(vars["policyName"]) {
+			http.Error(w, "No kill policy", 404)
+			return
+		}
+		mycluster.LogPrintf(cluster.LvlInfo, "Kill policy %s dropped by API", vars["policyName"])
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
 func (repman *ReplicationManager) handlerMuxSwitchSettings(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Access-Control-Allow-Origin", "*")
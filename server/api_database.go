@@ -11,6 +11,8 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/codegangsta/negroni"
 	"github.com/gorilla/mux"
@@ -56,6 +58,10 @@ func (repman *ReplicationManager) apiDatabaseUnprotectedHandler(router *mux.Rout
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServersPortConfig)),
 	))
 
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/{serverPort}/config-manifest", negroni.New(
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServersPortConfigManifest)),
+	))
+
 }
 
 func (repman *ReplicationManager) apiDatabaseProtectedHandler(router *mux.Router) {
@@ -80,6 +86,48 @@ func (repman *ReplicationManager) apiDatabaseProtectedHandler(router *mux.Router
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerStatusDelta)),
 	))
 
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/status-anomalies", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerStatusAnomalies)),
+	))
+
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/purge-lag", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerPurgeLag)),
+	))
+
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/replication-apply-bottleneck", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerReplicationApplyBottleneck)),
+	))
+
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/thread-pool", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerThreadPool)),
+	))
+
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/mdl-waiters", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerMDLWaiters)),
+	))
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/replication-threads", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerReplicationThreads)),
+	))
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/temp-usage", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerThreadTempUsage)),
+	))
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/lock-wait-chains", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerLockWaitChains)),
+	))
+
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/user-resource-usage", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerUserResourceUsage)),
+	))
+
 	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/errorlog", negroni.New(
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerErrorLog)),
@@ -108,6 +156,22 @@ func (repman *ReplicationManager) apiDatabaseProtectedHandler(router *mux.Router
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerSchemas)),
 	))
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/schemas/{schemaName}/table-storage", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerTableStorage)),
+	))
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/schemas/{schemaName}/json-schema", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerSchemaJSON)),
+	))
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/schemas/{schemaName}/tables/{tableName}/online-ddl", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerOnlineDDL)),
+	))
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/schemas/{schemaName}/tables/{tableName}/shard-key", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerShardKeyAlignment)),
+	))
 	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/status-innodb", negroni.New(
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerInnoDBStatus)),
@@ -193,6 +257,11 @@ func (repman *ReplicationManager) apiDatabaseProtectedHandler(router *mux.Router
 		negroni.Wrap(http.HandlerFunc(repman.handlerWaitInnoDBPurge)),
 	))
 
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/actions/wait-catch-up/{position}/{timeout}", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxServerWaitCatchUp)),
+	))
+
 	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/actions/toogle-slow-query-capture", negroni.New(
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxSwitchSlowQueryCapture)),
@@ -270,6 +339,16 @@ func (repman *ReplicationManager) apiDatabaseProtectedHandler(router *mux.Router
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxSkipReplicationEvent)),
 	))
 
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/actions/skip-replication-error/{errno}", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxSkipReplicationError)),
+	))
+
+	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/actions/kill-idle-connections/{seconds}", negroni.New(
+		negroni.HandlerFunc(repman.validateTokenMiddleware),
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxKillIdleConnections)),
+	))
+
 	router.Handle("/api/clusters/{clusterName}/servers/{serverName}/actions/run-jobs", negroni.New(
 		negroni.HandlerFunc(repman.validateTokenMiddleware),
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxRunJobs)),
@@ -639,6 +718,48 @@ func (repman *ReplicationManager) handlerWaitInnoDBPurge(w http.ResponseWriter,
 
 }
 
+func (repman *ReplicationManager) handlerMuxServerWaitCatchUp(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil {
+			timeout, err := strconv.ParseFloat(vars["timeout"], 64)
+			if err != nil || timeout <= 0 {
+				http.Error(w, "Invalid timeout", 400)
+				return
+			}
+			if timeout > float64(mycluster.Conf.APIWaitCatchUpMaxTimeout) {
+				http.Error(w, fmt.Sprintf("Timeout over api-wait-catch-up-max-timeout %ds", mycluster.Conf.APIWaitCatchUpMaxTimeout), 400)
+				return
+			}
+			waited, err := mycluster.WaitForReplicaToCatchUp(node, vars["position"], time.Duration(timeout*float64(time.Second)))
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err = e.Encode(map[string]float64{"waited": waited.Seconds()})
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+		} else {
+			http.Error(w, "Server Not Found", 500)
+			return
+		}
+	} else {
+		http.Error(w, "Cluster Not Found", 500)
+		return
+	}
+}
+
 func (repman *ReplicationManager) handlerMuxServerSwitchReadOnly(w http.ResponseWriter, r *http.Request) {
 
 	w.Header().Set("Access-Control-Allow-Origin", "*")
@@ -1362,7 +1483,7 @@ func (repman *ReplicationManager) handlerMuxServersPortConfig(w http.ResponseWri
 		node := mycluster.GetServerFromURL(vars["serverName"] + ":" + vars["serverPort"])
 		proxy := mycluster.GetProxyFromURL(vars["serverName"] + ":" + vars["serverPort"])
 		if node != nil {
-			node.GetDatabaseConfig()
+			node.GetDatabaseConfigManifest()
 			data, err := ioutil.ReadFile(string(node.Datadir + "/config.tar.gz"))
 			if err != nil {
 				r.URL.Path = r.URL.Path + ".tar.gz"
@@ -1390,6 +1511,40 @@ func (repman *ReplicationManager) handlerMuxServersPortConfig(w http.ResponseWri
 	}
 }
 
+func (repman *ReplicationManager) handlerMuxServersPortConfigManifest(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if repman.Conf.APISecureConfig {
+			if !repman.IsValidClusterACL(r, mycluster) {
+				http.Error(w, "No valid ACL", 403)
+				return
+			}
+		}
+		node := mycluster.GetServerFromURL(vars["serverName"] + ":" + vars["serverPort"])
+		if node != nil {
+			m, err := node.GetDatabaseConfigManifest()
+			if err != nil {
+				w.WriteHeader(404)
+				w.Write([]byte("404 Something went wrong - " + http.StatusText(404)))
+				return
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err = e.Encode(m)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+		} else {
+			http.Error(w, "No server", 500)
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+	}
+}
+
 func (repman *ReplicationManager) handlerMuxServerProcesslist(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
@@ -1404,6 +1559,9 @@ func (repman *ReplicationManager) handlerMuxServerProcesslist(w http.ResponseWri
 			e := json.NewEncoder(w)
 			e.SetIndent("", "\t")
 			prl := node.GetProcessList()
+			if mycluster.IsProcessListRedacted(repman.getUserFromRequest(r)) {
+				prl = node.GetProcessListRedacted()
+			}
 			err := e.Encode(prl)
 			if err != nil {
 				http.Error(w, "Encoding error", 500)
@@ -1698,7 +1856,7 @@ func (repman *ReplicationManager) handlerMuxServerStatusDelta(w http.ResponseWri
 	}
 }
 
-func (repman *ReplicationManager) handlerMuxServerTables(w http.ResponseWriter, r *http.Request) {
+func (repman *ReplicationManager) handlerMuxServerStatusAnomalies(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
 	mycluster := repman.getClusterByName(vars["clusterName"])
@@ -1711,25 +1869,22 @@ func (repman *ReplicationManager) handlerMuxServerTables(w http.ResponseWriter,
 		if node != nil && node.IsDown() == false {
 			e := json.NewEncoder(w)
 			e.SetIndent("", "\t")
-			l := node.GetTables()
-			err := e.Encode(l)
+			err := e.Encode(node.GetStatusAnomalies())
 			if err != nil {
 				http.Error(w, "Encoding error", 500)
 				return
 			}
-			return
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("503 -Not a Valid Server!"))
 		}
-
 	} else {
 		http.Error(w, "No cluster", 500)
 		return
 	}
 }
 
-func (repman *ReplicationManager) handlerMuxServerVTables(w http.ResponseWriter, r *http.Request) {
+func (repman *ReplicationManager) handlerMuxServerPurgeLag(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
 	mycluster := repman.getClusterByName(vars["clusterName"])
@@ -1742,25 +1897,22 @@ func (repman *ReplicationManager) handlerMuxServerVTables(w http.ResponseWriter,
 		if node != nil && node.IsDown() == false {
 			e := json.NewEncoder(w)
 			e.SetIndent("", "\t")
-			l := node.GetVTables()
-			err := e.Encode(l)
+			err := e.Encode(node.GetPurgeLagStatus())
 			if err != nil {
 				http.Error(w, "Encoding error", 500)
 				return
 			}
-			return
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("503 -Not a Valid Server!"))
 		}
-
 	} else {
 		http.Error(w, "No cluster", 500)
 		return
 	}
 }
 
-func (repman *ReplicationManager) handlerMuxRunJobs(w http.ResponseWriter, r *http.Request) {
+func (repman *ReplicationManager) handlerMuxServerUserResourceUsage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
 	mycluster := repman.getClusterByName(vars["clusterName"])
@@ -1770,13 +1922,14 @@ func (repman *ReplicationManager) handlerMuxRunJobs(w http.ResponseWriter, r *ht
 			return
 		}
 		node := mycluster.GetServerFromName(vars["serverName"])
-		if node != nil {
-			err := node.JobRunViaSSH()
+		if node != nil && node.IsDown() == false {
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err := e.Encode(node.GetUserResourceUsage())
 			if err != nil {
-				http.Error(w, "Encoding running job", 500)
+				http.Error(w, "Encoding error", 500)
 				return
 			}
-			return
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("503 -Not a Valid Server!"))
@@ -1787,7 +1940,7 @@ func (repman *ReplicationManager) handlerMuxRunJobs(w http.ResponseWriter, r *ht
 	}
 }
 
-func (repman *ReplicationManager) handlerMuxServerSchemas(w http.ResponseWriter, r *http.Request) {
+func (repman *ReplicationManager) handlerMuxServerTableStorage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
 	mycluster := repman.getClusterByName(vars["clusterName"])
@@ -1798,27 +1951,57 @@ func (repman *ReplicationManager) handlerMuxServerSchemas(w http.ResponseWriter,
 		}
 		node := mycluster.GetServerFromName(vars["serverName"])
 		if node != nil && node.IsDown() == false {
+			tables, err := node.GetTableStorageInfo(vars["schemaName"])
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
 			e := json.NewEncoder(w)
 			e.SetIndent("", "\t")
-			l, _, _ := node.GetSchemas()
-			err := e.Encode(l)
+			err = e.Encode(tables)
 			if err != nil {
 				http.Error(w, "Encoding error", 500)
 				return
 			}
-			return
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("503 -Not a Valid Server!"))
 		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
 
+func (repman *ReplicationManager) handlerMuxServerSchemaJSON(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			export, err := node.ExportSchemaJSON(vars["schemaName"])
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(export)
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
 	} else {
 		http.Error(w, "No cluster", 500)
 		return
 	}
 }
 
-func (repman *ReplicationManager) handlerMuxServerInnoDBStatus(w http.ResponseWriter, r *http.Request) {
+func (repman *ReplicationManager) handlerMuxServerOnlineDDL(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
 	mycluster := repman.getClusterByName(vars["clusterName"])
@@ -1829,27 +2012,42 @@ func (repman *ReplicationManager) handlerMuxServerInnoDBStatus(w http.ResponseWr
 		}
 		node := mycluster.GetServerFromName(vars["serverName"])
 		if node != nil && node.IsDown() == false {
+			alter := r.URL.Query().Get("alter")
+			if alter == "" && r.Body != nil {
+				body, err := ioutil.ReadAll(r.Body)
+				if err != nil {
+					http.Error(w, "Decode body error", 400)
+					return
+				}
+				alter = string(body)
+			}
+			if alter == "" {
+				http.Error(w, "No ALTER statement", 400)
+				return
+			}
+			feasibility, err := node.CheckOnlineDDLFeasibility(vars["schemaName"], vars["tableName"], alter)
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
 			e := json.NewEncoder(w)
 			e.SetIndent("", "\t")
-			l := node.GetInnoDBStatus()
-			err := e.Encode(l)
+			err = e.Encode(feasibility)
 			if err != nil {
 				http.Error(w, "Encoding error", 500)
 				return
 			}
-			return
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("503 -Not a Valid Server!"))
 		}
-
 	} else {
 		http.Error(w, "No cluster", 500)
 		return
 	}
 }
 
-func (repman *ReplicationManager) handlerMuxServerAllSlavesStatus(w http.ResponseWriter, r *http.Request) {
+func (repman *ReplicationManager) handlerMuxServerShardKeyAlignment(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
 	mycluster := repman.getClusterByName(vars["clusterName"])
@@ -1860,27 +2058,29 @@ func (repman *ReplicationManager) handlerMuxServerAllSlavesStatus(w http.Respons
 		}
 		node := mycluster.GetServerFromName(vars["serverName"])
 		if node != nil && node.IsDown() == false {
+			alignment, err := node.AnalyzeShardKeyAlignment(vars["schemaName"], vars["tableName"])
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
 			e := json.NewEncoder(w)
 			e.SetIndent("", "\t")
-			l := node.GetAllSlavesStatus()
-			err := e.Encode(l)
+			err = e.Encode(alignment)
 			if err != nil {
 				http.Error(w, "Encoding error", 500)
 				return
 			}
-			return
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("503 -Not a Valid Server!"))
 		}
-
 	} else {
 		http.Error(w, "No cluster", 500)
 		return
 	}
 }
 
-func (repman *ReplicationManager) handlerMuxServerMasterStatus(w http.ResponseWriter, r *http.Request) {
+func (repman *ReplicationManager) handlerMuxServerThreadPool(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
 	mycluster := repman.getClusterByName(vars["clusterName"])
@@ -1893,25 +2093,94 @@ func (repman *ReplicationManager) handlerMuxServerMasterStatus(w http.ResponseWr
 		if node != nil && node.IsDown() == false {
 			e := json.NewEncoder(w)
 			e.SetIndent("", "\t")
-			l := node.GetMasterStatus()
-			err := e.Encode(l)
+			err := e.Encode(node.GetThreadPoolStats())
 			if err != nil {
 				http.Error(w, "Encoding error", 500)
 				return
 			}
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxServerMDLWaiters(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
 			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			report, err := node.GetMDLWaiters()
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			if mycluster.IsProcessListRedacted(repman.getUserFromRequest(r)) {
+				report = report.Redacted()
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err = e.Encode(report)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("503 -Not a Valid Server!"))
 		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
 
+func (repman *ReplicationManager) handlerMuxServerReplicationThreads(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			threads, err := node.GetReplicationThreads()
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			if mycluster.IsProcessListRedacted(repman.getUserFromRequest(r)) {
+				threads = threads.Redacted()
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err = e.Encode(threads)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
 	} else {
 		http.Error(w, "No cluster", 500)
 		return
 	}
 }
 
-func (repman *ReplicationManager) handlerMuxSkipReplicationEvent(w http.ResponseWriter, r *http.Request) {
+func (repman *ReplicationManager) handlerMuxServerThreadTempUsage(w http.ResponseWriter, r *http.Request) {
 	w.Header().Set("Access-Control-Allow-Origin", "*")
 	vars := mux.Vars(r)
 	mycluster := repman.getClusterByName(vars["clusterName"])
@@ -1922,7 +2191,407 @@ func (repman *ReplicationManager) handlerMuxSkipReplicationEvent(w http.Response
 		}
 		node := mycluster.GetServerFromName(vars["serverName"])
 		if node != nil && node.IsDown() == false {
-			node.SkipReplicationEvent()
+			report, err := node.GetThreadTempUsage()
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			if mycluster.IsProcessListRedacted(repman.getUserFromRequest(r)) {
+				report = report.Redacted()
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err = e.Encode(report)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxServerLockWaitChains(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			chains, err := node.GetLockWaitChains()
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			if mycluster.IsProcessListRedacted(repman.getUserFromRequest(r)) {
+				for i := range chains {
+					chains[i] = chains[i].Redacted()
+				}
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err = e.Encode(chains)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxServerReplicationApplyBottleneck(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			bottleneck := node.GetReplicationApplyBottleneck()
+			if bottleneck != nil && mycluster.IsProcessListRedacted(repman.getUserFromRequest(r)) {
+				redacted := bottleneck.Redacted()
+				bottleneck = &redacted
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err := e.Encode(bottleneck)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxServerTables(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			l := node.GetTables()
+			err := e.Encode(l)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+			return
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxServerVTables(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			l := node.GetVTables()
+			err := e.Encode(l)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+			return
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxRunJobs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil {
+			err := node.JobRunViaSSH()
+			if err != nil {
+				http.Error(w, "Encoding running job", 500)
+				return
+			}
+			return
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxServerSchemas(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			l, _, _ := node.GetSchemas()
+			err := e.Encode(l)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+			return
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxServerInnoDBStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			l := node.GetInnoDBStatus()
+			err := e.Encode(l)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+			return
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxServerAllSlavesStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			l := node.GetAllSlavesStatus()
+			err := e.Encode(l)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+			return
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxServerMasterStatus(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			l := node.GetMasterStatus()
+			err := e.Encode(l)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+			return
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxSkipReplicationEvent(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			node.SkipReplicationEvent()
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxKillIdleConnections(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		seconds, err := strconv.Atoi(vars["seconds"])
+		if err != nil || seconds <= 0 {
+			http.Error(w, "Invalid idle seconds", 400)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			ids, err := node.ReapIdleConnections(time.Duration(seconds)*time.Second, r.URL.Query().Get("dryrun") == "true")
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err = e.Encode(ids)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
+		} else {
+			w.WriteHeader(http.StatusInternalServerError)
+			w.Write([]byte("503 -Not a Valid Server!"))
+		}
+	} else {
+		http.Error(w, "No cluster", 500)
+		return
+	}
+}
+
+func (repman *ReplicationManager) handlerMuxSkipReplicationError(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Access-Control-Allow-Origin", "*")
+	vars := mux.Vars(r)
+	mycluster := repman.getClusterByName(vars["clusterName"])
+	if mycluster != nil {
+		if !repman.IsValidClusterACL(r, mycluster) {
+			http.Error(w, "No valid ACL", 403)
+			return
+		}
+		errno, err := strconv.Atoi(vars["errno"])
+		if err != nil {
+			http.Error(w, "Invalid error code", 400)
+			return
+		}
+		node := mycluster.GetServerFromName(vars["serverName"])
+		if node != nil && node.IsDown() == false {
+			channel := node.ReplicationSourceName
+			if r.URL.Query().Get("channel") != "" {
+				channel = r.URL.Query().Get("channel")
+			}
+			skip, err := node.SkipReplicationError(channel, errno, r.URL.Query().Get("dryrun") == "true")
+			if err != nil {
+				http.Error(w, err.Error(), 500)
+				return
+			}
+			e := json.NewEncoder(w)
+			e.SetIndent("", "\t")
+			err = e.Encode(skip)
+			if err != nil {
+				http.Error(w, "Encoding error", 500)
+				return
+			}
 		} else {
 			w.WriteHeader(http.StatusInternalServerError)
 			w.Write([]byte("503 -Not a Valid Server!"))
@@ -66,6 +66,9 @@ func (repman *ReplicationManager) httpserver() {
 	router.Handle("/api/prometheus", negroni.New(
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxPrometheus)),
 	))
+	router.Handle("/api/openmetrics", negroni.New(
+		negroni.Wrap(http.HandlerFunc(repman.handlerMuxOpenMetrics)),
+	))
 
 	router.Handle("/api/timeout", negroni.New(
 		negroni.Wrap(http.HandlerFunc(repman.handlerMuxTimeout)),
@@ -10,6 +10,7 @@
 package dbhelper
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
@@ -51,6 +52,20 @@ type MetaDataLock struct {
 	Lock_type     sql.NullString `json:"lockType" db:"LOCK_TYPE"`
 	Lock_schema   sql.NullString `json:"lockSchema" db:"TABLE_SCHEMA"`
 	Lock_name     sql.NullString `json:"lockName" db:"TABLE_NAME"`
+	Lock_status   sql.NullString `json:"lockStatus" db:"LOCK_STATUS"`
+}
+
+type ThreadTempTables struct {
+	Thread_id       uint64 `json:"threadId" db:"Thread_id"`
+	Tmp_tables      uint64 `json:"tmpTables" db:"Tmp_tables"`
+	Tmp_disk_tables uint64 `json:"tmpDiskTables" db:"Tmp_disk_tables"`
+}
+
+type Deadlock struct {
+	Time         string   `json:"time"`
+	Transactions []string `json:"transactions"`
+	Signature    string   `json:"signature"`
+	Text         string   `json:"text"`
 }
 
 type ResponseTime struct {
@@ -144,6 +159,128 @@ type Processlist struct {
 	Progress     sql.NullFloat64 `json:"progress" db:"Progress"`
 	RowsSent     uint64          `json:"rowsSent" db:"Rows_sent"`
 	RowsExamined uint64          `json:"rowsExamined" db:"Rows_examined"`
+	Digest       string          `json:"digest" db:"-"`
+}
+
+// LockWait is a thread waiting for an InnoDB lock held by the blocking thread
+type LockWait struct {
+	WaitingThread  uint64         `json:"waitingThread" db:"Waiting_thread"`
+	BlockingThread uint64         `json:"blockingThread" db:"Blocking_thread"`
+	LockedTable    sql.NullString `json:"lockedTable" db:"Locked_table"`
+}
+
+type TableAccess struct {
+	Table_schema string `json:"tableSchema" db:"Table_schema"`
+	Table_name   string `json:"tableName" db:"Table_name"`
+	Count_read   uint64 `json:"countRead" db:"Count_read"`
+	Count_write  uint64 `json:"countWrite" db:"Count_write"`
+	Data_length  int64  `json:"dataLength" db:"-"`
+	Index_length int64  `json:"indexLength" db:"-"`
+}
+
+type TablePartition struct {
+	Partition_name        string `json:"partitionName" db:"Partition_name"`
+	Subpartition_name     string `json:"subpartitionName" db:"Subpartition_name"`
+	Partition_method      string `json:"partitionMethod" db:"Partition_method"`
+	Partition_expression  string `json:"partitionExpression" db:"Partition_expression"`
+	Partition_description string `json:"partitionDescription" db:"Partition_description"`
+	Partition_comment     string `json:"partitionComment" db:"Partition_comment"`
+	Table_rows            int64  `json:"tableRows" db:"Table_rows"`
+}
+
+type InnoDBTrx struct {
+	Trx_id              string `json:"trxId" db:"Trx_id"`
+	Trx_mysql_thread_id uint64 `json:"trxMysqlThreadId" db:"Trx_mysql_thread_id"`
+	Trx_state           string `json:"trxState" db:"Trx_state"`
+	Trx_started         string `json:"trxStarted" db:"Trx_started"`
+	Trx_age             int64  `json:"trxAge" db:"Trx_age"`
+}
+
+type BufferPoolStats struct {
+	Pool_id                   int64   `json:"poolId" db:"Pool_id"`
+	Pool_size                 int64   `json:"poolSize" db:"Pool_size"`
+	Free_buffers              int64   `json:"freeBuffers" db:"Free_buffers"`
+	Database_pages            int64   `json:"databasePages" db:"Database_pages"`
+	Modified_database_pages   int64   `json:"modifiedDatabasePages" db:"Modified_database_pages"`
+	Number_pages_get          int64   `json:"numberPagesGet" db:"Number_pages_get"`
+	Number_pages_read         int64   `json:"numberPagesRead" db:"Number_pages_read"`
+	Number_pages_read_ahead   int64   `json:"numberPagesReadAhead" db:"Number_pages_read_ahead"`
+	Number_read_ahead_evicted int64   `json:"numberReadAheadEvicted" db:"Number_read_ahead_evicted"`
+	Hit_rate                  float64 `json:"hitRate" db:"-"`
+	Read_ahead_effectiveness  float64 `json:"readAheadEffectiveness" db:"-"`
+}
+
+// ThreadPoolGroup is a thread group of the MariaDB thread pool
+type ThreadPoolGroup struct {
+	Group_id        int64 `json:"groupId" db:"Group_id"`
+	Connections     int64 `json:"connections" db:"Connections"`
+	Threads         int64 `json:"threads" db:"Threads"`
+	Active_threads  int64 `json:"activeThreads" db:"Active_threads"`
+	Standby_threads int64 `json:"standbyThreads" db:"Standby_threads"`
+	Queue_length    int64 `json:"queueLength" db:"Queue_length"`
+	Has_listener    bool  `json:"hasListener" db:"Has_listener"`
+	Is_stalled      bool  `json:"isStalled" db:"Is_stalled"`
+}
+
+type OpenTransaction struct {
+	Processlist
+	Trx InnoDBTrx `json:"trx"`
+}
+
+type SchemaDetail struct {
+	Name      string `json:"name" db:"Name"`
+	Charset   string `json:"charset" db:"Charset"`
+	Collation string `json:"collation" db:"Collation"`
+}
+
+type TableColumn struct {
+	Table                string `json:"table,omitempty" db:"Table_name"`
+	Name                 string `json:"name" db:"Column_name"`
+	Position             int64  `json:"position" db:"Ordinal_position"`
+	Type                 string `json:"type" db:"Column_type"`
+	Nullable             bool   `json:"nullable" db:"Is_nullable"`
+	HasDefault           bool   `json:"hasDefault" db:"Has_default"`
+	Default              string `json:"default" db:"Column_default"`
+	Collation            string `json:"collation" db:"Collation_name"`
+	Extra                string `json:"extra" db:"Extra"`
+	GenerationExpression string `json:"generationExpression" db:"Generation_expression"`
+	Generated            string `json:"generated"`
+	Invisible            bool   `json:"invisible"`
+}
+
+type TableIndexColumn struct {
+	Table    string `json:"table,omitempty" db:"Table_name"`
+	Index    string `json:"index" db:"Index_name"`
+	Column   string `json:"column" db:"Column_name"`
+	Position int64  `json:"position" db:"Seq_in_index"`
+	Unique   bool   `json:"unique" db:"Is_unique"`
+	Type     string `json:"type" db:"Index_type"`
+}
+
+// TableStorage is the engine, row format and create options of a table, the create options hold the
+// KEY_BLOCK_SIZE and the page compression settings
+type TableStorage struct {
+	Table          string         `json:"table" db:"Table_name"`
+	Engine         sql.NullString `json:"engine" db:"Engine"`
+	Row_format     sql.NullString `json:"rowFormat" db:"Row_format"`
+	Create_options sql.NullString `json:"createOptions" db:"Create_options"`
+	Data_length    int64          `json:"dataLength" db:"Data_length"`
+}
+
+type ForeignKey struct {
+	Constraint string `json:"constraint" db:"Constraint_name"`
+	Table      string `json:"table" db:"Table_name"`
+	Column     string `json:"column" db:"Column_name"`
+	RefSchema  string `json:"refSchema" db:"Referenced_table_schema"`
+	RefTable   string `json:"refTable" db:"Referenced_table_name"`
+	RefColumn  string `json:"refColumn" db:"Referenced_column_name"`
+	OnDelete   string `json:"onDelete" db:"Delete_rule"`
+	OnUpdate   string `json:"onUpdate" db:"Update_rule"`
+}
+
+type ProcesslistDigest struct {
+	Digest string `json:"digest"`
+	Count  int    `json:"count"`
 }
 
 type LogSlow struct {
@@ -201,6 +338,8 @@ type SlaveStatus struct {
 	ExecutedGtidSet      sql.NullString `db:"Executed_Gtid_Set" json:"executedGtidSet"`
 	RetrievedGtidSet     sql.NullString `db:"Retrieved_Gtid_Set" json:"retrievedGtidSet"`
 	SlaveSQLRunningState sql.NullString `db:"Slave_SQL_Running_State" json:"slaveSQLRunningState"`
+	SQLDelay             sql.NullInt64  `db:"SQL_Delay" json:"sqlDelay"`
+	SQLRemainingDelay    sql.NullInt64  `db:"SQL_Remaining_Delay" json:"sqlRemainingDelay"`
 	PGExternalID         sql.NullString `db:"external_id" json:"postgresExternalId"`
 }
 
@@ -287,11 +426,86 @@ func SQLiteConnect(path string) (*sqlx.DB, error) {
 	return db, err
 }
 
+// SummarizeDDLPartitions replace the partition definition list of a SHOW CREATE TABLE output
+// by the number of partitions, keeping the partitioning method and expression
+func SummarizeDDLPartitions(ddl string) string {
+	pos := strings.Index(ddl, "PARTITION BY ")
+	if pos < 0 {
+		return ddl
+	}
+	start := strings.Index(ddl[pos:], "(PARTITION ")
+	if start < 0 {
+		return ddl
+	}
+	start += pos
+	depth := 0
+	count := 0
+	var quote rune
+	for i, c := range ddl[start:] {
+		if quote != 0 {
+			if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch c {
+		case '\'', '"', '`':
+			quote = c
+		case '(':
+			depth++
+		case ',':
+			if depth == 1 {
+				count++
+			}
+		case ')':
+			depth--
+			if depth == 0 {
+				return ddl[:start] + "PARTITIONS " + strconv.Itoa(count+1) + ddl[start+i+1:]
+			}
+		}
+	}
+	return ddl
+}
+
 func GetQueryDigest(q string) string {
 	f := query.Fingerprint(q)
 	return f
 }
 
+// GetQueryWithLiterals substitute a representative literal to the ? and ... placeholders of a
+// normalized or prepared query so that it can be explained
+func GetQueryWithLiterals(q string) string {
+	var b strings.Builder
+	var quote rune
+	runes := []rune(q)
+	for i := 0; i < len(runes); i++ {
+		c := runes[i]
+		if quote != 0 {
+			b.WriteRune(c)
+			if c == '\\' && i+1 < len(runes) {
+				i++
+				b.WriteRune(runes[i])
+			} else if c == quote {
+				quote = 0
+			}
+			continue
+		}
+		switch {
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+			b.WriteRune(c)
+		case c == '?':
+			b.WriteString("1")
+		case c == '.' && i+2 < len(runes) && runes[i+1] == '.' && runes[i+2] == '.':
+			b.WriteString("1")
+			i += 2
+		default:
+			b.WriteRune(c)
+		}
+	}
+	return b.String()
+}
+
 func GetAddress(host string, port string, socket string) string {
 	var address string
 	if host != "" {
@@ -443,6 +657,125 @@ func GetProcesslist(db *sqlx.DB, version *MySQLVersion) ([]Processlist, string,
 	return pl, query, nil
 }
 
+// GetPoolConnectionIDs returns the thread ids of the idle connections of the pool, the connections are held until
+// all are read so the pool hands out a different one each time
+func GetPoolConnectionIDs(db *sqlx.DB, version *MySQLVersion) ([]uint64, string, error) {
+	ids := []uint64{}
+	query := "SELECT CONNECTION_ID()"
+	if version.IsPPostgreSQL() {
+		query = "SELECT pg_backend_pid()"
+	}
+	ctx := context.Background()
+	n := db.Stats().Idle
+	if n < 1 {
+		n = 1
+	}
+	var conns []*sql.Conn
+	defer func() {
+		for _, conn := range conns {
+			conn.Close()
+		}
+	}()
+	for i := 0; i < n; i++ {
+		conn, err := db.DB.Conn(ctx)
+		if err != nil {
+			return ids, query, err
+		}
+		conns = append(conns, conn)
+		var id uint64
+		if err := conn.QueryRowContext(ctx, query).Scan(&id); err != nil {
+			return ids, query, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, query, nil
+}
+
+// GetLockWaits returns the InnoDB lock waits, from performance_schema.data_lock_waits on MySQL 8 and from
+// information_schema.INNODB_LOCK_WAITS otherwise
+func GetLockWaits(db *sqlx.DB, version *MySQLVersion) ([]LockWait, string, error) {
+	lw := []LockWait{}
+	query := "SELECT r.trx_mysql_thread_id AS Waiting_thread, b.trx_mysql_thread_id AS Blocking_thread, l.lock_table AS Locked_table FROM information_schema.INNODB_LOCK_WAITS w INNER JOIN information_schema.INNODB_TRX r ON r.trx_id = w.requesting_trx_id INNER JOIN information_schema.INNODB_TRX b ON b.trx_id = w.blocking_trx_id LEFT JOIN information_schema.INNODB_LOCKS l ON l.lock_id = w.requested_lock_id"
+	if version.IsMySQLOrPercona() && version.Major >= 8 {
+		query = "SELECT tr.PROCESSLIST_ID AS Waiting_thread, tb.PROCESSLIST_ID AS Blocking_thread, CONCAT('`', l.OBJECT_SCHEMA, '`.`', l.OBJECT_NAME, '`') AS Locked_table FROM performance_schema.data_lock_waits w INNER JOIN performance_schema.threads tr ON tr.THREAD_ID = w.REQUESTING_THREAD_ID INNER JOIN performance_schema.threads tb ON tb.THREAD_ID = w.BLOCKING_THREAD_ID LEFT JOIN performance_schema.data_locks l ON l.ENGINE_LOCK_ID = w.REQUESTING_ENGINE_LOCK_ID WHERE tr.PROCESSLIST_ID IS NOT NULL AND tb.PROCESSLIST_ID IS NOT NULL"
+	}
+	err := db.Select(&lw, query)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get lock waits: %s", err)
+	}
+	return lw, query, nil
+}
+
+// GetProcesslistPFS returns the processlist from performance_schema without the mutex of SHOW PROCESSLIST,
+// performance_schema.processlist from MySQL 8.0.22 and performance_schema.threads otherwise
+func GetProcesslistPFS(db *sqlx.DB, version *MySQLVersion) ([]Processlist, string, error) {
+	pl := []Processlist{}
+	// background threads like the replication ones have no user, SHOW PROCESSLIST names them system user
+	query := "SELECT PROCESSLIST_ID AS Id, COALESCE(PROCESSLIST_USER,'system user') AS User, COALESCE(PROCESSLIST_HOST,'') AS Host, PROCESSLIST_DB AS db, COALESCE(PROCESSLIST_COMMAND,'') AS Command, PROCESSLIST_TIME AS Time, PROCESSLIST_STATE AS State, PROCESSLIST_INFO AS Info, 0 AS Progress FROM performance_schema.threads WHERE PROCESSLIST_ID IS NOT NULL"
+	if version.HasPFSProcesslist() {
+		query = "SELECT ID AS Id, COALESCE(USER,'system user') AS User, COALESCE(HOST,'') AS Host, DB AS db, COALESCE(COMMAND,'') AS Command, TIME AS Time, STATE AS State, INFO AS Info, 0 AS Progress FROM performance_schema.processlist"
+	}
+	if version.IsPPostgreSQL() {
+		return nil, query, errors.New("ERROR: performance_schema not available on PostgeSQL")
+	}
+	err := db.Select(&pl, query)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get processlist: %s", err)
+	}
+	return pl, query, nil
+}
+
+// GetProcesslistThread returns the current state of a thread of the processlist, sql.ErrNoRows once it ended
+func GetProcesslistThread(db *sqlx.DB, version *MySQLVersion, id uint64) (Processlist, string, error) {
+	var q Processlist
+	query := "SELECT ID AS Id, USER AS User, HOST AS Host, DB AS db, COMMAND AS Command, TIME AS Time, STATE AS State, INFO AS Info, 0 AS Progress FROM INFORMATION_SCHEMA.PROCESSLIST WHERE ID = ?"
+	if version.IsPPostgreSQL() {
+		return q, query, errors.New("ERROR: processlist thread lookup not available on PostgeSQL")
+	}
+	err := db.Get(&q, query, id)
+	return q, query, err
+}
+
+// GetPFSDisabledConsumers returns the consumers among names not enabled in performance_schema.setup_consumers
+func GetPFSDisabledConsumers(db *sqlx.DB, names ...string) ([]string, string, error) {
+	disabled := []string{}
+	query := "SELECT NAME FROM performance_schema.setup_consumers WHERE ENABLED<>'YES' AND NAME IN ('" + strings.Join(names, "','") + "')"
+	err := db.Select(&disabled, query)
+	return disabled, query, err
+}
+
+// GetPFSDisabledInstruments returns the performance_schema instruments of the list that are not enabled
+func GetPFSDisabledInstruments(db *sqlx.DB, names ...string) ([]string, string, error) {
+	disabled := []string{}
+	query := "SELECT NAME FROM performance_schema.setup_instruments WHERE ENABLED<>'YES' AND NAME IN ('" + strings.Join(names, "','") + "')"
+	err := db.Select(&disabled, query)
+	return disabled, query, err
+}
+
+// GetPFSMetaDataLocks returns the granted and pending table metadata locks of the client threads from
+// performance_schema.metadata_locks with the columns of information_schema.metadata_lock_info
+func GetPFSMetaDataLocks(db *sqlx.DB) ([]MetaDataLock, string, error) {
+	pl := []MetaDataLock{}
+	query := "SELECT t.PROCESSLIST_ID AS THREAD_ID, m.LOCK_TYPE AS LOCK_MODE, m.LOCK_DURATION, m.OBJECT_TYPE AS LOCK_TYPE, m.OBJECT_SCHEMA AS TABLE_SCHEMA, m.OBJECT_NAME AS TABLE_NAME, m.LOCK_STATUS FROM performance_schema.metadata_locks m INNER JOIN performance_schema.threads t ON t.THREAD_ID = m.OWNER_THREAD_ID WHERE m.OBJECT_TYPE = 'TABLE' AND m.LOCK_STATUS IN ('GRANTED','PENDING') AND t.PROCESSLIST_ID IS NOT NULL"
+	err := db.Select(&pl, query)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get performance_schema metadata locks: %s", err)
+	}
+	return pl, query, nil
+}
+
+// GetThreadTempTables returns the temporary tables created by the current or last statement of the client
+// threads from performance_schema.events_statements_current
+func GetThreadTempTables(db *sqlx.DB) ([]ThreadTempTables, string, error) {
+	tmp := []ThreadTempTables{}
+	query := "SELECT t.PROCESSLIST_ID AS Thread_id, COALESCE(s.CREATED_TMP_TABLES,0) AS Tmp_tables, COALESCE(s.CREATED_TMP_DISK_TABLES,0) AS Tmp_disk_tables FROM performance_schema.events_statements_current s INNER JOIN performance_schema.threads t ON t.THREAD_ID = s.THREAD_ID WHERE t.PROCESSLIST_ID IS NOT NULL"
+	err := db.Select(&tmp, query)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get thread temporary tables: %s", err)
+	}
+	return tmp, query, nil
+}
+
 func GetServers(db *sqlx.DB) ([]MySQLServer, string, error) {
 	db.MapperFunc(strings.Title)
 	var err error
@@ -561,6 +894,17 @@ type ChangeMasterOpt struct {
 }
 
 func ChangeMaster(db *sqlx.DB, opt ChangeMasterOpt, myver *MySQLVersion) (string, error) {
+	cm := GetChangeMasterStatement(opt, myver)
+	_, err := db.Exec(cm)
+	cm = strings.Replace(cm, opt.Password, "XXX", -1)
+	if err != nil {
+		return cm, fmt.Errorf("Change master statement %s failed, reason: %s", cm, err)
+	}
+	return cm, nil
+}
+
+// GetChangeMasterStatement returns the replication source statement ChangeMaster would run
+func GetChangeMasterStatement(opt ChangeMasterOpt, myver *MySQLVersion) string {
 	//CREATE PUBLICATION alltables FOR ALL TABLES;
 	cm := ""
 	if myver.IsPPostgreSQL() {
@@ -601,12 +945,7 @@ func ChangeMaster(db *sqlx.DB, opt ChangeMasterOpt, myver *MySQLVersion) (string
 			cm += " FOR CHANNEL '" + opt.Channel + "'"
 		}
 	}
-	_, err := db.Exec(cm)
-	cm = strings.Replace(cm, opt.Password, "XXX", -1)
-	if err != nil {
-		return cm, fmt.Errorf("Change master statement %s failed, reason: %s", cm, err)
-	}
-	return cm, nil
+	return cm
 }
 
 func MariaDBVersion(server string) int {
@@ -1422,13 +1761,28 @@ func GetEngineInnoDBVariables(db *sqlx.DB) (map[string]string, string, error) {
 	if err != nil {
 		return nil, logs, err
 	}
+	return ParseEngineInnoDBVariables(statusCol), logs, nil
+}
+
+func ParseEngineInnoDBVariables(statusCol string) map[string]string {
 	vars := make(map[string]string)
 	// 0 queries inside InnoDB, 0 queries in queue
 	// 0 read views open inside InnoDB
 	rQueries, _ := regexp.Compile(`(\d+) queries inside InnoDB, (\d+) queries in queue`)
 	rViews, _ := regexp.Compile(`(\d+) read views open inside InnoDB`)
 	rHistory, _ := regexp.Compile(`History list length (\d+)`)
+	// ---TRANSACTION 421234, ACTIVE 123 sec
+	rTrx, _ := regexp.Compile(`^---TRANSACTION (\d+), ACTIVE (\d+) sec`)
+	oldest := int64(-1)
 	for _, line := range strings.Split(statusCol, "\n") {
+		if data := rTrx.FindStringSubmatch(line); data != nil {
+			if active, _ := strconv.ParseInt(data[2], 10, 64); active > oldest {
+				oldest = active
+				vars["oldest_active_trx_id"] = data[1]
+				vars["oldest_active_trx_seconds"] = data[2]
+			}
+			continue
+		}
 		if data := rQueries.FindStringSubmatch(line); data != nil {
 			vars["queries_inside_innodb"] = data[1]
 			vars["queries_in_queue"] = data[2]
@@ -1439,7 +1793,39 @@ func GetEngineInnoDBVariables(db *sqlx.DB) (map[string]string, string, error) {
 			vars["history_list_lenght_inside_innodb"] = data[1]
 		}
 	}
-	return vars, logs, nil
+	return vars
+}
+
+// ParseEngineInnoDBDeadlock extract the LATEST DETECTED DEADLOCK section, nil if no deadlock since startup
+func ParseEngineInnoDBDeadlock(statusCol string) *Deadlock {
+	lines := strings.Split(statusCol, "\n")
+	start := -1
+	for i, line := range lines {
+		if strings.TrimSpace(line) == "LATEST DETECTED DEADLOCK" {
+			start = i + 2
+			break
+		}
+	}
+	if start < 0 || start >= len(lines) {
+		return nil
+	}
+	d := new(Deadlock)
+	var text []string
+	for _, line := range lines[start:] {
+		if strings.Trim(line, "-") == "" && line != "" {
+			break
+		}
+		text = append(text, line)
+		if d.Time == "" && strings.TrimSpace(line) != "" {
+			d.Time = strings.TrimSpace(line)
+		}
+		if strings.HasPrefix(line, "TRANSACTION ") {
+			d.Transactions = append(d.Transactions, strings.TrimSpace(line))
+		}
+	}
+	d.Text = strings.Join(text, "\n")
+	d.Signature = d.Time + "/" + strings.Join(d.Transactions, "/")
+	return d
 }
 
 func EnablePFSQueries(db *sqlx.DB) (string, error) {
@@ -1456,6 +1842,232 @@ func DisablePFSQueries(db *sqlx.DB) (string, error) {
 	return query, err
 }
 
+func GetTableAccessStats(db *sqlx.DB, version *MySQLVersion) ([]TableAccess, string, error) {
+	ta := []TableAccess{}
+	query := "SELECT OBJECT_SCHEMA AS Table_schema, OBJECT_NAME AS Table_name, COUNT_READ AS Count_read, COUNT_WRITE AS Count_write FROM performance_schema.table_io_waits_summary_by_table WHERE OBJECT_TYPE='TABLE' AND OBJECT_SCHEMA NOT IN ('mysql','performance_schema','information_schema','sys','replication_manager_schema')"
+	if version.IsPPostgreSQL() {
+		return nil, query, errors.New("ERROR: performance_schema not available on PostgeSQL")
+	}
+	err := db.Select(&ta, query)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get table access statistics: %s", err)
+	}
+	return ta, query, nil
+}
+
+func GetInnoDBTrx(db *sqlx.DB, version *MySQLVersion) ([]InnoDBTrx, string, error) {
+	trx := []InnoDBTrx{}
+	query := "SELECT CAST(trx_id AS CHAR) AS Trx_id, trx_mysql_thread_id AS Trx_mysql_thread_id, trx_state AS Trx_state, CAST(trx_started AS CHAR) AS Trx_started, TIMESTAMPDIFF(SECOND, trx_started, NOW()) AS Trx_age FROM information_schema.INNODB_TRX"
+	if version.IsPPostgreSQL() {
+		return nil, query, errors.New("ERROR: INNODB_TRX not available on PostgeSQL")
+	}
+	err := db.Select(&trx, query)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get innodb transactions: %s", err)
+	}
+	return trx, query, nil
+}
+
+// GetThreadPoolGroups returns the thread groups of information_schema.THREAD_POOL_GROUPS available from
+// MariaDB 10.5
+func GetThreadPoolGroups(db *sqlx.DB, version *MySQLVersion) ([]ThreadPoolGroup, string, error) {
+	tg := []ThreadPoolGroup{}
+	query := "SELECT GROUP_ID AS Group_id, CONNECTIONS AS Connections, THREADS AS Threads, ACTIVE_THREADS AS Active_threads, STANDBY_THREADS AS Standby_threads, QUEUE_LENGTH AS Queue_length, HAS_LISTENER AS Has_listener, IS_STALLED AS Is_stalled FROM information_schema.THREAD_POOL_GROUPS ORDER BY GROUP_ID"
+	if !version.IsMariaDB() || version.Major < 10 || (version.Major == 10 && version.Minor < 5) {
+		return nil, query, errors.New("ERROR: THREAD_POOL_GROUPS only available from MariaDB 10.5")
+	}
+	err := db.Select(&tg, query)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get thread pool groups: %s", err)
+	}
+	return tg, query, nil
+}
+
+// GetBufferPoolStats returns one row per buffer pool instance, hit rate and read ahead effectiveness are
+// computed from the counters since startup, a server with a single pool returns a single row
+func GetBufferPoolStats(db *sqlx.DB, version *MySQLVersion) ([]BufferPoolStats, string, error) {
+	bp := []BufferPoolStats{}
+	query := "SELECT POOL_ID AS Pool_id, POOL_SIZE AS Pool_size, FREE_BUFFERS AS Free_buffers, DATABASE_PAGES AS Database_pages, MODIFIED_DATABASE_PAGES AS Modified_database_pages, NUMBER_PAGES_GET AS Number_pages_get, NUMBER_PAGES_READ AS Number_pages_read, NUMBER_PAGES_READ_AHEAD AS Number_pages_read_ahead, NUMBER_READ_AHEAD_EVICTED AS Number_read_ahead_evicted FROM information_schema.INNODB_BUFFER_POOL_STATS ORDER BY POOL_ID"
+	if version.IsPPostgreSQL() {
+		return nil, query, errors.New("ERROR: INNODB_BUFFER_POOL_STATS not available on PostgeSQL")
+	}
+	err := db.Select(&bp, query)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get buffer pool stats: %s", err)
+	}
+	for i := range bp {
+		bp[i].Hit_rate = 1
+		if bp[i].Number_pages_get > 0 {
+			bp[i].Hit_rate = 1 - float64(bp[i].Number_pages_read)/float64(bp[i].Number_pages_get)
+		}
+		bp[i].Read_ahead_effectiveness = 1
+		if bp[i].Number_pages_read_ahead > 0 {
+			bp[i].Read_ahead_effectiveness = 1 - float64(bp[i].Number_read_ahead_evicted)/float64(bp[i].Number_pages_read_ahead)
+		}
+	}
+	return bp, query, nil
+}
+
+func GetTableForeignKeys(db *sqlx.DB, schema string) ([]ForeignKey, string, error) {
+	fk := []ForeignKey{}
+	query := "SELECT k.CONSTRAINT_NAME AS Constraint_name, k.TABLE_NAME AS Table_name, k.COLUMN_NAME AS Column_name, k.REFERENCED_TABLE_SCHEMA AS Referenced_table_schema, k.REFERENCED_TABLE_NAME AS Referenced_table_name, k.REFERENCED_COLUMN_NAME AS Referenced_column_name, r.DELETE_RULE AS Delete_rule, r.UPDATE_RULE AS Update_rule FROM information_schema.KEY_COLUMN_USAGE k INNER JOIN information_schema.REFERENTIAL_CONSTRAINTS r ON r.CONSTRAINT_SCHEMA=k.CONSTRAINT_SCHEMA AND r.CONSTRAINT_NAME=k.CONSTRAINT_NAME AND r.TABLE_NAME=k.TABLE_NAME WHERE k.TABLE_SCHEMA=? AND k.REFERENCED_TABLE_NAME IS NOT NULL ORDER BY k.TABLE_NAME, k.CONSTRAINT_NAME, k.ORDINAL_POSITION"
+	err := db.Select(&fk, query, schema)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get foreign keys: %s", err)
+	}
+	return fk, query, nil
+}
+
+// GetTableColumns returns the column metadata of a table in ordinal order, Generated is set to VIRTUAL or STORED
+// for generated columns and Invisible for columns hidden from SELECT *
+func GetTableColumns(db *sqlx.DB, myver *MySQLVersion, schema string, table string) ([]TableColumn, string, error) {
+	cols := []TableColumn{}
+	query := getColumnsQuery(myver) + " AND TABLE_NAME=? ORDER BY ORDINAL_POSITION"
+	err := db.Select(&cols, query, schema, table)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get table columns: %s", err)
+	}
+	for i := range cols {
+		cols[i].SetExtraFlags()
+	}
+	return cols, query, nil
+}
+
+// GetSchemaColumns returns the column metadata of all the tables of a schema ordered by table and ordinal position
+func GetSchemaColumns(db *sqlx.DB, myver *MySQLVersion, schema string) ([]TableColumn, string, error) {
+	cols := []TableColumn{}
+	query := getColumnsQuery(myver) + " ORDER BY TABLE_NAME, ORDINAL_POSITION"
+	err := db.Select(&cols, query, schema)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get schema columns: %s", err)
+	}
+	for i := range cols {
+		cols[i].SetExtraFlags()
+	}
+	return cols, query, nil
+}
+
+func getColumnsQuery(myver *MySQLVersion) string {
+	generation := "''"
+	if myver.IsMySQLOrPerconaGreater57() || (myver.IsMariaDB() && (myver.Major > 10 || (myver.Major == 10 && myver.Minor >= 2))) {
+		generation = "COALESCE(GENERATION_EXPRESSION,'')"
+	}
+	return "SELECT TABLE_NAME AS Table_name, COLUMN_NAME AS Column_name, ORDINAL_POSITION AS Ordinal_position, COLUMN_TYPE AS Column_type, IS_NULLABLE='YES' AS Is_nullable, COLUMN_DEFAULT IS NOT NULL AS Has_default, COALESCE(COLUMN_DEFAULT,'') AS Column_default, COALESCE(COLLATION_NAME,'') AS Collation_name, EXTRA AS Extra, " + generation + " AS Generation_expression FROM information_schema.COLUMNS WHERE TABLE_SCHEMA=?"
+}
+
+// GetTableStorage returns the row format and create options of the base tables of a schema ordered by name
+func GetTableStorage(db *sqlx.DB, schema string) ([]TableStorage, string, error) {
+	ts := []TableStorage{}
+	query := "SELECT TABLE_NAME AS Table_name, ENGINE AS Engine, ROW_FORMAT AS Row_format, CREATE_OPTIONS AS Create_options, COALESCE(DATA_LENGTH,0) AS Data_length FROM information_schema.TABLES WHERE TABLE_SCHEMA=? AND TABLE_TYPE='BASE TABLE' ORDER BY TABLE_NAME"
+	err := db.Select(&ts, query, schema)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get table storage: %s", err)
+	}
+	return ts, query, nil
+}
+
+// GetTableIndexColumns returns the columns of all the indexes of a table ordered by index and position in the index,
+// the key parts of a functional index are their expression between parentheses
+func GetTableIndexColumns(db *sqlx.DB, myver *MySQLVersion, schema string, table string) ([]TableIndexColumn, string, error) {
+	idx := []TableIndexColumn{}
+	query := getIndexColumnsQuery(myver) + " AND TABLE_NAME=? ORDER BY INDEX_NAME, SEQ_IN_INDEX"
+	err := db.Select(&idx, query, schema, table)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get table indexes: %s", err)
+	}
+	return idx, query, nil
+}
+
+// GetSchemaIndexColumns returns the columns of all the indexes of the tables of a schema ordered by table, index
+// and position in the index
+func GetSchemaIndexColumns(db *sqlx.DB, myver *MySQLVersion, schema string) ([]TableIndexColumn, string, error) {
+	idx := []TableIndexColumn{}
+	query := getIndexColumnsQuery(myver) + " ORDER BY TABLE_NAME, INDEX_NAME, SEQ_IN_INDEX"
+	err := db.Select(&idx, query, schema)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get schema indexes: %s", err)
+	}
+	return idx, query, nil
+}
+
+func getIndexColumnsQuery(myver *MySQLVersion) string {
+	return "SELECT TABLE_NAME AS Table_name, INDEX_NAME AS Index_name, " + getIndexColumnName(myver) + " AS Column_name, SEQ_IN_INDEX AS Seq_in_index, NON_UNIQUE=0 AS Is_unique, INDEX_TYPE AS Index_type FROM information_schema.STATISTICS WHERE TABLE_SCHEMA=?"
+}
+
+// getIndexColumnName returns the index part column name, COLUMN_NAME is NULL for the expression of a functional
+// index part
+func getIndexColumnName(myver *MySQLVersion) string {
+	if myver.HasFunctionalIndexes() {
+		return "COALESCE(COLUMN_NAME,CONCAT('(',EXPRESSION,')'),'')"
+	}
+	return "COALESCE(COLUMN_NAME,'')"
+}
+
+// GetTableUniqueIndexColumns returns the columns of the unique indexes of a table, the primary key included,
+// ordered by index and position in the index
+func GetTableUniqueIndexColumns(db *sqlx.DB, myver *MySQLVersion, schema string, table string) ([]TableIndexColumn, string, error) {
+	idx := []TableIndexColumn{}
+	query := "SELECT INDEX_NAME AS Index_name, " + getIndexColumnName(myver) + " AS Column_name, SEQ_IN_INDEX AS Seq_in_index FROM information_schema.STATISTICS WHERE TABLE_SCHEMA=? AND TABLE_NAME=? AND NON_UNIQUE=0 ORDER BY INDEX_NAME, SEQ_IN_INDEX"
+	err := db.Select(&idx, query, schema, table)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get table unique indexes: %s", err)
+	}
+	return idx, query, nil
+}
+
+// SetExtraFlags parses the information_schema EXTRA column, MariaDB and MySQL report VIRTUAL GENERATED,
+// STORED GENERATED or PERSISTENT GENERATED for generated columns and INVISIBLE for invisible ones
+func (col *TableColumn) SetExtraFlags() {
+	extra := strings.ToUpper(col.Extra)
+	col.Generated = ""
+	switch {
+	case strings.Contains(extra, "VIRTUAL"):
+		col.Generated = "VIRTUAL"
+	case strings.Contains(extra, "STORED"), strings.Contains(extra, "PERSISTENT"):
+		col.Generated = "STORED"
+	}
+	col.Invisible = strings.Contains(extra, "INVISIBLE")
+}
+
+func GetSchemaTableNames(db *sqlx.DB, schema string) ([]string, string, error) {
+	tables := []string{}
+	query := "SELECT TABLE_NAME FROM information_schema.TABLES WHERE TABLE_SCHEMA=? AND TABLE_TYPE='BASE TABLE' ORDER BY TABLE_NAME"
+	err := db.Select(&tables, query, schema)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get table list: %s", err)
+	}
+	return tables, query, nil
+}
+
+// GetSchemaTableCollations returns the default collation of the schema tables
+func GetSchemaTableCollations(db *sqlx.DB, schema string) (map[string]string, string, error) {
+	collations := make(map[string]string)
+	query := "SELECT TABLE_NAME, COALESCE(TABLE_COLLATION,'') FROM information_schema.TABLES WHERE TABLE_SCHEMA=? AND TABLE_TYPE='BASE TABLE'"
+	rows, err := db.Queryx(query, schema)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get table collations: %s", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var table, collation string
+		if err := rows.Scan(&table, &collation); err != nil {
+			return nil, query, fmt.Errorf("ERROR: Could not get table collations: %s", err)
+		}
+		collations[table] = collation
+	}
+	return collations, query, nil
+}
+
+func GetTablePartitions(db *sqlx.DB, schema string, table string) ([]TablePartition, string, error) {
+	tp := []TablePartition{}
+	query := "SELECT PARTITION_NAME AS Partition_name, COALESCE(SUBPARTITION_NAME,'') AS Subpartition_name, COALESCE(PARTITION_METHOD,'') AS Partition_method, COALESCE(PARTITION_EXPRESSION,'') AS Partition_expression, COALESCE(PARTITION_DESCRIPTION,'') AS Partition_description, COALESCE(PARTITION_COMMENT,'') AS Partition_comment, COALESCE(TABLE_ROWS,0) AS Table_rows FROM information_schema.PARTITIONS WHERE TABLE_SCHEMA=? AND TABLE_NAME=? AND PARTITION_NAME IS NOT NULL ORDER BY PARTITION_ORDINAL_POSITION, SUBPARTITION_ORDINAL_POSITION"
+	err := db.Select(&tp, query, schema, table)
+	if err != nil {
+		return nil, query, fmt.Errorf("ERROR: Could not get table partitions: %s", err)
+	}
+	return tp, query, nil
+}
+
 func GetQueries(db *sqlx.DB) (map[string]PFSQuery, string, error) {
 
 	vars := make(map[string]PFSQuery)
@@ -1512,7 +2124,7 @@ func GetTableChecksumResult(db *sqlx.DB) (map[uint64]chunk, string, error) {
 	defer rows.Close()
 	for rows.Next() {
 		var v chunk
-		err = rows.Scan(&v.ChunkId, &v.ChunkMinKey, &v.ChunkMaxKey, v.ChunkCheckSum)
+		err = rows.Scan(&v.ChunkId, &v.ChunkMinKey, &v.ChunkMaxKey, &v.ChunkCheckSum)
 		if err != nil {
 			return vars, query, err
 		}
@@ -1739,6 +2351,16 @@ func GetSchemas(db *sqlx.DB) ([]string, string, error) {
 	return sch, query, nil
 }
 
+func GetSchemasDetailed(db *sqlx.DB) ([]SchemaDetail, string, error) {
+	sch := []SchemaDetail{}
+	query := "SELECT SCHEMA_NAME AS Name, DEFAULT_CHARACTER_SET_NAME AS Charset, DEFAULT_COLLATION_NAME AS Collation FROM information_schema.SCHEMATA WHERE  SCHEMA_NAME NOT IN('information_schema','mysql','performance_schema')"
+	err := db.Select(&sch, query)
+	if err != nil {
+		return nil, query, errors.New("Could not get schema list")
+	}
+	return sch, query, nil
+}
+
 func GetSchemasMap(db *sqlx.DB) (map[string]string, string, error) {
 	query := "SELECT SCHEMA_NAME FROM information_schema.SCHEMATA WHERE  SCHEMA_NAME NOT IN('information_schema','mysql','performance_schema')"
 	schemas := make(map[string]string)
@@ -1945,6 +2567,55 @@ func SkipBinlogEvent(db *sqlx.DB, Channel string, myver *MySQLVersion) (string,
 	return query, err
 }
 
+// GetFailedTransactionGTIDs returns the GTIDs of the transactions the appliers failed on from performance_schema
+func GetFailedTransactionGTIDs(db *sqlx.DB, Channel string, myver *MySQLVersion) ([]string, string, error) {
+	gtids := []string{}
+	column := "LAST_SEEN_TRANSACTION"
+	if myver.Major >= 8 {
+		column = "APPLYING_TRANSACTION"
+	}
+	query := "SELECT " + column + " FROM performance_schema.replication_applier_status_by_worker WHERE CHANNEL_NAME=? AND LAST_ERROR_NUMBER<>0 AND " + column + "<>''"
+	err := db.Select(&gtids, query, Channel)
+	return gtids, query, err
+}
+
+// ReplicationFilter is an active filter of a replication channel
+type ReplicationFilter struct {
+	Channel string `db:"CHANNEL_NAME" json:"channel"`
+	Name    string `db:"FILTER_NAME" json:"name"`
+	Rule    string `db:"FILTER_RULE" json:"rule"`
+}
+
+// GetReplicationApplierFilters returns the global and per channel filters of MySQL 8 from performance_schema,
+// CHANGE REPLICATION FILTER ... FOR CHANNEL filters are not in the replicate_* variables
+func GetReplicationApplierFilters(db *sqlx.DB, myver *MySQLVersion) ([]ReplicationFilter, string, error) {
+	filters := []ReplicationFilter{}
+	query := "SELECT CHANNEL_NAME, FILTER_NAME, FILTER_RULE FROM performance_schema.replication_applier_filters WHERE FILTER_RULE<>''"
+	err := db.Select(&filters, query)
+	return filters, query, err
+}
+
+// SkipGTIDTransaction commits an empty transaction with the GTID of the failing transaction, the applier then
+// skips it, GTID_NEXT is a session variable so the statements run on a single connection
+func SkipGTIDTransaction(db *sqlx.DB, gtid string) (string, error) {
+	ctx := context.Background()
+	conn, err := db.DB.Conn(ctx)
+	if err != nil {
+		return "", err
+	}
+	defer conn.Close()
+	logs := ""
+	for _, stmt := range []string{"SET GTID_NEXT='" + gtid + "'", "BEGIN", "COMMIT", "SET GTID_NEXT='AUTOMATIC'"} {
+		logs += stmt + ";"
+		_, err = conn.ExecContext(ctx, stmt)
+		if err != nil {
+			conn.ExecContext(ctx, "SET GTID_NEXT='AUTOMATIC'")
+			return logs, err
+		}
+	}
+	return logs, nil
+}
+
 func StartSlave(db *sqlx.DB, Channel string, myver *MySQLVersion) (string, error) {
 	cmd := ""
 	if myver.IsPPostgreSQL() {
@@ -2140,6 +2811,31 @@ func MasterPosWait(db *sqlx.DB, log string, pos string, timeout int) (string, er
 	return query + "(" + log + "-" + pos + "-" + strconv.Itoa(timeout) + ")", err
 }
 
+// WaitGTID blocks until the GTID set is applied or timeout seconds elapsed, false is returned on timeout,
+// MariaDB waits with MASTER_GTID_WAIT and MySQL with WAIT_FOR_EXECUTED_GTID_SET
+func WaitGTID(db *sqlx.DB, myver *MySQLVersion, gtid string, timeout float64) (bool, string, error) {
+	query := "SELECT WAIT_FOR_EXECUTED_GTID_SET(?, ?)"
+	if myver.IsMariaDB() {
+		query = "SELECT MASTER_GTID_WAIT(?, ?)"
+	}
+	var res sql.NullInt64
+	err := db.QueryRowx(query, gtid, timeout).Scan(&res)
+	return res.Valid && res.Int64 == 0, query + "(" + gtid + "-" + strconv.FormatFloat(timeout, 'f', -1, 64) + ")", err
+}
+
+// WaitPosition blocks until the master binlog position is applied or timeout seconds elapsed, false is returned
+// on timeout, MASTER_POS_WAIT returns NULL when the SQL thread is not running
+func WaitPosition(db *sqlx.DB, log string, pos string, timeout float64) (bool, string, error) {
+	query := "SELECT MASTER_POS_WAIT(?, ?, ?)"
+	logs := query + "(" + log + "-" + pos + "-" + strconv.FormatFloat(timeout, 'f', -1, 64) + ")"
+	var res sql.NullInt64
+	err := db.QueryRowx(query, log, pos, timeout).Scan(&res)
+	if err == nil && !res.Valid {
+		err = errors.New("Replication SQL thread not running")
+	}
+	return res.Valid && res.Int64 >= 0, logs, err
+}
+
 func SetReadOnly(db *sqlx.DB, flag bool) (string, error) {
 	if flag == true {
 		query := "SET GLOBAL read_only=1"
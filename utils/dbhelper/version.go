@@ -10,6 +10,7 @@
 package dbhelper
 
 import (
+	"fmt"
 	"strconv"
 	"strings"
 )
@@ -102,3 +103,38 @@ func (mv *MySQLVersion) IsMySQLOrPerconaGreater57() bool {
 	}
 	return false
 }
+
+// Compare returns -1, 0 or 1 when mv is older, equal or newer than other, flavor is not compared
+func (mv *MySQLVersion) Compare(other *MySQLVersion) int {
+	a := [3]int{mv.Major, mv.Minor, mv.Release}
+	b := [3]int{other.Major, other.Minor, other.Release}
+	for i := range a {
+		if a[i] < b[i] {
+			return -1
+		}
+		if a[i] > b[i] {
+			return 1
+		}
+	}
+	return 0
+}
+
+// HasPFSProcesslist returns true when performance_schema.processlist is available, from MySQL 8.0.22
+func (mv *MySQLVersion) HasPFSProcesslist() bool {
+	return mv.IsMySQLOrPercona() && mv.Compare(&MySQLVersion{Major: 8, Minor: 0, Release: 22}) >= 0
+}
+
+// HasFunctionalIndexes returns true when index parts can be expressions without COLUMN_NAME, from MySQL 8.0.13
+func (mv *MySQLVersion) HasFunctionalIndexes() bool {
+	return mv.IsMySQLOrPercona() && mv.Compare(&MySQLVersion{Major: 8, Minor: 0, Release: 13}) >= 0
+}
+
+// HasReplicationApplierFilters returns true when the per channel filters are in
+// performance_schema.replication_applier_filters, from MySQL 8.0
+func (mv *MySQLVersion) HasReplicationApplierFilters() bool {
+	return mv != nil && mv.IsMySQLOrPercona() && mv.Major >= 8
+}
+
+func (mv *MySQLVersion) ToString() string {
+	return fmt.Sprintf("%s %d.%d.%d", mv.Flavor, mv.Major, mv.Minor, mv.Release)
+}
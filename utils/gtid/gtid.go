@@ -203,3 +203,27 @@ func (gl List) Equal(glcomp *List) bool {
 	}
 	return false
 }
+
+// Covers returns true when the list has applied at least all the transactions of glcomp, MariaDB sequences are
+// compared per domain, MySQL sequences per source server uuid
+func (gl List) Covers(glcomp *List, perDomain bool) bool {
+	if glcomp == nil {
+		return true
+	}
+	for _, c := range *glcomp {
+		found := false
+		for _, g := range gl {
+			if g.DomainID != c.DomainID || (!perDomain && g.ServerID != c.ServerID) {
+				continue
+			}
+			if g.SeqNo < c.SeqNo {
+				return false
+			}
+			found = true
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
@@ -44,3 +44,16 @@ func TestEqualGtid(t *testing.T) {
 	re := list1.Equal(list2)
 	t.Log("Comparison returned ", re)
 }
+
+func TestCoversGtid(t *testing.T) {
+	source := NewList("0-1-120,1-2-40")
+	if !source.Covers(NewList("0-3-100,1-2-40"), true) {
+		t.Error("Expected per domain position to be covered")
+	}
+	if source.Covers(NewList("0-1-121"), true) || source.Covers(NewList("2-1-1"), true) {
+		t.Error("Expected ahead or unknown domain not to be covered")
+	}
+	if source.Covers(NewList("0-3-100"), false) {
+		t.Error("Expected unknown server not to be covered")
+	}
+}
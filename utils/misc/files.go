@@ -160,3 +160,51 @@ func CopyDir(src string, dst string) (err error) {
 
 	return
 }
+
+// Symlink creates link pointing to target and returns the action taken: "created", "unchanged" when link
+// already points to target or "replaced" when an existing link pointing elsewhere was removed, an existing
+// regular file is never removed and a target that would resolve back to link is refused
+func Symlink(target string, link string) (string, error) {
+	if SymlinkLoops(target, link) {
+		return "", fmt.Errorf("symlink %s to %s would form a loop", link, target)
+	}
+	action := "created"
+	if fi, err := os.Lstat(link); err == nil {
+		if fi.Mode()&os.ModeSymlink == 0 {
+			return "", fmt.Errorf("%s exists and is not a symlink", link)
+		}
+		if current, err := os.Readlink(link); err == nil && current == target {
+			return "unchanged", nil
+		}
+		if err := os.Remove(link); err != nil {
+			return "", err
+		}
+		action = "replaced"
+	}
+	return action, os.Symlink(target, link)
+}
+
+// SymlinkLoops returns true when following target, relative to the directory of link, leads back to link
+func SymlinkLoops(target string, link string) bool {
+	link = filepath.Clean(link)
+	cur := target
+	dir := filepath.Dir(link)
+	for i := 0; i < 40; i++ {
+		if !filepath.IsAbs(cur) {
+			cur = filepath.Join(dir, cur)
+		}
+		cur = filepath.Clean(cur)
+		if cur == link {
+			return true
+		}
+		fi, err := os.Lstat(cur)
+		if err != nil || fi.Mode()&os.ModeSymlink == 0 {
+			return false
+		}
+		dir = filepath.Dir(cur)
+		if cur, err = os.Readlink(cur); err != nil {
+			return false
+		}
+	}
+	return true
+}
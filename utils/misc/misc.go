@@ -14,6 +14,7 @@ import (
 	"log"
 	"net"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 )
@@ -134,6 +135,23 @@ func ExtractKey(s string, r map[string]string) string {
 	return s2
 }
 
+var envKeyRegexp = regexp.MustCompile(`%%ENV:.*?%%`)
+
+// ExtractMissingKeys returns the sorted %%ENV:...%% tokens of s that are not keys of r
+func ExtractMissingKeys(s string, r map[string]string) []string {
+	missing := []string{}
+	seen := make(map[string]bool)
+	for _, key := range envKeyRegexp.FindAllString(s, -1) {
+		if _, ok := r[key]; ok || seen[key] {
+			continue
+		}
+		seen[key] = true
+		missing = append(missing, key)
+	}
+	sort.Strings(missing)
+	return missing
+}
+
 func Unbracket(mystring string) string {
 	return strings.Replace(strings.Replace(mystring, "[", "", -1), "]", "", -1)
 }
@@ -144,3 +162,35 @@ func Bool2Int(b bool) int {
 	}
 	return 0
 }
+
+var iniKeyRegexp = regexp.MustCompile(`^[A-Za-z0-9_.-]+$`)
+
+/* Check that a my.cnf style snippet only contains sections, options, includes and comments,
+options must follow a section */
+func ValidateIniConfig(s string) error {
+	section := false
+	for i, line := range strings.Split(s, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";") {
+			continue
+		}
+		if strings.HasPrefix(line, "!include ") || strings.HasPrefix(line, "!includedir ") {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			if !strings.HasSuffix(line, "]") || len(line) < 3 {
+				return fmt.Errorf("Malformed section at line %d: %s", i+1, line)
+			}
+			section = true
+			continue
+		}
+		if !section {
+			return fmt.Errorf("Option outside of section at line %d: %s", i+1, line)
+		}
+		key := strings.TrimSpace(strings.SplitN(line, "=", 2)[0])
+		if !iniKeyRegexp.MatchString(key) {
+			return fmt.Errorf("Malformed option at line %d: %s", i+1, line)
+		}
+	}
+	return nil
+}
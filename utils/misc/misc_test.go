@@ -9,7 +9,10 @@
 
 package misc
 
-import "testing"
+import (
+	"os"
+	"testing"
+)
 
 func TestGetLocalIP(t *testing.T) {
 	ip := GetLocalIP()
@@ -39,3 +42,53 @@ func TestGetIPSafe(t *testing.T) {
 	}
 	t.Log("192.168.0.1 got ip", ip)
 }
+
+func TestValidateIniConfig(t *testing.T) {
+	valid := "# override\n[mysqld]\nmax_connections = 500\nskip-name-resolve\nloose-innodb_buffer_pool_size=1G\n\n[client]\n!includedir /etc/mysql/custom.d\n"
+	if err := ValidateIniConfig(valid); err != nil {
+		t.Fatal(err)
+	}
+	for _, invalid := range []string{"max_connections = 500\n", "[mysqld\nmax_connections = 500\n", "[mysqld]\nmax connections = 500\n"} {
+		if err := ValidateIniConfig(invalid); err == nil {
+			t.Fatalf("Expected error for %q", invalid)
+		}
+	}
+}
+
+func TestExtractMissingKeys(t *testing.T) {
+	env := map[string]string{"%%ENV:SERVER_PORT%%": "3306", "%%ENV:SERVER_HOST%%": ""}
+	content := "port=%%ENV:SERVER_PORT%%\nhost=%%ENV:SERVER_HOST%%\ndatadir=%%ENV:DATADIR%%/%%ENV:POD%%\ntmpdir=%%ENV:DATADIR%%/tmp\n"
+	missing := ExtractMissingKeys(content, env)
+	if len(missing) != 2 || missing[0] != "%%ENV:DATADIR%%" || missing[1] != "%%ENV:POD%%" {
+		t.Fatalf("Expected DATADIR and POD missing, got %v", missing)
+	}
+	if missing := ExtractMissingKeys("port=3306", env); len(missing) != 0 {
+		t.Fatalf("Expected no missing key, got %v", missing)
+	}
+}
+
+func TestSymlink(t *testing.T) {
+	dir := t.TempDir()
+	link := dir + "/conf.d/current"
+	os.MkdirAll(dir+"/conf.d", 0755)
+	os.WriteFile(dir+"/conf.d/a.cnf", []byte("[mysqld]\n"), 0644)
+	for _, c := range []struct{ target, action string }{{"a.cnf", "created"}, {"a.cnf", "unchanged"}, {"b.cnf", "replaced"}} {
+		action, err := Symlink(c.target, link)
+		if err != nil || action != c.action {
+			t.Fatalf("Got %s %v linking %s, expected %s", action, err, c.target, c.action)
+		}
+	}
+	if _, err := Symlink("current", link); err == nil {
+		t.Fatal("Expected error on self referencing symlink")
+	}
+	os.Symlink("current", dir+"/conf.d/other")
+	if _, err := Symlink("other", link); err == nil {
+		t.Fatal("Expected error on symlink loop through another link")
+	}
+	if _, err := Symlink("b.cnf", dir+"/conf.d/a.cnf"); err == nil {
+		t.Fatal("Expected error replacing a regular file")
+	}
+	if target, _ := os.Readlink(link); target != "b.cnf" {
+		t.Fatalf("Got link to %s, expected b.cnf kept", target)
+	}
+}